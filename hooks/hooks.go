@@ -0,0 +1,428 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// User hooks: shell commands run on session events.
+//
+// This is the escape hatch for everything asmgr should not grow a feature
+// for — a Slack webhook, a sound, an auto-commit. Events come from the
+// activity recorder, the same transitions the timeline and notifications
+// see, so a hook fires once per change whether the list or the attach
+// watcher noticed it. Commands run through the shell with the event in
+// ASMGR_* environment variables, in the session's directory, concurrently and
+// under a timeout; a hook that fails is reported, never retried.
+
+const ConfigFile = "hooks.json"
+
+// DefaultTimeout bounds a hook that does not set its own.
+const DefaultTimeout = 30 * time.Second
+
+// Hook names, as they appear in the configuration and in ASMGR_EVENT.
+const (
+	OnWaiting       = "on_waiting"
+	OnIdleAfterBusy = "on_idle_after_busy"
+	OnStalled       = "on_stalled"
+	OnStart         = "on_start"
+	OnStop          = "on_stop"
+	OnTabExit       = "on_tab_exit"
+	OnDiffChanged   = "on_diff_changed"
+)
+
+// Config is hooks.json: the global hooks, and how long any hook may run.
+type Config struct {
+	session.Hooks
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// ConfigPath returns the path of hooks.json.
+func ConfigPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "agent-session-manager", ConfigFile)
+}
+
+// LoadConfig reads hooks.json. A missing file is no hooks; a file that does
+// not parse is an error, since silently running none of them would be worse.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+	return cfg, nil
+}
+
+// Timeout is how long a hook may run before it is killed.
+func (c Config) Timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Command returns the command configured for the named hook.
+func Command(h session.Hooks, name string) string {
+	switch name {
+	case OnWaiting:
+		return h.OnWaiting
+	case OnIdleAfterBusy:
+		return h.OnIdleAfterBusy
+	case OnStalled:
+		return h.OnStalled
+	case OnStart:
+		return h.OnStart
+	case OnStop:
+		return h.OnStop
+	case OnTabExit:
+		return h.OnTabExit
+	case OnDiffChanged:
+		return h.OnDiffChanged
+	}
+	return ""
+}
+
+// Target is what the hooks need to know about the session an event is for,
+// captured on the update loop so the runner never reads the model.
+type Target struct {
+	Hooks   session.Hooks // Resolved: session over project over global
+	Path    string
+	Project string
+	Inst    *session.Instance // For the tab's last line
+}
+
+// Resolve merges the hooks that apply to inst.
+func Resolve(global session.Hooks, project *session.Hooks, inst *session.Instance) session.Hooks {
+	return inst.Hooks.Over(project.Over(global))
+}
+
+// hookFor names the hook an event fires, or "" for none. First sightings
+// carry From == To and are not changes.
+func hookFor(e session.ActivityEvent) string {
+	switch e.Kind {
+	case session.EventStart:
+		return OnStart
+	case session.EventStop:
+		return OnStop
+	case session.EventExit:
+		return OnTabExit
+	case session.EventActivity:
+		if e.From == e.To {
+			return ""
+		}
+		if e.To == session.ActivityWaiting {
+			return OnWaiting
+		}
+		if e.To == session.ActivityStalled {
+			return OnStalled
+		}
+		if e.From.Working() && e.To == session.ActivityIdle {
+			return OnIdleAfterBusy
+		}
+	}
+	return ""
+}
+
+// Runner runs hooks. It is shared by the list and the attach watcher, and
+// remembers each session's working tree for on_diff_changed.
+type Runner struct {
+	timeout time.Duration
+
+	mu           sync.Mutex
+	fingerprints map[string]string       // Session ID → working tree fingerprint
+	working      map[string]map[int]bool // Session ID → its tabs at work
+	// Queued events track the working tree one batch at a time, in the
+	// order they were queued: the next turn to give out, and the one whose
+	// turn it is.
+	turns         *sync.Cond
+	next, serving uint64
+}
+
+// NewRunner returns a runner with cfg's timeout.
+func NewRunner(cfg Config) *Runner {
+	r := &Runner{timeout: cfg.Timeout(), fingerprints: make(map[string]string), working: make(map[string]map[int]bool)}
+	r.turns = sync.NewCond(&r.mu)
+	return r
+}
+
+// Handle runs every hook the events call for and waits for them. Events for
+// sessions missing from targets are ignored. Blocks for up to the timeout,
+// so call it off the UI thread.
+func (r *Runner) Handle(events []session.ActivityEvent, targets map[string]Target) error {
+	return r.Queue(events, targets)()
+}
+
+// Queue puts the events in line behind those queued before, and returns
+// the function that handles them as Handle does. Call Queue in the order
+// the events happened and the function anywhere: a task's start is always
+// fingerprinted before its finish, even when the two polls' functions run
+// the other way round.
+func (r *Runner) Queue(events []session.ActivityEvent, targets map[string]Target) func() error {
+	r.mu.Lock()
+	turn := r.next
+	r.next++
+	r.mu.Unlock()
+	return func() error { return r.handle(turn, events, targets) }
+}
+
+// handle runs the hooks for events queued as turn.
+func (r *Runner) handle(turn uint64, events []session.ActivityEvent, targets map[string]Target) error {
+	changed := r.trackDiffs(turn, events, targets)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	run := func(name, command string, e session.ActivityEvent, t Target) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.run(name, command, e, t); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i, e := range events {
+		t, ok := targets[e.SessionID]
+		if !ok {
+			continue
+		}
+		if name := hookFor(e); name != "" {
+			if command := Command(t.Hooks, name); command != "" {
+				run(name, command, e, t)
+			}
+		}
+		if changed[i] {
+			run(OnDiffChanged, t.Hooks.OnDiffChanged, e, t)
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// trackDiffs waits for turn, then reports for each event whether it fires
+// on_diff_changed. One turn at a time, so every fingerprint is taken in the
+// order its event happened.
+func (r *Runner) trackDiffs(turn uint64, events []session.ActivityEvent, targets map[string]Target) []bool {
+	r.mu.Lock()
+	for r.serving != turn {
+		r.turns.Wait()
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.serving++
+		r.turns.Broadcast()
+		r.mu.Unlock()
+	}()
+
+	changed := make([]bool, len(events))
+	for i, e := range events {
+		if t, ok := targets[e.SessionID]; ok && t.Hooks.OnDiffChanged != "" {
+			changed[i] = r.diffChanged(e, t.Path)
+		}
+	}
+	return changed
+}
+
+// diffChanged reports whether e ends a task that changed the working tree.
+// The tree is fingerprinted when the first of a session's tabs starts
+// working, and again when a tab finishes; the hook fires when the two
+// differ. Once no tab is working the fingerprint is dropped, so what the user
+// edits between tasks is never taken for the next task's change. Called only
+// in turn, so no other event's fingerprint is taken meanwhile.
+func (r *Runner) diffChanged(e session.ActivityEvent, path string) bool {
+	switch {
+	case e.Kind == session.EventStop:
+		r.mu.Lock()
+		delete(r.working, e.SessionID)
+		delete(r.fingerprints, e.SessionID)
+		r.mu.Unlock()
+		return false
+	case e.Kind == session.EventExit:
+		r.mu.Lock()
+		r.stopWorking(e.SessionID, e.Window)
+		r.mu.Unlock()
+		return false
+	case e.Kind != session.EventActivity || e.From == e.To:
+		return false
+	}
+
+	r.mu.Lock()
+	tabs := r.working[e.SessionID]
+	busy := tabs[e.Window]
+	first := len(tabs) == 0
+	before, known := r.fingerprints[e.SessionID]
+	r.mu.Unlock()
+	starting := e.To.Working() && !busy
+	finishing := e.To == session.ActivityIdle && busy
+	if !starting && !finishing {
+		return false
+	}
+	if starting && !first {
+		// Another tab is at work: its fingerprint covers this task too
+		r.mu.Lock()
+		r.startWorking(e.SessionID, e.Window)
+		r.mu.Unlock()
+		return false
+	}
+
+	after, ok := Fingerprint(path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if starting {
+		r.startWorking(e.SessionID, e.Window)
+		if ok {
+			r.fingerprints[e.SessionID] = after
+		}
+		return false
+	}
+	if r.stopWorking(e.SessionID, e.Window) && ok {
+		// The tabs still at work are compared from here on
+		r.fingerprints[e.SessionID] = after
+	}
+	return ok && known && after != before
+}
+
+// startWorking marks a session's tab at work. r.mu must be held.
+func (r *Runner) startWorking(sessionID string, window int) {
+	if r.working[sessionID] == nil {
+		r.working[sessionID] = make(map[int]bool)
+	}
+	r.working[sessionID][window] = true
+}
+
+// stopWorking marks a session's tab no longer at work, and drops the
+// session's fingerprint once none is. Reports whether others still are.
+// r.mu must be held.
+func (r *Runner) stopWorking(sessionID string, window int) bool {
+	delete(r.working[sessionID], window)
+	if len(r.working[sessionID]) > 0 {
+		return true
+	}
+	delete(r.working, sessionID)
+	delete(r.fingerprints, sessionID)
+	return false
+}
+
+// run executes one hook and describes its failure, if any.
+func (r *Runner) run(name, command string, e session.ActivityEvent, t Target) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = t.Path
+	cmd.Env = append(os.Environ(), Env(name, e, t)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if last := lastLine(output.String()); last != "" {
+		err = fmt.Errorf("%w: %s", err, last)
+	}
+	return fmt.Errorf("hook %s for %s failed: %w", name, e.Session, err)
+}
+
+// Env is the environment a hook sees on top of asmgr's own.
+func Env(name string, e session.ActivityEvent, t Target) []string {
+	env := []string{
+		"ASMGR_EVENT=" + name,
+		"ASMGR_SESSION=" + e.Session,
+		"ASMGR_SESSION_ID=" + e.SessionID,
+		"ASMGR_PATH=" + t.Path,
+		"ASMGR_PROJECT=" + t.Project,
+	}
+	if e.Window == session.SessionWindow {
+		return env
+	}
+	env = append(env,
+		"ASMGR_TAB="+e.Tab,
+		"ASMGR_WINDOW="+strconv.Itoa(e.Window),
+		"ASMGR_AGENT="+string(e.Agent),
+	)
+	if e.Kind == session.EventActivity {
+		env = append(env,
+			"ASMGR_PREV_STATE="+e.From.String(),
+			"ASMGR_STATE="+e.To.String(),
+			"ASMGR_PREV_STATE_SECONDS="+strconv.Itoa(int(e.Held.Seconds())),
+		)
+	}
+	if t.Inst != nil && e.Kind != session.EventExit {
+		env = append(env, "ASMGR_LAST_LINE="+t.Inst.GetLastLineForWindow(e.Window, e.Agent))
+	}
+	return env
+}
+
+// Fingerprint summarises the working tree at dir: tracked changes against
+// HEAD, plus the size and modification time of untracked files. It never
+// writes — not even the index's stat cache — so it can run while an agent is
+// using the repository. ok is false outside a git repository.
+func Fingerprint(dir string) (fingerprint string, ok bool) {
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+		return cmd.Output()
+	}
+
+	status, err := git("status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	h.Write(status)
+	if diff, err := git("diff", "HEAD", "--no-ext-diff", "--binary"); err == nil {
+		h.Write(diff)
+	}
+	for _, entry := range strings.Split(string(status), "\x00") {
+		if !strings.HasPrefix(entry, "?? ") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, entry[3:])); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", entry[3:], info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,126 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+func activity(from, to session.SessionActivity) session.ActivityEvent {
+	return session.ActivityEvent{
+		Time: time.Now(), SessionID: "s", Session: "api", Window: 1, Tab: "tests",
+		Agent: session.AgentClaude, Kind: session.EventActivity, From: from, To: to,
+	}
+}
+
+// A session's own hook beats its project's, which beats the global one; an
+// empty command at one level falls through to the next.
+func TestResolvePrefersTheMostSpecific(t *testing.T) {
+	t.Parallel()
+
+	global := session.Hooks{OnWaiting: "global", OnStop: "global"}
+	project := &session.Hooks{OnWaiting: "project"}
+	inst := &session.Instance{Hooks: &session.Hooks{OnStart: "session"}}
+
+	got := Resolve(global, project, inst)
+	want := session.Hooks{OnWaiting: "project", OnStop: "global", OnStart: "session"}
+	if got != want {
+		t.Errorf("Resolve = %+v, want %+v", got, want)
+	}
+}
+
+// The hook learns what happened from its environment, runs in the session's
+// directory, and a failure comes back with the last thing it printed.
+func TestHandleRunsHooksWithTheEvent(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+
+	dir := t.TempDir()
+	target := Target{
+		Path: dir,
+		Hooks: session.Hooks{
+			OnWaiting: `echo "$ASMGR_SESSION $ASMGR_TAB $ASMGR_PREV_STATE $ASMGR_STATE" > out`,
+			OnStop:    `echo "webhook refused" >&2; exit 3`,
+		},
+	}
+	runner := NewRunner(Config{})
+
+	if err := runner.Handle([]session.ActivityEvent{activity(session.ActivityBusy, session.ActivityWaiting)}, map[string]Target{"s": target}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "api tests busy waiting" {
+		t.Errorf("hook saw %q", got)
+	}
+
+	stop := session.ActivityEvent{SessionID: "s", Session: "api", Window: session.SessionWindow, Kind: session.EventStop}
+	err = runner.Handle([]session.ActivityEvent{stop}, map[string]Target{"s": target})
+	if err == nil || !strings.Contains(err.Error(), "on_stop") || !strings.Contains(err.Error(), "webhook refused") {
+		t.Errorf("failure reported as %v", err)
+	}
+}
+
+// on_diff_changed compares the tree when work started with the tree when it
+// finished: a task that only talked does not fire it, one that wrote does,
+// and the user's own edits between tasks are not taken for the next one's.
+func TestDiffChangedNeedsAChangedTree(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git")
+	}
+
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	target := Target{Path: dir, Hooks: session.Hooks{OnDiffChanged: "echo x >> fired"}}
+	targets := map[string]Target{"s": target}
+	runner := NewRunner(Config{})
+	fired := func() int {
+		data, _ := os.ReadFile(filepath.Join(dir, "fired"))
+		return strings.Count(string(data), "x")
+	}
+	task := func(work func()) {
+		runner.Handle([]session.ActivityEvent{activity(session.ActivityIdle, session.ActivityBusy)}, targets)
+		work()
+		runner.Handle([]session.ActivityEvent{activity(session.ActivityBusy, session.ActivityIdle)}, targets)
+	}
+
+	task(func() {})
+	if n := fired(); n != 0 {
+		t.Fatalf("fired %d times for a task that changed nothing", n)
+	}
+	task(func() { os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644) })
+	if n := fired(); n != 1 {
+		t.Fatalf("fired %d times for a task that wrote a file, want 1", n)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine\n"), 0644)
+	task(func() {})
+	if n := fired(); n != 1 {
+		t.Fatalf("fired %d times: an edit made between tasks was taken for the task's", n)
+	}
+
+	// Two polls' commands run the other way round: the finish waits for the
+	// start, instead of being missed and leaving the tab at work for good
+	start := runner.Queue([]session.ActivityEvent{activity(session.ActivityIdle, session.ActivityBusy)}, targets)
+	finish := runner.Queue([]session.ActivityEvent{activity(session.ActivityBusy, session.ActivityIdle)}, targets)
+	done := make(chan error)
+	go func() { done <- finish() }()
+	start()
+	<-done
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	if len(runner.working) != 0 {
+		t.Errorf("still at work after the finish: %v", runner.working)
+	}
+}
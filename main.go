@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -62,6 +63,46 @@ func main() {
 				fmt.Printf("Detection patterns already current (version %d)\n", version)
 			}
 			return
+		case "--install-claude-hooks", "--remove-claude-hooks":
+			if err := setupClaudeHooks(os.Args[1] == "--install-claude-hooks"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "hook":
+			// Run by Claude Code on its own events, with the event on stdin.
+			// Always exits 0 and never writes to stdout: Claude shows a
+			// failing hook to the user, and feeds some hooks' stdout to the
+			// model.
+			session.SetTmuxBinary(os.Getenv("ASMGR_TMUX"))
+			recordClaudeHook()
+			return
+		case "--install-codex-notify", "--remove-codex-notify":
+			if err := setupCodexNotify(os.Args[1] == "--install-codex-notify"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "codex-notify":
+			// Run by Codex with the event as its last argument. As with the
+			// Claude hook, a failure is never Codex's problem.
+			session.SetTmuxBinary(os.Getenv("ASMGR_TMUX"))
+			recordCodexNotify(os.Args[2:])
+			return
+		case "send":
+			session.SetTmuxBinary(os.Getenv("ASMGR_TMUX"))
+			if err := runSend(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "scheduler":
+			session.SetTmuxBinary(os.Getenv("ASMGR_TMUX"))
+			if err := runScheduler(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		case "refresh-status":
 			if len(os.Args) < 3 {
 				os.Exit(1)
@@ -127,16 +168,115 @@ func printHelp() {
 	fmt.Printf(`%s - Agent Session Manager
 
 Usage: %s [options]
+       %s send [--project NAME] [--snippet NAME] [--var LABEL=VALUE]... SESSION [TEXT...]
+       %s scheduler [--once] [--interval 30s]
 
 Options:
   -v, --version    Show version
   -u, --update     Update to latest version
       --refresh-patterns
                    Re-fetch the activity-detection patterns now
+      --install-claude-hooks
+                   Let Claude Code report its state to %s through hooks
+      --remove-claude-hooks
+                   Take those hooks out of Claude Code's settings again
+      --install-codex-notify
+                   Let Codex report finished turns to %s through notify
+      --remove-codex-notify
+                   Take that notify setting out of Codex's config again
   -h, --help       Show this help
 
-Run without arguments to start the TUI.
-`, ui.AppName, ui.AppName)
+Run without arguments to start the TUI. send types a prompt, or a snippet
+with its variables filled in, into a running session's main agent.
+scheduler sends the scheduled prompts of projects not open in the TUI.
+`, ui.AppName, ui.AppName, ui.AppName, ui.AppName, ui.AppName, ui.AppName)
+}
+
+// setupClaudeHooks installs or removes the Claude Code hooks that run
+// `asmgr hook`, in Claude's user settings.
+func setupClaudeHooks(install bool) error {
+	path, err := session.ClaudeSettingsPath()
+	if err != nil {
+		return err
+	}
+	if !install {
+		if err := session.RemoveClaudeHooks(path); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s hooks from %s\n", ui.AppName, path)
+		return nil
+	}
+	command, err := session.ClaudeHookCommand()
+	if err != nil {
+		return err
+	}
+	if err := session.InstallClaudeHooks(path, command); err != nil {
+		return err
+	}
+	fmt.Printf("Installed hooks in %s\n", path)
+	fmt.Println("Claude sessions started from now on report their state and session ID.")
+	return nil
+}
+
+// recordClaudeHook stores the hook event on stdin as the report of the
+// window it came from. Claude sessions outside asmgr run the hooks too, and
+// are ignored.
+func recordClaudeHook() {
+	payload, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return
+	}
+	target, ok := session.ReportTarget()
+	if !ok {
+		return
+	}
+	if err := session.RecordClaudeHook(target, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "%s hook: %v\n", ui.AppName, err)
+	}
+}
+
+// setupCodexNotify installs or removes the notify setting that runs
+// `asmgr codex-notify`, in Codex's config.
+func setupCodexNotify(install bool) error {
+	path, err := session.CodexConfigPath()
+	if err != nil {
+		return err
+	}
+	if !install {
+		if err := session.RemoveCodexNotify(path); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s notify from %s\n", ui.AppName, path)
+		return nil
+	}
+	line, err := session.CodexNotifyLine()
+	if err != nil {
+		return err
+	}
+	if err := session.InstallCodexNotify(path, line); err != nil {
+		return err
+	}
+	fmt.Printf("Set notify in %s\n", path)
+	fmt.Println("Codex sessions started from now on report finished turns and their session ID.")
+	return nil
+}
+
+// recordCodexNotify stores the notify event in args as the report of the
+// window it came from. Codex passes it as the last argument; nothing is
+// read from stdin, so the hook never waits on a terminal.
+func recordCodexNotify(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "%s codex-notify: no event given\n", ui.AppName)
+		return
+	}
+	payload := []byte(args[len(args)-1])
+	target, ok := session.ReportTarget()
+	if !ok {
+		return
+	}
+	if err := session.RecordCodexNotify(target, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "%s codex-notify: %v\n", ui.AppName, err)
+	}
 }
 
 func runUpdate() error {
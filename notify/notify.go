@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Telling the user an agent needs them, without them watching the list.
+//
+// Three channels, because no one of them reaches everyone: a desktop
+// notification needs a notification daemon and a desktop; an OSC escape needs
+// a terminal that understands it (iTerm2, WezTerm, kitty, foot, Windows
+// Terminal…); the bell reaches anything with a speaker or a visual bell, and
+// says nothing else. Each is attempted independently and failures are quiet —
+// a missing notify-send is a setup, not an error.
+
+const ConfigFile = "notifications.json"
+
+// Config is notifications.json. Every field has a default, so the file only
+// needs the settings being changed.
+type Config struct {
+	Enabled bool `json:"enabled"`
+	Desktop bool `json:"desktop"` // notify-send / D-Bus on Linux, osascript on macOS
+	// Terminal picks the escape sequence: "osc9", "osc777" or "off".
+	Terminal string `json:"terminal"`
+	Bell     bool   `json:"bell"`
+
+	OnWaiting       bool `json:"on_waiting"`         // A tab starts waiting on you
+	OnIdleAfterBusy bool `json:"on_idle_after_busy"` // A tab finishes working
+	OnStalled       bool `json:"on_stalled"`         // A tab stops making progress
+	// MinBusySeconds keeps short replies quiet: a tab has to have been busy
+	// at least this long before finishing is worth a notification.
+	MinBusySeconds int `json:"min_busy_seconds"`
+
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// QuietHours is a daily window with no notifications, in local time. Start
+// after End wraps past midnight.
+type QuietHours struct {
+	Start string `json:"start"` // "22:00"
+	End   string `json:"end"`   // "08:00"
+}
+
+// DefaultConfig is what applies without a notifications.json.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:         true,
+		Desktop:         true,
+		Terminal:        "osc9",
+		OnWaiting:       true,
+		OnIdleAfterBusy: true,
+		OnStalled:       true,
+		MinBusySeconds:  30,
+	}
+}
+
+// ConfigPath returns the path of notifications.json.
+func ConfigPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "agent-session-manager", ConfigFile)
+}
+
+// LoadConfig reads notifications.json over the defaults. A file that does not
+// parse is ignored rather than turning notifications off.
+func LoadConfig() Config {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		return cfg
+	}
+	loaded := cfg
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return cfg
+	}
+	return loaded
+}
+
+// InQuietHours reports whether t falls in the configured quiet window.
+func (c Config) InQuietHours(t time.Time) bool {
+	if c.QuietHours == nil {
+		return false
+	}
+	start, okStart := minutesOfDay(c.QuietHours.Start)
+	end, okEnd := minutesOfDay(c.QuietHours.End)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+func minutesOfDay(hhmm string) (int, bool) {
+	var h, m int
+	if _, err := fmt.Sscanf(hhmm, "%d:%d", &h, &m); err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// Wants reports whether an activity event is worth a notification at t.
+func (c Config) Wants(e session.ActivityEvent, t time.Time) bool {
+	if !c.Enabled || c.InQuietHours(t) {
+		return false
+	}
+	// First sightings carry From == To; nothing changed that anyone saw.
+	if e.Kind != session.EventActivity || e.From == e.To {
+		return false
+	}
+	switch {
+	case e.To == session.ActivityWaiting:
+		return c.OnWaiting
+	case e.To == session.ActivityStalled:
+		return c.OnStalled
+	case e.From.Working() && e.To == session.ActivityIdle:
+		return c.OnIdleAfterBusy && e.Held >= time.Duration(c.MinBusySeconds)*time.Second
+	}
+	return false
+}
+
+// Notification is one message to deliver.
+type Notification struct {
+	Title string
+	Body  string
+}
+
+// ForEvent words the notification for an event Wants accepted.
+func ForEvent(e session.ActivityEvent) Notification {
+	tab := e.Tab
+	if tab == "" {
+		tab = string(e.Agent)
+	}
+	if e.To == session.ActivityWaiting {
+		return Notification{
+			Title: fmt.Sprintf("%s is waiting", e.Session),
+			Body:  fmt.Sprintf("%s needs your input", tab),
+		}
+	}
+	if e.To == session.ActivityStalled {
+		return Notification{
+			Title: fmt.Sprintf("%s is stalled", e.Session),
+			Body:  fmt.Sprintf("%s is busy but has stopped making progress", tab),
+		}
+	}
+	return Notification{
+		Title: fmt.Sprintf("%s is done", e.Session),
+		Body:  fmt.Sprintf("%s finished after %s", tab, e.Held.Round(time.Second)),
+	}
+}
+
+// Send delivers n on every enabled channel. Blocks while the desktop
+// notifier runs, so call it off the UI thread.
+func Send(c Config, n Notification) {
+	if c.Terminal == "osc9" || c.Terminal == "osc777" || c.Bell {
+		writeTerminal(c, n)
+	}
+	if c.Desktop {
+		sendDesktop(n)
+	}
+}
+
+// writeTerminal writes the escape sequences to the controlling terminal.
+// Standard output belongs to the interface — or, while attached, to tmux —
+// so the tty is opened directly.
+func writeTerminal(c Config, n Notification) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+
+	var seq string
+	switch c.Terminal {
+	case "osc9":
+		seq = "\033]9;" + oscSafe(n.Title+": "+n.Body) + "\a"
+	case "osc777":
+		// Fields are separated by semicolons, so the title cannot contain one.
+		title := strings.ReplaceAll(n.Title, ";", ",")
+		seq = "\033]777;notify;" + oscSafe(title) + ";" + oscSafe(n.Body) + "\a"
+	}
+	if seq != "" && os.Getenv("TMUX") != "" {
+		// Inside tmux the sequence has to be passed through explicitly, with
+		// every escape doubled; it also needs allow-passthrough on.
+		seq = "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	}
+	if c.Bell {
+		seq += "\a"
+	}
+	tty.WriteString(seq)
+}
+
+// oscSafe removes what would end or corrupt an OSC string early.
+func oscSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// sendDesktop raises a desktop notification with whatever the platform has.
+func sendDesktop(n Notification) {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if path, err := exec.LookPath("notify-send"); err == nil {
+			exec.Command(path, "--app-name=asmgr", n.Title, n.Body).Run()
+			return
+		}
+		// No libnotify client: talk to the notification daemon directly.
+		if path, err := exec.LookPath("gdbus"); err == nil {
+			exec.Command(path, "call", "--session",
+				"--dest", "org.freedesktop.Notifications",
+				"--object-path", "/org/freedesktop/Notifications",
+				"--method", "org.freedesktop.Notifications.Notify",
+				"asmgr", "0", "", n.Title, n.Body, "[]", "{}", "-1").Run()
+		}
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", n.Body, n.Title)
+		exec.Command("osascript", "-e", script).Run()
+	}
+}
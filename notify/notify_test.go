@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Quiet hours are local wall-clock times and may wrap midnight, which is
+// the usual case: "22:00" to "08:00".
+func TestQuietHoursWrapMidnight(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConfig()
+	cfg.QuietHours = &QuietHours{Start: "22:00", End: "08:00"}
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.Local) }
+
+	for _, quiet := range []time.Time{at(22, 0), at(23, 59), at(3, 0), at(7, 59)} {
+		if !cfg.InQuietHours(quiet) {
+			t.Errorf("%s should be quiet", quiet.Format("15:04"))
+		}
+	}
+	for _, loud := range []time.Time{at(8, 0), at(12, 0), at(21, 59)} {
+		if cfg.InQuietHours(loud) {
+			t.Errorf("%s should not be quiet", loud.Format("15:04"))
+		}
+	}
+}
+
+// Starting to wait always matters; finishing only matters after real work,
+// or every one-line reply would ping.
+func TestWantsFiltersShortReplies(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConfig()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	event := func(from, to session.SessionActivity, held time.Duration) session.ActivityEvent {
+		return session.ActivityEvent{Kind: session.EventActivity, From: from, To: to, Held: held}
+	}
+
+	if !cfg.Wants(event(session.ActivityBusy, session.ActivityWaiting, time.Second), now) {
+		t.Error("a tab starting to wait was not announced")
+	}
+	if cfg.Wants(event(session.ActivityBusy, session.ActivityIdle, 5*time.Second), now) {
+		t.Error("a five-second reply was announced")
+	}
+	if !cfg.Wants(event(session.ActivityBusy, session.ActivityIdle, 2*time.Minute), now) {
+		t.Error("a two-minute task finishing was not announced")
+	}
+	if cfg.Wants(event(session.ActivityWaiting, session.ActivityWaiting, 0), now) {
+		t.Error("a first sighting was announced as a change")
+	}
+}
+
+// A tab that stalls is announced, and one that gives up or is interrupted
+// afterwards has finished, the same as a tab that was busy to the end.
+func TestWantsStalledTabs(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConfig()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	stalled := session.ActivityEvent{Kind: session.EventActivity, From: session.ActivityBusy, To: session.ActivityStalled}
+	if !cfg.Wants(stalled, now) {
+		t.Error("a tab stalling was not announced")
+	}
+	if n := ForEvent(stalled); !strings.Contains(n.Title, "stalled") {
+		t.Errorf("stall worded as %q", n.Title)
+	}
+	finished := session.ActivityEvent{Kind: session.EventActivity, From: session.ActivityStalled, To: session.ActivityIdle, Held: 10 * time.Minute}
+	if !cfg.Wants(finished, now) {
+		t.Error("a stalled tab going idle was not announced as finished")
+	}
+	cfg.OnStalled = false
+	if cfg.Wants(stalled, now) {
+		t.Error("on_stalled off still announced a stall")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/izll/agent-session-manager/notify"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// runScheduler runs the scheduled prompts of every project not open in the
+// TUI, until interrupted, or once with --once for a crontab or systemd timer.
+// A project the TUI has open is locked, and its schedules are left to it;
+// the scheduler locks a project itself while making its runs, so the TUI
+// cannot open it and write sessions and schedules the runs then overwrite.
+func runScheduler(args []string) error {
+	flags := flag.NewFlagSet("scheduler", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	once := flags.Bool("once", false, "check once and exit")
+	interval := flags.Duration("interval", 30*time.Second, "how often to check")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w\nusage: scheduler [--once] [--interval 30s]", err)
+	}
+
+	storage, err := session.NewStorage()
+	if err != nil {
+		return err
+	}
+	if err := storage.LockScheduler(); err != nil {
+		return err
+	}
+	defer storage.UnlockScheduler()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		checkAllSchedules(storage)
+		if *once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAllSchedules makes the due runs of every unlocked project, holding
+// its lock meanwhile, and reports the missed ones.
+func checkAllSchedules(storage *session.Storage) {
+	projectIDs := []string{""}
+	names := map[string]string{"": "default"}
+	if projectsData, err := storage.LoadProjects(); err == nil && projectsData != nil {
+		for _, p := range projectsData.Projects {
+			projectIDs = append(projectIDs, p.ID)
+			names[p.ID] = p.Name
+		}
+	}
+
+	for _, id := range projectIDs {
+		if locked, _ := storage.IsProjectLocked(id); locked {
+			continue
+		}
+		if err := storage.LockProject(id); err != nil {
+			logSchedule(names[id], "%v", err)
+			continue
+		}
+		if err := storage.SetActiveProject(id); err != nil {
+			logSchedule(names[id], "%v", err)
+		} else if err := checkProjectSchedules(storage, names[id]); err != nil {
+			logSchedule(names[id], "%v", err)
+		}
+		storage.UnlockProject()
+	}
+}
+
+// checkProjectSchedules makes the due runs of the active project.
+func checkProjectSchedules(storage *session.Storage, project string) error {
+	path := storage.SchedulesPath()
+	schedules, err := session.LoadSchedules(path)
+	if err != nil || len(schedules) == 0 {
+		return err
+	}
+	now := time.Now()
+	runs, missed := session.TakeDueSchedules(schedules, now)
+	if len(runs) == 0 && len(missed) == 0 {
+		return nil
+	}
+	// Taken before they are made, as the TUI does
+	if err := session.SaveSchedules(path, schedules); err != nil {
+		return err
+	}
+
+	instances, _, err := storage.LoadAll()
+	if err != nil {
+		return err
+	}
+	history := session.NewPromptHistory(storage.PromptHistoryPath())
+	byID := make(map[string]*session.Instance)
+	for _, inst := range instances {
+		byID[inst.ID] = inst
+	}
+
+	count := 0
+	for _, run := range missed {
+		count += len(run.Missed)
+		logSchedule(project, "missed %d run(s) of %q from %s", len(run.Missed), run.Schedule.Text, run.Due.Format("Jan 2 15:04"))
+	}
+	if count > 0 {
+		notify.Send(notify.LoadConfig(), notify.Notification{
+			Title: "Scheduled prompts missed",
+			Body:  fmt.Sprintf("%d scheduled run(s) in %s were not made on time", count, project),
+		})
+	}
+
+	for _, run := range runs {
+		inst := byID[run.Schedule.SessionID]
+		if inst == nil {
+			err = fmt.Errorf("the session no longer exists")
+		} else {
+			wasAlive := inst.IsAlive()
+			err = session.RunSchedule(inst, run, history)
+			if !wasAlive && inst.IsAlive() {
+				inst.UpdateStatus()
+				if saveErr := storage.UpdateInstance(inst); saveErr != nil {
+					logSchedule(project, "%v", saveErr)
+				}
+			}
+		}
+		session.RecordScheduleRun(schedules, run, time.Now(), err)
+		target := run.Schedule.SessionID
+		if inst != nil {
+			target = inst.Name
+		}
+		if err != nil {
+			logSchedule(project, "%s: %v", target, err)
+		} else {
+			logSchedule(project, "%s: sent %q", target, run.Schedule.Text)
+		}
+	}
+	return session.SaveSchedules(path, schedules)
+}
+
+// logSchedule prints one line of the scheduler's log.
+func logSchedule(project, format string, args ...any) {
+	fmt.Printf("%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), project, fmt.Sprintf(format, args...))
+}
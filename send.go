@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// varFlags collects repeated --var LABEL=VALUE answers to a snippet's
+// {{ask:LABEL}} questions.
+type varFlags map[string]string
+
+func (v varFlags) String() string { return "" }
+
+func (v varFlags) Set(value string) error {
+	label, answer, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected LABEL=VALUE, got %q", value)
+	}
+	v[strings.TrimSpace(label)] = answer
+	return nil
+}
+
+// runSend types a prompt into a running session's main agent: the text
+// given, a snippet, or a snippet followed by the text. For scripts and
+// editor bindings, which cannot answer questions — a snippet's {{ask:...}}
+// fields are all given with --var.
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	project := flags.String("project", "", "project name or ID")
+	snippetName := flags.String("snippet", "", "snippet to send")
+	answers := varFlags{}
+	flags.Var(answers, "var", "answer to an {{ask:...}} field, as LABEL=VALUE")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w\nusage: send [--project NAME] [--snippet NAME] [--var LABEL=VALUE]... SESSION [TEXT...]", err)
+	}
+	if flags.NArg() < 1 {
+		return fmt.Errorf("usage: send [--project NAME] [--snippet NAME] [--var LABEL=VALUE]... SESSION [TEXT...]")
+	}
+	name := flags.Arg(0)
+	text := strings.Join(flags.Args()[1:], " ")
+	if text == "" && *snippetName == "" {
+		return fmt.Errorf("nothing to send: give the text or --snippet")
+	}
+
+	inst, settings, storage, err := findSessionForSend(*project, name)
+	if err != nil {
+		return err
+	}
+	if !inst.IsAlive() {
+		return fmt.Errorf("session %s is not running", inst.Name)
+	}
+
+	if *snippetName != "" {
+		snippets, err := session.LoadSnippets(settings.Snippets, inst.Path)
+		if err != nil {
+			return err
+		}
+		snippet, ok := session.FindSnippet(snippets, *snippetName)
+		if !ok {
+			return fmt.Errorf("no snippet named %q", *snippetName)
+		}
+		expanded, err := session.ExpandSnippet(snippet.Text, session.SnippetContext{
+			Instance:  inst,
+			Answers:   answers,
+			Clipboard: clipboard.ReadAll,
+		})
+		if err != nil {
+			return fmt.Errorf("snippet %s: %w", snippet.Name, err)
+		}
+		if text != "" {
+			expanded += "\n\n" + text
+		}
+		text = expanded
+	}
+
+	window := inst.GetMainWindowIndex()
+	activity := inst.DetectActivityForWindow(window)
+	if err := inst.SendPromptToWindow(window, text); err != nil {
+		return err
+	}
+	history := session.NewPromptHistory(storage.PromptHistoryPath())
+	history.Append(inst.SentPrompt(window, text, session.SentFromCLI, activity))
+	return nil
+}
+
+// findSessionForSend finds a session by name or ID, with its project's
+// settings and a storage on its project: in the named project, or else the
+// default project first and then every other one.
+func findSessionForSend(projectName, name string) (*session.Instance, *session.Settings, *session.Storage, error) {
+	storage, err := session.NewStorage()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	projectIDs := []string{""}
+	projectsData, err := storage.LoadProjects()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if projectsData == nil {
+		projectsData = &session.ProjectsData{}
+	}
+	if projectName != "" {
+		projectIDs = nil
+		for _, p := range projectsData.Projects {
+			if p.Name == projectName || p.ID == projectName {
+				projectIDs = []string{p.ID}
+				break
+			}
+		}
+		if projectIDs == nil {
+			return nil, nil, nil, fmt.Errorf("no project named %q", projectName)
+		}
+	} else {
+		for _, p := range projectsData.Projects {
+			projectIDs = append(projectIDs, p.ID)
+		}
+	}
+
+	for _, id := range projectIDs {
+		if err := storage.SetActiveProject(id); err != nil {
+			return nil, nil, nil, err
+		}
+		instances, _, settings, err := storage.LoadAllWithSettings()
+		if err != nil {
+			continue
+		}
+		for _, inst := range instances {
+			if inst.Name == name || inst.ID == name {
+				if settings == nil {
+					settings = &session.Settings{}
+				}
+				return inst, settings, storage, nil
+			}
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("no session named %q", name)
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The activity log: what each tab was doing, and when it changed.
+//
+// The poller only ever holds the latest reading, which answers "what is it
+// doing now" and nothing else. How long an agent sat waiting for a human, or
+// how many permission prompts a session raised this afternoon, needs the
+// transitions themselves — so every change the poller sees is appended to a
+// per-project JSONL file next to sessions.json, one event per line.
+//
+// It records what asmgr saw, not what happened. While asmgr is closed the
+// agents carry on in tmux unobserved, and the log simply holds the last state
+// until the next reading says otherwise.
+
+// ActivityEventKind says what an ActivityEvent records.
+type ActivityEventKind string
+
+const (
+	EventActivity ActivityEventKind = "activity" // A tab changed state
+	EventStart    ActivityEventKind = "start"    // The session started
+	EventStop     ActivityEventKind = "stop"     // The session stopped
+	EventExit     ActivityEventKind = "exit"     // A tab's process exited
+)
+
+// ActivityEvent is one line of the activity log.
+type ActivityEvent struct {
+	Time      time.Time         `json:"time"`
+	SessionID string            `json:"session_id"`
+	Session   string            `json:"session,omitempty"` // Name at the time, for reading the file by hand
+	Window    int               `json:"window"`
+	Tab       string            `json:"tab,omitempty"`
+	Agent     AgentType         `json:"agent,omitempty"`
+	Kind      ActivityEventKind `json:"kind"`
+	From      SessionActivity   `json:"from"`
+	To        SessionActivity   `json:"to"`
+	// Held is how long the tab had been in From, when that is known. It is
+	// what lets a busy→idle event tell a long task from a one-line reply.
+	Held time.Duration `json:"held,omitempty"`
+}
+
+const (
+	activityLogFile = "activity.jsonl"
+	// Past this size the log is rewritten without its oldest entries. Checked
+	// when a project opens, not per append, so a long-running session never
+	// pays for it.
+	activityLogMaxBytes  = 4 << 20
+	activityLogRetention = 14 * 24 * time.Hour
+)
+
+// ActivityLogPath returns the active project's activity log, next to its
+// sessions.json.
+func (s *Storage) ActivityLogPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), activityLogFile)
+}
+
+// ActivityLog appends to and reads one project's activity log.
+type ActivityLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewActivityLog returns a log backed by the given file. The file is created
+// on the first append.
+func NewActivityLog(path string) *ActivityLog {
+	return &ActivityLog{path: path}
+}
+
+// Append writes the events at the end of the log.
+func (l *ActivityLog) Append(events ...ActivityEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open activity log: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return fmt.Errorf("failed to write activity log: %w", err)
+		}
+	}
+	return w.Flush()
+}
+
+// Since returns the events at or after t, oldest first. Lines that do not
+// parse are skipped: one torn write must not cost the whole history.
+func (l *ActivityLog) Since(t time.Time) ([]ActivityEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readSince(t)
+}
+
+func (l *ActivityLog) readSince(t time.Time) ([]ActivityEvent, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity log: %w", err)
+	}
+	defer f.Close()
+
+	var events []ActivityEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event ActivityEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.Time.Before(t) {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return events, fmt.Errorf("failed to read activity log: %w", err)
+	}
+
+	// Appends are in order already; the sort only matters if two processes
+	// ever interleaved, and keeps every reader's assumption true regardless.
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+	return events, nil
+}
+
+// Compact drops entries older than the retention period once the file has
+// grown past its size limit. If what is left is still over it — a busy
+// fortnight — the oldest of those go too, down to half the limit, so the
+// next open does not compact again straight away.
+func (l *ActivityLog) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if err != nil || info.Size() < activityLogMaxBytes {
+		return nil
+	}
+	events, err := l.readSince(time.Now().Add(-activityLogRetention))
+	if err != nil {
+		return err
+	}
+	lines := make([][]byte, 0, len(events))
+	size := 0
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, line)
+		size += len(line) + 1
+	}
+	if size >= activityLogMaxBytes {
+		for len(lines) > 0 && size > activityLogMaxBytes/2 {
+			size -= len(lines[0]) + 1
+			lines = lines[1:]
+		}
+	}
+
+	tmp := l.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact activity log: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact activity log: %w", err)
+	}
+	f.Close()
+	return os.Rename(tmp, l.path)
+}
@@ -0,0 +1,428 @@
+package session
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Turning polls into events, and events back into a timeline.
+//
+// A poll is a snapshot; the log wants changes. ActivityRecorder sits between
+// the two, remembering the last state it recorded for every tab so that each
+// poll only produces the tabs that actually moved. It is seeded from the log
+// on open, which is what stops every restart of asmgr from logging every tab
+// again as if it were new.
+
+// TabObservation is one tab as a poll saw it.
+type TabObservation struct {
+	Window   int
+	Tab      string
+	Agent    AgentType
+	Activity SessionActivity
+	Dead     bool // The tab's process has exited
+	// Unreadable marks a failed capture. Nothing is known about the tab this
+	// time, and recording idle for it would invent idle time.
+	Unreadable bool
+}
+
+// SessionObservation is one session as a poll saw it.
+type SessionObservation struct {
+	ID      string
+	Name    string
+	Stopped bool
+	Tabs    []TabObservation
+}
+
+// SessionWindow marks events about a whole session rather than one tab.
+const SessionWindow = -1
+
+// recentActivityRetention is how much history the recorder keeps in memory:
+// enough for the longest timeline range, with some slack.
+const recentActivityRetention = 25 * time.Hour
+
+type tabKey struct {
+	session string
+	window  int
+}
+
+type tabRecord struct {
+	activity SessionActivity
+	since    time.Time
+	dead     bool
+	tab      string
+	agent    AgentType
+	name     string // Session name
+}
+
+// activityReplay is the state an event stream leaves behind.
+type activityReplay struct {
+	tabs    map[tabKey]tabRecord
+	running map[string]bool
+}
+
+func newActivityReplay() activityReplay {
+	return activityReplay{tabs: map[tabKey]tabRecord{}, running: map[string]bool{}}
+}
+
+func (r activityReplay) clone() activityReplay {
+	c := newActivityReplay()
+	for k, v := range r.tabs {
+		c.tabs[k] = v
+	}
+	for k, v := range r.running {
+		c.running[k] = v
+	}
+	return c
+}
+
+func (r activityReplay) apply(e ActivityEvent) {
+	switch e.Kind {
+	case EventActivity:
+		r.running[e.SessionID] = true
+		r.tabs[tabKey{e.SessionID, e.Window}] = tabRecord{
+			activity: e.To, since: e.Time, tab: e.Tab, agent: e.Agent, name: e.Session,
+		}
+	case EventExit:
+		r.tabs[tabKey{e.SessionID, e.Window}] = tabRecord{
+			since: e.Time, dead: true, tab: e.Tab, agent: e.Agent, name: e.Session,
+		}
+	case EventStart:
+		r.running[e.SessionID] = true
+	case EventStop:
+		r.running[e.SessionID] = false
+		for k := range r.tabs {
+			if k.session == e.SessionID {
+				delete(r.tabs, k)
+			}
+		}
+	}
+}
+
+// ActivityRecorder turns successive polls into activity events and keeps the
+// last day of them in memory for the timeline.
+type ActivityRecorder struct {
+	log *ActivityLog
+
+	// The recorder is shared with the watcher that runs while a session is
+	// attached, so it guards itself rather than relying on the update loop.
+	mu       sync.Mutex
+	current  activityReplay  // State after every recorded event
+	baseline activityReplay  // State as of horizon
+	horizon  time.Time       // Oldest moment recent covers
+	recent   []ActivityEvent // Events after horizon, oldest first
+}
+
+// NewActivityRecorder returns a recorder writing to log. A nil log records
+// in memory only.
+func NewActivityRecorder(log *ActivityLog) *ActivityRecorder {
+	return &ActivityRecorder{
+		log:      log,
+		current:  newActivityReplay(),
+		baseline: newActivityReplay(),
+	}
+}
+
+// Load seeds the recorder from its log, so the first poll after opening a
+// project is compared against what was last recorded rather than nothing.
+func (r *ActivityRecorder) Load(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = newActivityReplay()
+	r.baseline = newActivityReplay()
+	r.horizon = now.Add(-recentActivityRetention)
+	r.recent = nil
+	if r.log == nil {
+		return nil
+	}
+
+	r.log.Compact()
+	events, err := r.log.Since(time.Time{})
+	for _, e := range events {
+		if e.Time.Before(r.horizon) {
+			r.baseline.apply(e)
+		} else {
+			r.recent = append(r.recent, e)
+		}
+	}
+	r.current = r.baseline.clone()
+	for _, e := range r.recent {
+		r.current.apply(e)
+	}
+	return err
+}
+
+// Record compares a poll with the last recorded state, and logs and returns
+// whatever changed. Sessions missing from observations are left as they were.
+func (r *ActivityRecorder) Record(now time.Time, observations []SessionObservation) ([]ActivityEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []ActivityEvent
+	for _, obs := range observations {
+		events = append(events, r.diff(now, obs)...)
+	}
+	for _, e := range events {
+		r.current.apply(e)
+	}
+	r.recent = append(r.recent, events...)
+	r.prune(now)
+
+	if r.log == nil {
+		return events, nil
+	}
+	return events, r.log.Append(events...)
+}
+
+// diff works out the events that take the recorded state to obs.
+func (r *ActivityRecorder) diff(now time.Time, obs SessionObservation) []ActivityEvent {
+	var events []ActivityEvent
+	session := ActivityEvent{Time: now, SessionID: obs.ID, Session: obs.Name, Window: SessionWindow}
+
+	running, known := r.current.running[obs.ID]
+	if obs.Stopped {
+		if known && running {
+			e := session
+			e.Kind = EventStop
+			events = append(events, e)
+		} else if !known {
+			r.current.running[obs.ID] = false
+		}
+		return events
+	}
+	if known && !running {
+		e := session
+		e.Kind = EventStart
+		events = append(events, e)
+	}
+
+	observed := make(map[int]bool, len(obs.Tabs))
+	for _, tab := range obs.Tabs {
+		observed[tab.Window] = true
+		if tab.Unreadable {
+			continue
+		}
+		key := tabKey{obs.ID, tab.Window}
+		rec, seen := r.current.tabs[key]
+		if known && !running {
+			// Everything was cleared by the stop; this is a fresh start.
+			seen = false
+		}
+		e := ActivityEvent{
+			Time: now, SessionID: obs.ID, Session: obs.Name,
+			Window: tab.Window, Tab: tab.Tab, Agent: tab.Agent,
+		}
+
+		switch {
+		case tab.Dead:
+			if seen && !rec.dead {
+				e.Kind = EventExit
+				e.From = rec.activity
+				e.Held = now.Sub(rec.since)
+				events = append(events, e)
+			} else if !seen {
+				r.current.tabs[key] = tabRecord{since: now, dead: true, tab: tab.Tab, agent: tab.Agent, name: obs.Name}
+			}
+		case !seen || rec.dead:
+			// First sighting, or a respawned tab. From equals To: nothing
+			// changed as far as anyone watching could tell, but the timeline
+			// needs to know the tab exists and what it was doing.
+			e.Kind = EventActivity
+			e.From = tab.Activity
+			e.To = tab.Activity
+			events = append(events, e)
+		case rec.activity != tab.Activity:
+			e.Kind = EventActivity
+			e.From = rec.activity
+			e.To = tab.Activity
+			e.Held = now.Sub(rec.since)
+			events = append(events, e)
+		}
+	}
+
+	// A tab that is no longer there was closed.
+	for key, rec := range r.current.tabs {
+		if key.session != obs.ID || observed[key.window] || rec.dead {
+			continue
+		}
+		events = append(events, ActivityEvent{
+			Time: now, SessionID: obs.ID, Session: obs.Name,
+			Window: key.window, Tab: rec.tab, Agent: rec.agent,
+			Kind: EventExit, From: rec.activity, Held: now.Sub(rec.since),
+		})
+	}
+	return events
+}
+
+// prune folds events that fell out of the retention window into the
+// baseline. Done in batches of an hour so it is not work on every poll.
+func (r *ActivityRecorder) prune(now time.Time) {
+	cutoff := now.Add(-recentActivityRetention)
+	if cutoff.Sub(r.horizon) < time.Hour {
+		return
+	}
+	n := 0
+	for n < len(r.recent) && r.recent[n].Time.Before(cutoff) {
+		r.baseline.apply(r.recent[n])
+		n++
+	}
+	r.recent = append([]ActivityEvent(nil), r.recent[n:]...)
+	r.horizon = cutoff
+}
+
+// Since returns the events from t on, led by one synthetic event per tab
+// giving its state at t. Without those a tab that was waiting before t and
+// still is would show nothing at all.
+func (r *ActivityRecorder) Since(t time.Time) []ActivityEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t.Before(r.horizon) {
+		t = r.horizon
+	}
+	state := r.baseline.clone()
+	i := 0
+	for i < len(r.recent) && r.recent[i].Time.Before(t) {
+		state.apply(r.recent[i])
+		i++
+	}
+
+	var events []ActivityEvent
+	for key, rec := range state.tabs {
+		if rec.dead || !state.running[key.session] {
+			continue
+		}
+		events = append(events, ActivityEvent{
+			Time: t, SessionID: key.session, Session: rec.name,
+			Window: key.window, Tab: rec.tab, Agent: rec.agent,
+			Kind: EventActivity, From: rec.activity, To: rec.activity,
+		})
+	}
+	sort.Slice(events, func(a, b int) bool { return events[a].Window < events[b].Window })
+	return append(events, r.recent[i:]...)
+}
+
+// ActivitySince returns how long a tab has been in its current state, as
+// far as the recorder knows.
+func (r *ActivityRecorder) ActivitySince(sessionID string, window int) (SessionActivity, time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.current.tabs[tabKey{sessionID, window}]
+	if !ok || rec.dead {
+		return ActivityIdle, time.Time{}, false
+	}
+	return rec.activity, rec.since, true
+}
+
+// TimelineSpan is a stretch of time a tab spent in one state.
+type TimelineSpan struct {
+	Start    time.Time
+	End      time.Time
+	Activity SessionActivity
+}
+
+// TabTimeline is one tab's spans, oldest first.
+type TabTimeline struct {
+	Window int
+	Tab    string
+	Agent  AgentType
+	Spans  []TimelineSpan
+}
+
+// ActivitySummary is one session's activity over a time range.
+type ActivitySummary struct {
+	Tabs    []TabTimeline
+	Busy    time.Duration // Summed over tabs: two busy tabs for an hour is two hours
+	Stalled time.Duration // Busy with no progress; not included in Busy
+	Waiting time.Duration
+	Prompts int // Times a tab started waiting on the user
+}
+
+// SummarizeActivity builds one session's timeline between from and to.
+// events must be oldest first; anything before from only sets the starting
+// state, and gaps with no recorded state are left out of every span.
+func SummarizeActivity(events []ActivityEvent, sessionID string, from, to time.Time) ActivitySummary {
+	type open struct {
+		activity SessionActivity
+		start    time.Time
+	}
+	var summary ActivitySummary
+	spans := map[int]open{}
+	tabs := map[int]*TabTimeline{}
+
+	tabFor := func(e ActivityEvent) *TabTimeline {
+		tl, ok := tabs[e.Window]
+		if !ok {
+			tl = &TabTimeline{Window: e.Window}
+			tabs[e.Window] = tl
+		}
+		if e.Tab != "" {
+			tl.Tab = e.Tab
+		}
+		if e.Agent != "" {
+			tl.Agent = e.Agent
+		}
+		return tl
+	}
+	closeSpan := func(window int, at time.Time) {
+		s, ok := spans[window]
+		if !ok {
+			return
+		}
+		delete(spans, window)
+		start, end := s.start, at
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			return
+		}
+		tl := tabs[window]
+		tl.Spans = append(tl.Spans, TimelineSpan{Start: start, End: end, Activity: s.activity})
+		switch s.activity {
+		case ActivityBusy:
+			summary.Busy += end.Sub(start)
+		case ActivityStalled:
+			summary.Stalled += end.Sub(start)
+		case ActivityWaiting:
+			summary.Waiting += end.Sub(start)
+		}
+	}
+
+	for _, e := range events {
+		if e.SessionID != sessionID || e.Time.After(to) {
+			continue
+		}
+		switch e.Kind {
+		case EventActivity:
+			closeSpan(e.Window, e.Time)
+			tabFor(e)
+			spans[e.Window] = open{activity: e.To, start: e.Time}
+			if e.To == ActivityWaiting && e.From != ActivityWaiting && !e.Time.Before(from) {
+				summary.Prompts++
+			}
+		case EventExit:
+			tabFor(e)
+			closeSpan(e.Window, e.Time)
+		case EventStop:
+			for window := range spans {
+				closeSpan(window, e.Time)
+			}
+		}
+	}
+	for window := range spans {
+		closeSpan(window, to)
+	}
+
+	for _, tl := range tabs {
+		if len(tl.Spans) > 0 {
+			summary.Tabs = append(summary.Tabs, *tl)
+		}
+	}
+	sort.Slice(summary.Tabs, func(a, b int) bool { return summary.Tabs[a].Window < summary.Tabs[b].Window })
+	return summary
+}
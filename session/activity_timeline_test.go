@@ -0,0 +1,157 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func observe(id string, activity ...SessionActivity) SessionObservation {
+	obs := SessionObservation{ID: id, Name: id}
+	for window, a := range activity {
+		obs.Tabs = append(obs.Tabs, TabObservation{Window: window, Tab: "tab", Activity: a})
+	}
+	return obs
+}
+
+// A poll is a snapshot, and an unchanged snapshot is not an event: only the
+// tab that moved is recorded, with how long it had held its previous state.
+func TestRecorderLogsOnlyWhatChanged(t *testing.T) {
+	t.Parallel()
+
+	r := NewActivityRecorder(nil)
+	t0 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	first, _ := r.Record(t0, []SessionObservation{observe("s", ActivityIdle, ActivityIdle)})
+	if len(first) != 2 {
+		t.Fatalf("first sighting produced %d events, want one per tab", len(first))
+	}
+
+	same, _ := r.Record(t0.Add(time.Second), []SessionObservation{observe("s", ActivityIdle, ActivityIdle)})
+	if len(same) != 0 {
+		t.Errorf("an unchanged poll produced %d events", len(same))
+	}
+
+	moved, _ := r.Record(t0.Add(time.Minute), []SessionObservation{observe("s", ActivityIdle, ActivityWaiting)})
+	if len(moved) != 1 {
+		t.Fatalf("one tab moved but %d events were produced", len(moved))
+	}
+	e := moved[0]
+	if e.Window != 1 || e.From != ActivityIdle || e.To != ActivityWaiting || e.Held != time.Minute {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+// A failed capture says nothing about the tab. Recording it as idle would
+// invent idle time and, worse, a fresh waiting prompt when the next capture
+// succeeds.
+func TestRecorderIgnoresUnreadableTabs(t *testing.T) {
+	t.Parallel()
+
+	r := NewActivityRecorder(nil)
+	t0 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	r.Record(t0, []SessionObservation{observe("s", ActivityWaiting)})
+
+	obs := observe("s", ActivityIdle)
+	obs.Tabs[0].Unreadable = true
+	if events, _ := r.Record(t0.Add(time.Second), []SessionObservation{obs}); len(events) != 0 {
+		t.Errorf("an unreadable tab produced %+v", events)
+	}
+}
+
+// Reopening a project must compare the first poll with what the log last
+// said, not with nothing — otherwise every restart logs every tab again.
+func TestRecorderResumesFromItsLog(t *testing.T) {
+	t.Parallel()
+
+	log := NewActivityLog(filepath.Join(t.TempDir(), "activity.jsonl"))
+	now := time.Now()
+
+	first := NewActivityRecorder(log)
+	first.Load(now)
+	first.Record(now, []SessionObservation{observe("s", ActivityBusy)})
+
+	second := NewActivityRecorder(log)
+	if err := second.Load(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := second.Record(now.Add(time.Minute), []SessionObservation{observe("s", ActivityBusy)}); len(events) != 0 {
+		t.Errorf("an unchanged tab was logged again after reopening: %+v", events)
+	}
+	if events, _ := second.Record(now.Add(2*time.Minute), []SessionObservation{{ID: "s", Stopped: true}}); len(events) != 1 || events[0].Kind != EventStop {
+		t.Errorf("stopping after reopening produced %+v, want one stop", events)
+	}
+}
+
+// A log over its size limit with nothing old enough to age out still
+// shrinks: the oldest entries go, the newest stay.
+func TestCompactTrimsRecentEntriesPastTheLimit(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "activity.jsonl")
+	log := NewActivityLog(path)
+	now := time.Now()
+	var events []ActivityEvent
+	for i := 0; i < 40000; i++ {
+		events = append(events, ActivityEvent{
+			Time: now.Add(time.Duration(i-40000) * time.Second), SessionID: "s", Session: "api",
+			Tab: "tests", Agent: AgentClaude, Kind: EventActivity, From: ActivityIdle, To: ActivityBusy,
+		})
+	}
+	if err := log.Append(events...); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Size() < activityLogMaxBytes {
+		t.Fatalf("test log is only %d bytes", info.Size())
+	}
+
+	if err := log.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	if info.Size() >= activityLogMaxBytes {
+		t.Errorf("%d bytes after compacting", info.Size())
+	}
+	kept, err := log.Since(time.Time{})
+	if err != nil || len(kept) == 0 {
+		t.Fatalf("kept %d entries, err %v", len(kept), err)
+	}
+	if last := kept[len(kept)-1]; !last.Time.Equal(events[len(events)-1].Time) {
+		t.Errorf("newest entry kept is from %v", last.Time)
+	}
+	if !kept[0].Time.After(events[0].Time) {
+		t.Error("the oldest entry was kept")
+	}
+}
+
+// The summary is what the leads read: busy and waiting time clipped to the
+// range, and a prompt counted each time a tab starts waiting on the user.
+func TestSummarizeActivityClipsToTheRange(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	events := []ActivityEvent{
+		{Time: t0, SessionID: "s", Window: 0, Kind: EventActivity, From: ActivityIdle, To: ActivityBusy},
+		{Time: t0.Add(30 * time.Minute), SessionID: "s", Window: 0, Kind: EventActivity, From: ActivityBusy, To: ActivityWaiting},
+		{Time: t0.Add(40 * time.Minute), SessionID: "s", Window: 0, Kind: EventActivity, From: ActivityWaiting, To: ActivityBusy},
+		{Time: t0.Add(50 * time.Minute), SessionID: "other", Window: 0, Kind: EventActivity, From: ActivityIdle, To: ActivityWaiting},
+		{Time: t0.Add(60 * time.Minute), SessionID: "s", Window: SessionWindow, Kind: EventStop},
+	}
+
+	// The range starts 10 minutes in and runs past the stop.
+	summary := SummarizeActivity(events, "s", t0.Add(10*time.Minute), t0.Add(90*time.Minute))
+
+	if summary.Busy != 40*time.Minute {
+		t.Errorf("busy = %v, want 40m (20m before the prompt, 20m after)", summary.Busy)
+	}
+	if summary.Waiting != 10*time.Minute {
+		t.Errorf("waiting = %v, want 10m", summary.Waiting)
+	}
+	if summary.Prompts != 1 {
+		t.Errorf("prompts = %d, want 1", summary.Prompts)
+	}
+	if len(summary.Tabs) != 1 || len(summary.Tabs[0].Spans) != 3 {
+		t.Errorf("unexpected tabs %+v", summary.Tabs)
+	}
+}
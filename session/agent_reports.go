@@ -0,0 +1,234 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What an agent says about itself, as opposed to what its screen suggests.
+//
+// Claude Code can run hooks on its own events — a session starting, a tool
+// about to run, a permission prompt, the end of a turn — and each one knows
+// exactly what happened and in which conversation. Installed hooks call
+// `asmgr hook`, which finds the tmux window it was run from and writes a
+// report for that window here; Codex's notify program does the same through
+// `asmgr codex-notify`. The poller reads it alongside the screen.
+//
+// The screen is still read. Hooks say nothing when the user interrupts with
+// Esc, and an agent started before the hooks were installed sends nothing at
+// all, so a report is a strong hint that the screen can overrule only where
+// it is unambiguous: a spinner that is really turning, an idle prompt long
+// after the last report said busy.
+
+// AgentReport is the latest an agent has reported about one tmux window.
+type AgentReport struct {
+	Time      time.Time       `json:"time"`
+	Agent     AgentType       `json:"agent"`
+	Event     string          `json:"event"` // The agent's own name for it, e.g. "PreToolUse"
+	State     SessionActivity `json:"state"`
+	SessionID string          `json:"session_id,omitempty"` // The agent's conversation ID
+	// Tool and Subject are the tool request in flight: what a permission
+	// prompt is asking about.
+	Tool    string `json:"tool,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// agentReportsDir is where reports are kept, one file per window. Global
+// rather than per project: the hook does not know the project, and tmux
+// session names are unique across them.
+func agentReportsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "agent-session-manager", "agent-reports"), nil
+}
+
+// agentReportPath is the file for a tmux target, "session:window".
+func agentReportPath(target string) (string, error) {
+	dir, err := agentReportsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strings.ReplaceAll(target, ":", "_")+".json"), nil
+}
+
+// ReadAgentReport returns the latest report for a tmux target.
+func ReadAgentReport(target string) (AgentReport, bool) {
+	path, err := agentReportPath(target)
+	if err != nil {
+		return AgentReport{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AgentReport{}, false
+	}
+	var report AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return AgentReport{}, false
+	}
+	return report, true
+}
+
+// reportLockWait is how long a hook waits for another one writing the same
+// window's report; reportLockStale is how old a lock may get before it is
+// taken for one left by a hook that was killed.
+const (
+	reportLockWait  = 2 * time.Second
+	reportLockStale = 10 * time.Second
+)
+
+// lockAgentReport takes the lock on a report file, for a read-modify-write:
+// two hooks for one window, a Stop and a Notification in quick succession,
+// run at once. Returns the function that releases it.
+func lockAgentReport(path string) (func(), error) {
+	lock := path + ".lock"
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock agent report: %w", err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > reportLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Since(start) > reportLockWait {
+			return nil, fmt.Errorf("agent report %s is locked by another hook", filepath.Base(path))
+		}
+	}
+}
+
+// UpdateAgentReport replaces the report for a tmux target with what apply
+// makes of the last one, holding the report's lock meanwhile so no update
+// is lost. Written to a temporary file of its own and renamed, so a poll
+// never reads half of one.
+func UpdateAgentReport(target string, apply func(prev AgentReport, ok bool) (AgentReport, error)) error {
+	path, err := agentReportPath(target)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	unlock, err := lockAgentReport(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	prev, ok := ReadAgentReport(target)
+	report, err := apply(prev, ok)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write agent report: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write agent report: %w", err)
+	}
+	return nil
+}
+
+// forgetAgentReports removes every report from a tmux session's windows, so
+// a session started again under the same name does not inherit them.
+func forgetAgentReports(sessionName string) {
+	dir, err := agentReportsDir()
+	if err != nil {
+		return
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, sessionName+"_*.json"))
+	for _, path := range paths {
+		window := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), sessionName+"_"), ".json")
+		if _, err := strconv.Atoi(window); err == nil {
+			os.Remove(path)
+		}
+	}
+}
+
+// AgentReportForWindow returns the latest report from the agent in one of
+// the session's windows.
+func (i *Instance) AgentReportForWindow(windowIdx int) (AgentReport, bool) {
+	return ReadAgentReport(i.windowTarget(windowIdx))
+}
+
+// reconcileReport decides a window's state from its agent's report and what
+// the screen shows. It also reports whether the report decided it, in which
+// case the busy grace period, a guess about gaps between phases, does not
+// apply.
+func reconcileReport(report AgentReport, screen SessionActivity, now time.Time) (SessionActivity, bool) {
+	age := now.Sub(report.Time)
+	switch report.State {
+	case ActivityWaiting:
+		// Answered: the agent is working again before its next hook fires.
+		if screen == ActivityBusy {
+			return ActivityBusy, false
+		}
+		// Declined with Esc, which ends the turn without a Stop.
+		if screen == ActivityIdle && age > busyGracePeriod {
+			return ActivityIdle, false
+		}
+		return ActivityWaiting, true
+	case ActivityBusy:
+		if screen == ActivityWaiting {
+			return ActivityWaiting, false
+		}
+		// Interrupted with Esc, which no hook reports.
+		if screen == ActivityIdle && age > busyGracePeriod {
+			return ActivityIdle, false
+		}
+		return ActivityBusy, true
+	case ActivityIdle:
+		// Working again without a hook saying so: hooks removed, or a turn
+		// the agent started on its own.
+		if screen != ActivityIdle && age > busyGracePeriod {
+			return screen, false
+		}
+		return ActivityIdle, true
+	}
+	return screen, false
+}
+
+// ReportTarget finds the tmux window a hook was run from, through the pane
+// tmux names in TMUX_PANE. Only windows of sessions this app manages count.
+func ReportTarget() (string, bool) {
+	pane := os.Getenv("TMUX_PANE")
+	if pane == "" {
+		return "", false
+	}
+	output, err := TmuxCommand("display-message", "-p", "-t", pane, "#{session_name}:#{window_index}").Output()
+	if err != nil {
+		return "", false
+	}
+	target := strings.TrimSpace(string(output))
+	if !strings.HasPrefix(target, "asm_") || !strings.Contains(target, ":") {
+		return "", false
+	}
+	return target, true
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A report decides the state except where the screen plainly disagrees: an
+// answered prompt already working again, an Esc that no hook reports, an
+// agent that started working without a hook saying so.
+func TestReconcileReport(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-time.Second)
+	old := now.Add(-time.Minute)
+
+	for _, c := range []struct {
+		name    string
+		state   SessionActivity
+		at      time.Time
+		screen  SessionActivity
+		want    SessionActivity
+		decided bool
+	}{
+		{"prompt showing", ActivityWaiting, old, ActivityIdle, ActivityIdle, false},
+		{"prompt just raised", ActivityWaiting, fresh, ActivityIdle, ActivityWaiting, true},
+		{"prompt answered", ActivityWaiting, fresh, ActivityBusy, ActivityBusy, false},
+		{"between tools", ActivityBusy, fresh, ActivityIdle, ActivityBusy, true},
+		{"interrupted", ActivityBusy, old, ActivityIdle, ActivityIdle, false},
+		{"prompt the hook missed", ActivityBusy, old, ActivityWaiting, ActivityWaiting, false},
+		{"turn over, spinner still drawn", ActivityIdle, fresh, ActivityBusy, ActivityIdle, true},
+		{"working without a hook", ActivityIdle, old, ActivityBusy, ActivityBusy, false},
+	} {
+		got, decided := reconcileReport(AgentReport{State: c.state, Time: c.at}, c.screen, now)
+		if got != c.want || decided != c.decided {
+			t.Errorf("%s: %v (decided %v), want %v (decided %v)", c.name, got, decided, c.want, c.decided)
+		}
+	}
+}
+
+// Hooks for one window that run at once each add to the report the other
+// left, rather than one overwriting the other's.
+func TestUpdateAgentReportLosesNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	const hooks = 20
+	var wg sync.WaitGroup
+	for i := 0; i < hooks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := UpdateAgentReport("asm_api:0", func(prev AgentReport, ok bool) (AgentReport, error) {
+				prev.Message += "x"
+				return prev, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	report, ok := ReadAgentReport("asm_api:0")
+	if !ok || len(report.Message) != hooks {
+		t.Errorf("report after %d updates: %q", hooks, report.Message)
+	}
+	path, _ := agentReportPath("asm_api:0")
+	if leftovers, _ := filepath.Glob(path + ".*"); len(leftovers) != 0 {
+		t.Errorf("left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import "path/filepath"
+
+// ApprovalRule matches the subject of a permission prompt: the command it
+// wants to run, or the file it wants to change. `*` matches any run of
+// characters, and a rule must match the whole subject.
+type ApprovalRule struct {
+	Command string `json:"command,omitempty"` // "go test *", "git status"
+	File    string `json:"file,omitempty"`    // "src/*", relative to the session's path
+}
+
+// ApprovalPolicy decides which permission prompts asmgr answers on the user's
+// behalf. Set per project (Settings) and per session (Instance); the rules of
+// both apply, and a deny rule anywhere wins over every allow rule.
+type ApprovalPolicy struct {
+	Allow []ApprovalRule `json:"allow,omitempty"` // Answered "yes" automatically
+	Deny  []ApprovalRule `json:"deny,omitempty"`  // Never answered automatically
+}
+
+// Merge returns the rules of p and other together.
+func (p *ApprovalPolicy) Merge(other *ApprovalPolicy) ApprovalPolicy {
+	var merged ApprovalPolicy
+	for _, policy := range []*ApprovalPolicy{p, other} {
+		if policy == nil {
+			continue
+		}
+		merged.Allow = append(merged.Allow, policy.Allow...)
+		merged.Deny = append(merged.Deny, policy.Deny...)
+	}
+	return merged
+}
+
+// Empty reports a policy that approves nothing.
+func (p ApprovalPolicy) Empty() bool {
+	return len(p.Allow) == 0
+}
+
+// approvalLogFile is the audit log of automatic answers, per project.
+const approvalLogFile = "approvals.jsonl"
+
+// ApprovalLogPath returns the active project's approval audit log, next to
+// its sessions.json.
+func (s *Storage) ApprovalLogPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), approvalLogFile)
+}
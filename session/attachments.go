@@ -0,0 +1,304 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Files, diffs and other tabs' output sent along with a prompt.
+//
+// The failing test run is in a terminal tab, the agent is in another, and
+// between them is a copy through the system clipboard that loses half the
+// lines. An attachment names what to include — a file of the session, its
+// diff, the last lines of a tab's pane, another session's last answer — and
+// is read when the prompt is sent, so what the agent sees is current.
+//
+// An agent that reads files itself is given "@path" and reads the file when
+// it wants it; everything else, and every agent without @ references, gets
+// the content inline in a fenced block.
+
+// AttachmentKind is what an attachment includes.
+type AttachmentKind int
+
+const (
+	AttachFile        AttachmentKind = iota // A file under the session's path
+	AttachSessionDiff                       // Changes since the session started
+	AttachFullDiff                          // All uncommitted changes
+	AttachPane                              // The last lines of a tab
+	AttachAnswer                            // Another session's last answer
+)
+
+// Attachment is one thing to send with a prompt.
+type Attachment struct {
+	Kind   AttachmentKind
+	Path   string    // AttachFile: relative to the session's path
+	Source *Instance // AttachPane, AttachAnswer: the session to read
+	Window int       // AttachPane: the tab's window
+	Tab    string    // AttachPane: the tab's name, for the label
+	Lines  int       // AttachPane: how many lines
+}
+
+// maxAttachmentBytes is the most an inline file may hold. Past this, a prompt
+// is mostly file, and an agent that needs it can be told the path.
+const maxAttachmentBytes = 256 * 1024
+
+// Label is a short name for the attachment, for the prompt dialog.
+func (a Attachment) Label() string {
+	switch a.Kind {
+	case AttachFile:
+		return a.Path
+	case AttachSessionDiff:
+		return "session diff"
+	case AttachFullDiff:
+		return "full diff"
+	case AttachPane:
+		return fmt.Sprintf("%d lines of %s", a.Lines, a.tabName())
+	case AttachAnswer:
+		if a.Source != nil {
+			return "last answer of " + a.Source.Name
+		}
+	}
+	return "attachment"
+}
+
+// tabName names a pane attachment's tab.
+func (a Attachment) tabName() string {
+	if a.Tab != "" {
+		return a.Tab
+	}
+	return fmt.Sprintf("window %d", a.Window)
+}
+
+// supportsFileReferences is whether an agent reads "@path" in a prompt as the
+// file at that path.
+func supportsFileReferences(agent AgentType) bool {
+	switch agent {
+	case AgentClaude, AgentGemini, AgentOpenCode:
+		return true
+	}
+	return false
+}
+
+// ExpandAttachments appends a window's prompt text with its attachments, in
+// the form the window's agent takes them.
+func (i *Instance) ExpandAttachments(windowIdx int, text string, attachments []Attachment) (string, error) {
+	if len(attachments) == 0 {
+		return text, nil
+	}
+	agent := i.windowAgent(windowIdx)
+
+	var refs []string
+	var blocks []string
+	for _, a := range attachments {
+		if a.Kind == AttachFile && supportsFileReferences(agent) {
+			refs = append(refs, "@"+a.Path)
+			continue
+		}
+		heading, lang, content, err := i.readAttachment(a)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", a.Label(), err)
+		}
+		blocks = append(blocks, heading+"\n"+fenced(lang, content))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(text, "\n"))
+	if len(refs) > 0 {
+		b.WriteString("\n\n" + strings.Join(refs, " "))
+	}
+	for _, block := range blocks {
+		b.WriteString("\n\n" + block)
+	}
+	return strings.TrimLeft(b.String(), "\n"), nil
+}
+
+// readAttachment reads what an attachment includes now, with the heading
+// and fence language to show it under.
+func (i *Instance) readAttachment(a Attachment) (heading, lang, content string, err error) {
+	switch a.Kind {
+	case AttachFile:
+		content, err := readAttachedFile(i.Path, a.Path)
+		return fmt.Sprintf("`%s`:", a.Path), fenceLanguage(a.Path), content, err
+	case AttachSessionDiff, AttachFullDiff:
+		diff := i.GetFullDiff()
+		heading = "Uncommitted changes:"
+		if a.Kind == AttachSessionDiff {
+			diff = i.GetSessionDiff()
+			heading = "Changes since the session started:"
+		}
+		if diff.Error != nil {
+			return "", "", "", diff.Error
+		}
+		if diff.IsEmpty() {
+			return "", "", "", fmt.Errorf("there are no changes")
+		}
+		return heading, "diff", diff.Content, nil
+	case AttachPane:
+		if a.Source == nil {
+			return "", "", "", fmt.Errorf("no session")
+		}
+		content, err := a.Source.CapturePaneLines(a.Window, a.Lines)
+		return fmt.Sprintf("Last %d lines of the %s tab:", a.Lines, a.tabName()), "", content, err
+	case AttachAnswer:
+		if a.Source == nil {
+			return "", "", "", fmt.Errorf("no session")
+		}
+		content, err := a.Source.LastAnswer()
+		return fmt.Sprintf("The last answer in session %s:", a.Source.Name), "", content, err
+	}
+	return "", "", "", fmt.Errorf("unknown attachment")
+}
+
+// readAttachedFile reads a file for inlining: under root, text, and not too
+// large.
+func readAttachedFile(root, rel string) (string, error) {
+	path := filepath.Join(root, rel)
+	if r, err := filepath.Rel(root, path); err != nil || strings.HasPrefix(r, "..") {
+		return "", fmt.Errorf("not under the session's path")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxAttachmentBytes {
+		return "", fmt.Errorf("%d KB is too large to include", info.Size()/1024)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return "", fmt.Errorf("binary file")
+	}
+	return string(data), nil
+}
+
+// fenced puts content in a code fence longer than any run of backticks in
+// it, so the content cannot close it early.
+func fenced(lang, content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(content, "\n") + "\n" + fence
+}
+
+// fenceLanguage is the fence's language for a file, from its extension.
+func fenceLanguage(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	switch ext {
+	case "", "txt":
+		return ""
+	case "yml":
+		return "yaml"
+	case "md":
+		return "markdown"
+	}
+	return ext
+}
+
+// CapturePaneLines returns the last lines of a window's pane, wrapped lines
+// joined and the empty rows below the output left out.
+func (i *Instance) CapturePaneLines(windowIdx, lines int) (string, error) {
+	if !i.IsAlive() {
+		return "", fmt.Errorf("session not running")
+	}
+	// Captured with some to spare: the rows under a short output are empty
+	output, err := TmuxCommand("capture-pane", "-t", i.windowTarget(windowIdx), "-p", "-J", "-S", fmt.Sprintf("-%d", lines+100)).Output()
+	if err != nil {
+		return "", fmt.Errorf("capture failed: %w", err)
+	}
+	all := strings.Split(strings.TrimRight(string(output), "\n "), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+// LastAnswer is the agent's last reply: from Claude's transcript, from the
+// last Codex notify report, or else the end of its screen.
+func (i *Instance) LastAnswer() (string, error) {
+	agent := i.windowAgent(i.GetMainWindowIndex())
+	if agent == AgentClaude && i.ResumeSessionID != "" {
+		entry := HistoryEntry{
+			Agent:       AgentClaude,
+			SessionFile: filepath.Join(GetClaudeProjectDir(i.Path), i.ResumeSessionID+".jsonl"),
+		}
+		if messages, err := entry.LoadConversation(); err == nil {
+			if answer := lastAssistantTurn(messages); answer != "" {
+				return answer, nil
+			}
+		}
+	}
+	if report, ok := i.AgentReportForWindow(i.GetMainWindowIndex()); ok && report.Agent == agent && report.Message != "" {
+		return report.Message, nil
+	}
+	return i.CapturePaneLines(i.GetMainWindowIndex(), 40)
+}
+
+// lastAssistantTurn joins the assistant's messages since the last user
+// message: one answer, split around its tool calls.
+func lastAssistantTurn(messages []ConversationMessage) string {
+	start := len(messages)
+	for start > 0 && messages[start-1].Role == "assistant" {
+		start--
+	}
+	var parts []string
+	for _, m := range messages[start:] {
+		parts = append(parts, strings.TrimSpace(m.Content))
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
+}
+
+// maxListedFiles caps the file finder's list in a directory that is not a
+// repository, where nothing says which files matter.
+const maxListedFiles = 5000
+
+// ListFiles lists the files under dir for the file finder: git's tracked and
+// untracked-but-not-ignored files in a repository, else a walk that skips
+// hidden and dependency directories.
+func ListFiles(dir string) ([]string, error) {
+	output, err := exec.Command("git", "-C", dir, "ls-files", "--cached", "--others", "--exclude-standard").Output()
+	if err == nil {
+		var files []string
+		for _, line := range strings.Split(string(output), "\n") {
+			if line != "" {
+				files = append(files, line)
+			}
+		}
+		return files, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			files = append(files, rel)
+		}
+		if len(files) >= maxListedFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files, err
+}
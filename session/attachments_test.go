@@ -0,0 +1,93 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A file goes as @path to an agent that reads files, and inline, fenced, to
+// one that does not.
+func TestExpandAttachmentsByAgent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []Attachment{{Kind: AttachFile, Path: "main.go"}}
+
+	claude := &Instance{Path: dir, Agent: AgentClaude}
+	got, err := claude.ExpandAttachments(0, "Review this", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Review this\n\n@main.go" {
+		t.Errorf("claude:\n%s", got)
+	}
+
+	aider := &Instance{Path: dir, Agent: AgentAider}
+	got, err = aider.ExpandAttachments(0, "Review this", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Review this\n\n`main.go`:\n```go\npackage main\n```"; got != want {
+		t.Errorf("aider:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Inlined files stay under the session's path and are text.
+func TestReadAttachedFileRefuses(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), []byte("\x89PNG\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"logo.png", "../outside.txt", "missing.txt"} {
+		if _, err := readAttachedFile(dir, rel); err == nil {
+			t.Errorf("%s was read", rel)
+		}
+	}
+}
+
+// Content with its own fences gets a longer one around it.
+func TestFencedOutlastsContent(t *testing.T) {
+	got := fenced("markdown", "Run:\n```sh\nmake\n```\n")
+	if !strings.HasPrefix(got, "````markdown\n") || !strings.HasSuffix(got, "\n````") {
+		t.Errorf("fenced:\n%s", got)
+	}
+}
+
+// The last answer is every assistant message since the last user message.
+func TestLastAssistantTurn(t *testing.T) {
+	messages := []ConversationMessage{
+		{Role: "user", Content: "fix the test"},
+		{Role: "assistant", Content: "Old answer"},
+		{Role: "user", Content: "and the lint"},
+		{Role: "assistant", Content: "Running the linter."},
+		{Role: "assistant", Content: "Fixed two warnings.\n"},
+	}
+	if got := lastAssistantTurn(messages); got != "Running the linter.\n\nFixed two warnings." {
+		t.Errorf("last turn = %q", got)
+	}
+}
+
+// Outside a repository, the finder walks the directory, skipping hidden and
+// dependency directories.
+func TestListFilesWithoutGit(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"src/app.go", ".cache/x", "node_modules/pkg/index.js", "README"} {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := ListFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, ",") != "README,"+filepath.Join("src", "app.go") {
+		t.Errorf("files = %v", files)
+	}
+}
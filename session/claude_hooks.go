@@ -0,0 +1,289 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Claude Code hooks, installed into Claude's own settings.
+//
+// Claude runs each hook command with a JSON description of the event on
+// stdin. The ones installed here all run `asmgr hook`, which turns the event
+// into an AgentReport for the window it came from:
+//
+//	SessionStart       idle, and the conversation's ID — also on /clear and resume
+//	UserPromptSubmit   busy
+//	PreToolUse         busy, and the tool about to run
+//	PostToolUse        busy, the tool is done
+//	Notification       waiting on a permission prompt, or idle at the input
+//	Stop               idle, the turn is over
+//
+// UserPromptSubmit and PostToolUse are not strictly needed, but without them
+// a report would say "waiting" until the end of the turn after a permission
+// prompt is answered, and "idle" until the first tool after a prompt is sent.
+//
+// Entries are recognised as ours by their command, so installing twice does
+// not add them twice and removing leaves the user's own hooks alone.
+
+// claudeHookEvents are the events hooks are installed for.
+var claudeHookEvents = []string{
+	"SessionStart", "UserPromptSubmit", "PreToolUse", "PostToolUse", "Notification", "Stop",
+}
+
+// claudeToolEvents take a matcher, the tool names the hook runs for.
+var claudeToolEvents = map[string]bool{"PreToolUse": true, "PostToolUse": true}
+
+// claudeHookSubcommand ends the command of every hook asmgr installs.
+const claudeHookSubcommand = " hook"
+
+// claudeHookPayload is the part of Claude's hook input asmgr reads.
+type claudeHookPayload struct {
+	SessionID        string          `json:"session_id"`
+	HookEventName    string          `json:"hook_event_name"`
+	Message          string          `json:"message"`
+	NotificationType string          `json:"notification_type"`
+	ToolName         string          `json:"tool_name"`
+	ToolInput        json.RawMessage `json:"tool_input"`
+}
+
+// toolSubject picks what a tool call is about from its input: the command
+// for Bash, the file for edits, the pattern for searches.
+func toolSubject(input json.RawMessage) string {
+	var fields map[string]any
+	if json.Unmarshal(input, &fields) != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "file_path", "notebook_path", "path", "pattern", "url", "query", "description"} {
+		if value, ok := fields[key].(string); ok && value != "" {
+			return strings.Join(strings.Fields(value), " ")
+		}
+	}
+	return ""
+}
+
+// ApplyClaudeHook updates prev, the window's last report, with a hook event.
+// The conversation ID carries over events that lack it, and the tool from
+// PreToolUse over the permission prompt that follows.
+func ApplyClaudeHook(prev AgentReport, payload []byte, now time.Time) (AgentReport, error) {
+	var p claudeHookPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return prev, fmt.Errorf("failed to parse hook input: %w", err)
+	}
+	report := prev
+	report.Time = now
+	report.Agent = AgentClaude
+	report.Event = p.HookEventName
+	report.Message = p.Message
+	if p.SessionID != "" {
+		report.SessionID = p.SessionID
+	}
+
+	switch p.HookEventName {
+	case "SessionStart":
+		report.State = ActivityIdle
+		report.Tool, report.Subject = "", ""
+	case "UserPromptSubmit", "PostToolUse":
+		report.State = ActivityBusy
+		report.Tool, report.Subject = "", ""
+	case "PreToolUse":
+		report.State = ActivityBusy
+		report.Tool, report.Subject = p.ToolName, toolSubject(p.ToolInput)
+	case "Notification":
+		lower := strings.ToLower(p.Message)
+		switch {
+		case p.NotificationType == "permission_prompt" || strings.Contains(lower, "permission"):
+			report.State = ActivityWaiting
+		case p.NotificationType == "idle_prompt" || strings.Contains(lower, "waiting for your input"):
+			report.State = ActivityIdle
+			report.Tool, report.Subject = "", ""
+		default:
+			// Anything else Claude wants to say changes nothing
+			report.Event = prev.Event
+			report.Message = prev.Message
+		}
+	case "Stop":
+		report.State = ActivityIdle
+		report.Tool, report.Subject = "", ""
+	default:
+		return prev, fmt.Errorf("unknown hook event %q", p.HookEventName)
+	}
+	return report, nil
+}
+
+// RecordClaudeHook applies a hook event to the report of a tmux target.
+func RecordClaudeHook(target string, payload []byte) error {
+	return UpdateAgentReport(target, func(prev AgentReport, ok bool) (AgentReport, error) {
+		if ok && prev.Agent != AgentClaude {
+			// Another agent used to run in this window
+			prev = AgentReport{}
+		}
+		return ApplyClaudeHook(prev, payload, time.Now())
+	})
+}
+
+// ClaudeSettingsPath is Claude Code's user settings file, where hooks that
+// apply to every project go.
+func ClaudeSettingsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "settings.json"), nil
+}
+
+// ClaudeHookCommand is the command installed hooks run: this executable,
+// quoted for the shell Claude runs it with.
+func ClaudeHookCommand() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return "'" + strings.ReplaceAll(exe, "'", `'\''`) + "'" + claudeHookSubcommand, nil
+}
+
+// InstallClaudeHooks adds hooks running command to the Claude settings file
+// at path, replacing any asmgr installed before. The previous file is kept
+// next to it with a .bak suffix.
+func InstallClaudeHooks(path, command string) error {
+	settings, err := readClaudeSettings(path)
+	if err != nil {
+		return err
+	}
+	hooks := withoutOurHooks(settings)
+	for _, event := range claudeHookEvents {
+		entry := map[string]any{
+			"hooks": []any{map[string]any{"type": "command", "command": command}},
+		}
+		if claudeToolEvents[event] {
+			entry["matcher"] = "*"
+		}
+		list, _ := hooks[event].([]any)
+		hooks[event] = append(list, entry)
+	}
+	settings["hooks"] = hooks
+	return writeClaudeSettings(path, settings)
+}
+
+// RemoveClaudeHooks takes the hooks asmgr installed out of the Claude
+// settings file at path.
+func RemoveClaudeHooks(path string) error {
+	settings, err := readClaudeSettings(path)
+	if err != nil {
+		return err
+	}
+	hooks := withoutOurHooks(settings)
+	if len(hooks) == 0 {
+		delete(settings, "hooks")
+	} else {
+		settings["hooks"] = hooks
+	}
+	return writeClaudeSettings(path, settings)
+}
+
+// ClaudeHooksInstalled reports whether the settings file at path runs asmgr
+// for every event it needs.
+func ClaudeHooksInstalled(path string) bool {
+	settings, err := readClaudeSettings(path)
+	if err != nil {
+		return false
+	}
+	hooks, _ := settings["hooks"].(map[string]any)
+	for _, event := range claudeHookEvents {
+		list, _ := hooks[event].([]any)
+		found := false
+		for _, entry := range list {
+			if isOurHookEntry(entry) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// readClaudeSettings loads a settings file as generic JSON, so everything
+// asmgr does not know about is written back as it was. A missing file is an
+// empty one.
+func readClaudeSettings(path string) (map[string]any, error) {
+	settings := make(map[string]any)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// writeClaudeSettings saves settings to path, backing up what was there.
+func writeClaudeSettings(path string, settings map[string]any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// withoutOurHooks returns the settings' hooks with asmgr's entries taken out
+// and events left with none dropped.
+func withoutOurHooks(settings map[string]any) map[string]any {
+	hooks, _ := settings["hooks"].(map[string]any)
+	kept := make(map[string]any, len(hooks))
+	for event, value := range hooks {
+		list, ok := value.([]any)
+		if !ok {
+			kept[event] = value
+			continue
+		}
+		var others []any
+		for _, entry := range list {
+			if !isOurHookEntry(entry) {
+				others = append(others, entry)
+			}
+		}
+		if len(others) > 0 {
+			kept[event] = others
+		}
+	}
+	return kept
+}
+
+// isOurHookEntry reports whether a hook entry runs `asmgr hook`. The binary
+// may have moved since it was installed, so it goes by the subcommand.
+func isOurHookEntry(entry any) bool {
+	fields, _ := entry.(map[string]any)
+	list, _ := fields["hooks"].([]any)
+	for _, hook := range list {
+		h, _ := hook.(map[string]any)
+		command, _ := h["command"].(string)
+		if strings.HasSuffix(command, claudeHookSubcommand) && strings.Contains(command, "asmgr") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A turn as Claude's hooks describe it: the conversation ID from the start
+// survives every event that does not repeat it, and a permission prompt names
+// the tool from the PreToolUse before it.
+func TestApplyClaudeHookFollowsATurn(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	report := AgentReport{}
+	for _, step := range []struct {
+		payload string
+		state   SessionActivity
+		tool    string
+	}{
+		{`{"hook_event_name":"SessionStart","session_id":"abc-123","source":"startup"}`, ActivityIdle, ""},
+		{`{"hook_event_name":"UserPromptSubmit","prompt":"run the tests"}`, ActivityBusy, ""},
+		{`{"hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"npm   test","description":"Run tests"}}`, ActivityBusy, "Bash"},
+		{`{"hook_event_name":"Notification","message":"Claude needs your permission to use Bash"}`, ActivityWaiting, "Bash"},
+		{`{"hook_event_name":"PostToolUse","tool_name":"Bash"}`, ActivityBusy, ""},
+		{`{"hook_event_name":"Stop"}`, ActivityIdle, ""},
+		{`{"hook_event_name":"Notification","notification_type":"idle_prompt","message":"Claude is waiting for your input"}`, ActivityIdle, ""},
+	} {
+		next, err := ApplyClaudeHook(report, []byte(step.payload), now)
+		if err != nil {
+			t.Fatalf("%s: %v", step.payload, err)
+		}
+		report = next
+		if report.State != step.state || report.Tool != step.tool {
+			t.Errorf("%s: state %v tool %q, want %v %q", step.payload, report.State, report.Tool, step.state, step.tool)
+		}
+		if report.SessionID != "abc-123" {
+			t.Errorf("%s: session ID %q lost", step.payload, report.SessionID)
+		}
+		if step.state == ActivityWaiting && report.Subject != "npm test" {
+			t.Errorf("permission prompt is about %q, want the command", report.Subject)
+		}
+	}
+
+	if _, err := ApplyClaudeHook(report, []byte(`not json`), now); err == nil {
+		t.Error("malformed input was accepted")
+	}
+}
+
+// Installing replaces asmgr's own entries rather than adding to them, and
+// removing takes out only those: the user's hooks and other settings are
+// written back as they were.
+func TestInstallAndRemoveClaudeHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	original := `{"model":"opus","hooks":{"Stop":[{"hooks":[{"type":"command","command":"say done"}]}]}}`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	command := "'/usr/local/bin/asmgr' hook"
+	for range 2 {
+		if err := InstallClaudeHooks(path, command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !ClaudeHooksInstalled(path) {
+		t.Fatal("hooks not installed")
+	}
+	settings, _ := readClaudeSettings(path)
+	hooks := settings["hooks"].(map[string]any)
+	if got := len(hooks["Stop"].([]any)); got != 2 {
+		t.Errorf("Stop has %d entries after installing twice, want the user's and one of ours", got)
+	}
+	pre := hooks["PreToolUse"].([]any)[0].(map[string]any)
+	if pre["matcher"] != "*" {
+		t.Errorf("PreToolUse matcher %v, want every tool", pre["matcher"])
+	}
+	if backup, err := os.ReadFile(path + ".bak"); err != nil || len(backup) == 0 {
+		t.Errorf("no backup of the previous settings: %v", err)
+	}
+
+	if err := RemoveClaudeHooks(path); err != nil {
+		t.Fatal(err)
+	}
+	if ClaudeHooksInstalled(path) {
+		t.Fatal("hooks still installed")
+	}
+	data, _ := os.ReadFile(path)
+	var got, want any
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(original), &want)
+	if gotJSON, _ := json.Marshal(got); string(gotJSON) != mustMarshal(want) {
+		t.Errorf("settings after removing:\n%s\nwant:\n%s", gotJSON, mustMarshal(want))
+	}
+}
+
+func mustMarshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
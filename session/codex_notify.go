@@ -0,0 +1,222 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Codex's notify program, pointed at asmgr.
+//
+// Codex runs one program, named by `notify` in ~/.codex/config.toml, when a
+// turn completes and when it asks for approval, with the event as JSON in its
+// last argument. Pointed at `asmgr codex-notify`, that becomes an AgentReport
+// for the window Codex runs in, like Claude's hooks: an exact end of turn
+// where the screen only offers a spinner that stopped, and the conversation
+// ID, which resume otherwise has to dig out of Codex's rollout files.
+//
+// There is no event for a turn starting, so a report never says busy; the
+// screen says that on its own soon enough, and reconcileReport lets it.
+//
+// Codex takes a single notify program. One the user has set already is not
+// replaced: installing fails and says so, rather than silently disconnecting
+// whatever it was.
+
+// codexNotifySubcommand is the argument installed notify commands run asmgr
+// with.
+const codexNotifySubcommand = "codex-notify"
+
+// codexNotifyPayload is the part of Codex's notify input asmgr reads. The
+// conversation ID has gone by several names across Codex versions.
+type codexNotifyPayload struct {
+	Type           string   `json:"type"`
+	ThreadID       string   `json:"thread-id"`
+	SessionID      string   `json:"session-id"`
+	ConversationID string   `json:"conversation-id"`
+	LastMessage    string   `json:"last-assistant-message"`
+	Command        []string `json:"command"`
+	Message        string   `json:"message"`
+}
+
+// ApplyCodexNotify updates prev, the window's last report, with a notify
+// event.
+func ApplyCodexNotify(prev AgentReport, payload []byte, now time.Time) (AgentReport, error) {
+	var p codexNotifyPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return prev, fmt.Errorf("failed to parse notify input: %w", err)
+	}
+	report := prev
+	report.Time = now
+	report.Agent = AgentCodex
+	report.Event = p.Type
+	for _, id := range []string{p.ThreadID, p.SessionID, p.ConversationID} {
+		if id != "" {
+			report.SessionID = id
+			break
+		}
+	}
+
+	switch {
+	case p.Type == "agent-turn-complete":
+		report.State = ActivityIdle
+		report.Tool, report.Subject = "", ""
+		report.Message = p.LastMessage
+	case strings.Contains(p.Type, "approval"):
+		report.State = ActivityWaiting
+		report.Message = p.Message
+		if len(p.Command) > 0 {
+			report.Tool, report.Subject = "shell", strings.Join(p.Command, " ")
+		}
+	default:
+		return prev, fmt.Errorf("unknown notify event %q", p.Type)
+	}
+	return report, nil
+}
+
+// RecordCodexNotify applies a notify event to the report of a tmux target.
+func RecordCodexNotify(target string, payload []byte) error {
+	return UpdateAgentReport(target, func(prev AgentReport, ok bool) (AgentReport, error) {
+		if ok && prev.Agent != AgentCodex {
+			prev = AgentReport{}
+		}
+		return ApplyCodexNotify(prev, payload, time.Now())
+	})
+}
+
+// CodexConfigPath is Codex's configuration file, honouring CODEX_HOME as
+// Codex does.
+func CodexConfigPath() (string, error) {
+	if dir := os.Getenv("CODEX_HOME"); dir != "" {
+		return filepath.Join(dir, "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".codex", "config.toml"), nil
+}
+
+// CodexNotifyLine is the notify setting that runs this executable.
+func CodexNotifyLine() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	args, _ := json.Marshal([]string{exe, codexNotifySubcommand})
+	return "notify = " + string(args), nil
+}
+
+// InstallCodexNotify sets notify in the Codex config at path to line,
+// replacing one asmgr set before. The previous file is kept next to it with
+// a .bak suffix.
+func InstallCodexNotify(path, line string) error {
+	lines, err := readCodexConfig(path)
+	if err != nil {
+		return err
+	}
+	start, end := findCodexNotify(lines)
+	if start >= 0 {
+		existing := strings.Join(lines[start:end], "\n")
+		if !strings.Contains(existing, codexNotifySubcommand) {
+			return fmt.Errorf("%s already sets notify to another program; remove it first, Codex runs only one", path)
+		}
+		lines = append(lines[:start:start], lines[end:]...)
+	}
+	// Top-level keys must come before the first table, so it goes first
+	lines = append([]string{line}, lines...)
+	return writeCodexConfig(path, lines)
+}
+
+// RemoveCodexNotify takes asmgr's notify setting out of the Codex config at
+// path. Someone else's is left where it is.
+func RemoveCodexNotify(path string) error {
+	lines, err := readCodexConfig(path)
+	if err != nil {
+		return err
+	}
+	start, end := findCodexNotify(lines)
+	if start < 0 || !strings.Contains(strings.Join(lines[start:end], "\n"), codexNotifySubcommand) {
+		return nil
+	}
+	return writeCodexConfig(path, append(lines[:start:start], lines[end:]...))
+}
+
+// CodexNotifyInstalled reports whether the Codex config at path runs asmgr.
+func CodexNotifyInstalled(path string) bool {
+	lines, err := readCodexConfig(path)
+	if err != nil {
+		return false
+	}
+	start, end := findCodexNotify(lines)
+	return start >= 0 && strings.Contains(strings.Join(lines[start:end], "\n"), codexNotifySubcommand)
+}
+
+// findCodexNotify finds the top-level notify setting in a config's lines,
+// returning the range it spans — an array may run over several — or -1.
+// Only as much TOML is understood as that takes: anything after the first
+// table header belongs to that table.
+func findCodexNotify(lines []string) (int, int) {
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			return -1, -1
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(key) != "notify" {
+			continue
+		}
+		end := idx + 1
+		depth := strings.Count(value, "[") - strings.Count(value, "]")
+		for depth > 0 && end < len(lines) {
+			depth += strings.Count(lines[end], "[") - strings.Count(lines[end], "]")
+			end++
+		}
+		return idx, end
+	}
+	return -1, -1
+}
+
+// readCodexConfig loads a config file as lines; a missing file has none.
+func readCodexConfig(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// writeCodexConfig saves a config's lines to path, backing up what was there.
+func writeCodexConfig(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
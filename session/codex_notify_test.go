@@ -0,0 +1,79 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A finished turn is idle and names the conversation; an approval request is
+// waiting and keeps it.
+func TestApplyCodexNotify(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	report, err := ApplyCodexNotify(AgentReport{}, []byte(`{"type":"agent-turn-complete","thread-id":"0199a2b3-c4d5","turn-id":"12","input-messages":["fix it"],"last-assistant-message":"Done."}`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.State != ActivityIdle || report.SessionID != "0199a2b3-c4d5" || report.Agent != AgentCodex {
+		t.Errorf("turn complete: %+v", report)
+	}
+
+	report, err = ApplyCodexNotify(report, []byte(`{"type":"approval-requested","command":["cargo","test"]}`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.State != ActivityWaiting || report.Subject != "cargo test" || report.SessionID != "0199a2b3-c4d5" {
+		t.Errorf("approval: %+v", report)
+	}
+
+	if _, err := ApplyCodexNotify(report, []byte(`{"type":"something-new"}`), now); err == nil {
+		t.Error("an unknown event was accepted")
+	}
+}
+
+// notify goes in as a top-level key, ahead of every table, and comes out
+// again leaving the rest of the file as it was. A notify program the user set
+// is never replaced.
+func TestInstallAndRemoveCodexNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	original := "model = \"o3\"\n\n[profiles.fast]\nmodel = \"o4-mini\"\nnotify = \"not top-level\"\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	line := `notify = ["/usr/local/bin/asmgr", "codex-notify"]`
+	for range 2 {
+		if err := InstallCodexNotify(path, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if got := string(data); got != line+"\n"+original {
+		t.Errorf("installed config:\n%s", got)
+	}
+	if !CodexNotifyInstalled(path) {
+		t.Error("notify not installed")
+	}
+
+	if err := RemoveCodexNotify(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != original {
+		t.Errorf("config after removing:\n%s", data)
+	}
+
+	users := "notify = [\n  \"notify-send\",\n  \"Codex\",\n]\n" + original
+	os.WriteFile(path, []byte(users), 0644)
+	if err := InstallCodexNotify(path, line); err == nil || !strings.Contains(err.Error(), "another program") {
+		t.Errorf("replaced the user's notify program: %v", err)
+	}
+	if err := RemoveCodexNotify(path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != users {
+		t.Error("removed the user's notify program")
+	}
+}
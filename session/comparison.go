@@ -0,0 +1,190 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// One prompt, several agents, and the best answer kept.
+//
+// Evaluating agents on a real ticket means giving each the same prompt on
+// the same commit and looking at what each one did. A comparison does the
+// setting up: one session per agent, each in a worktree of its own on a
+// branch started at the repository's HEAD, all sent the prompt. It then
+// keeps track of them as a set — when each was sent the prompt, when it
+// finished — until one is kept as the winner. Keeping the winner leaves its
+// session, worktree and branch alone, and deletes the others', uncommitted
+// work and all.
+
+// comparisonsFile is the file name of a project's comparisons.
+const comparisonsFile = "comparisons.json"
+
+// compareBranchPrefix starts the name of every comparison branch.
+const compareBranchPrefix = "compare/"
+
+// ComparisonRun is one agent's session in a comparison.
+type ComparisonRun struct {
+	SessionID string    `json:"session_id"`
+	Agent     AgentType `json:"agent"`
+	Branch    string    `json:"branch"`
+	Worktree  string    `json:"worktree"`
+	// SentAt is when the agent was sent the prompt, FinishedAt when it next
+	// went from busy to idle after that.
+	SentAt     time.Time `json:"sent_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Error      string    `json:"error,omitempty"` // Why it was not sent the prompt
+}
+
+// Comparison is a set of agents given the same prompt on the same commit.
+type Comparison struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Prompt  string          `json:"prompt"`
+	Repo    string          `json:"repo"`
+	Base    string          `json:"base"`                  // The commit every branch starts at
+	Branch  string          `json:"base_branch,omitempty"` // The repository's branch at the time
+	Created time.Time       `json:"created"`
+	Runs    []ComparisonRun `json:"runs"`
+	Winner  string          `json:"winner,omitempty"` // The kept run's session ID
+}
+
+// ComparisonsPath is the active project's comparisons file.
+func (s *Storage) ComparisonsPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), comparisonsFile)
+}
+
+// LoadComparisons reads a comparisons file. A missing file is no
+// comparisons.
+func LoadComparisons(path string) ([]*Comparison, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read comparisons: %w", err)
+	}
+	var comparisons []*Comparison
+	if err := json.Unmarshal(data, &comparisons); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return comparisons, nil
+}
+
+// SaveComparisons writes a comparisons file, whole, by rename.
+func SaveComparisons(path string, comparisons []*Comparison) error {
+	if comparisons == nil {
+		comparisons = []*Comparison{}
+	}
+	data, err := json.MarshalIndent(comparisons, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write comparisons: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewComparison returns an empty comparison of the repository dir is in, at
+// its current commit.
+func NewComparison(name, prompt, dir string, now time.Time) (*Comparison, error) {
+	if BranchSlug(name) == "" {
+		return nil, fmt.Errorf("a comparison needs a name")
+	}
+	repo, commit, branch, err := RepoHead(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{
+		ID:      fmt.Sprintf("cmp_%d", now.UnixNano()),
+		Name:    name,
+		Prompt:  prompt,
+		Repo:    repo,
+		Base:    commit,
+		Branch:  branch,
+		Created: now,
+	}, nil
+}
+
+// AddRun makes a worktree under root for one more agent, and returns its
+// session, not yet saved or started. The session's diff starts at the
+// comparison's commit.
+func (c *Comparison) AddRun(root string, agent AgentType, autoYes bool) (*Instance, error) {
+	branch, dir := FreeWorktree(c.Repo, root, compareBranchPrefix, BranchSlug(c.Name)+"-"+string(agent))
+	if err := AddWorktree(c.Repo, branch, dir, c.Base); err != nil {
+		return nil, err
+	}
+	inst, err := NewInstance(filepath.Base(dir), dir, autoYes, agent)
+	if err != nil {
+		return nil, errors.Join(err, RemoveWorktree(c.Repo, dir), DeleteBranch(c.Repo, branch))
+	}
+	inst.BaseCommitSHA = c.Base
+	inst.RepoPath, inst.Branch, inst.WorktreePath, inst.BaseBranch = c.Repo, branch, dir, c.Branch
+	c.Runs = append(c.Runs, ComparisonRun{
+		SessionID: inst.ID,
+		Agent:     agent,
+		Branch:    branch,
+		Worktree:  dir,
+	})
+	return inst, nil
+}
+
+// Run returns the comparison's run of a session, or nil.
+func (c *Comparison) Run(sessionID string) *ComparisonRun {
+	for i := range c.Runs {
+		if c.Runs[i].SessionID == sessionID {
+			return &c.Runs[i]
+		}
+	}
+	return nil
+}
+
+// Decided is whether a winner was kept.
+func (c *Comparison) Decided() bool {
+	return c.Winner != ""
+}
+
+// MarkFinished records, for each run sent the prompt and not finished yet,
+// the first time events show its session going from busy to idle after it
+// was sent. Reports whether any run finished.
+func (c *Comparison) MarkFinished(events []ActivityEvent) bool {
+	changed := false
+	for i := range c.Runs {
+		run := &c.Runs[i]
+		if run.SentAt.IsZero() || !run.FinishedAt.IsZero() {
+			continue
+		}
+		for _, e := range events {
+			if e.Kind == EventActivity && e.SessionID == run.SessionID &&
+				e.From.Working() && e.To == ActivityIdle && e.Time.After(run.SentAt) {
+				run.FinishedAt = e.Time
+				changed = true
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// Elapsed is how long a run has worked on the prompt: until it finished, or
+// until now.
+func (r ComparisonRun) Elapsed(now time.Time) time.Duration {
+	switch {
+	case r.SentAt.IsZero():
+		return 0
+	case !r.FinishedAt.IsZero():
+		return r.FinishedAt.Sub(r.SentAt)
+	}
+	return now.Sub(r.SentAt)
+}
+
+// RemoveRunCheckout deletes a run's worktree and branch. Its session is the
+// caller's to remove.
+func (c *Comparison) RemoveRunCheckout(run ComparisonRun) error {
+	return errors.Join(RemoveWorktree(c.Repo, run.Worktree), DeleteBranch(c.Repo, run.Branch))
+}
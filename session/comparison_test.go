@@ -0,0 +1,124 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gitRepo makes a repository with one commit of one file, or skips the test
+// without git.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.email=a@b", "-c", "user.name=a", "commit", "-q", "-m", "init"},
+	} {
+		if err := exec.Command("git", append([]string{"-C", dir}, args...)...).Run(); err != nil {
+			t.Skipf("git unavailable: %v", err)
+		}
+	}
+	return dir
+}
+
+// Each agent gets a branch and worktree of its own at the repository's
+// HEAD; what it changes there, new files included, is counted without
+// touching its index; and removing the run leaves nothing behind.
+func TestComparisonRunWorktree(t *testing.T) {
+	repo := gitRepo(t)
+	root := t.TempDir()
+
+	c, err := NewComparison("Login bug", "fix the login", repo, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Branch != "main" {
+		t.Errorf("base branch = %q, want main", c.Branch)
+	}
+	claude, err := c.AddRun(root, AgentClaude, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codex, err := c.AddRun(root, AgentCodex, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claude.Path == codex.Path || c.Runs[0].Branch != "compare/login-bug-claude" {
+		t.Fatalf("runs = %+v", c.Runs)
+	}
+	if claude.BaseCommitSHA != c.Base {
+		t.Errorf("session base = %q, want %q", claude.BaseCommitSHA, c.Base)
+	}
+
+	if err := os.WriteFile(filepath.Join(claude.Path, "main.go"), []byte("package main\n\nfunc main() { login() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(claude.Path, "login.go"), []byte("package main\n\nfunc login() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := SummarizeDiff(claude.Path, c.Base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != (DiffSummary{Files: 2, Added: 4, Removed: 1}) {
+		t.Errorf("summary = %+v", sum)
+	}
+	if staged, _ := git(claude.Path, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("counting staged %q", staged)
+	}
+
+	if err := c.RemoveRunCheckout(c.Runs[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(codex.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree still there: %v", err)
+	}
+	if branchExists(repo, c.Runs[1].Branch) {
+		t.Errorf("branch %s still there", c.Runs[1].Branch)
+	}
+
+	// A second comparison of the same name does not take the first's places
+	branch, dir := FreeWorktree(repo, root, compareBranchPrefix, "login-bug-claude")
+	if branch != "compare/login-bug-claude-2" || filepath.Base(dir) != "login-bug-claude-2" {
+		t.Errorf("free worktree = %s, %s", branch, dir)
+	}
+}
+
+// A run finishes on its first busy-to-idle after it was sent the prompt:
+// not on one from before, and not again.
+func TestComparisonMarkFinished(t *testing.T) {
+	t.Parallel()
+
+	sent := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	c := &Comparison{Runs: []ComparisonRun{{SessionID: "a", SentAt: sent}, {SessionID: "b"}}}
+	finish := func(id string, at time.Time) ActivityEvent {
+		return ActivityEvent{Time: at, SessionID: id, Kind: EventActivity, From: ActivityBusy, To: ActivityIdle}
+	}
+
+	if c.MarkFinished([]ActivityEvent{finish("a", sent.Add(-time.Minute)), finish("b", sent.Add(time.Minute))}) {
+		t.Error("finished on an event from before the prompt, or without one")
+	}
+	if !c.MarkFinished([]ActivityEvent{finish("a", sent.Add(3*time.Minute))}) {
+		t.Fatal("did not finish")
+	}
+	c.MarkFinished([]ActivityEvent{finish("a", sent.Add(5*time.Minute))})
+	if got := c.Runs[0].Elapsed(sent.Add(time.Hour)); got != 3*time.Minute {
+		t.Errorf("elapsed = %s, want 3m", got)
+	}
+
+	path := filepath.Join(t.TempDir(), comparisonsFile)
+	if err := SaveComparisons(path, []*Comparison{c}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadComparisons(path)
+	if err != nil || len(loaded) != 1 || !loaded[0].Runs[0].FinishedAt.Equal(c.Runs[0].FinishedAt) {
+		t.Errorf("loaded %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,172 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron expressions for recurring scheduled prompts.
+//
+// The five fields everyone already knows from crontab — minute, hour, day of
+// month, month, day of week — with *, lists, ranges and steps, day and month
+// names, and the @hourly/@daily/@weekly/@monthly shorthands. As in cron, a
+// day matches when either of its two day fields does, if both are given.
+// Times are local: "0 9 * * mon-fri" is nine in the morning where the
+// laptop is.
+
+// CronSpec is a parsed cron expression.
+type CronSpec struct {
+	minute, hour, dom, month, dow uint64 // Bit n set: n matches
+	domAny, dowAny                bool   // The field was *
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var cronDayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// ParseCron parses a five-field cron expression or one of its @ shorthands.
+func ParseCron(expr string) (CronSpec, error) {
+	expr = strings.TrimSpace(strings.ToLower(expr))
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return CronSpec{}, fmt.Errorf("cron expression %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	var spec CronSpec
+	var err error
+	if spec.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return CronSpec{}, fmt.Errorf("minute: %w", err)
+	}
+	if spec.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return CronSpec{}, fmt.Errorf("hour: %w", err)
+	}
+	if spec.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return CronSpec{}, fmt.Errorf("day of month: %w", err)
+	}
+	if spec.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return CronSpec{}, fmt.Errorf("month: %w", err)
+	}
+	// 7 is Sunday too
+	if spec.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return CronSpec{}, fmt.Errorf("day of week: %w", err)
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	spec.domAny = fields[2] == "*"
+	spec.dowAny = fields[4] == "*"
+	return spec, nil
+}
+
+// parseCronField turns one field into a bit set of the values it matches.
+func parseCronField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[s]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("%q is not a value from %d to %d", s, lo, hi)
+		}
+		return n, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%q is not a step", stepPart)
+			}
+			step = n
+		}
+
+		start, end := lo, hi
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = value(from); err != nil {
+				return 0, err
+			}
+			if end, err = value(to); err != nil {
+				return 0, err
+			}
+			if end < start {
+				return 0, fmt.Errorf("range %q runs backwards", rangePart)
+			}
+		default:
+			n, err := value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			start = n
+			if !hasStep {
+				end = n
+			}
+		}
+		for n := start; n <= end; n += step {
+			bits |= 1 << n
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches applies cron's rule for the two day fields: with both
+// restricted, either one matching is enough.
+func (c CronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next is the first time after t the expression matches, or the zero time if
+// there is none within five years (February 30th).
+func (c CronSpec) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
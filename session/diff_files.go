@@ -0,0 +1,166 @@
+package session
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A diff split by file and hunk.
+//
+// git diff prints one stream; the diff view lists its files, counts their
+// lines and jumps between files and hunks, so it needs to know where each
+// starts. ParseDiff reads the stream getDiff produces — a/ and b/ prefixes,
+// no colour — into files, each with its own lines and hunks. Nothing is lost:
+// a file's lines, in order, are exactly what the stream had for it.
+
+// FileStatus is how a file changed.
+type FileStatus string
+
+const (
+	FileModified FileStatus = "modified"
+	FileNew      FileStatus = "new"
+	FileDeleted  FileStatus = "deleted"
+	FileRenamed  FileStatus = "renamed"
+)
+
+// Letter is the status as git status abbreviates it.
+func (s FileStatus) Letter() string {
+	switch s {
+	case FileNew:
+		return "A"
+	case FileDeleted:
+		return "D"
+	case FileRenamed:
+		return "R"
+	}
+	return "M"
+}
+
+// DiffHunk is one @@ section of a file's diff, as lines of the file's Lines.
+type DiffHunk struct {
+	Start int // Index of its @@ line
+	End   int // Index after its last line
+}
+
+// DiffFile is one file's part of a diff.
+type DiffFile struct {
+	Path    string     // Its path after the change; before, if deleted
+	OldPath string     // Its path before a rename
+	Status  FileStatus // How it changed
+	Binary  bool       // No lines to show: git only says it differs
+	Added   int        // Lines added
+	Removed int        // Lines removed
+	Lines   []string   // Its part of the diff, from the diff --git line on
+	Hunks   []DiffHunk // Its hunks, in order
+}
+
+// ParseDiff splits a diff into its files.
+func ParseDiff(content string) []DiffFile {
+	var files []DiffFile
+	var f *DiffFile
+	inHunk := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, DiffFile{Path: diffGitPath(line), Status: FileModified})
+			f = &files[len(files)-1]
+			inHunk = false
+		}
+		if f == nil {
+			continue
+		}
+		n := len(f.Lines)
+		f.Lines = append(f.Lines, line)
+
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			f.Hunks = append(f.Hunks, DiffHunk{Start: n, End: n + 1})
+			continue
+		}
+		if inHunk {
+			f.Hunks[len(f.Hunks)-1].End = n + 1
+			switch {
+			case strings.HasPrefix(line, "+"):
+				f.Added++
+			case strings.HasPrefix(line, "-"):
+				f.Removed++
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			f.Status = FileNew
+		case strings.HasPrefix(line, "deleted file mode"):
+			f.Status = FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			f.Status = FileRenamed
+			f.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			f.Path = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			f.Binary = true
+		case strings.HasPrefix(line, "+++ ") && line != "+++ /dev/null":
+			f.Path = strings.TrimPrefix(unquotePath(strings.TrimPrefix(line, "+++ ")), "b/")
+		}
+	}
+	return files
+}
+
+// ParseHunkHeader reads where a hunk starts in the old and new file from its
+// @@ -a,b +c,d @@ line, and how far it reaches.
+func ParseHunkHeader(line string) (oldStart, oldEnd, newStart, newEnd int, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "@@" {
+		return 0, 0, 0, 0, false
+	}
+	hunkRange := func(field, sign string) (int, int, bool) {
+		field, found := strings.CutPrefix(field, sign)
+		if !found {
+			return 0, 0, false
+		}
+		start, count, hasCount := strings.Cut(field, ",")
+		from, err := strconv.Atoi(start)
+		if err != nil {
+			return 0, 0, false
+		}
+		n := 1
+		if hasCount {
+			if n, err = strconv.Atoi(count); err != nil {
+				return 0, 0, false
+			}
+		}
+		return from, from + n, true
+	}
+	oldStart, oldEnd, okOld := hunkRange(fields[1], "-")
+	newStart, newEnd, okNew := hunkRange(fields[2], "+")
+	return oldStart, oldEnd, newStart, newEnd, okOld && okNew
+}
+
+// diffGitPath takes the path from a diff --git line. Both sides are the same
+// path unless the file was renamed, which later lines tell anyway.
+func diffGitPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if strings.HasPrefix(rest, `"`) {
+		// Quoted: a path with characters git escapes
+		if end := strings.Index(rest[1:], `" `); end >= 0 {
+			return strings.TrimPrefix(unquotePath(rest[:end+2]), "a/")
+		}
+	}
+	if n := len(rest); n >= 5 && (n-5)%2 == 0 && strings.HasPrefix(rest, "a/") {
+		return rest[2 : 2+(n-5)/2]
+	}
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+3:]
+	}
+	return rest
+}
+
+// unquotePath undoes git's quoting of a path with unusual characters.
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
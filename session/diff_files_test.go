@@ -0,0 +1,123 @@
+package session
+
+import "testing"
+
+// testDiff has a file of each kind git tells apart: modified with two hunks,
+// new, deleted, renamed with a change, binary and one whose name git quotes.
+const testDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-func main() {}
++func main() { run() }
+@@ -10,2 +10,3 @@ func run() {
+ 	a := 1
++	b := 2
+diff --git a/login.go b/login.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/login.go
+@@ -0,0 +1,2 @@
++package main
++func login() {}
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 4444444..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/util.go b/internal/util.go
+similarity index 90%
+rename from util.go
+rename to internal/util.go
+index 5555555..6666666 100644
+--- a/util.go
++++ b/internal/util.go
+@@ -1 +1 @@
+-package main
++package internal
+diff --git a/logo.png b/logo.png
+index 7777777..8888888 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git "a/caf\303\251.txt" "b/caf\303\251.txt"
+new file mode 100644
+index 0000000..9999999
+--- /dev/null
++++ "b/caf\303\251.txt"
+@@ -0,0 +1 @@
++menu
+`
+
+// Each file gets its path, status, counts and hunks, and its lines are
+// exactly its part of the stream.
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff(testDiff)
+	want := []struct {
+		path, oldPath  string
+		status         FileStatus
+		binary         bool
+		added, removed int
+		hunks          int
+	}{
+		{"main.go", "", FileModified, false, 2, 1, 2},
+		{"login.go", "", FileNew, false, 2, 0, 1},
+		{"old.go", "", FileDeleted, false, 0, 1, 1},
+		{"internal/util.go", "util.go", FileRenamed, false, 1, 1, 1},
+		{"logo.png", "", FileModified, true, 0, 0, 0},
+		{"café.txt", "", FileNew, false, 1, 0, 1},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("%d files, want %d", len(files), len(want))
+	}
+	lines := 0
+	for i, w := range want {
+		f := files[i]
+		if f.Path != w.path || f.OldPath != w.oldPath || f.Status != w.status || f.Binary != w.binary {
+			t.Errorf("file %d = %q from %q, %s, binary %v", i, f.Path, f.OldPath, f.Status, f.Binary)
+		}
+		if f.Added != w.added || f.Removed != w.removed || len(f.Hunks) != w.hunks {
+			t.Errorf("%s: +%d -%d in %d hunks, want +%d -%d in %d", w.path, f.Added, f.Removed, len(f.Hunks), w.added, w.removed, w.hunks)
+		}
+		lines += len(f.Lines)
+	}
+	if lines != 46 {
+		t.Errorf("%d lines in all, want the stream's 46", lines)
+	}
+
+	main := files[0]
+	if h := main.Hunks[1]; main.Lines[h.Start] != "@@ -10,2 +10,3 @@ func run() {" || h.End != len(main.Lines) {
+		t.Errorf("second hunk = %+v", h)
+	}
+	if h := main.Hunks[0]; h.Start != 4 || h.End != 8 {
+		t.Errorf("first hunk = %+v, want lines 4-8", h)
+	}
+}
+
+// A path with spaces is read from the diff --git line when nothing else
+// names it, as for a binary file.
+func TestDiffGitPath(t *testing.T) {
+	for line, want := range map[string]string{
+		"diff --git a/my file.png b/my file.png":     "my file.png",
+		"diff --git a/a b/c.go b/a b/c.go":           "a b/c.go",
+		`diff --git "a/caf\303\251" "b/caf\303\251"`: "café",
+	} {
+		if got := diffGitPath(line); got != want {
+			t.Errorf("diffGitPath(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+// A hunk header gives where the hunk is in the old and new file; a count
+// left out is 1.
+func TestParseHunkHeader(t *testing.T) {
+	if o, oe, n, ne, ok := ParseHunkHeader("@@ -98 +99,0 @@ func f() {"); !ok || o != 98 || oe != 99 || n != 99 || ne != 99 {
+		t.Errorf("ParseHunkHeader = %d %d %d %d %v", o, oe, n, ne, ok)
+	}
+	if _, _, _, _, ok := ParseHunkHeader("@@ bad @@"); ok {
+		t.Error("a broken header parsed")
+	}
+}
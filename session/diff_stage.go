@@ -0,0 +1,77 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Staging, unstaging and discarding part of a diff.
+//
+// An agent's change is rarely all wanted: the fix is, the reformatting of
+// three unrelated files is not. The diff view lets you keep or throw away a
+// hunk or a file at a time. Each is a patch cut from the diff on screen — the
+// file's header lines and the hunk — given to git apply: onto the index to
+// stage it, reversed onto the index to unstage it, reversed onto the working
+// tree to discard it. git checks that the patch still fits, so a file the
+// agent has changed again since the diff was read is refused, not mangled.
+
+// PatchAction is what is done with a patch cut from a diff.
+type PatchAction string
+
+const (
+	PatchStage   PatchAction = "stage"   // Add it to the index
+	PatchUnstage PatchAction = "unstage" // Take it back out of the index
+	PatchDiscard PatchAction = "discard" // Undo it in the working tree
+)
+
+// Patch is the file's part of the diff, or with hunk >= 0 only that hunk of
+// it, as git apply takes it.
+func (f DiffFile) Patch(hunk int) (string, error) {
+	if f.Binary {
+		return "", fmt.Errorf("%s is a binary file: the diff has none of its content to apply", f.Path)
+	}
+	lines := f.Lines
+	if hunk >= 0 {
+		if hunk >= len(f.Hunks) {
+			return "", fmt.Errorf("%s has no hunk %d", f.Path, hunk+1)
+		}
+		h := f.Hunks[hunk]
+		lines = append(append([]string{}, f.Lines[:f.Hunks[0].Start]...), f.Lines[h.Start:h.End]...)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// ApplyPatch stages, unstages or discards a patch cut from one of the
+// session's diffs. Patches name paths from the top of the repository, so
+// they are applied there whichever directory the session is in.
+func (i *Instance) ApplyPatch(action PatchAction, patch string) error {
+	root, err := git(i.Path, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	args := []string{"-C", root, "apply", "--whitespace=nowarn"}
+	switch action {
+	case PatchStage:
+		args = append(args, "--cached")
+	case PatchUnstage:
+		args = append(args, "--cached", "--reverse")
+	case PatchDiscard:
+		args = append(args, "--reverse")
+	default:
+		return fmt.Errorf("unknown patch action %q", action)
+	}
+
+	cmd := exec.Command("git", append(args, "-")...)
+	cmd.Stdin = strings.NewReader(patch)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to %s: %s", action, msg)
+		}
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
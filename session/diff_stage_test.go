@@ -0,0 +1,98 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A hunk staged from the diff is all the index gets, and unstaging it puts
+// the index back; a discarded hunk is gone from the file while the rest of the
+// change stays, and discarding a new file removes it. The session sits in a
+// subdirectory: patches still apply from the top of the repository.
+func TestApplyPatch(t *testing.T) {
+	repo := gitRepo(t)
+	var lines []string
+	for n := 1; n <= 20; n++ {
+		lines = append(lines, "line "+strings.Repeat("x", n))
+	}
+	original := strings.Join(lines, "\n") + "\n"
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cmd/list.txt", original)
+	if _, err := git(repo, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "-c", "user.email=a@b", "-c", "user.name=a", "commit", "-q", "-m", "list"); err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(strings.Replace(original, "line x\n", "first\n", 1), "line "+strings.Repeat("x", 20)+"\n", "last\n", 1)
+	write("cmd/list.txt", changed)
+	write("cmd/notes.txt", "scratch\n")
+
+	inst := &Instance{Path: filepath.Join(repo, "cmd")}
+	file := func(path string) DiffFile {
+		t.Helper()
+		for _, f := range ParseDiff(inst.GetFullDiff().Content) {
+			if f.Path == path {
+				return f
+			}
+		}
+		t.Fatalf("%s not in the diff", path)
+		return DiffFile{}
+	}
+
+	list := file("cmd/list.txt")
+	if len(list.Hunks) != 2 {
+		t.Fatalf("%d hunks, want 2", len(list.Hunks))
+	}
+	patch, err := list.Patch(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inst.ApplyPatch(PatchStage, patch); err != nil {
+		t.Fatal(err)
+	}
+	staged := inst.GetStagedDiff()
+	if !strings.Contains(staged.Content, "+first") || strings.Contains(staged.Content, "+last") {
+		t.Errorf("staged:\n%s", staged.Content)
+	}
+
+	if err := inst.ApplyPatch(PatchUnstage, patch); err != nil {
+		t.Fatal(err)
+	}
+	if staged := inst.GetStagedDiff(); !staged.IsEmpty() {
+		t.Errorf("still staged after unstaging:\n%s", staged.Content)
+	}
+
+	patch, _ = list.Patch(1)
+	if err := inst.ApplyPatch(PatchDiscard, patch); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(repo, "cmd", "list.txt"))
+	if want := strings.Replace(original, "line x\n", "first\n", 1); string(data) != want {
+		t.Errorf("after discarding the second hunk:\n%s", data)
+	}
+	if err := inst.ApplyPatch(PatchDiscard, patch); err == nil {
+		t.Error("a hunk discarded twice")
+	}
+
+	patch, _ = file("cmd/notes.txt").Patch(-1)
+	if err := inst.ApplyPatch(PatchDiscard, patch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "cmd", "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("the new file was not removed: %v", err)
+	}
+	if _, err := (DiffFile{Path: "logo.png", Binary: true}).Patch(-1); err == nil {
+		t.Error("a patch of a binary file")
+	}
+}
@@ -0,0 +1,333 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finishing a session's work.
+//
+// What an agent did has to end up in a commit, and the commit somewhere:
+// merged into the branch the session started from, replayed on top of it, or
+// written out as a patch or bundle for someone else. Done by hand that is a
+// terminal tab and half a dozen git commands; finishing does it in one go,
+// after which the session is stopped and a worktree of its own removed.
+//
+// Only a session with a checkout or a branch of its own is finished: in a
+// shared checkout the changes, and the commits since the session started,
+// are the user's and other sessions' as much as its agent's. Merging and
+// rebasing need a branch made from another one: a session in its own
+// worktree, or a compared agent's.
+//
+// What is committed is what the user staged, if anything, from the diff view
+// or with git; only with nothing staged is everything committed. The agent
+// must be idle: git reading files it is still writing, or rebasing under it,
+// would commit half an edit or stop halfway.
+
+// FinishAction is what happens to the session's work once committed.
+type FinishAction string
+
+const (
+	FinishMerge  FinishAction = "merge"  // Merge the branch into the one it was made from
+	FinishRebase FinishAction = "rebase" // Rebase it onto that branch and fast-forward it
+	FinishPatch  FinishAction = "patch"  // Write the commits to a patch file
+	FinishBundle FinishAction = "bundle" // Write them to a git bundle
+	FinishLeave  FinishAction = "leave"  // Keep the commit where it is
+)
+
+// exportsDir is the directory, under the config directory, that patches and
+// bundles are written to.
+const exportsDir = "exports"
+
+// ExportsDir is where finished sessions' patches and bundles are written.
+func (s *Storage) ExportsDir() string {
+	return filepath.Join(s.configDir, exportsDir)
+}
+
+// draftCommitTimeout is how long the agent has to write a commit message.
+const draftCommitTimeout = 3 * time.Minute
+
+// draftCommitPrompt asks the agent for a commit message and nothing else.
+const draftCommitPrompt = "Write a git commit message for the uncommitted changes in this working tree: " +
+	"a summary line of at most 72 characters, then a blank line and a short body if it helps. " +
+	"Do not commit or change any files. Reply with the message only, in a fenced code block."
+
+// CanIntegrate is whether the session's work can be merged or rebased: it
+// has a branch of its own and knows the branch it was made from.
+func (i *Instance) CanIntegrate() bool {
+	return i.Branch != "" && i.BaseBranch != "" && i.RepoPath != ""
+}
+
+// CanFinish is whether the session's work is its own to finish: it has a
+// worktree or a branch of its own.
+func (i *Instance) CanFinish() bool {
+	return i.CanIntegrate() || i.WorktreePath != ""
+}
+
+// FinishActions lists what the session's work can be finished with; none
+// for a session that cannot be finished.
+func (i *Instance) FinishActions() []FinishAction {
+	if !i.CanFinish() {
+		return nil
+	}
+	var actions []FinishAction
+	if i.CanIntegrate() {
+		actions = append(actions, FinishMerge, FinishRebase)
+	}
+	return append(actions, FinishPatch, FinishBundle, FinishLeave)
+}
+
+// HasUncommitted is whether the session's checkout has anything to commit,
+// new files included.
+func (i *Instance) HasUncommitted() (bool, error) {
+	status, err := git(i.Path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return status != "", nil
+}
+
+// HasStaged is whether anything is staged in the session's checkout.
+func (i *Instance) HasStaged() (bool, error) {
+	staged, err := git(i.Path, "diff", "--cached", "--name-only")
+	if err != nil {
+		return false, err
+	}
+	return staged != "", nil
+}
+
+// DraftCommitMessage asks the session's agent for a commit message for its
+// changes and waits for the answer. The agent must be idle to be asked.
+func (i *Instance) DraftCommitMessage() (string, error) {
+	window := i.GetMainWindowIndex()
+	if activity := i.DetectActivityForWindow(window); activity != ActivityIdle {
+		return "", fmt.Errorf("%s is %s: ask again once it is idle", i.Name, activity)
+	}
+	mark, err := i.PaneMark(window)
+	if err != nil {
+		return "", err
+	}
+	since := time.Now()
+	if err := i.SendPromptToWindow(window, draftCommitPrompt); err != nil {
+		return "", err
+	}
+	// Let it get going, or the idle screen before the prompt would do
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(time.Second) {
+		if i.DetectActivityForWindow(window) != ActivityIdle {
+			break
+		}
+	}
+	if err := waitForAgent(i, window, draftCommitTimeout); err != nil {
+		return "", err
+	}
+	answer, err := i.AnswerSince(window, mark, since)
+	if err != nil {
+		return "", err
+	}
+	message := CommitMessageFromAnswer(answer)
+	if message == "" {
+		return "", fmt.Errorf("%s did not write a commit message", i.Name)
+	}
+	return message, nil
+}
+
+// CommitMessageFromAnswer takes the commit message out of an agent's answer:
+// the first fenced block, indented as the tab showed it or not, else the
+// whole answer.
+func CommitMessageFromAnswer(answer string) string {
+	lines := strings.Split(answer, "\n")
+	start := -1
+	for n, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		if start < 0 {
+			start = n + 1
+			continue
+		}
+		return dedent(lines[start:n])
+	}
+	return strings.TrimSpace(answer)
+}
+
+// dedent joins lines with the indent they all share taken off.
+func dedent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for n, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[n] = line[indent:]
+		}
+		lines[n] = strings.TrimRight(lines[n], " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Finish commits the session's staged changes, or all of them if none are
+// staged, with the message, then does the action with its work. Patches and
+// bundles are written to exportDir. Returns what was done, to tell.
+func (i *Instance) Finish(action FinishAction, message, exportDir string) (string, error) {
+	if !i.CanFinish() {
+		return "", fmt.Errorf("%s has no worktree or branch of its own: commit its changes with git", i.Name)
+	}
+	if activity := i.DetectActivityForWindow(i.GetMainWindowIndex()); activity != ActivityIdle {
+		return "", fmt.Errorf("%s is %s: finish once it is idle", i.Name, activity)
+	}
+	dirty, err := i.HasUncommitted()
+	if err != nil {
+		return "", err
+	}
+	if dirty {
+		if strings.TrimSpace(message) == "" {
+			return "", fmt.Errorf("write a commit message first")
+		}
+		staged, err := i.HasStaged()
+		if err != nil {
+			return "", err
+		}
+		if !staged {
+			if _, err := git(i.Path, "add", "-A"); err != nil {
+				return "", err
+			}
+		}
+		if _, err := git(i.Path, "commit", "-q", "-m", strings.TrimSpace(message)); err != nil {
+			return "", err
+		}
+	}
+
+	switch action {
+	case FinishMerge, FinishRebase:
+		return i.integrate(action)
+	case FinishPatch, FinishBundle:
+		return i.export(action, exportDir)
+	}
+	branch := i.Branch
+	if branch == "" {
+		branch, _ = git(i.Path, "symbolic-ref", "--quiet", "--short", "HEAD")
+	}
+	if !dirty {
+		return fmt.Sprintf("Nothing to commit; %s left as it is", branch), nil
+	}
+	return fmt.Sprintf("Committed on %s", branch), nil
+}
+
+// integrate brings the session's branch into the branch it was made from,
+// which must be checked out, with nothing uncommitted, in the repository.
+// A merge or rebase that stops on conflicts is undone.
+func (i *Instance) integrate(action FinishAction) (string, error) {
+	if !i.CanIntegrate() {
+		return "", fmt.Errorf("%s has no branch of its own to %s", i.Name, action)
+	}
+	current, _ := git(i.RepoPath, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if current != i.BaseBranch {
+		return "", fmt.Errorf("check out %s in %s to %s into it", i.BaseBranch, i.RepoPath, action)
+	}
+	if status, err := git(i.RepoPath, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return "", err
+	} else if status != "" {
+		return "", fmt.Errorf("%s has uncommitted changes: commit or stash them first", i.RepoPath)
+	}
+
+	if action == FinishRebase {
+		if dirty, err := i.HasUncommitted(); err != nil {
+			return "", err
+		} else if dirty {
+			return "", fmt.Errorf("%s has changes left uncommitted: discard them, or stage them to commit, before rebasing", i.Name)
+		}
+		if _, err := git(i.Path, "rebase", i.BaseBranch); err != nil {
+			git(i.Path, "rebase", "--abort")
+			return "", fmt.Errorf("rebase onto %s stopped, nothing changed: %w", i.BaseBranch, err)
+		}
+		if _, err := git(i.RepoPath, "merge", "--ff-only", i.Branch); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Rebased %s onto %s and fast-forwarded %s", i.Branch, i.BaseBranch, i.BaseBranch), nil
+	}
+	if _, err := git(i.RepoPath, "merge", "--no-edit", i.Branch); err != nil {
+		git(i.RepoPath, "merge", "--abort")
+		return "", fmt.Errorf("merge into %s stopped, nothing changed: %w", i.BaseBranch, err)
+	}
+	return fmt.Sprintf("Merged %s into %s", i.Branch, i.BaseBranch), nil
+}
+
+// export writes the session's commits since its branch point, or its base
+// commit, to a patch or a bundle in dir, under a name not taken yet.
+func (i *Instance) export(action FinishAction, dir string) (string, error) {
+	base := i.branchPoint()
+	if base == "" {
+		base = i.BaseCommitSHA
+	}
+	if base == "" {
+		return "", fmt.Errorf("no base commit to export the changes from")
+	}
+	root, _, branch, err := RepoHead(i.Path)
+	if err != nil {
+		return "", err
+	}
+	if i.Branch != "" {
+		branch = i.Branch
+	}
+	name := BranchSlug(branch)
+	if name == "" {
+		name = BranchSlug(i.Name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := freeFile(dir, BranchSlug(filepath.Base(root))+"-"+name, "."+string(action))
+
+	if action == FinishBundle {
+		ref := branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := git(i.Path, "bundle", "create", path, base+".."+ref); err != nil {
+			return "", err
+		}
+		return "Wrote " + path, nil
+	}
+	patch, err := git(i.Path, "format-patch", "--stdout", base+"..HEAD")
+	if err != nil {
+		return "", err
+	}
+	if patch == "" {
+		return "", fmt.Errorf("no commits to export since %s", shortSHA(base))
+	}
+	if err := os.WriteFile(path, []byte(patch+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write the patch: %w", err)
+	}
+	return "Wrote " + path, nil
+}
+
+// freeFile is dir/name+ext, or with -2, -3, ... before ext if taken.
+func freeFile(dir, name, ext string) string {
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, name+ext)
+		if n > 1 {
+			path = filepath.Join(dir, name+"-"+strconv.Itoa(n)+ext)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+	}
+}
+
+// shortSHA is a commit's abbreviation, as git shows it.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The message is the answer's first fenced block, without the indent a tab
+// shows it with, or the whole answer if it has none.
+func TestCommitMessageFromAnswer(t *testing.T) {
+	t.Parallel()
+
+	answer := "Here it is:\n\n  ```text\n  Fix login redirect\n\n  Keep the return URL.\n  ```\n\nAnything else?"
+	if got := CommitMessageFromAnswer(answer); got != "Fix login redirect\n\nKeep the return URL." {
+		t.Errorf("message = %q", got)
+	}
+	if got := CommitMessageFromAnswer("  Fix login redirect \n"); got != "Fix login redirect" {
+		t.Errorf("message = %q", got)
+	}
+}
+
+// finishRepo makes a repository and a session in a worktree of its own with
+// one new file in it, with a git identity to commit as.
+func finishRepo(t *testing.T) (repo string, inst *Instance) {
+	t.Helper()
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "a")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "a@b")
+	}
+	repo = gitRepo(t)
+	inst = &Instance{Name: "login", Path: repo}
+	if err := inst.UseWorktree(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inst.Path, "login.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return repo, inst
+}
+
+// Merging commits the changes with the message and merges the branch into
+// the one it was made from; not without a message, and not into a branch
+// that is not checked out.
+func TestFinishMerge(t *testing.T) {
+	repo, inst := finishRepo(t)
+
+	if _, err := inst.Finish(FinishMerge, " ", ""); err == nil {
+		t.Error("committed without a message")
+	}
+	if _, err := git(repo, "checkout", "-q", "-b", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inst.Finish(FinishMerge, "Add login", ""); err == nil || !strings.Contains(err.Error(), "check out main") {
+		t.Errorf("merged into a branch not checked out: %v", err)
+	}
+	if _, err := git(repo, "checkout", "-q", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := inst.Finish(FinishMerge, "Add login", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Merged login into main" {
+		t.Errorf("result = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "login.go")); err != nil {
+		t.Errorf("main lacks the change: %v", err)
+	}
+	if subject, _ := git(repo, "log", "-1", "--format=%s", inst.Branch); subject != "Add login" {
+		t.Errorf("commit subject = %q", subject)
+	}
+}
+
+// Rebasing replays the session's commit on what the base branch got since,
+// and moves the base branch to it.
+func TestFinishRebase(t *testing.T) {
+	repo, inst := finishRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "upstream.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "commit", "-q", "-m", "upstream"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inst.Finish(FinishRebase, "Add login", ""); err != nil {
+		t.Fatal(err)
+	}
+	main, _ := git(repo, "rev-parse", "main")
+	branch, _ := git(repo, "rev-parse", inst.Branch)
+	if main != branch {
+		t.Errorf("main at %s, branch at %s", main, branch)
+	}
+	if parent, _ := git(repo, "log", "-1", "--format=%s", "main~1"); parent != "upstream" {
+		t.Errorf("rebased onto %q", parent)
+	}
+}
+
+// A patch and a bundle hold the session's commit, under names that do not
+// overwrite each other.
+func TestFinishExport(t *testing.T) {
+	_, inst := finishRepo(t)
+	dir := t.TempDir()
+
+	got, err := inst.Finish(FinishPatch, "Add login", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := strings.TrimPrefix(got, "Wrote ")
+	patch, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(patch), "Subject: [PATCH] Add login") || !strings.Contains(string(patch), "login.go") {
+		t.Errorf("patch:\n%s", patch)
+	}
+
+	again, err := inst.Finish(FinishPatch, "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == got {
+		t.Errorf("second patch overwrote the first: %s", again)
+	}
+
+	bundle, err := inst.Finish(FinishBundle, "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(inst.Path, "bundle", "verify", strings.TrimPrefix(bundle, "Wrote ")); err != nil {
+		t.Errorf("bundle: %v", err)
+	}
+}
+
+// With something staged only that is committed, and the rest is left as it
+// was; a session in a shared checkout is not finished at all.
+func TestFinishCommitsStaged(t *testing.T) {
+	repo, inst := finishRepo(t)
+	if err := os.WriteFile(filepath.Join(inst.Path, "notes.txt"), []byte("todo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(inst.Path, "add", "login.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inst.Finish(FinishLeave, "Add login", ""); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := git(inst.Path, "show", "--name-only", "--format=", "HEAD"); files != "login.go" {
+		t.Errorf("committed %q, want only login.go", files)
+	}
+	if status, _ := git(inst.Path, "status", "--porcelain"); status != "?? notes.txt" {
+		t.Errorf("left %q, want notes.txt untouched", status)
+	}
+	if _, err := inst.integrate(FinishRebase); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+		t.Errorf("rebased over changes left uncommitted: %v", err)
+	}
+
+	shared := &Instance{Name: "shared", Path: repo}
+	if actions := shared.FinishActions(); len(actions) != 0 {
+		t.Errorf("actions for a shared checkout: %v", actions)
+	}
+	if _, err := shared.Finish(FinishPatch, "Everything", t.TempDir()); err == nil {
+		t.Error("finished a session in a shared checkout")
+	}
+}
@@ -0,0 +1,222 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Handing a task over to another agent.
+//
+// Claude stops at its usage limit halfway through a change, and Codex, in
+// the same directory, knows nothing of it. A handoff writes the new agent
+// the briefing a person would: the end of the conversation so far, the
+// session's or tab's notes, and which files have changed. It is sent as the
+// opening prompt of a new tab running the other agent, in the same working
+// tree, so the changes are already there.
+//
+// The conversation is read from the agent's own transcript where there is a
+// reader for it — Claude's and Gemini's — and is otherwise what the tab
+// shows. Only the last few turns are kept, each message cut to a length that
+// keeps the whole readable.
+
+// DefaultHandoffTurns is how many turns a handoff carries unless told
+// otherwise: enough for the task and where it got to.
+const DefaultHandoffTurns = 6
+
+// maxHandoffMessage is the most of one message a handoff carries; a longer
+// one keeps its beginning and end.
+const maxHandoffMessage = 2000
+
+// maxHandoffFiles is the most changed files a handoff lists.
+const maxHandoffFiles = 40
+
+// handoffScreenLines is how much of the tab a handoff carries when there is
+// no transcript to read.
+const handoffScreenLines = 120
+
+// Handoff is what one agent passes to the next.
+type Handoff struct {
+	Path     string
+	From     AgentType
+	Notes    string
+	Changes  string                // The changed files, or empty
+	Messages []ConversationMessage // The last turns of the conversation
+	Screen   string                // The tab's output, when there is no transcript
+}
+
+// PrepareHandoff reads what a window's agent has to hand over: the last
+// turns of its conversation, its notes and the changes in the session's
+// directory.
+func (i *Instance) PrepareHandoff(windowIdx, turns int) (Handoff, error) {
+	h := Handoff{Path: i.Path, From: i.windowAgent(windowIdx)}
+	h.Notes = i.Notes
+	if fw := i.GetFollowedWindow(windowIdx); fw != nil && fw.Index != i.GetMainWindowIndex() {
+		h.Notes = fw.Notes
+	}
+	h.Changes = i.changesSummary()
+
+	messages, err := i.windowConversation(windowIdx)
+	if err == nil && len(messages) > 0 {
+		h.Messages = CondenseConversation(messages, turns)
+		return h, nil
+	}
+	screen, screenErr := i.CapturePaneLines(windowIdx, handoffScreenLines)
+	if screenErr != nil {
+		return h, fmt.Errorf("nothing to hand over: %w", screenErr)
+	}
+	h.Screen = screen
+	return h, nil
+}
+
+// windowConversation reads the transcript of a window's agent: Claude's by
+// the tab's conversation ID, or the project's latest; Gemini's latest for the
+// directory. nil for agents without a reader.
+func (i *Instance) windowConversation(windowIdx int) ([]ConversationMessage, error) {
+	agent := i.windowAgent(windowIdx)
+	entry := HistoryEntry{Agent: agent}
+	switch agent {
+	case AgentClaude:
+		conversation := i.ResumeSessionID
+		if fw := i.GetFollowedWindow(windowIdx); fw != nil && fw.Index != i.GetMainWindowIndex() {
+			conversation = fw.ResumeSessionID
+		}
+		if report, ok := i.AgentReportForWindow(windowIdx); ok && report.Agent == AgentClaude && report.SessionID != "" {
+			conversation = report.SessionID
+		}
+		dir := GetClaudeProjectDir(i.Path)
+		entry.SessionFile = filepath.Join(dir, conversation+".jsonl")
+		if conversation == "" {
+			entry.SessionFile = newestFile(dir, "", ".jsonl")
+		}
+	case AgentGemini:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256([]byte(i.Path))
+		entry.SessionFile = newestFile(filepath.Join(home, ".gemini", "tmp", hex.EncodeToString(hash[:]), "chats"), "session-", ".json")
+	default:
+		return nil, nil
+	}
+	return entry.LoadConversation()
+}
+
+// newestFile is the most recently changed file in dir with the prefix and
+// suffix, or "".
+func newestFile(dir, prefix, suffix string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	newest, path := int64(0), ""
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) || !strings.HasSuffix(e.Name(), suffix) {
+			continue
+		}
+		if info, err := e.Info(); err == nil && info.ModTime().UnixNano() > newest {
+			newest, path = info.ModTime().UnixNano(), filepath.Join(dir, e.Name())
+		}
+	}
+	return path
+}
+
+// CondenseConversation keeps the last turns — a user message and the
+// replies to it — of a conversation, each message cut to a readable length.
+func CondenseConversation(messages []ConversationMessage, turns int) []ConversationMessage {
+	start, seen := len(messages), 0
+	for start > 0 && seen < turns {
+		start--
+		if messages[start].Role == "user" {
+			seen++
+		}
+	}
+	condensed := make([]ConversationMessage, 0, len(messages)-start)
+	for _, m := range messages[start:] {
+		m.Content = cutMiddle(strings.TrimSpace(m.Content), maxHandoffMessage)
+		if m.Content != "" {
+			condensed = append(condensed, m)
+		}
+	}
+	return condensed
+}
+
+// cutMiddle shortens text to about limit runes, keeping its beginning and,
+// where an answer usually sums up, its end.
+func cutMiddle(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	head, tail := limit*2/3, limit/3
+	return string(runes[:head]) + fmt.Sprintf("\n[… %d characters left out …]\n", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+// changesSummary lists what changed in the session's directory since the
+// session's base commit, or HEAD: the counts, then the files. Empty outside
+// a repository or without changes.
+func (i *Instance) changesSummary() string {
+	base := i.BaseCommitSHA
+	if base == "" {
+		base = "HEAD"
+	}
+	sum, err := SummarizeDiff(i.Path, base)
+	if err != nil || sum.Files == 0 {
+		return ""
+	}
+	var files []string
+	if out, err := git(i.Path, "diff", "--name-status", base); err == nil && out != "" {
+		files = strings.Split(out, "\n")
+	}
+	if out, err := git(i.Path, "ls-files", "--others", "--exclude-standard"); err == nil && out != "" {
+		for _, name := range strings.Split(out, "\n") {
+			files = append(files, "A\t"+name)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files changed, +%d -%d\n", sum.Files, sum.Added, sum.Removed)
+	for n, f := range files {
+		if n == maxHandoffFiles {
+			fmt.Fprintf(&b, "… and %d more\n", len(files)-n)
+			break
+		}
+		b.WriteString(strings.ReplaceAll(f, "\t", " ") + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// speaker names who wrote a message, for the transcript.
+func (h Handoff) speaker(role string) string {
+	if role == "user" {
+		return "User"
+	}
+	return string(h.From)
+}
+
+// Prompt is the handoff as the new agent's opening prompt.
+func (h Handoff) Prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are taking over a task from another coding agent (%s) that stopped before finishing. ", h.From)
+	b.WriteString("Its changes are already in this working tree: check them and carry on from where it left off, rather than starting over.\n\n")
+	fmt.Fprintf(&b, "Working directory: %s\n", h.Path)
+
+	if notes := strings.TrimSpace(h.Notes); notes != "" {
+		b.WriteString("\n## Notes\n\n" + notes + "\n")
+	}
+	if h.Changes != "" {
+		b.WriteString("\n## Changes so far\n\n" + h.Changes + "\n")
+	}
+	if len(h.Messages) > 0 {
+		b.WriteString("\n## The end of its conversation\n")
+		for _, m := range h.Messages {
+			fmt.Fprintf(&b, "\n**%s:**\n%s\n", h.speaker(m.Role), m.Content)
+		}
+	} else if h.Screen != "" {
+		b.WriteString("\n## Its screen when it stopped\n\n" + fenced("", h.Screen) + "\n")
+	}
+	b.WriteString("\nContinue the task.")
+	return b.String()
+}
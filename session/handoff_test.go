@@ -0,0 +1,76 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The last turns are kept from their user message on, and a long message
+// keeps its beginning and end.
+func TestCondenseConversation(t *testing.T) {
+	t.Parallel()
+
+	messages := []ConversationMessage{
+		{Role: "user", Content: "first task"},
+		{Role: "assistant", Content: "done"},
+		{Role: "user", Content: "second task"},
+		{Role: "assistant", Content: "reading"},
+		{Role: "assistant", Content: "start " + strings.Repeat("x", 3*maxHandoffMessage) + " end"},
+		{Role: "user", Content: "third task"},
+		{Role: "assistant", Content: "  "},
+	}
+	got := CondenseConversation(messages, 2)
+	if len(got) != 4 || got[0].Content != "second task" || got[3].Content != "third task" {
+		t.Fatalf("condensed = %+v", got)
+	}
+	long := got[2].Content
+	if len([]rune(long)) > maxHandoffMessage+60 || !strings.HasPrefix(long, "start ") || !strings.HasSuffix(long, " end") {
+		t.Errorf("long message cut to %d runes: %.40q…", len([]rune(long)), long)
+	}
+	if all := CondenseConversation(messages, 10); len(all) != 6 {
+		t.Errorf("more turns than there are kept %d messages, want 6", len(all))
+	}
+}
+
+// The prompt names the agent handing over, and carries the notes, the
+// changes and the conversation; without a transcript, the screen.
+func TestHandoffPrompt(t *testing.T) {
+	t.Parallel()
+
+	h := Handoff{
+		Path:     "/src/app",
+		From:     AgentClaude,
+		Notes:    "Ticket 42: login fails",
+		Changes:  "1 files changed, +3 -1\nM login.go",
+		Messages: []ConversationMessage{{Role: "user", Content: "fix login"}, {Role: "assistant", Content: "patched login.go"}},
+	}
+	prompt := h.Prompt()
+	for _, want := range []string{"(claude)", "/src/app", "Ticket 42", "M login.go", "**User:**\nfix login", "**claude:**\npatched login.go"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+
+	h.Messages, h.Screen = nil, "$ make test\nFAIL"
+	if prompt := h.Prompt(); !strings.Contains(prompt, "```\n$ make test\nFAIL\n```") {
+		t.Errorf("prompt lacks the screen:\n%s", prompt)
+	}
+}
+
+// The changes are counted from the session's base commit and listed, new
+// files included.
+func TestHandoffChangesSummary(t *testing.T) {
+	repo := gitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "login.go"), []byte("package main\n\nfunc login() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := (&Instance{Path: repo}).changesSummary()
+	if got != "2 files changed, +3 -2\nM main.go\nA login.go" {
+		t.Errorf("summary = %q", got)
+	}
+}
@@ -0,0 +1,35 @@
+package session
+
+// Hooks are shell commands run on session events. They can be set globally
+// (hooks.json), per project (Settings) and per session (Instance); the most
+// specific non-empty command wins.
+type Hooks struct {
+	OnWaiting       string `json:"on_waiting,omitempty"`         // A tab starts waiting on the user
+	OnIdleAfterBusy string `json:"on_idle_after_busy,omitempty"` // A tab finishes working
+	OnStalled       string `json:"on_stalled,omitempty"`         // A tab stops making progress
+	OnStart         string `json:"on_start,omitempty"`           // A stopped session is running again
+	OnStop          string `json:"on_stop,omitempty"`            // A running session stops
+	OnTabExit       string `json:"on_tab_exit,omitempty"`        // A tab's process exits
+	OnDiffChanged   string `json:"on_diff_changed,omitempty"`    // A task leaves the working tree different
+}
+
+// Over returns h with the gaps filled from base.
+func (h *Hooks) Over(base Hooks) Hooks {
+	if h == nil {
+		return base
+	}
+	merged := *h
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&merged.OnWaiting, base.OnWaiting)
+	fill(&merged.OnIdleAfterBusy, base.OnIdleAfterBusy)
+	fill(&merged.OnStalled, base.OnStalled)
+	fill(&merged.OnStart, base.OnStart)
+	fill(&merged.OnStop, base.OnStop)
+	fill(&merged.OnTabExit, base.OnTabExit)
+	fill(&merged.OnDiffChanged, base.OnDiffChanged)
+	return merged
+}
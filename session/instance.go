@@ -129,6 +129,16 @@ type Instance struct {
 	FollowedWindows []FollowedWindow `json:"followed_windows,omitempty"`  // Windows tracked as agents (window 0 is main agent)
 	BaseCommitSHA   string           `json:"base_commit_sha,omitempty"`   // Git HEAD commit at session start (for diff)
 	Favorite        bool             `json:"favorite,omitempty"`          // Whether session is marked as favorite
+	Muted           bool             `json:"muted,omitempty"`             // No notifications for this session
+	Hooks           *Hooks           `json:"hooks,omitempty"`             // Session-level hook commands
+	Approval        *ApprovalPolicy  `json:"approval,omitempty"`          // Session-level auto-approval rules
+	PromptQueue     []QueuedPrompt   `json:"prompt_queue,omitempty"`      // Prompts for the main agent, sent as it goes idle
+	QueuePaused     bool             `json:"queue_paused,omitempty"`      // Hold every queue in the session
+	RepoPath        string           `json:"repo_path,omitempty"`         // Repository the session's worktree belongs to
+	Branch          string           `json:"branch,omitempty"`            // Branch checked out in the session's worktree
+	WorktreePath    string           `json:"worktree_path,omitempty"`     // Worktree of the session's own, removed with it on request
+	BaseBranch      string           `json:"base_branch,omitempty"`       // Branch the worktree's branch was made from
+	ReviewComments  []ReviewComment  `json:"review_comments,omitempty"`   // Comments on the diff for the agent, kept until resolved
 }
 
 // DiffStats contains git diff statistics and content
@@ -146,14 +156,15 @@ func (d *DiffStats) IsEmpty() bool {
 
 // FollowedWindow represents a tmux window tracked as an agent
 type FollowedWindow struct {
-	Index           int       `json:"index"`
-	Agent           AgentType `json:"agent"`
-	Name            string    `json:"name"`              // Tab name for display
-	CustomCommand   string    `json:"custom_command"`    // For custom agents
-	AutoYes         bool      `json:"auto_yes"`          // YOLO mode for this tab
-	ResumeSessionID string    `json:"resume_session_id"` // Resume session ID for this tab
-	Notes           string    `json:"notes,omitempty"`   // User notes for this tab
-	Stopped         bool      `json:"stopped,omitempty"` // Tab is stopped (window killed but can resume)
+	Index           int            `json:"index"`
+	Agent           AgentType      `json:"agent"`
+	Name            string         `json:"name"`                   // Tab name for display
+	CustomCommand   string         `json:"custom_command"`         // For custom agents
+	AutoYes         bool           `json:"auto_yes"`               // YOLO mode for this tab
+	ResumeSessionID string         `json:"resume_session_id"`      // Resume session ID for this tab
+	Notes           string         `json:"notes,omitempty"`        // User notes for this tab
+	Stopped         bool           `json:"stopped,omitempty"`      // Tab is stopped (window killed but can resume)
+	PromptQueue     []QueuedPrompt `json:"prompt_queue,omitempty"` // Prompts for this tab, sent as it goes idle
 }
 
 // GetAgentConfig returns the agent configuration for this instance
@@ -519,6 +530,7 @@ func (i *Instance) restoreFollowedWindows() {
 			Agent:         fw.Agent,
 			Name:          fw.Name,
 			CustomCommand: fw.CustomCommand,
+			PromptQueue:   fw.PromptQueue,
 		})
 	}
 
@@ -529,6 +541,7 @@ func (i *Instance) restoreFollowedWindows() {
 
 func (i *Instance) Stop() error {
 	InvalidateMainWindow(i.TmuxSessionName())
+	forgetAgentReports(i.TmuxSessionName())
 
 	if i.Status != StatusRunning {
 		return nil
@@ -1562,11 +1575,27 @@ func (i *Instance) SendPrompt(text string) error {
 	if !i.IsAlive() {
 		return fmt.Errorf("session not running")
 	}
+	return sendPromptTo(i.TmuxSessionName(), text)
+}
 
-	sessionName := i.TmuxSessionName()
+// SendPromptToWindow is SendPrompt for a given window rather than whichever
+// one is active, for prompts sent without the user choosing the tab.
+func (i *Instance) SendPromptToWindow(windowIdx int, text string) error {
+	if !i.IsAlive() {
+		return fmt.Errorf("session not running")
+	}
+	return sendPromptTo(i.windowTarget(windowIdx), text)
+}
 
+// windowTarget is the tmux target of one of the session's windows.
+func (i *Instance) windowTarget(windowIdx int) string {
+	return fmt.Sprintf("%s:%d", i.TmuxSessionName(), windowIdx)
+}
+
+// sendPromptTo types text into a tmux target and presses Enter.
+func sendPromptTo(target, text string) error {
 	// First send text literally with -l flag to avoid key interpretation
-	cmd := TmuxCommand("send-keys", "-l", "-t", sessionName, text)
+	cmd := TmuxCommand("send-keys", "-l", "-t", target, text)
 	if err := cmd.Run(); err != nil {
 		return err
 	}
@@ -1575,7 +1604,7 @@ func (i *Instance) SendPrompt(text string) error {
 	time.Sleep(50 * time.Millisecond)
 
 	// Then send Enter separately
-	cmd = TmuxCommand("send-keys", "-t", sessionName, "Enter")
+	cmd = TmuxCommand("send-keys", "-t", target, "Enter")
 	return cmd.Run()
 }
 
@@ -1589,20 +1618,33 @@ func (i *Instance) UpdateStatus() {
 
 // Git diff functions
 
-// GetSessionDiff returns diff since session start (BaseCommitSHA)
+// GetSessionDiff returns diff since session start (BaseCommitSHA), or for a
+// session in its own worktree since its branch point
 func (i *Instance) GetSessionDiff() *DiffStats {
+	if base := i.branchPoint(); base != "" {
+		return i.getDiff(base)
+	}
 	if i.BaseCommitSHA == "" {
 		return &DiffStats{Error: fmt.Errorf("no base commit (not a git repo or session started before tracking)")}
 	}
 	return i.getDiff(i.BaseCommitSHA)
 }
 
-// GetFullDiff returns all uncommitted changes (staged + unstaged)
+// GetFullDiff returns the uncommitted changes not yet staged: the working
+// tree against the index
 func (i *Instance) GetFullDiff() *DiffStats {
 	return i.getDiff("")
 }
 
-// getDiff executes git diff and parses the result
+// GetStagedDiff returns the changes staged for the next commit: the index
+// against HEAD
+func (i *Instance) GetStagedDiff() *DiffStats {
+	return i.getDiff("--cached")
+}
+
+// getDiff executes git diff against baseRef — a commit, --cached for the
+// index against HEAD, or "" for the working tree against the index — and
+// parses the result
 func (i *Instance) getDiff(baseRef string) *DiffStats {
 	stats := &DiffStats{}
 
@@ -1611,16 +1653,28 @@ func (i *Instance) getDiff(baseRef string) *DiffStats {
 		return stats
 	}
 
-	// Stage untracked files with intent-to-add for diff visibility
-	i.stageUntrackedFiles()
+	// Untracked files show as new through a copy of the index they are
+	// added to with intent-to-add; the repository's own index is left alone
+	index, cleanup, err := i.tempIndex()
+	if err != nil {
+		stats.Error = fmt.Errorf("git diff failed: %w", err)
+		return stats
+	}
+	defer cleanup()
+	env := append(os.Environ(), "GIT_INDEX_FILE="+index)
+	add := exec.Command("git", "-C", i.Path, "add", "-N", ".")
+	add.Env = env
+	add.Run()
 
-	// Build git diff command
-	args := []string{"-C", i.Path, "--no-pager", "diff"}
+	// Build git diff command, in the form ParseDiff reads whatever the
+	// user's configuration
+	args := []string{"-C", i.Path, "--no-pager", "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/"}
 	if baseRef != "" {
 		args = append(args, baseRef)
 	}
 
 	cmd := exec.Command("git", args...)
+	cmd.Env = env
 	output, err := cmd.Output()
 	if err != nil {
 		stats.Error = fmt.Errorf("git diff failed: %w", err)
@@ -1639,9 +1693,38 @@ func (i *Instance) isGitRepo() bool {
 	return cmd.Run() == nil
 }
 
-// stageUntrackedFiles adds untracked files with --intent-to-add for diff visibility
-func (i *Instance) stageUntrackedFiles() {
-	exec.Command("git", "-C", i.Path, "add", "-N", ".").Run()
+// tempIndex copies the repository's index to a temporary file, for git
+// commands that have to write an index without touching the real one. The
+// returned function removes the copy.
+func (i *Instance) tempIndex() (string, func(), error) {
+	output, err := exec.Command("git", "-C", i.Path, "rev-parse", "--git-path", "index").Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find the index: %w", err)
+	}
+	index := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(i.Path, index)
+	}
+
+	dir, err := os.MkdirTemp("", "asmgr-index-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	temp := filepath.Join(dir, "index")
+	data, err := os.ReadFile(index)
+	switch {
+	case err == nil:
+		err = os.WriteFile(temp, data, 0644)
+	case os.IsNotExist(err):
+		// Nothing added yet: git starts the copy empty
+		err = nil
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy the index: %w", err)
+	}
+	return temp, cleanup, nil
 }
 
 // countDiffLines counts added and removed lines in diff content
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A new file shows in the diff as git shows an added one, and the
+// repository's index is left as it was: the file is still untracked, nothing
+// is staged.
+func TestGetDiffLeavesIndexAlone(t *testing.T) {
+	repo := gitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "login.go"), []byte("package main\n\nfunc login() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(repo, ".git", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inst := &Instance{Path: repo}
+	diff := inst.GetFullDiff()
+	if diff.Error != nil {
+		t.Fatal(diff.Error)
+	}
+	for _, want := range []string{"diff --git a/login.go b/login.go", "new file mode", "+func login() {}", "-func main() {}"} {
+		if !strings.Contains(diff.Content, want) {
+			t.Errorf("diff lacks %q:\n%s", want, diff.Content)
+		}
+	}
+	if diff.Added != 3 || diff.Removed != 2 {
+		t.Errorf("+%d -%d, want +3 -2", diff.Added, diff.Removed)
+	}
+
+	if status, _ := git(repo, "status", "--porcelain"); status != "M main.go\n?? login.go" {
+		t.Errorf("status after the diff:\n%s", status)
+	}
+	after, err := os.ReadFile(filepath.Join(repo, ".git", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("the diff rewrote the index")
+	}
+}
@@ -43,6 +43,7 @@ type agentPatterned struct {
 	Waiting       []string `json:"waiting"`
 	Busy          []string `json:"busy"`
 	ExtraSpinners []string `json:"extraSpinners"`
+	RateLimited   []string `json:"rateLimited"`
 }
 
 var (
@@ -134,9 +135,10 @@ func patternsFor(agent AgentType) (AgentPatterns, bool) {
 		spinners = append(spinners, entry.ExtraSpinners...)
 	}
 	return AgentPatterns{
-		WaitingPatterns: entry.Waiting,
-		BusyPatterns:    entry.Busy,
-		Spinners:        spinners,
+		WaitingPatterns:   entry.Waiting,
+		BusyPatterns:      entry.Busy,
+		Spinners:          spinners,
+		RateLimitPatterns: entry.RateLimited,
 	}, true
 }
 
@@ -116,6 +116,10 @@ func TestFilePatternsMatchCompiledDefaults(t *testing.T) {
 			t.Errorf("%q: spinners differ\n  file:     %q\n  compiled: %q",
 				agent, fromFile.Spinners, compiled.Spinners)
 		}
+		if !sameStrings(fromFile.RateLimitPatterns, compiled.RateLimitPatterns) {
+			t.Errorf("%q: rate limit patterns differ\n  file:     %q\n  compiled: %q",
+				agent, fromFile.RateLimitPatterns, compiled.RateLimitPatterns)
+		}
 	}
 }
 
@@ -0,0 +1,282 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// One session's answer, sent on as the next session's prompt.
+//
+// An implementer Claude in one pane, a reviewer Codex in the other, and a
+// person copying the answer of each into the other all day. A pipeline does
+// the copying: it names its stages — a session, the tab to use, and a
+// template to wrap what that stage is sent — and when the stage it is waiting
+// on goes idle, it takes that stage's answer and sends it on to the next.
+//
+// A looping pipeline goes back to its first stage after the last, and stops
+// once its rounds are done: with two stages and three rounds, the reviewer
+// reviews three times and each review goes back to the implementer. A
+// pipeline that does not loop stops once its last stage has been sent to.
+//
+// The answer is taken from where the agent says it best: the Claude
+// transcript, the message of Codex's last notify report, or, for anything
+// else, what the tab printed since it was sent its prompt.
+
+// pipelinesFile is the file name of a project's pipelines.
+const pipelinesFile = "pipelines.json"
+
+// PipelineStage is one session in a pipeline.
+type PipelineStage struct {
+	SessionID string `json:"session_id"`
+	// Tab is the name of the followed tab to use; empty for the main agent
+	Tab string `json:"tab,omitempty"`
+	// Template wraps what the stage is sent. {{output}} is the previous
+	// stage's answer; with no template, the answer is sent as it is.
+	Template string `json:"template,omitempty"`
+}
+
+// Pipeline passes answers from stage to stage.
+type Pipeline struct {
+	ID     string          `json:"id"`
+	Stages []PipelineStage `json:"stages"`
+	Loop   bool            `json:"loop,omitempty"`
+	Rounds int             `json:"rounds,omitempty"` // With Loop: how many times the last stage runs
+
+	Running bool `json:"running,omitempty"`
+	// Stage is the stage whose answer the pipeline is waiting for, and
+	// Round how many times the first stage has started.
+	Stage int `json:"stage"`
+	Round int `json:"round"`
+	// Since is when the awaited stage was sent its prompt, or when the
+	// pipeline started waiting on it; only finishing after this counts.
+	Since time.Time `json:"since,omitzero"`
+	// Mark is the awaited stage's pane line when it was sent its prompt, to
+	// read what it printed since. -1 for none.
+	Mark       int    `json:"mark"`
+	LastResult string `json:"last_result,omitempty"`
+}
+
+// PipelinesPath is the active project's pipelines file.
+func (s *Storage) PipelinesPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), pipelinesFile)
+}
+
+// LoadPipelines reads a pipelines file. A missing file is no pipelines.
+func LoadPipelines(path string) ([]*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipelines: %w", err)
+	}
+	var pipelines []*Pipeline
+	if err := json.Unmarshal(data, &pipelines); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return pipelines, nil
+}
+
+// SavePipelines writes a pipelines file, whole, by rename.
+func SavePipelines(path string, pipelines []*Pipeline) error {
+	if pipelines == nil {
+		pipelines = []*Pipeline{}
+	}
+	data, err := json.MarshalIndent(pipelines, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pipelines: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewPipeline returns a stopped pipeline with an ID.
+func NewPipeline(stages []PipelineStage, loop bool, rounds int) *Pipeline {
+	return &Pipeline{
+		ID:     fmt.Sprintf("pipe_%d", time.Now().UnixNano()),
+		Stages: stages,
+		Loop:   loop,
+		Rounds: rounds,
+		Mark:   -1,
+	}
+}
+
+// Validate reports what keeps the pipeline from running.
+func (p *Pipeline) Validate() error {
+	if len(p.Stages) < 2 {
+		return fmt.Errorf("a pipeline needs at least two stages")
+	}
+	for i, s := range p.Stages {
+		next := p.Stages[(i+1)%len(p.Stages)]
+		if (i < len(p.Stages)-1 || p.Loop) && s.SessionID == next.SessionID && s.Tab == next.Tab {
+			return fmt.Errorf("stage %d sends to its own tab", i+1)
+		}
+	}
+	if p.Loop && p.Rounds < 1 {
+		return fmt.Errorf("a looping pipeline needs at least one round")
+	}
+	return nil
+}
+
+// Start sets the pipeline waiting on its first stage's next answer.
+func (p *Pipeline) Start(now time.Time) {
+	p.Running = true
+	p.Stage = 0
+	p.Round = 1
+	p.Since = now
+	p.Mark = -1
+	p.LastResult = ""
+}
+
+// Stop stops the pipeline where it is.
+func (p *Pipeline) Stop(result string) {
+	p.Running = false
+	p.LastResult = result
+}
+
+// PipelineStep is the pipeline passing one stage's answer to the next.
+type PipelineStep struct {
+	PipelineID string
+	From, To   PipelineStage
+	Mark       int       // From's pane line when it was sent its prompt
+	Since      time.Time // When From was sent its prompt
+	Round      int       // The round To is sent in
+	Rounds     int
+	Last       bool // The pipeline stops once this is sent
+	Queue      bool // To was busy or had prompts queued: it goes behind them
+}
+
+// Advance moves the pipeline past its awaited stage, which has finished, and
+// returns the step to make. The pipeline waits on the next stage from now;
+// after its last step, it stops.
+func (p *Pipeline) Advance(now time.Time) PipelineStep {
+	from := p.Stage
+	to := from + 1
+	round := p.Round
+	if to == len(p.Stages) {
+		to = 0
+		round++
+	}
+	last := (!p.Loop && to == len(p.Stages)-1) || (p.Loop && to == 0 && round > p.Rounds)
+	step := PipelineStep{
+		PipelineID: p.ID,
+		From:       p.Stages[from],
+		To:         p.Stages[to],
+		Mark:       p.Mark,
+		Since:      p.Since,
+		Round:      min(round, max(p.Rounds, 1)),
+		Rounds:     p.Rounds,
+		Last:       last,
+	}
+	p.Stage, p.Round, p.Since, p.Mark = to, round, now, -1
+	if last {
+		p.Stop("done")
+	}
+	return step
+}
+
+// ExpandPipelineTemplate wraps a stage's answer in the next stage's
+// template: {{output}}, {{from}} (the answering session's name), {{round}}
+// and {{rounds}}.
+func ExpandPipelineTemplate(template, output, from string, round, rounds int) string {
+	if strings.TrimSpace(template) == "" {
+		return output
+	}
+	if !strings.Contains(template, "{{output}}") {
+		template += "\n\n{{output}}"
+	}
+	return strings.NewReplacer(
+		"{{output}}", output,
+		"{{from}}", from,
+		"{{round}}", strconv.Itoa(round),
+		"{{rounds}}", strconv.Itoa(rounds),
+	).Replace(template)
+}
+
+// PaneMark returns the absolute line of a window's cursor: where what it
+// prints next starts, whatever scrolls by before it is read.
+func (i *Instance) PaneMark(windowIdx int) (int, error) {
+	output, err := TmuxCommand("display-message", "-p", "-t", i.windowTarget(windowIdx), "#{history_size} #{cursor_y}").Output()
+	if err != nil {
+		return -1, fmt.Errorf("failed to read the pane position: %w", err)
+	}
+	var history, cursor int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%d %d", &history, &cursor); err != nil {
+		return -1, fmt.Errorf("failed to read the pane position: %w", err)
+	}
+	return history + cursor, nil
+}
+
+// OutputSince returns what a window printed from a PaneMark on.
+func (i *Instance) OutputSince(windowIdx, mark int) (string, error) {
+	now, err := i.PaneMark(windowIdx)
+	if err != nil {
+		return "", err
+	}
+	output, err := TmuxCommand("display-message", "-p", "-t", i.windowTarget(windowIdx), "#{history_size}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the pane position: %w", err)
+	}
+	history, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	if mark < 0 || mark > now {
+		// No mark, or the pane was cleared since: the end of the screen
+		return i.CapturePaneLines(windowIdx, 40)
+	}
+	// Negative lines are history; a mark scrolled out of it starts at its top
+	start := max(mark-history, -history)
+	captured, err := TmuxCommand("capture-pane", "-t", i.windowTarget(windowIdx), "-p", "-J", "-S", strconv.Itoa(start)).Output()
+	if err != nil {
+		return "", fmt.Errorf("capture failed: %w", err)
+	}
+	return strings.TrimSpace(string(captured)), nil
+}
+
+// AnswerSince is a window's agent's answer to a prompt sent at since: the
+// end of Claude's transcript, the message of Codex's notify report, or else
+// what the tab printed from mark on.
+func (i *Instance) AnswerSince(windowIdx, mark int, since time.Time) (string, error) {
+	agent := i.windowAgent(windowIdx)
+	report, reported := i.AgentReportForWindow(windowIdx)
+	reported = reported && report.Agent == agent && !report.Time.Before(since)
+
+	switch agent {
+	case AgentClaude:
+		conversation := i.ResumeSessionID
+		if fw := i.GetFollowedWindow(windowIdx); fw != nil && fw.Index != i.GetMainWindowIndex() {
+			conversation = fw.ResumeSessionID
+		}
+		if reported && report.SessionID != "" {
+			conversation = report.SessionID
+		}
+		if conversation != "" {
+			entry := HistoryEntry{
+				Agent:       AgentClaude,
+				SessionFile: filepath.Join(GetClaudeProjectDir(i.Path), conversation+".jsonl"),
+			}
+			if messages, err := entry.LoadConversation(); err == nil && len(messages) > 0 {
+				last := messages[len(messages)-1]
+				if answer := lastAssistantTurn(messages); answer != "" && !last.Timestamp.Before(since) {
+					return answer, nil
+				}
+			}
+		}
+	case AgentCodex:
+		if reported && report.Message != "" {
+			return report.Message, nil
+		}
+	}
+
+	output, err := i.OutputSince(windowIdx, mark)
+	if err == nil && strings.TrimSpace(output) == "" {
+		err = fmt.Errorf("%s printed nothing", i.Name)
+	}
+	return output, err
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// An implementer and a reviewer for two rounds: each review goes back to the
+// implementer, and the pipeline stops with the second one sent.
+func TestPipelineLoopsForItsRounds(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	p := NewPipeline([]PipelineStage{{SessionID: "impl"}, {SessionID: "review"}}, true, 2)
+	p.Start(now)
+
+	var sent []string
+	for p.Running {
+		step := p.Advance(now)
+		sent = append(sent, step.From.SessionID+">"+step.To.SessionID)
+		if len(sent) > 10 {
+			t.Fatal("the pipeline never stopped")
+		}
+	}
+	want := []string{"impl>review", "review>impl", "impl>review", "review>impl"}
+	if len(sent) != len(want) {
+		t.Fatalf("steps = %v, want %v", sent, want)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Fatalf("steps = %v, want %v", sent, want)
+		}
+	}
+	if p.LastResult != "done" {
+		t.Errorf("LastResult = %q, want done", p.LastResult)
+	}
+}
+
+// Without a loop, the pipeline stops once the last stage has been sent to:
+// nothing waits on its answer.
+func TestPipelineChainStopsAtTheLastStage(t *testing.T) {
+	t.Parallel()
+
+	p := NewPipeline([]PipelineStage{{SessionID: "a"}, {SessionID: "b"}, {SessionID: "c"}}, false, 0)
+	p.Start(time.Now())
+	if step := p.Advance(time.Now()); step.Last || !p.Running {
+		t.Fatalf("stopped after the first step: %+v", step)
+	}
+	if step := p.Advance(time.Now()); !step.Last || p.Running || step.To.SessionID != "c" {
+		t.Errorf("second step %+v, running %v", step, p.Running)
+	}
+}
+
+// A stage cannot send to itself, and a loop needs rounds.
+func TestPipelineValidate(t *testing.T) {
+	t.Parallel()
+
+	if err := NewPipeline([]PipelineStage{{SessionID: "a"}}, false, 0).Validate(); err == nil {
+		t.Error("one stage was accepted")
+	}
+	if err := NewPipeline([]PipelineStage{{SessionID: "a"}, {SessionID: "a"}}, false, 0).Validate(); err == nil {
+		t.Error("a stage sending to its own tab was accepted")
+	}
+	if err := NewPipeline([]PipelineStage{{SessionID: "a"}, {SessionID: "a", Tab: "review"}}, true, 1).Validate(); err != nil {
+		t.Errorf("two tabs of one session: %v", err)
+	}
+	if err := NewPipeline([]PipelineStage{{SessionID: "a"}, {SessionID: "b"}}, true, 0).Validate(); err == nil {
+		t.Error("a loop without rounds was accepted")
+	}
+}
+
+// The answer goes where {{output}}, or below a template without one, and is
+// not itself expanded.
+func TestExpandPipelineTemplate(t *testing.T) {
+	t.Parallel()
+
+	got := ExpandPipelineTemplate("Round {{round}}/{{rounds}} from {{from}}:\n{{output}}", "see {{round}}", "impl", 1, 3)
+	if got != "Round 1/3 from impl:\nsee {{round}}" {
+		t.Errorf("got %q", got)
+	}
+	if got := ExpandPipelineTemplate("Review this", "diff", "impl", 1, 1); got != "Review this\n\ndiff" {
+		t.Errorf("without {{output}}: %q", got)
+	}
+	if got := ExpandPipelineTemplate("", "as is", "impl", 1, 1); got != "as is" {
+		t.Errorf("no template: %q", got)
+	}
+}
+
+func TestPipelinesSaveAndLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), pipelinesFile)
+	p := NewPipeline([]PipelineStage{{SessionID: "a", Template: "t"}, {SessionID: "b", Tab: "review"}}, true, 3)
+	p.Start(time.Now())
+	if err := SavePipelines(path, []*Pipeline{p}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPipelines(path)
+	if err != nil || len(loaded) != 1 {
+		t.Fatalf("loaded %v, %v", loaded, err)
+	}
+	got := loaded[0]
+	if got.ID != p.ID || !got.Running || got.Round != 1 || got.Stages[1].Tab != "review" || got.Mark != -1 {
+		t.Errorf("loaded %+v", got)
+	}
+}
@@ -0,0 +1,357 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reading a permission prompt off the screen, and answering it.
+//
+// Claude and Codex both ask as a short numbered menu under a question:
+//
+//	Do you want to proceed?
+//	❯ 1. Yes
+//	  2. Yes, and don't ask again for npm test commands in /src/app
+//	  3. No, and tell Claude what to do differently (esc)
+//
+//	Would you like to run the following command?
+//	  $ npm test
+//	› 1. Yes, proceed (y)
+//	  2. Yes, and don't ask again for commands that start with `npm` (p)
+//	  3. No, and tell Codex what to do differently (esc)
+//
+// The menu is found from the bottom of the pane — the prompt is always the
+// last thing drawn — and the question is the nearest line above it ending in
+// a question mark. What is read is shown to the user before anything is sent,
+// so a misread costs a dialog, not a wrong answer.
+
+// PromptOption is one choice in a permission prompt.
+type PromptOption struct {
+	Number   int
+	Label    string
+	Key      string // tmux key that picks this option directly, or "" to navigate
+	Selected bool   // The option the agent's cursor is on
+}
+
+// PromptKind is what a prompt asks permission for.
+type PromptKind string
+
+const (
+	PromptOther   PromptKind = ""
+	PromptCommand PromptKind = "command" // Running a shell command
+	PromptFile    PromptKind = "file"    // Creating or changing a file
+)
+
+// PromptDialog is a permission prompt read from a pane.
+type PromptDialog struct {
+	Question string
+	Context  []string // What is being asked about: the command, the file…
+	Options  []PromptOption
+	// Kind and Subject are the command or file the prompt is about, when it
+	// could be told exactly. Approval rules are matched against Subject, so
+	// anything uncertain is left as PromptOther.
+	Kind    PromptKind
+	Subject string
+}
+
+// Same reports whether two reads show the same prompt, so an answer chosen
+// for one is not sent to another that replaced it.
+func (d PromptDialog) Same(other PromptDialog) bool {
+	if d.Question != other.Question || len(d.Options) != len(other.Options) {
+		return false
+	}
+	for idx := range d.Options {
+		if d.Options[idx].Label != other.Options[idx].Label {
+			return false
+		}
+	}
+	return true
+}
+
+// promptOptionLine matches "❯ 1. Yes" and its relatives; the marker is the
+// agent's cursor.
+var promptOptionLine = regexp.MustCompile(`^([❯›>▸]\s*)?(\d)[.)]\s+(.+)$`)
+
+// promptOptionKey matches a trailing shortcut hint: "(y)", "(esc)".
+var promptOptionKey = regexp.MustCompile(`\s*\((esc|[a-z])\)$`)
+
+// promptSearchLines bounds how far from the bottom a prompt may start, so an
+// old numbered list in the scrollback is not taken for a live menu.
+const promptSearchLines = 15
+
+// ParsePromptDialog reads the permission prompt at the bottom of a capture.
+func ParsePromptDialog(lines []string, agent AgentType) (PromptDialog, bool) {
+	clean := make([]string, len(lines))
+	for idx, line := range lines {
+		clean[idx] = cleanDialogLine(line)
+	}
+
+	// The last option: the bottom of the menu.
+	last := -1
+	for idx, seen := len(clean)-1, 0; idx >= 0 && seen < promptSearchLines; idx-- {
+		if clean[idx] == "" {
+			continue
+		}
+		seen++
+		if promptOptionLine.MatchString(clean[idx]) {
+			last = idx
+			break
+		}
+	}
+	if last < 0 {
+		return PromptDialog{}, false
+	}
+
+	// Up to option 1. A line between two options continues the one above it,
+	// as long labels wrap.
+	var options []PromptOption
+	var wrapped []string
+	first := -1
+	for idx := last; idx >= 0 && first < 0; idx-- {
+		line := clean[idx]
+		match := promptOptionLine.FindStringSubmatch(line)
+		if match == nil {
+			if line == "" || isSeparatorLine(line) {
+				return PromptDialog{}, false
+			}
+			wrapped = append([]string{line}, wrapped...)
+			continue
+		}
+		number, _ := strconv.Atoi(match[2])
+		label := strings.Join(append([]string{match[3]}, wrapped...), " ")
+		wrapped = nil
+		options = append([]PromptOption{{Number: number, Label: label, Selected: match[1] != ""}}, options...)
+		if number == 1 {
+			first = idx
+		}
+	}
+	if first < 0 || len(options) < 2 {
+		return PromptDialog{}, false
+	}
+	for idx := range options {
+		if options[idx].Number != idx+1 {
+			return PromptDialog{}, false
+		}
+		options[idx].Key = optionKey(&options[idx], agent)
+	}
+
+	// The question, and what lies between it and the menu. Codex puts the
+	// agent's own reason between the two, which may end in a question mark
+	// too, so a line in the agent's waiting wording wins over the nearest.
+	question, worded := -1, -1
+	waiting := getAgentPatterns(agent).WaitingPatterns
+	for idx := first - 1; idx >= 0 && idx >= first-12 && worded < 0; idx-- {
+		if isSeparatorLine(clean[idx]) {
+			break
+		}
+		if !strings.HasSuffix(clean[idx], "?") {
+			continue
+		}
+		if question < 0 {
+			question = idx
+		}
+		lower := strings.ToLower(clean[idx])
+		for _, pattern := range waiting {
+			if strings.Contains(lower, pattern) {
+				worded = idx
+				break
+			}
+		}
+	}
+	if worded >= 0 {
+		question = worded
+	}
+	if question < 0 {
+		return PromptDialog{}, false
+	}
+	d := PromptDialog{Question: clean[question], Options: options}
+
+	// Above the question, what the prompt is about — but only inside the
+	// prompt's own frame. Without a separator to stop at, the lines above are
+	// the agent's earlier output.
+	var above []string
+	for idx := question - 1; idx >= 0 && idx >= question-8; idx-- {
+		if isSeparatorLine(clean[idx]) {
+			d.Context = above
+			break
+		}
+		if clean[idx] != "" {
+			above = append([]string{clean[idx]}, above...)
+		}
+	}
+	for idx := question + 1; idx < first; idx++ {
+		if clean[idx] != "" {
+			d.Context = append(d.Context, clean[idx])
+		}
+	}
+	d.Kind, d.Subject = promptSubject(clean, question, first)
+	return d, true
+}
+
+// claudeFileQuestion is Claude asking to write a file: "Do you want to make
+// this edit to main.go?", "Do you want to create notes.md?".
+var claudeFileQuestion = regexp.MustCompile(`(?i)^do you want to (?:make this edit to|create|overwrite) (\S+)\?$`)
+
+// promptSubject works out the command or file a prompt is about.
+//
+//	Claude:  Bash command            Codex:  $ npm test
+//	           npm test
+//	           Run the unit tests
+//	         Do you want to proceed?
+func promptSubject(clean []string, question, first int) (PromptKind, string) {
+	// Codex: the command follows "$ " between the question and the menu.
+	for idx := question + 1; idx < first; idx++ {
+		if !strings.HasPrefix(clean[idx], "$ ") {
+			continue
+		}
+		command := []string{strings.TrimPrefix(clean[idx], "$ ")}
+		for idx++; idx < first && clean[idx] != ""; idx++ {
+			command = append(command, clean[idx])
+		}
+		return PromptCommand, strings.Join(command, "\n")
+	}
+
+	// Claude: a "Bash command" frame over the question. Its last line is a
+	// description when it reads as one; if it might be part of the command it
+	// is kept, so that a rule has to allow it too.
+	for idx := question - 1; idx >= 0 && idx >= question-20; idx-- {
+		if isSeparatorLine(clean[idx]) {
+			break
+		}
+		if !strings.EqualFold(clean[idx], "Bash command") {
+			continue
+		}
+		var command []string
+		for _, line := range clean[idx+1 : question] {
+			if line != "" {
+				command = append(command, line)
+			}
+		}
+		if len(command) > 1 && isProse(command[len(command)-1]) {
+			command = command[:len(command)-1]
+		}
+		if len(command) == 0 {
+			return PromptOther, ""
+		}
+		return PromptCommand, strings.Join(command, "\n")
+	}
+
+	// Claude writing a file: the question names it; the frame above has its
+	// path, which is what a rule like "src/*" needs.
+	match := claudeFileQuestion.FindStringSubmatch(clean[question])
+	if match == nil {
+		return PromptOther, ""
+	}
+	name := match[1]
+	for idx := question - 1; idx >= 0 && idx >= question-40; idx-- {
+		line := strings.Trim(clean[idx], "│╭╮╰╯ ")
+		if line == name || (strings.HasSuffix(line, "/"+name) && !strings.Contains(line, " ")) {
+			return PromptFile, line
+		}
+	}
+	return PromptFile, name
+}
+
+// isProse reports a line that reads as a sentence rather than a command:
+// capitalised, several words, none of a shell's punctuation.
+func isProse(line string) bool {
+	line = strings.TrimSuffix(line, ".")
+	if line == "" || !unicode.IsUpper([]rune(line)[0]) || !strings.Contains(line, " ") {
+		return false
+	}
+	return !strings.ContainsAny(line, "/.$|&;<>=`\\\"'_~*()[]{}") && !strings.Contains(line, " -")
+}
+
+// optionKey works out the key that picks an option, stripping its hint from
+// the label. Claude takes the option's number; Codex takes the letter in
+// brackets. Without either the choice is made by moving the cursor.
+func optionKey(o *PromptOption, agent AgentType) string {
+	hint := ""
+	if match := promptOptionKey.FindStringSubmatch(o.Label); match != nil {
+		hint = match[1]
+		o.Label = strings.TrimSpace(o.Label[:len(o.Label)-len(match[0])])
+	}
+	switch {
+	case agent == AgentClaude:
+		return strconv.Itoa(o.Number)
+	case hint == "esc":
+		return "Escape"
+	default:
+		return hint
+	}
+}
+
+// cleanDialogLine strips colour, box borders and padding from a pane line.
+func cleanDialogLine(line string) string {
+	line = strings.TrimSpace(stripANSIForDetect(line))
+	line = strings.TrimPrefix(line, "│")
+	line = strings.TrimSuffix(line, "│")
+	return strings.TrimSpace(line)
+}
+
+// isSeparatorLine reports a horizontal rule, like the ones framing Claude's
+// input area and dialogs.
+func isSeparatorLine(line string) bool {
+	return strings.Count(line, "─")+strings.Count(line, "━")+strings.Count(line, "╌") > 20
+}
+
+// windowAgent returns the agent running in a window of the session.
+func (i *Instance) windowAgent(windowIdx int) AgentType {
+	agent := i.Agent
+	if fw := i.GetFollowedWindow(windowIdx); fw != nil {
+		agent = fw.Agent
+	}
+	if agent == "" {
+		return AgentClaude
+	}
+	return agent
+}
+
+// ReadPromptDialog captures a window and reads the prompt waiting in it.
+func (i *Instance) ReadPromptDialog(windowIdx int) (PromptDialog, bool) {
+	if !i.IsAlive() {
+		return PromptDialog{}, false
+	}
+	target := fmt.Sprintf("%s:%d", i.TmuxSessionName(), windowIdx)
+	output, err := TmuxCommand("capture-pane", "-t", target, "-p", "-S", "-50").Output()
+	if err != nil {
+		return PromptDialog{}, false
+	}
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	return ParsePromptDialog(lines, i.windowAgent(windowIdx))
+}
+
+// AnswerPrompt picks option choice (an index into d.Options) in the prompt
+// waiting in a window.
+func (i *Instance) AnswerPrompt(windowIdx int, d PromptDialog, choice int) error {
+	if !i.IsAlive() {
+		return fmt.Errorf("session not running")
+	}
+	if choice < 0 || choice >= len(d.Options) {
+		return fmt.Errorf("no option %d", choice+1)
+	}
+	target := fmt.Sprintf("%s:%d", i.TmuxSessionName(), windowIdx)
+	if key := d.Options[choice].Key; key != "" {
+		return TmuxCommand("send-keys", "-t", target, key).Run()
+	}
+
+	// No shortcut: walk the agent's cursor to the option and confirm.
+	cursor := 0
+	for idx, o := range d.Options {
+		if o.Selected {
+			cursor = idx
+		}
+	}
+	args := []string{"send-keys", "-t", target}
+	for ; cursor < choice; cursor++ {
+		args = append(args, "Down")
+	}
+	for ; cursor > choice; cursor-- {
+		args = append(args, "Up")
+	}
+	args = append(args, "Enter")
+	return TmuxCommand(args...).Run()
+}
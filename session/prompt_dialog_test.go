@@ -0,0 +1,137 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+// claudeBashPane is Claude asking to run a command, framed by the separators
+// of its input area.
+const claudeBashPane = `
+● Running the test suite now.
+
+────────────────────────────────────────────────────────────────────────────────
+ Bash command
+
+   npm test
+   Run the unit tests
+
+ Do you want to proceed?
+ ❯ 1. Yes
+   2. Yes, and don't ask again for npm test commands in
+   /home/dev/projects/app
+   3. No, and tell Claude what to do differently (esc)
+`
+
+// Claude's menu is answered by number. A label wrapped onto a second line is
+// still one option, and the command being asked about comes along as context.
+func TestParseClaudePrompt(t *testing.T) {
+	d, ok := ParsePromptDialog(strings.Split(claudeBashPane, "\n"), AgentClaude)
+	if !ok {
+		t.Fatal("the prompt was not found")
+	}
+	if d.Question != "Do you want to proceed?" {
+		t.Errorf("question = %q", d.Question)
+	}
+	if len(d.Options) != 3 {
+		t.Fatalf("options = %+v", d.Options)
+	}
+	if got := d.Options[1].Label; got != "Yes, and don't ask again for npm test commands in /home/dev/projects/app" {
+		t.Errorf("wrapped label = %q", got)
+	}
+	if d.Options[2].Label != "No, and tell Claude what to do differently" || d.Options[2].Key != "3" {
+		t.Errorf("third option = %+v", d.Options[2])
+	}
+	if !d.Options[0].Selected {
+		t.Error("the agent's cursor was not read")
+	}
+	if strings.Join(d.Context, "|") != "Bash command|npm test|Run the unit tests" {
+		t.Errorf("context = %q", d.Context)
+	}
+	if d.Kind != PromptCommand || d.Subject != "npm test" {
+		t.Errorf("subject = %s %q, want the command without its description", d.Kind, d.Subject)
+	}
+}
+
+// Codex's menu is answered by the letter in brackets, and its question is the
+// approval wording rather than the agent's own reason, which may also end in
+// a question mark.
+func TestParseCodexPrompt(t *testing.T) {
+	d, ok := ParsePromptDialog(strings.Split(codexApprovalPane, "\n"), AgentCodex)
+	if !ok {
+		t.Fatal("the prompt was not found")
+	}
+	if d.Question != "Would you like to run the following command?" {
+		t.Errorf("question = %q", d.Question)
+	}
+	keys := []string{}
+	for _, o := range d.Options {
+		keys = append(keys, o.Key)
+	}
+	if strings.Join(keys, ",") != "y,p,Escape" {
+		t.Errorf("keys = %v", keys)
+	}
+	if d.Options[0].Label != "Yes, proceed" {
+		t.Errorf("first label = %q", d.Options[0].Label)
+	}
+	if len(d.Context) == 0 || !strings.HasPrefix(d.Context[len(d.Context)-1], "$ curl") {
+		t.Errorf("context = %q", d.Context)
+	}
+	if d.Kind != PromptCommand || !strings.HasPrefix(d.Subject, "curl -sS") {
+		t.Errorf("subject = %s %q", d.Kind, d.Subject)
+	}
+}
+
+// A numbered list in the agent's answer is not a prompt: there is no question
+// over it.
+func TestParseIgnoresNumberedProse(t *testing.T) {
+	pane := `
+● Here is the plan:
+  1. Add the migration
+  2. Update the model
+  3. Write the tests
+`
+	if d, ok := ParsePromptDialog(strings.Split(pane, "\n"), AgentClaude); ok {
+		t.Errorf("prose was read as a prompt: %+v", d)
+	}
+}
+
+// The last line of a Bash frame is dropped as a description only when it
+// reads as one; a line that might be a command stays in the subject, where a
+// rule has to allow it.
+func TestPromptSubjectKeepsWhatMightBeCommand(t *testing.T) {
+	pane := strings.Replace(claudeBashPane, "Run the unit tests", "rm -rf build", 1)
+	d, ok := ParsePromptDialog(strings.Split(pane, "\n"), AgentClaude)
+	if !ok {
+		t.Fatal("the prompt was not found")
+	}
+	if d.Subject != "npm test\nrm -rf build" {
+		t.Errorf("subject = %q", d.Subject)
+	}
+}
+
+// Claude's file prompts name the file in the question; the path comes from
+// the frame above it.
+func TestPromptSubjectForFileEdits(t *testing.T) {
+	pane := `
+────────────────────────────────────────────────────────────────────────────────
+ Edit file
+ ╭──────────────────────────────────────────────────────────────────────────╮
+ │ src/app/main.go                                                          │
+ │                                                                          │
+ │  12 -    return nil                                                      │
+ │  12 +    return err                                                      │
+ ╰──────────────────────────────────────────────────────────────────────────╯
+ Do you want to make this edit to main.go?
+ ❯ 1. Yes
+   2. Yes, allow all edits during this session (shift+tab)
+   3. No, and tell Claude what to do differently (esc)
+`
+	d, ok := ParsePromptDialog(strings.Split(pane, "\n"), AgentClaude)
+	if !ok {
+		t.Fatal("the prompt was not found")
+	}
+	if d.Kind != PromptFile || d.Subject != "src/app/main.go" {
+		t.Errorf("subject = %s %q", d.Kind, d.Subject)
+	}
+}
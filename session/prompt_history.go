@@ -0,0 +1,233 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The prompt history: what was sent to which agent, and when.
+//
+// A prompt typed into the dialog used to be gone once sent. Every prompt
+// asmgr sends — from the dialog, a queue, a broadcast, a schedule, or
+// `asmgr send` — is appended to a per-project JSONL file next to
+// sessions.json, with the tab it went to and what that tab was doing at the
+// time. The prompt dialog recalls a session's prompts from it, the history
+// panel re-sends them, and read by hand it is a record of what each agent
+// was asked.
+
+// How a prompt was sent.
+const (
+	SentFromDialog    = "prompt"
+	SentFromQueue     = "queue"
+	SentFromBroadcast = "broadcast"
+	SentFromSchedule  = "schedule"
+	SentFromCLI       = "send"
+	SentFromHistory   = "history"
+	SentFromPipeline  = "pipeline"
+	SentFromCompare   = "compare"
+	SentFromHandoff   = "handoff"
+	SentFromReview    = "review"
+)
+
+// SentPrompt is one line of the prompt history.
+type SentPrompt struct {
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id"`
+	Session   string    `json:"session,omitempty"` // Name at the time, for reading the file by hand
+	Window    int       `json:"window"`
+	Tab       string    `json:"tab,omitempty"`
+	Agent     AgentType `json:"agent,omitempty"`
+	// Activity is what the tab was doing when the prompt was sent: a prompt
+	// sent to a busy agent is read differently from one sent to an idle one.
+	Activity SessionActivity `json:"activity"`
+	Via      string          `json:"via"`
+	Text     string          `json:"text"`
+}
+
+const (
+	promptHistoryFile = "prompts.jsonl"
+	// Past this size the history is rewritten without its oldest half.
+	// Checked when a project opens, as the activity log is.
+	promptHistoryMaxBytes = 8 << 20
+)
+
+// PromptHistoryPath returns the active project's prompt history, next to its
+// sessions.json.
+func (s *Storage) PromptHistoryPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), promptHistoryFile)
+}
+
+// PromptHistory appends to and reads one project's prompt history. A nil
+// history records nothing.
+type PromptHistory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewPromptHistory returns a history backed by the given file. The file is
+// created on the first append.
+func NewPromptHistory(path string) *PromptHistory {
+	return &PromptHistory{path: path}
+}
+
+// SentPrompt describes a prompt sent to one of the session's windows now.
+func (i *Instance) SentPrompt(windowIdx int, text, via string, activity SessionActivity) SentPrompt {
+	tab := ""
+	if fw := i.GetFollowedWindow(windowIdx); fw != nil && fw.Index != i.GetMainWindowIndex() {
+		tab = fw.Name
+	}
+	return SentPrompt{
+		Time:      time.Now(),
+		SessionID: i.ID,
+		Session:   i.Name,
+		Window:    windowIdx,
+		Tab:       tab,
+		Agent:     i.windowAgent(windowIdx),
+		Activity:  activity,
+		Via:       via,
+		Text:      text,
+	}
+}
+
+// TabWindow returns the window of a followed tab by name, or the main
+// agent's window for no name.
+func (i *Instance) TabWindow(tab string) (int, error) {
+	if tab == "" {
+		return i.GetMainWindowIndex(), nil
+	}
+	for _, fw := range i.FollowedWindows {
+		if fw.Name == tab {
+			return fw.Index, nil
+		}
+	}
+	return 0, fmt.Errorf("session %s has no tab %q", i.Name, tab)
+}
+
+// Append writes the prompts at the end of the history.
+func (h *PromptHistory) Append(prompts ...SentPrompt) error {
+	if h == nil || len(prompts) == 0 {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open prompt history: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, p := range prompts {
+		if err := enc.Encode(p); err != nil {
+			return fmt.Errorf("failed to write prompt history: %w", err)
+		}
+	}
+	return w.Flush()
+}
+
+// All returns every prompt in the history, oldest first. Lines that do not
+// parse are skipped.
+func (h *PromptHistory) All() ([]SentPrompt, error) {
+	if h == nil {
+		return nil, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.read()
+}
+
+// ForSession returns a session's prompts, oldest first.
+func (h *PromptHistory) ForSession(sessionID string) ([]SentPrompt, error) {
+	all, err := h.All()
+	var prompts []SentPrompt
+	for _, p := range all {
+		if p.SessionID == sessionID {
+			prompts = append(prompts, p)
+		}
+	}
+	return prompts, err
+}
+
+func (h *PromptHistory) read() ([]SentPrompt, error) {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt history: %w", err)
+	}
+	defer f.Close()
+
+	var prompts []SentPrompt
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var p SentPrompt
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			continue
+		}
+		prompts = append(prompts, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return prompts, fmt.Errorf("failed to read prompt history: %w", err)
+	}
+	return prompts, nil
+}
+
+// Compact drops the older half of the history once the file has grown past
+// its size limit.
+func (h *PromptHistory) Compact() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	info, err := os.Stat(h.path)
+	if err != nil || info.Size() < promptHistoryMaxBytes {
+		return nil
+	}
+	prompts, err := h.read()
+	if err != nil {
+		return err
+	}
+	prompts = prompts[len(prompts)/2:]
+
+	tmp := h.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact prompt history: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, p := range prompts {
+		enc.Encode(p)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact prompt history: %w", err)
+	}
+	f.Close()
+	return os.Rename(tmp, h.path)
+}
+
+// RecallTexts are the texts to step through with Up and Down, oldest first,
+// without a prompt sent twice in a row appearing twice.
+func RecallTexts(prompts []SentPrompt) []string {
+	var texts []string
+	for _, p := range prompts {
+		if len(texts) > 0 && texts[len(texts)-1] == p.Text {
+			continue
+		}
+		texts = append(texts, p.Text)
+	}
+	return texts
+}
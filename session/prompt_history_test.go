@@ -0,0 +1,68 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Prompts go in one session's history and come back in the order they were
+// sent, with what the tab was doing spelt out in the file.
+func TestPromptHistoryAppendAndRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), promptHistoryFile)
+	h := NewPromptHistory(path)
+	t0 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	h.Append(
+		SentPrompt{Time: t0, SessionID: "a", Text: "first", Activity: ActivityIdle, Via: SentFromDialog},
+		SentPrompt{Time: t0.Add(time.Minute), SessionID: "b", Text: "other", Via: SentFromBroadcast},
+	)
+	h.Append(SentPrompt{Time: t0.Add(2 * time.Minute), SessionID: "a", Text: "second", Activity: ActivityBusy, Via: SentFromQueue})
+
+	prompts, err := h.ForSession("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 2 || prompts[0].Text != "first" || prompts[1].Text != "second" {
+		t.Fatalf("session a's prompts = %+v", prompts)
+	}
+	if prompts[1].Activity != ActivityBusy || prompts[1].Via != SentFromQueue {
+		t.Errorf("second prompt read back as %+v", prompts[1])
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"activity":"busy"`) {
+		t.Errorf("activity not written by name:\n%s", data)
+	}
+
+	var nilHistory *PromptHistory
+	if err := nilHistory.Append(SentPrompt{Text: "x"}); err != nil {
+		t.Errorf("a nil history should record nothing, got %v", err)
+	}
+}
+
+// A broken line is skipped, not the end of the history.
+func TestPromptHistorySkipsBadLines(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), promptHistoryFile)
+	os.WriteFile(path, []byte("{\"session_id\":\"a\",\"text\":\"one\"}\nnot json\n{\"session_id\":\"a\",\"text\":\"two\"}\n"), 0644)
+	prompts, err := NewPromptHistory(path).All()
+	if err != nil || len(prompts) != 2 {
+		t.Fatalf("got %d prompts, err %v", len(prompts), err)
+	}
+}
+
+// Recall steps through texts, not sends: the same prompt sent twice in a row
+// is one step.
+func TestRecallTextsCollapsesRepeats(t *testing.T) {
+	t.Parallel()
+
+	got := RecallTexts([]SentPrompt{{Text: "a"}, {Text: "b"}, {Text: "b"}, {Text: "a"}})
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "a" {
+		t.Errorf("RecallTexts = %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// Prompts lined up for an agent to take one at a time.
+//
+// Each tab keeps its own queue — the main agent on the Instance, every other
+// tab on its FollowedWindow — so it is saved with the session and follows a
+// tab to its new window index when the session is restored. What sends them
+// is in the ui package, which sees the tab finish; this is only the list and
+// the operations on it.
+
+// QueuedPrompt is a prompt waiting for its tab to finish what it is doing.
+type QueuedPrompt struct {
+	Text  string    `json:"text"`
+	Added time.Time `json:"added"`
+}
+
+// queueFor returns the queue of a window: a followed tab's own, or the main
+// agent's for any other window.
+func (i *Instance) queueFor(windowIdx int) *[]QueuedPrompt {
+	for idx := range i.FollowedWindows {
+		if i.FollowedWindows[idx].Index == windowIdx {
+			return &i.FollowedWindows[idx].PromptQueue
+		}
+	}
+	return &i.PromptQueue
+}
+
+// QueuedPrompts returns the prompts queued for a window, in order.
+func (i *Instance) QueuedPrompts(windowIdx int) []QueuedPrompt {
+	return *i.queueFor(windowIdx)
+}
+
+// SetQueuedPrompts replaces a window's queue, after it has been reordered or
+// edited.
+func (i *Instance) SetQueuedPrompts(windowIdx int, prompts []QueuedPrompt) {
+	*i.queueFor(windowIdx) = prompts
+}
+
+// EnqueuePrompt adds a prompt to the end of a window's queue. A terminal tab
+// has none: a shell never reports finishing.
+func (i *Instance) EnqueuePrompt(windowIdx int, text string, now time.Time) error {
+	if i.windowAgent(windowIdx) == AgentTerminal {
+		return fmt.Errorf("terminal tabs have no prompt queue")
+	}
+	queue := i.queueFor(windowIdx)
+	*queue = append(*queue, QueuedPrompt{Text: text, Added: now})
+	return nil
+}
+
+// DequeuePrompt takes the first prompt off a window's queue.
+func (i *Instance) DequeuePrompt(windowIdx int) (QueuedPrompt, bool) {
+	queue := i.queueFor(windowIdx)
+	if len(*queue) == 0 {
+		return QueuedPrompt{}, false
+	}
+	prompt := (*queue)[0]
+	*queue = (*queue)[1:]
+	if len(*queue) == 0 {
+		*queue = nil
+	}
+	return prompt, true
+}
+
+// RequeuePrompt puts a prompt back at the front of a window's queue, when
+// sending it failed.
+func (i *Instance) RequeuePrompt(windowIdx int, prompt QueuedPrompt) {
+	queue := i.queueFor(windowIdx)
+	*queue = append([]QueuedPrompt{prompt}, *queue...)
+}
+
+// QueuedCount is how many prompts are queued across the session's tabs.
+func (i *Instance) QueuedCount() int {
+	count := len(i.PromptQueue)
+	for _, fw := range i.FollowedWindows {
+		count += len(fw.PromptQueue)
+	}
+	return count
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Noticing that an agent has hit its provider's limit, and until when.
+//
+// Claude prints "5-hour limit reached ∙ resets 3pm (Europe/Budapest)", Codex
+// "You've hit your usage limit … try again in 2 hours 5 minutes", Gemini a
+// "high demand" notice. The agent then sits at its prompt, idle as far as the
+// spinner goes, until someone types into it — which on an overnight run is
+// the next morning. The wording is matched with the agent's rateLimited
+// patterns; the reset time is read from the same line when it states one.
+//
+// A message stays on screen after its limit has passed, so a reset time read
+// as "3pm" would roll over to tomorrow's 3pm on every poll after it. The first
+// reading is kept instead, against the message it came from, for as long as
+// that message is on screen.
+
+// RateLimit is a limit message seen in a tab.
+type RateLimit struct {
+	Message string
+	Until   time.Time // Zero when the message gives no reset time
+}
+
+// Active reports whether the limit still holds at now. A limit with no reset
+// time holds until the message goes away.
+func (r RateLimit) Active(now time.Time) bool {
+	return r.Until.IsZero() || now.Before(r.Until)
+}
+
+// rateLimitSearchLines bounds how far up a limit message may be, so one from
+// earlier in the conversation is not read as the current state.
+const rateLimitSearchLines = 12
+
+// FindRateLimit looks for a limit message near the bottom of a capture.
+func FindRateLimit(lines []string, patterns []string, now time.Time) (RateLimit, bool) {
+	if len(patterns) == 0 {
+		return RateLimit{}, false
+	}
+	for idx, seen := len(lines)-1, 0; idx >= 0 && seen < rateLimitSearchLines; idx-- {
+		line := strings.TrimSpace(stripANSIForDetect(lines[idx]))
+		if line == "" {
+			continue
+		}
+		seen++
+		lower := strings.ToLower(line)
+		for _, pattern := range patterns {
+			if !strings.Contains(lower, pattern) {
+				continue
+			}
+			limit := RateLimit{Message: line}
+			// The reset time is sometimes on the line after: Codex wraps.
+			text := line
+			if idx+1 < len(lines) {
+				text += " " + strings.TrimSpace(stripANSIForDetect(lines[idx+1]))
+			}
+			if until, ok := ParseResetTime(text, now); ok {
+				limit.Until = until
+			}
+			return limit, true
+		}
+	}
+	return RateLimit{}, false
+}
+
+var (
+	// resetEpoch is Claude's older form: "Claude AI usage limit reached|1760000000".
+	resetEpoch = regexp.MustCompile(`\|(\d{10})\b`)
+	// resetIn is a relative time: "try again in 2 hours 5 minutes", "in 37m".
+	resetIn     = regexp.MustCompile(`(?i)\bin\s+((?:\d+\s*(?:hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b[\s,]*(?:and\s+)?)+)`)
+	resetInPart = regexp.MustCompile(`(?i)(\d+)\s*(h|m|s)`)
+	// resetAt is a clock time: "resets 3pm (Europe/Budapest)", "reset at
+	// 15:30", "try again at 3:05 PM".
+	resetAt = regexp.MustCompile(`(?i)\b(?:resets?|try again|available)\s+(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?(?:\s*\(([^)]+)\))?`)
+)
+
+// ParseResetTime reads when a limit lifts from a limit message. A clock time
+// already past today is taken to mean tomorrow.
+func ParseResetTime(text string, now time.Time) (time.Time, bool) {
+	if match := resetEpoch.FindStringSubmatch(text); match != nil {
+		seconds, _ := strconv.ParseInt(match[1], 10, 64)
+		return time.Unix(seconds, 0), true
+	}
+
+	if match := resetIn.FindStringSubmatch(text); match != nil {
+		var wait time.Duration
+		for _, part := range resetInPart.FindAllStringSubmatch(match[1], -1) {
+			n, _ := strconv.Atoi(part[1])
+			switch strings.ToLower(part[2]) {
+			case "h":
+				wait += time.Duration(n) * time.Hour
+			case "m":
+				wait += time.Duration(n) * time.Minute
+			case "s":
+				wait += time.Duration(n) * time.Second
+			}
+		}
+		if wait > 0 {
+			return now.Add(wait), true
+		}
+	}
+
+	match := resetAt.FindStringSubmatch(text)
+	if match == nil {
+		return time.Time{}, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+	switch strings.ToLower(match[3]) {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+	loc := now.Location()
+	if match[4] != "" {
+		if named, err := time.LoadLocation(strings.TrimSpace(match[4])); err == nil {
+			loc = named
+		}
+	}
+	local := now.In(loc)
+	until := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// rateLimitMarks holds the limit seen in each target (session:window), read
+// once per message.
+var rateLimitMarks sync.Map // map[string]RateLimit
+
+// trackRateLimit records what a capture of target says about limits. A
+// message already seen keeps its first reading.
+func trackRateLimit(target string, lines []string, patterns []string, now time.Time) {
+	limit, ok := FindRateLimit(lines, patterns, now)
+	if !ok {
+		rateLimitMarks.Delete(target)
+		return
+	}
+	if value, seen := rateLimitMarks.Load(target); seen && value.(RateLimit).Message == limit.Message {
+		return
+	}
+	rateLimitMarks.Store(target, limit)
+}
+
+// RateLimitForWindow returns the limit message the last poll saw in a window,
+// whether or not it still holds.
+func (i *Instance) RateLimitForWindow(windowIdx int) (RateLimit, bool) {
+	value, ok := rateLimitMarks.Load(i.windowTarget(windowIdx))
+	if !ok {
+		return RateLimit{}, false
+	}
+	return value.(RateLimit), true
+}
+
+// RateLimitConfig is a project's auto-continue settings, under "rate_limit"
+// in its Settings.
+type RateLimitConfig struct {
+	// AutoContinue sends Prompt to a limited tab once its limit has passed.
+	AutoContinue bool `json:"auto_continue,omitempty"`
+	// Prompt is what is sent; "continue" when empty. "/resume" and the like
+	// work too — it is typed into the agent like anything else.
+	Prompt string `json:"prompt,omitempty"`
+	// MarginSeconds is how long after the reset time to wait, for clocks that
+	// disagree; a minute when zero.
+	MarginSeconds int `json:"margin_seconds,omitempty"`
+}
+
+// DefaultContinuePrompt is sent when the configuration names no prompt.
+const DefaultContinuePrompt = "continue"
+
+// ContinuePrompt is the prompt c sends.
+func (c *RateLimitConfig) ContinuePrompt() string {
+	if c == nil || strings.TrimSpace(c.Prompt) == "" {
+		return DefaultContinuePrompt
+	}
+	return c.Prompt
+}
+
+// Margin is how long after a reset time the prompt is sent.
+func (c *RateLimitConfig) Margin() time.Duration {
+	if c == nil || c.MarginSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.MarginSeconds) * time.Second
+}
+
+// Enabled reports whether limited tabs are continued automatically.
+func (c *RateLimitConfig) Enabled() bool {
+	return c != nil && c.AutoContinue
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Each agent states its reset time its own way; all of them come out as a
+// moment, and a clock time already past today means tomorrow.
+func TestParseResetTime(t *testing.T) {
+	budapest, err := time.LoadLocation("Europe/Budapest")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	now := time.Date(2026, 3, 10, 16, 20, 0, 0, budapest)
+
+	for _, c := range []struct {
+		text string
+		want time.Time
+	}{
+		{"5-hour limit reached ∙ resets 3pm (Europe/Budapest)", time.Date(2026, 3, 11, 15, 0, 0, 0, budapest)},
+		{"Your limit will reset at 11:30pm", time.Date(2026, 3, 10, 23, 30, 0, 0, budapest)},
+		{"You've hit your usage limit. Try again in 2 hours 5 minutes.", now.Add(2*time.Hour + 5*time.Minute)},
+		{"Rate limit reached, try again in 37m", now.Add(37 * time.Minute)},
+		{"Claude AI usage limit reached|1773163200", time.Unix(1773163200, 0)},
+		{"try again at 18:05", time.Date(2026, 3, 10, 18, 5, 0, 0, budapest)},
+	} {
+		got, ok := ParseResetTime(c.text, now)
+		if !ok {
+			t.Errorf("%q: no reset time", c.text)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("%q: %s, want %s", c.text, got, c.want)
+		}
+	}
+
+	if _, ok := ParseResetTime("We are experiencing high demand.", now); ok {
+		t.Error("a message without a time gave one")
+	}
+}
+
+// The message is found near the bottom of the pane only, and a limit whose
+// reset has passed is kept as read rather than moved to tomorrow.
+func TestRateLimitKeepsItsFirstReading(t *testing.T) {
+	patterns := agentPatterns[AgentClaude].RateLimitPatterns
+	pane := strings.Split("● Refactoring the parser.\n\n⎿ 5-hour limit reached ∙ resets 3pm\n\n> \n", "\n")
+	morning := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	target := "rate-limit-test:0"
+	defer rateLimitMarks.Delete(target)
+	trackRateLimit(target, pane, patterns, morning)
+	value, ok := rateLimitMarks.Load(target)
+	if !ok {
+		t.Fatal("the limit message was not seen")
+	}
+	first := value.(RateLimit)
+	if want := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC); !first.Until.Equal(want) {
+		t.Fatalf("until %s, want %s", first.Until, want)
+	}
+
+	evening := time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC)
+	trackRateLimit(target, pane, patterns, evening)
+	value, _ = rateLimitMarks.Load(target)
+	if limit := value.(RateLimit); !limit.Until.Equal(first.Until) || limit.Active(evening) {
+		t.Errorf("after the reset the limit reads %s, active %v", limit.Until, limit.Active(evening))
+	}
+
+	scrolled := append(append([]string{}, pane...), strings.Split(strings.Repeat("more output\n", rateLimitSearchLines), "\n")...)
+	trackRateLimit(target, scrolled, patterns, evening)
+	if _, ok := rateLimitMarks.Load(target); ok {
+		t.Error("a message scrolled out of reach still counts")
+	}
+}
@@ -0,0 +1,267 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Review comments on a session's diff.
+//
+// Reading an agent's diff and then typing out what is wrong with it — which
+// file, which line, quoting the code — is most of the work of a review. A
+// comment is put on a line or a hunk of the diff view instead, and all of
+// them go to the agent as one prompt that says where each is and quotes the
+// code it is about.
+//
+// Comments are kept with the session until resolved. Each holds on to the
+// lines of the file it is about, as they were: once the agent changes them,
+// those lines are no longer in the file and the comment is marked resolved;
+// if they only moved, the comment moves with them. A comment on removed
+// lines, or on a whole hunk, holds on to the hunk's lines as the file has
+// them now.
+
+// maxReviewQuote is the most lines of code a comment quotes in the prompt.
+const maxReviewQuote = 12
+
+// ReviewComment is a comment on lines of a session's diff, for its agent.
+type ReviewComment struct {
+	Path       string    `json:"path"`                  // File, from the top of the repository
+	Line       int       `json:"line"`                  // Line it is on; in the old file for a removed line
+	EndLine    int       `json:"end_line,omitempty"`    // Last line, for a comment on a hunk
+	Removed    bool      `json:"removed,omitempty"`     // On a removed line: Line is in the old file
+	Hunk       bool      `json:"hunk,omitempty"`        // On a whole hunk
+	Code       []string  `json:"code"`                  // The lines it is about, as the diff shows them
+	Anchor     []string  `json:"anchor,omitempty"`      // Lines of the file it holds on to: gone, it is resolved
+	AnchorLine int       `json:"anchor_line,omitempty"` // Line the anchor was last found at
+	Text       string    `json:"text"`
+	Created    time.Time `json:"created"`
+	Sent       bool      `json:"sent,omitempty"`     // Sent or queued to the agent
+	Resolved   bool      `json:"resolved,omitempty"` // Its lines changed, or resolved by hand
+}
+
+// Where says where the comment is: the file and line or lines.
+func (c ReviewComment) Where() string {
+	switch {
+	case c.Hunk && c.EndLine > c.Line:
+		return fmt.Sprintf("%s:%d-%d", c.Path, c.Line, c.EndLine)
+	case c.Removed:
+		return fmt.Sprintf("%s:%d (removed)", c.Path, c.Line)
+	}
+	return fmt.Sprintf("%s:%d", c.Path, c.Line)
+}
+
+// LineNumbers gives each of the file's diff lines its number in the old and
+// the new file, 0 where it has none: headers, and the side a line is not on.
+func (f DiffFile) LineNumbers() (oldNo, newNo []int) {
+	oldNo = make([]int, len(f.Lines))
+	newNo = make([]int, len(f.Lines))
+	for _, h := range f.Hunks {
+		oldLine, _, newLine, _, ok := ParseHunkHeader(f.Lines[h.Start])
+		if !ok {
+			continue
+		}
+		for n := h.Start + 1; n < h.End; n++ {
+			switch {
+			case strings.HasPrefix(f.Lines[n], "-"):
+				oldNo[n] = oldLine
+				oldLine++
+			case strings.HasPrefix(f.Lines[n], "+"):
+				newNo[n] = newLine
+				newLine++
+			case strings.HasPrefix(f.Lines[n], `\`):
+			default:
+				oldNo[n], newNo[n] = oldLine, newLine
+				oldLine++
+				newLine++
+			}
+		}
+	}
+	return oldNo, newNo
+}
+
+// CommentLine is the index into the file's diff lines a comment is shown
+// under: its line, or its hunk's header; -1 if the diff no longer has it.
+func (f DiffFile) CommentLine(c ReviewComment) int {
+	if c.Path != f.Path {
+		return -1
+	}
+	if c.Hunk {
+		for _, h := range f.Hunks {
+			if _, _, newStart, newEnd, ok := ParseHunkHeader(f.Lines[h.Start]); ok && c.Line >= newStart && c.Line <= max(newEnd-1, newStart) {
+				return h.Start
+			}
+		}
+		return -1
+	}
+	oldNo, newNo := f.LineNumbers()
+	for n := range f.Lines {
+		if (c.Removed && oldNo[n] == c.Line && newNo[n] == 0) || (!c.Removed && newNo[n] == c.Line) {
+			return n
+		}
+	}
+	return -1
+}
+
+// NewReviewComment makes a comment on one of a file's diff lines, or with
+// line < 0 on the whole of one of its hunks.
+func NewReviewComment(f DiffFile, hunk, line int, text string, now time.Time) (ReviewComment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ReviewComment{}, fmt.Errorf("the comment is empty")
+	}
+	if hunk < 0 || hunk >= len(f.Hunks) {
+		return ReviewComment{}, fmt.Errorf("select a hunk or a line to comment on")
+	}
+	h := f.Hunks[hunk]
+	if line >= 0 && (line <= h.Start || line >= h.End || strings.HasPrefix(f.Lines[line], `\`)) {
+		return ReviewComment{}, fmt.Errorf("that line is not in the hunk")
+	}
+	c := ReviewComment{Path: f.Path, Text: text, Created: now}
+	oldNo, newNo := f.LineNumbers()
+
+	// The hunk's lines as the file has them now, for a comment with no line
+	// of its own there
+	var hunkAnchor []string
+	hunkAnchorLine := 0
+	for n := h.Start + 1; n < h.End; n++ {
+		if newNo[n] != 0 {
+			if hunkAnchorLine == 0 {
+				hunkAnchorLine = newNo[n]
+			}
+			hunkAnchor = append(hunkAnchor, f.Lines[n][1:])
+		}
+	}
+
+	if line < 0 {
+		c.Hunk = true
+		_, _, c.Line, c.EndLine, _ = ParseHunkHeader(f.Lines[h.Start])
+		c.EndLine = max(c.EndLine-1, c.Line)
+		for n := h.Start + 1; n < h.End; n++ {
+			if strings.HasPrefix(f.Lines[n], "+") || strings.HasPrefix(f.Lines[n], "-") {
+				c.Code = append(c.Code, f.Lines[n])
+			}
+		}
+		c.Anchor, c.AnchorLine = hunkAnchor, hunkAnchorLine
+		return c, nil
+	}
+
+	c.Code = []string{f.Lines[line]}
+	if newNo[line] == 0 {
+		c.Line, c.Removed = oldNo[line], true
+		c.Anchor, c.AnchorLine = hunkAnchor, hunkAnchorLine
+		return c, nil
+	}
+	c.Line = newNo[line]
+	if text := f.Lines[line][1:]; strings.TrimSpace(text) != "" {
+		c.Anchor, c.AnchorLine = []string{text}, c.Line
+	} else {
+		// A blank line is everywhere: hold on to the hunk
+		c.Anchor, c.AnchorLine = hunkAnchor, hunkAnchorLine
+	}
+	return c, nil
+}
+
+// findAnchor is the line number the anchor starts at in the file's lines,
+// the nearest to where it was, or 0 if the file no longer has it.
+func findAnchor(lines, anchor []string, near int) int {
+	found := 0
+	for start := 0; start+len(anchor) <= len(lines); start++ {
+		match := true
+		for k, line := range anchor {
+			if lines[start+k] != line {
+				match = false
+				break
+			}
+		}
+		if match && (found == 0 || abs(start+1-near) < abs(found-near)) {
+			found = start + 1
+		}
+	}
+	return found
+}
+
+// abs is the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// OpenReviewComments is how many of the session's comments are not resolved.
+func (i *Instance) OpenReviewComments() int {
+	open := 0
+	for _, c := range i.ReviewComments {
+		if !c.Resolved {
+			open++
+		}
+	}
+	return open
+}
+
+// UpdateReviewComments looks for each open comment's lines in its file:
+// gone, the comment is resolved; moved, it moves with them. Reports whether
+// anything changed, for the session to be saved.
+func (i *Instance) UpdateReviewComments() bool {
+	if i.OpenReviewComments() == 0 {
+		return false
+	}
+	root, err := git(i.Path, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return false
+	}
+	files := map[string][]string{}
+	changed := false
+	for k := range i.ReviewComments {
+		c := &i.ReviewComments[k]
+		if c.Resolved || len(c.Anchor) == 0 {
+			continue
+		}
+		lines, ok := files[c.Path]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(root, c.Path))
+			if err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			files[c.Path] = lines
+		}
+		at := findAnchor(lines, c.Anchor, c.AnchorLine)
+		switch {
+		case at == 0:
+			c.Resolved = true
+			changed = true
+		case at != c.AnchorLine:
+			if !c.Removed {
+				c.Line += at - c.AnchorLine
+				if c.Hunk {
+					c.EndLine += at - c.AnchorLine
+				}
+			}
+			c.AnchorLine = at
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ReviewPrompt bundles comments into one prompt for the agent: for each, the
+// file and line, the code it is about, and what to do.
+func ReviewPrompt(comments []ReviewComment) string {
+	var b strings.Builder
+	b.WriteString("Please address these review comments on your changes. Each gives the file and line, quotes the diff, then says what to change.\n")
+	for n, c := range comments {
+		fmt.Fprintf(&b, "\n%d. %s\n```diff\n", n+1, c.Where())
+		for k, line := range c.Code {
+			if k == maxReviewQuote {
+				fmt.Fprintf(&b, "… %d more lines\n", len(c.Code)-k)
+				break
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("```\n" + c.Text + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A comment on an added line is on its line in the new file and quotes it;
+// one on a hunk spans the hunk. Lines added above move the comment with its
+// line; once the line itself is changed, the comment is resolved.
+func TestReviewComments(t *testing.T) {
+	repo := gitRepo(t)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+
+	inst := &Instance{Path: repo}
+	files := ParseDiff(inst.GetFullDiff().Content)
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("diff: %+v", files)
+	}
+	f := files[0]
+	added := -1
+	for n, line := range f.Lines {
+		if line == "+\tprintln(\"hi\")" {
+			added = n
+		}
+	}
+	now := time.Now()
+	onLine, err := NewReviewComment(f, 0, added, "  Use fmt.  ", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onLine.Line != 4 || onLine.Removed || onLine.Text != "Use fmt." || onLine.Where() != "main.go:4" {
+		t.Errorf("comment on the line: %+v", onLine)
+	}
+	onHunk, err := NewReviewComment(f, 0, -1, "Needs a test", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !onHunk.Hunk || onHunk.Where() != "main.go:1-5" || len(onHunk.Code) != 4 {
+		t.Errorf("comment on the hunk: %+v", onHunk)
+	}
+	if f.CommentLine(onLine) != added || f.CommentLine(onHunk) != f.Hunks[0].Start {
+		t.Errorf("shown under lines %d and %d", f.CommentLine(onLine), f.CommentLine(onHunk))
+	}
+	if _, err := NewReviewComment(f, 0, added, " ", now); err == nil {
+		t.Error("an empty comment made")
+	}
+
+	prompt := ReviewPrompt([]ReviewComment{onLine, onHunk})
+	for _, want := range []string{"1. main.go:4\n```diff\n+\tprintln(\"hi\")\n```\nUse fmt.\n", "2. main.go:1-5\n"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+
+	inst.ReviewComments = []ReviewComment{onLine, onHunk}
+	if inst.UpdateReviewComments() {
+		t.Error("comments changed with the file as it was")
+	}
+	write("package main\n\n// main says hi\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	inst.UpdateReviewComments()
+	if c := inst.ReviewComments[0]; c.Resolved || c.Line != 5 {
+		t.Errorf("after a line was added above: line %d, resolved %v", c.Line, c.Resolved)
+	}
+	write("package main\n\n// main says hi\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	if !inst.UpdateReviewComments() || !inst.ReviewComments[0].Resolved || !inst.ReviewComments[1].Resolved {
+		t.Errorf("after the line changed: %+v", inst.ReviewComments)
+	}
+	if inst.OpenReviewComments() != 0 {
+		t.Errorf("%d open", inst.OpenReviewComments())
+	}
+}
@@ -0,0 +1,341 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prompts sent at a time, or on a schedule.
+//
+// "Every weekday at nine, pull and summarise the overnight CI failures":
+// a schedule names a session, the tab to type into, the prompt, and either a
+// cron expression or a single time. Schedules are kept per project in
+// schedules.json beside its sessions, in their own file because two
+// processes write them — the TUI while the project is open in it, and
+// `asmgr scheduler` while it is not.
+//
+// A run is only made close to its time. A laptop that slept through nine
+// o'clock does not send "good morning, pull main" at two in the afternoon;
+// the runs it slept through are recorded as missed, and shown, so nobody
+// wonders why the summary never came.
+
+// schedulesFile is the file name of a project's schedules.
+const schedulesFile = "schedules.json"
+
+// MissedAfter is how late a run can still be made. Later than this, it is
+// missed.
+const MissedAfter = 5 * time.Minute
+
+// maxMissed is how many missed runs a schedule keeps to report.
+const maxMissed = 20
+
+// Schedule is a prompt to send at a time, once or repeatedly.
+type Schedule struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	// Tab is the name of the followed tab to send to; empty for the main
+	// agent. Names survive a restart, window indexes do not.
+	Tab            string      `json:"tab,omitempty"`
+	Text           string      `json:"text"`
+	Cron           string      `json:"cron,omitempty"` // Recurring
+	At             time.Time   `json:"at,omitzero"`    // Once
+	StartIfStopped bool        `json:"start_if_stopped,omitempty"`
+	Paused         bool        `json:"paused,omitempty"`
+	Next           time.Time   `json:"next,omitzero"` // Zero once a one-off has run
+	LastRun        time.Time   `json:"last_run,omitzero"`
+	LastResult     string      `json:"last_result,omitempty"`
+	Missed         []time.Time `json:"missed,omitempty"` // Not yet acknowledged
+}
+
+// ScheduleRun is one run of a schedule that is due, or one that was missed.
+type ScheduleRun struct {
+	Schedule Schedule    // As it was when due
+	Due      time.Time   // The time it was for
+	Missed   []time.Time // For a missed run, every time that was missed
+}
+
+// SchedulesPath is the active project's schedules file.
+func (s *Storage) SchedulesPath() string {
+	return filepath.Join(filepath.Dir(s.configPath), schedulesFile)
+}
+
+// LockScheduler takes the lock that keeps a second `asmgr scheduler` from
+// running alongside the first.
+func (s *Storage) LockScheduler() error {
+	lockPath := filepath.Join(s.configDir, "scheduler.lock")
+	if locked, pid := lockHolder(lockPath); locked {
+		return fmt.Errorf("a scheduler is already running (PID: %d)", pid)
+	}
+	if err := os.MkdirAll(s.configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	if err := os.WriteFile(lockPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+	return nil
+}
+
+// UnlockScheduler releases the scheduler lock.
+func (s *Storage) UnlockScheduler() {
+	os.Remove(filepath.Join(s.configDir, "scheduler.lock"))
+}
+
+// LoadSchedules reads a schedules file; a missing one has none.
+func LoadSchedules(path string) ([]*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var schedules []*Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return schedules, nil
+}
+
+// SaveSchedules writes a schedules file, whole, by rename.
+func SaveSchedules(path string, schedules []*Schedule) error {
+	if schedules == nil {
+		schedules = []*Schedule{}
+	}
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewSchedule makes a schedule from when it runs, as the schedule dialog
+// takes it: a cron expression ("0 9 * * mon-fri", "@daily"), a time of day
+// ("09:00", today or else tomorrow), a date and time ("2026-11-02 09:00"), or
+// a delay ("in 30m", "+2h").
+func NewSchedule(sessionID, tab, when, text string, now time.Time) (*Schedule, error) {
+	s := &Schedule{
+		ID:        fmt.Sprintf("sch_%d", time.Now().UnixNano()),
+		SessionID: sessionID,
+		Tab:       tab,
+		Text:      text,
+	}
+	if err := s.SetWhen(when, now); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetWhen changes when a schedule runs; see NewSchedule for the forms.
+func (s *Schedule) SetWhen(when string, now time.Time) error {
+	when = strings.TrimSpace(when)
+	if at, ok := parseOneOff(when, now); ok {
+		if !at.After(now) {
+			return fmt.Errorf("%s is in the past", at.Format("2006-01-02 15:04"))
+		}
+		s.Cron, s.At, s.Next = "", at, at
+		return nil
+	}
+	spec, err := ParseCron(when)
+	if err != nil {
+		return fmt.Errorf("%q is neither a time nor a cron expression: %w", when, err)
+	}
+	next := spec.Next(now)
+	if next.IsZero() {
+		return fmt.Errorf("%q never matches", when)
+	}
+	s.Cron, s.At, s.Next = when, time.Time{}, next
+	return nil
+}
+
+// When is how the schedule's time was given, for editing it.
+func (s *Schedule) When() string {
+	if s.Cron != "" {
+		return s.Cron
+	}
+	return s.At.Format("2006-01-02 15:04")
+}
+
+// parseOneOff reads the single-time forms of NewSchedule.
+func parseOneOff(when string, now time.Time) (time.Time, bool) {
+	delay := strings.TrimPrefix(strings.TrimPrefix(when, "in "), "+")
+	if delay != when {
+		if d, err := time.ParseDuration(strings.ReplaceAll(delay, " ", "")); err == nil && d > 0 {
+			// Runs are made on the minute: a delay shorter than what is
+			// left of this one runs at the next
+			at := now.Add(d).Truncate(time.Minute)
+			if !at.After(now) {
+				at = at.Add(time.Minute)
+			}
+			return at, true
+		}
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if at, err := time.ParseInLocation(layout, when, now.Location()); err == nil {
+			return at, true
+		}
+	}
+	if clock, err := time.ParseInLocation("15:04", when, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, true
+	}
+	return time.Time{}, false
+}
+
+// advance moves Next past now: the cron expression's next match, or none for
+// a one-off that has had its time.
+func (s *Schedule) advance(now time.Time) {
+	if s.Cron == "" {
+		s.Next = time.Time{}
+		return
+	}
+	spec, err := ParseCron(s.Cron)
+	if err != nil {
+		// Edited by hand into something unreadable: stop rather than spin
+		s.Next = time.Time{}
+		s.LastResult = err.Error()
+		return
+	}
+	s.Next = spec.Next(now)
+}
+
+// TakeDueSchedules advances every schedule whose time has come, and returns
+// the runs to make now and the runs that were missed. Runs are taken when
+// they are due, before they are made, so a slow send is not started twice.
+func TakeDueSchedules(schedules []*Schedule, now time.Time) (runs, missed []ScheduleRun) {
+	for _, s := range schedules {
+		if s.Paused || s.Next.IsZero() || s.Next.After(now) {
+			continue
+		}
+		due := s.Next
+		if now.Sub(due) <= MissedAfter {
+			runs = append(runs, ScheduleRun{Schedule: *s, Due: due})
+			s.advance(now)
+			continue
+		}
+
+		// Every run between then and now was missed
+		var times []time.Time
+		for t := due; !t.IsZero() && !t.After(now) && len(times) < maxMissed; {
+			times = append(times, t)
+			s.Next = t
+			s.advance(t)
+			t = s.Next
+		}
+		s.advance(now)
+		s.Missed = append(s.Missed, times...)
+		if len(s.Missed) > maxMissed {
+			s.Missed = s.Missed[len(s.Missed)-maxMissed:]
+		}
+		s.LastResult = fmt.Sprintf("missed %d run(s) from %s", len(times), due.Format("Jan 2 15:04"))
+		missed = append(missed, ScheduleRun{Schedule: *s, Due: due, Missed: times})
+	}
+	return runs, missed
+}
+
+// RecordScheduleRun stores the outcome of a run on its schedule, if the
+// schedule is still there.
+func RecordScheduleRun(schedules []*Schedule, run ScheduleRun, at time.Time, err error) {
+	for _, s := range schedules {
+		if s.ID != run.Schedule.ID {
+			continue
+		}
+		s.LastRun = at
+		s.LastResult = "sent"
+		if err != nil {
+			s.LastResult = "failed: " + err.Error()
+		}
+		return
+	}
+}
+
+// agentStartTimeout is how long a session started for a schedule has to show
+// its agent ready for input.
+const agentStartTimeout = 60 * time.Second
+
+// RunSchedule sends a run's prompt to its session, starting the session
+// first if the schedule allows it. Blocks while the agent starts, so call it
+// off the UI thread. A tab waiting on a permission prompt is not sent to:
+// the text would be taken as the answer. A sent prompt is added to history.
+func RunSchedule(inst *Instance, run ScheduleRun, history *PromptHistory) error {
+	s := run.Schedule
+	if !inst.IsAlive() {
+		if !s.StartIfStopped {
+			return fmt.Errorf("session %s is not running", inst.Name)
+		}
+		if err := CheckAgentCommand(inst); err != nil {
+			return err
+		}
+		if err := inst.Start(); err != nil {
+			return err
+		}
+		if err := waitForAgent(inst, inst.GetMainWindowIndex(), agentStartTimeout); err != nil {
+			return err
+		}
+	}
+
+	window, err := inst.TabWindow(s.Tab)
+	if err != nil {
+		return err
+	}
+	activity := inst.DetectActivityForWindow(window)
+	if activity == ActivityWaiting {
+		return fmt.Errorf("tab is waiting on a prompt")
+	}
+	if err := inst.SendPromptToWindow(window, s.Text); err != nil {
+		return err
+	}
+	history.Append(inst.SentPrompt(window, s.Text, SentFromSchedule, activity))
+	return nil
+}
+
+// waitForAgent waits for a freshly started agent to draw its input: an idle
+// screen with something on it, the same two seconds running.
+func waitForAgent(inst *Instance, window int, timeout time.Duration) error {
+	target := inst.windowTarget(window)
+	deadline := time.Now().Add(timeout)
+	previous := ""
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		output, err := TmuxCommand("capture-pane", "-t", target, "-p").Output()
+		if err != nil {
+			continue
+		}
+		screen := strings.TrimSpace(string(output))
+		if screen != "" && screen == previous && inst.DetectActivityForWindow(window) == ActivityIdle {
+			return nil
+		}
+		previous = screen
+	}
+	return fmt.Errorf("agent in %s did not become ready within %s", inst.Name, timeout)
+}
+
+// SendFirstPrompt waits for a just-started agent in a window to be ready and
+// sends it a prompt. An agent that opens on a question of its own — whether
+// to trust the folder, say — is left to be answered.
+func SendFirstPrompt(inst *Instance, window int, text, via string, history *PromptHistory) error {
+	if err := waitForAgent(inst, window, agentStartTimeout); err != nil {
+		if inst.DetectActivityForWindow(window) == ActivityWaiting {
+			return fmt.Errorf("%s is asking something before it starts: answer it, then send the prompt again", inst.Name)
+		}
+		return err
+	}
+	activity := inst.DetectActivityForWindow(window)
+	if err := inst.SendPromptToWindow(window, text); err != nil {
+		return fmt.Errorf("failed to send to %s: %w", inst.Name, err)
+	}
+	history.Append(inst.SentPrompt(window, text, via, activity))
+	return nil
+}
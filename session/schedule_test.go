@@ -0,0 +1,119 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Cron's fields, names, steps and its either-day rule, in local time.
+func TestCronNext(t *testing.T) {
+	// A Friday evening
+	from := time.Date(2026, 10, 16, 18, 30, 0, 0, time.Local)
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 9 * * mon-fri", time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2026, 10, 16, 18, 45, 0, 0, time.Local)},
+		{"@daily", time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local)},
+		{"30 8 1 * *", time.Date(2026, 11, 1, 8, 30, 0, 0, time.Local)},
+		// The 1st or a Sunday, whichever comes first
+		{"0 12 1 * sun", time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)},
+		{"0 0 30 feb *", time.Time{}},
+	}
+	for _, c := range cases {
+		spec, err := ParseCron(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if got := spec.Next(from); !got.Equal(c.want) {
+			t.Errorf("%s: next = %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"0 9 * *", "60 * * * *", "0 9 * * fri-mon", "*/0 * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+// A schedule's time is a cron expression, a time of day, a date and time or
+// a delay; one in the past is refused.
+func TestScheduleWhen(t *testing.T) {
+	now := time.Date(2026, 10, 16, 18, 30, 20, 0, time.Local)
+	cases := []struct {
+		when string
+		cron bool
+		next time.Time
+	}{
+		{"0 9 * * mon-fri", true, time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)},
+		{"17:00", false, time.Date(2026, 10, 17, 17, 0, 0, 0, time.Local)},
+		{"19:00", false, time.Date(2026, 10, 16, 19, 0, 0, 0, time.Local)},
+		{"2026-11-02 09:00", false, time.Date(2026, 11, 2, 9, 0, 0, 0, time.Local)},
+		{"in 2h", false, time.Date(2026, 10, 16, 20, 30, 0, 0, time.Local)},
+		{"in 30s", false, time.Date(2026, 10, 16, 18, 31, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		s, err := NewSchedule("asm_1", "", c.when, "git pull", now)
+		if err != nil {
+			t.Errorf("%s: %v", c.when, err)
+			continue
+		}
+		if (s.Cron != "") != c.cron || !s.Next.Equal(c.next) {
+			t.Errorf("%s: cron %q, next %v; want next %v", c.when, s.Cron, s.Next, c.next)
+		}
+	}
+	if _, err := NewSchedule("asm_1", "", "2026-10-01 09:00", "git pull", now); err == nil {
+		t.Error("a time in the past was accepted")
+	}
+}
+
+// A run on time is taken and the schedule moves on; runs slept through are
+// missed, every one of them, and none is made late.
+func TestTakeDueSchedules(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)
+	daily, _ := NewSchedule("asm_1", "", "0 9 * * *", "summarise CI", monday.Add(-time.Hour))
+	once, _ := NewSchedule("asm_2", "", "2026-10-19 09:00", "deploy", monday.Add(-time.Hour))
+	paused, _ := NewSchedule("asm_3", "", "0 9 * * *", "paused", monday.Add(-time.Hour))
+	paused.Paused = true
+	schedules := []*Schedule{daily, once, paused}
+
+	runs, missed := TakeDueSchedules(schedules, monday.Add(time.Minute))
+	if len(runs) != 2 || len(missed) != 0 {
+		t.Fatalf("runs %d, missed %d", len(runs), len(missed))
+	}
+	if !daily.Next.Equal(monday.AddDate(0, 0, 1)) || !once.Next.IsZero() {
+		t.Errorf("after the runs: daily next %v, once next %v", daily.Next, once.Next)
+	}
+	if runs, _ := TakeDueSchedules(schedules, monday.Add(2*time.Minute)); len(runs) != 0 {
+		t.Error("a run was taken twice")
+	}
+
+	// Asleep from Tuesday to Thursday lunchtime
+	thursday := monday.AddDate(0, 0, 3).Add(3 * time.Hour)
+	runs, missed = TakeDueSchedules(schedules, thursday)
+	if len(runs) != 0 || len(missed) != 1 || len(missed[0].Missed) != 3 {
+		t.Fatalf("runs %d, missed %+v", len(runs), missed)
+	}
+	if len(daily.Missed) != 3 || !daily.Next.Equal(monday.AddDate(0, 0, 4)) {
+		t.Errorf("daily: missed %v, next %v", daily.Missed, daily.Next)
+	}
+
+	RecordScheduleRun(schedules, ScheduleRun{Schedule: *once}, monday, nil)
+	if once.LastResult != "sent" || !once.LastRun.Equal(monday) {
+		t.Errorf("once: %q at %v", once.LastResult, once.LastRun)
+	}
+
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	if err := SaveSchedules(path, schedules); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSchedules(path)
+	if err != nil || len(loaded) != 3 || loaded[0].Cron != "0 9 * * *" || len(loaded[0].Missed) != 3 {
+		t.Errorf("loaded %+v, %v", loaded, err)
+	}
+}
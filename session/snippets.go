@@ -0,0 +1,254 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Saved prompts, and the variables they can fill in.
+//
+// A team's review, test and PR-description prompts are written once and kept
+// in three places, most specific last: snippets.json next to the rest of the
+// configuration, for everyone's own; "snippets" in a project's settings; and
+// .asmgr/snippets.json in a repository, checked in and shared. A snippet
+// with the same name in a later place replaces the earlier one.
+//
+// A snippet's text is a template. {{name}} is replaced with what the session
+// knows — its name, path, branch, changed files, notes — and {{ask:Label}}
+// with an answer asked for when the snippet is used. Values are looked up
+// only when a snippet refers to them: the branch costs a git call, and the
+// clipboard may not be readable at all.
+
+// Snippet is a saved prompt.
+type Snippet struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Text        string `json:"text"`
+	// Source is where the snippet was found: "global", "project" or "repo".
+	Source string `json:"-"`
+}
+
+// snippetsFile is the layout of snippets.json, global or in a repository.
+type snippetsFile struct {
+	Snippets []Snippet `json:"snippets"`
+}
+
+// RepoSnippetsPath is where a repository keeps its shared snippets.
+const RepoSnippetsPath = ".asmgr/snippets.json"
+
+// GlobalSnippetsPath is the user's own snippets file.
+func GlobalSnippetsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "agent-session-manager", "snippets.json"), nil
+}
+
+// readSnippetsFile loads a snippets file; a missing one has none.
+func readSnippetsFile(path, source string) ([]Snippet, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var file snippetsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range file.Snippets {
+		file.Snippets[i].Source = source
+	}
+	return file.Snippets, nil
+}
+
+// repoRoot is the top of the git repository dir is in, or dir itself.
+func repoRoot(dir string) string {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return dir
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// LoadSnippets gathers the snippets available to a session in dir: global,
+// then project, then the repository's, later ones replacing earlier ones of
+// the same name. Sorted by name. dir may be empty, for no repository.
+func LoadSnippets(project []Snippet, dir string) ([]Snippet, error) {
+	byName := make(map[string]Snippet)
+	add := func(snippets []Snippet) {
+		for _, s := range snippets {
+			if s.Name != "" {
+				byName[s.Name] = s
+			}
+		}
+	}
+
+	var errs []error
+	if path, err := GlobalSnippetsPath(); err == nil {
+		global, err := readSnippetsFile(path, "global")
+		if err != nil {
+			errs = append(errs, err)
+		}
+		add(global)
+	}
+	for _, s := range project {
+		s.Source = "project"
+		add([]Snippet{s})
+	}
+	if dir != "" {
+		repo, err := readSnippetsFile(filepath.Join(repoRoot(dir), RepoSnippetsPath), "repo")
+		if err != nil {
+			errs = append(errs, err)
+		}
+		add(repo)
+	}
+
+	snippets := make([]Snippet, 0, len(byName))
+	for _, s := range byName {
+		snippets = append(snippets, s)
+	}
+	sort.Slice(snippets, func(a, b int) bool { return snippets[a].Name < snippets[b].Name })
+	if len(errs) > 0 {
+		return snippets, errs[0]
+	}
+	return snippets, nil
+}
+
+// FindSnippet returns the snippet of a name.
+func FindSnippet(snippets []Snippet, name string) (Snippet, bool) {
+	for _, s := range snippets {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// templateVar matches {{ name }} in a snippet.
+var templateVar = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// SnippetQuestions are the {{ask:Label}} fields of a template, in order of
+// first appearance. A label asked twice is answered once.
+func SnippetQuestions(text string) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, match := range templateVar.FindAllStringSubmatch(text, -1) {
+		label, ok := strings.CutPrefix(match[1], "ask:")
+		if !ok {
+			continue
+		}
+		label = strings.TrimSpace(label)
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// SnippetContext is what a template's variables are filled in from.
+type SnippetContext struct {
+	Instance  *Instance
+	Answers   map[string]string      // {{ask:Label}} answers, by label
+	Clipboard func() (string, error) // Reads the clipboard, if there is one
+}
+
+// ExpandSnippet fills in a template's variables. A variable it does not know,
+// or a question without an answer, is an error rather than left in the text:
+// a prompt with "{{git.brnach}}" in it is not what anyone meant to send.
+func ExpandSnippet(text string, ctx SnippetContext) (string, error) {
+	var errs []string
+	cache := make(map[string]string)
+	expanded := templateVar.ReplaceAllStringFunc(text, func(match string) string {
+		name := templateVar.FindStringSubmatch(match)[1]
+		if value, ok := cache[name]; ok {
+			return value
+		}
+		value, err := ctx.lookup(name)
+		if err != nil {
+			errs = append(errs, err.Error())
+			return match
+		}
+		cache[name] = value
+		return value
+	})
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return expanded, nil
+}
+
+// lookup resolves one variable.
+func (c SnippetContext) lookup(name string) (string, error) {
+	if label, ok := strings.CutPrefix(name, "ask:"); ok {
+		answer, ok := c.Answers[strings.TrimSpace(label)]
+		if !ok {
+			return "", fmt.Errorf("no answer for %q", strings.TrimSpace(label))
+		}
+		return answer, nil
+	}
+	if name == "clipboard" {
+		if c.Clipboard == nil {
+			return "", fmt.Errorf("no clipboard")
+		}
+		text, err := c.Clipboard()
+		if err != nil {
+			return "", fmt.Errorf("clipboard: %w", err)
+		}
+		return text, nil
+	}
+
+	inst := c.Instance
+	if inst == nil {
+		return "", fmt.Errorf("{{%s}} needs a session", name)
+	}
+	switch name {
+	case "session.name":
+		return inst.Name, nil
+	case "path":
+		return inst.Path, nil
+	case "notes":
+		return inst.Notes, nil
+	case "agent":
+		return string(inst.Agent), nil
+	case "git.branch":
+		output, err := exec.Command("git", "-C", inst.Path, "rev-parse", "--abbrev-ref", "HEAD").Output()
+		if err != nil {
+			return "", fmt.Errorf("{{git.branch}}: %s is not a git repository", inst.Path)
+		}
+		return strings.TrimSpace(string(output)), nil
+	case "diff.files":
+		output, err := exec.Command("git", "-C", inst.Path, "status", "--porcelain=v1", "--untracked-files=all").Output()
+		if err != nil {
+			return "", fmt.Errorf("{{diff.files}}: %s is not a git repository", inst.Path)
+		}
+		return strings.Join(changedFiles(string(output)), "\n"), nil
+	}
+	return "", fmt.Errorf("unknown variable {{%s}}", name)
+}
+
+// changedFiles reads the file names out of `git status --porcelain`, the new
+// name for renames.
+func changedFiles(status string) []string {
+	var files []string
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		name := line[3:]
+		if _, to, ok := strings.Cut(name, " -> "); ok {
+			name = to
+		}
+		files = append(files, strings.Trim(name, `"`))
+	}
+	return files
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Variables are filled in from the session and the answers; a question asked
+// twice is one question.
+func TestExpandSnippet(t *testing.T) {
+	text := "Review {{session.name}} in {{ path }} for {{ask:Ticket id}}.\n{{notes}}\nSee {{ask:Ticket id}}: {{clipboard}}"
+	if got := SnippetQuestions(text); len(got) != 1 || got[0] != "Ticket id" {
+		t.Errorf("questions = %q", got)
+	}
+
+	inst := &Instance{Name: "api", Path: "/src/api", Notes: "Keep the v1 routes."}
+	got, err := ExpandSnippet(text, SnippetContext{
+		Instance:  inst,
+		Answers:   map[string]string{"Ticket id": "ENG-42"},
+		Clipboard: func() (string, error) { return "stack trace", nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Review api in /src/api for ENG-42.\nKeep the v1 routes.\nSee ENG-42: stack trace"
+	if got != want {
+		t.Errorf("expanded:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// A misspelt variable, an unanswered question or an unreadable clipboard
+// stops the snippet instead of sending it half filled in.
+func TestExpandSnippetErrors(t *testing.T) {
+	inst := &Instance{Name: "api", Path: "/src/api"}
+	for _, text := range []string{"{{git.brnach}}", "{{ask:Ticket id}}", "{{clipboard}}"} {
+		_, err := ExpandSnippet(text, SnippetContext{
+			Instance:  inst,
+			Clipboard: func() (string, error) { return "", errors.New("no display") },
+		})
+		if err == nil {
+			t.Errorf("%s expanded without an error", text)
+		}
+	}
+}
+
+// The branch and changed files come from git, the new name for a rename.
+func TestExpandSnippetGit(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "feature/login"},
+		{"-c", "user.email=a@b", "-c", "user.name=a", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if err := exec.Command("git", append([]string{"-C", dir}, args...)...).Run(); err != nil {
+			t.Skipf("git unavailable: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "login.go"), []byte("package login\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExpandSnippet("{{git.branch}}: {{diff.files}}", SnippetContext{Instance: &Instance{Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "feature/login: login.go" {
+		t.Errorf("expanded = %q", got)
+	}
+	if files := changedFiles(" M a.go\nR  old.go -> new.go\n?? \"sp ace.go\"\n"); strings.Join(files, ",") != "a.go,new.go,sp ace.go" {
+		t.Errorf("changed files = %q", files)
+	}
+}
+
+// The repository's snippets replace the project's, which replace the user's
+// own, name by name.
+func TestLoadSnippetsPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSnippets := func(path, json string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(json), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSnippets(filepath.Join(home, ".config", "agent-session-manager", "snippets.json"),
+		`{"snippets":[{"name":"review","text":"global review"},{"name":"tests","text":"global tests"},{"name":"pr","text":"global pr"}]}`)
+	repo := t.TempDir()
+	writeSnippets(filepath.Join(repo, RepoSnippetsPath), `{"snippets":[{"name":"review","text":"repo review"}]}`)
+
+	project := []Snippet{{Name: "review", Text: "project review"}, {Name: "tests", Text: "project tests"}}
+	snippets, err := LoadSnippets(project, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range snippets {
+		got = append(got, s.Name+"="+s.Source)
+	}
+	if strings.Join(got, " ") != "pr=global review=repo tests=project" {
+		t.Errorf("snippets = %v", got)
+	}
+	if s, _ := FindSnippet(snippets, "review"); s.Text != "repo review" {
+		t.Errorf("review = %q", s.Text)
+	}
+}
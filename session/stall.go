@@ -0,0 +1,238 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Telling a stuck agent from a slow one.
+//
+// A tab that shows a spinner is reported busy, and an agent whose request has
+// hung — a dropped connection, a tool that never returns — shows one forever.
+// What gives it away is that nothing else on the screen moves: the spinner
+// turns and the elapsed-time counter climbs, but no output arrives. So each
+// busy capture is reduced to a fingerprint with exactly those two things
+// removed, and a tab whose fingerprint has not changed for the stall timeout
+// is reported stalled instead of busy. A token counter is left in: an agent
+// thinking quietly is still making progress, and the count is how it shows.
+//
+// A terminal tab has no agent whose progress can be measured: a dev server,
+// a REPL or `tail -f` sits quiet for hours and is healthy. So terminal tabs
+// are left out, unless the project names programs to watch — a build that
+// hangs on a lock is one in the foreground printing nothing for as long.
+
+// DefaultStallTimeout is how long a busy tab may show no progress before it
+// is reported stalled, when the project does not say.
+const DefaultStallTimeout = 5 * time.Minute
+
+// stallTimeout is the timeout in effect, in nanoseconds; 0 turns detection
+// off. Set per project by the list, read by every poll.
+var stallTimeout atomic.Int64
+
+func init() {
+	stallTimeout.Store(int64(DefaultStallTimeout))
+}
+
+// SetStallTimeout sets how long a tab may show no progress before it is
+// stalled. Zero or less turns stall detection off.
+func SetStallTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	stallTimeout.Store(int64(d))
+}
+
+// StallTimeout returns the timeout set by SetStallTimeout.
+func StallTimeout() time.Duration {
+	return time.Duration(stallTimeout.Load())
+}
+
+// StallConfig is a project's stall settings, under "stall" in its Settings.
+type StallConfig struct {
+	// AfterSeconds is how long a tab may show no progress. 0 keeps the
+	// default; a negative value turns detection off.
+	AfterSeconds int `json:"after_seconds,omitempty"`
+	// Interrupt sends Escape to an agent tab when it stalls, which makes
+	// Claude and Codex give up on the request and take input again.
+	Interrupt bool `json:"interrupt,omitempty"`
+	// Terminal lists the programs, by command name ("make", "cargo"), that
+	// stall a terminal tab when in its foreground and quiet for the timeout.
+	// Terminal tabs running anything else are never stalled.
+	Terminal []string `json:"terminal,omitempty"`
+}
+
+// stallTerminal is the set of programs watched in terminal tabs, as a
+// map[string]bool. Set per project by the list, read by every poll.
+var stallTerminal atomic.Value
+
+// SetStallTerminalCommands sets the programs that stall a terminal tab; none
+// leaves terminal tabs out of stall detection.
+func SetStallTerminalCommands(commands []string) {
+	watched := make(map[string]bool, len(commands))
+	for _, command := range commands {
+		if command = strings.TrimSpace(command); command != "" {
+			watched[command] = true
+		}
+	}
+	stallTerminal.Store(watched)
+}
+
+// TerminalCommands is the programs c has watched in terminal tabs.
+func (c *StallConfig) TerminalCommands() []string {
+	if c == nil {
+		return nil
+	}
+	return c.Terminal
+}
+
+// stallWatched reports whether a terminal tab's foreground program is one
+// of those set by SetStallTerminalCommands.
+func stallWatched(command string) bool {
+	watched, _ := stallTerminal.Load().(map[string]bool)
+	return watched[strings.TrimPrefix(command, "-")]
+}
+
+// Timeout is the stall timeout c asks for; a nil config asks for the default.
+func (c *StallConfig) Timeout() time.Duration {
+	switch {
+	case c == nil || c.AfterSeconds == 0:
+		return DefaultStallTimeout
+	case c.AfterSeconds < 0:
+		return 0
+	}
+	return time.Duration(c.AfterSeconds) * time.Second
+}
+
+// InterruptOnStall reports whether stalled agents should be interrupted.
+func (c *StallConfig) InterruptOnStall() bool {
+	return c != nil && c.Interrupt
+}
+
+// elapsedCounter matches the running timers agents print next to their
+// spinners: "12s", "1.4s", "1m 05s", "2h 3m", "1m".
+var elapsedCounter = regexp.MustCompile(`\b(?:\d+h\s*)?(?:\d+m\s*)?\d+(?:\.\d+)?s\b|\b\d+[hm]\b`)
+
+// spinnerFrames are glyphs agents animate while working, beyond the braille
+// block: Claude's stars, Gemini's dots.
+const spinnerFrames = "·✢✳✶✻✽*∴∵⋮⋯✦"
+
+// isSpinnerFrame reports a character that only animates.
+func isSpinnerFrame(r rune) bool {
+	return (r >= 0x2800 && r <= 0x28FF) || strings.ContainsRune(spinnerFrames, r)
+}
+
+// progressFingerprint reduces a capture to what changes when an agent makes
+// progress: the text, without spinner frames, elapsed-time counters or
+// trailing blanks.
+func progressFingerprint(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		line = stripANSIForDetect(line)
+		line = strings.Map(func(r rune) rune {
+			if isSpinnerFrame(r) {
+				return -1
+			}
+			return r
+		}, line)
+		line = elapsedCounter.ReplaceAllString(line, "")
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// stallMark is when a target's screen last showed progress, and what it
+// looked like then.
+type stallMark struct {
+	fingerprint string
+	since       time.Time
+}
+
+// stallMarks tracks progress per target (session:window).
+var stallMarks sync.Map // map[string]stallMark
+
+// noProgressFor records a capture of target and reports how long its screen
+// has gone without progress.
+func noProgressFor(target string, lines []string, now time.Time) time.Duration {
+	fingerprint := progressFingerprint(lines)
+	if value, ok := stallMarks.Load(target); ok {
+		mark := value.(stallMark)
+		if mark.fingerprint == fingerprint {
+			return now.Sub(mark.since)
+		}
+	}
+	stallMarks.Store(target, stallMark{fingerprint: fingerprint, since: now})
+	return 0
+}
+
+// isStalled records a capture of target and reports whether it has shown no
+// progress for the stall timeout.
+func isStalled(target string, lines []string) bool {
+	timeout := StallTimeout()
+	if timeout <= 0 {
+		stallMarks.Delete(target)
+		return false
+	}
+	return noProgressFor(target, lines, time.Now()) >= timeout
+}
+
+// forgetStall drops a target's progress mark: it is not working, so there is
+// nothing to be stalled on.
+func forgetStall(target string) {
+	stallMarks.Delete(target)
+}
+
+// detectTerminalActivity reports a terminal tab stalled when a watched
+// program in its foreground has printed nothing for the stall timeout, and
+// idle otherwise.
+func detectTerminalActivity(target string) (SessionActivity, bool) {
+	if watched, _ := stallTerminal.Load().(map[string]bool); StallTimeout() <= 0 || len(watched) == 0 {
+		forgetStall(target)
+		return ActivityIdle, true
+	}
+	output, err := TmuxCommand("display-message", "-p", "-t", target, "#{pane_dead}:#{pane_current_command}").Output()
+	if err != nil {
+		return ActivityIdle, false
+	}
+	dead, command, _ := strings.Cut(strings.TrimSpace(string(output)), ":")
+	if dead == "1" || !stallWatched(command) {
+		forgetStall(target)
+		return ActivityIdle, true
+	}
+
+	output, err = TmuxCommand("capture-pane", "-t", target, "-p", "-S", "-50").Output()
+	if err != nil {
+		return ActivityIdle, false
+	}
+	if isStalled(target, strings.Split(string(output), "\n")) {
+		return ActivityStalled, true
+	}
+	return ActivityIdle, true
+}
+
+// SetWindowStalled marks a window stalled, or clears the mark, in a tmux
+// window option the status bar is built from. Kept in tmux rather than the
+// instance so `asmgr refresh-status`, run by tmux itself, sees it too.
+func (i *Instance) SetWindowStalled(windowIdx int, stalled bool) error {
+	target := fmt.Sprintf("%s:%d", i.TmuxSessionName(), windowIdx)
+	if stalled {
+		return TmuxCommand("set-option", "-w", "-t", target, "@asmgr_stalled", "1").Run()
+	}
+	return TmuxCommand("set-option", "-w", "-u", "-t", target, "@asmgr_stalled").Run()
+}
+
+// InterruptWindow sends Escape to a window, the key Claude and Codex take as
+// "stop what you are doing".
+func (i *Instance) InterruptWindow(windowIdx int) error {
+	if !i.IsAlive() {
+		return fmt.Errorf("session not running")
+	}
+	target := fmt.Sprintf("%s:%d", i.TmuxSessionName(), windowIdx)
+	return TmuxCommand("send-keys", "-t", target, "Escape").Run()
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// A stuck Claude keeps turning its spinner and counting seconds; neither is
+// progress. The token count is: an agent thinking quietly shows it climbing.
+func TestProgressFingerprintIgnoresSpinnerAndTimer(t *testing.T) {
+	before := strings.Split("● Reading the config.\n\n✻ Cogitating… (12s · ↑ 1.2k tokens · esc to interrupt)\n", "\n")
+	later := strings.Split("● Reading the config.\n\n✽ Cogitating… (1m 05s · ↑ 1.2k tokens · esc to interrupt)   \n", "\n")
+	if progressFingerprint(before) != progressFingerprint(later) {
+		t.Errorf("spinner and timer changes read as progress:\n%q\n%q", progressFingerprint(before), progressFingerprint(later))
+	}
+
+	thinking := strings.Split("● Reading the config.\n\n✻ Cogitating… (1m 05s · ↑ 3.4k tokens · esc to interrupt)\n", "\n")
+	if progressFingerprint(before) == progressFingerprint(thinking) {
+		t.Error("a climbing token count was not read as progress")
+	}
+
+	codex := []string{"⠋ Working (12s • esc to interrupt)"}
+	codexLater := []string{"⠼ Working (2m 3s • esc to interrupt)"}
+	if progressFingerprint(codex) != progressFingerprint(codexLater) {
+		t.Errorf("Codex's spinner read as progress: %q vs %q", progressFingerprint(codex), progressFingerprint(codexLater))
+	}
+}
+
+// The clock starts at the last change: output that moves resets it, a screen
+// that only ticks does not.
+func TestNoProgressForCountsFromTheLastChange(t *testing.T) {
+	target := "stall-test:1"
+	defer forgetStall(target)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if held := noProgressFor(target, []string{"⠋ Working (1s)"}, start); held != 0 {
+		t.Errorf("first capture held for %s", held)
+	}
+	if held := noProgressFor(target, []string{"⠙ Working (3m 0s)"}, start.Add(3*time.Minute)); held != 3*time.Minute {
+		t.Errorf("unchanged screen held for %s, want 3m", held)
+	}
+	if held := noProgressFor(target, []string{"wrote main.go", "⠹ Working (4m 0s)"}, start.Add(4*time.Minute)); held != 0 {
+		t.Errorf("new output held for %s, want a reset", held)
+	}
+	if held := noProgressFor(target, []string{"wrote main.go", "⠸ Working (6m 0s)"}, start.Add(6*time.Minute)); held != 2*time.Minute {
+		t.Errorf("held for %s after the reset, want 2m", held)
+	}
+}
+
+// A project without a stall setting gets the default; a negative value is
+// the way to turn detection off.
+func TestStallConfigTimeout(t *testing.T) {
+	var unset *StallConfig
+	for _, c := range []struct {
+		cfg  *StallConfig
+		want time.Duration
+	}{
+		{unset, DefaultStallTimeout},
+		{&StallConfig{}, DefaultStallTimeout},
+		{&StallConfig{AfterSeconds: 90}, 90 * time.Second},
+		{&StallConfig{AfterSeconds: -1}, 0},
+	} {
+		if got := c.cfg.Timeout(); got != c.want {
+			t.Errorf("%+v: timeout %s, want %s", c.cfg, got, c.want)
+		}
+	}
+	if unset.InterruptOnStall() {
+		t.Error("a project without settings interrupts stalled agents")
+	}
+}
+
+// A quiet program in a terminal tab is not stalled unless the project names
+// it: a dev server or a REPL sits quiet by design.
+func TestStallWatchesOnlyListedTerminalCommands(t *testing.T) {
+	defer SetStallTerminalCommands(nil)
+
+	SetStallTerminalCommands((*StallConfig)(nil).TerminalCommands())
+	if stallWatched("sleep") || stallWatched("make") {
+		t.Error("a terminal program is watched with none listed")
+	}
+	SetStallTerminalCommands([]string{"make", " cargo "})
+	if stallWatched("sleep") {
+		t.Error("sleep, not listed, is watched")
+	}
+	if !stallWatched("make") || !stallWatched("cargo") {
+		t.Error("a listed program is not watched")
+	}
+	SetStallTerminalCommands([]string{"sleep"})
+	if !stallWatched("sleep") {
+		t.Error("sleep, opted in, is not watched")
+	}
+}
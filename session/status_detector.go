@@ -38,14 +38,57 @@ type SessionActivity int
 const (
 	ActivityIdle    SessionActivity = iota // No activity, no prompt
 	ActivityBusy                           // Agent is working
+	ActivityStalled                        // Busy, but nothing has moved for the stall timeout
 	ActivityWaiting                        // Agent needs user input/permission
 )
 
+// activityNames is the spelling used wherever an activity leaves the process:
+// the activity log, hook environments. Numbers would tie those files to the
+// order of the constants above.
+var activityNames = map[SessionActivity]string{
+	ActivityIdle:    "idle",
+	ActivityBusy:    "busy",
+	ActivityStalled: "stalled",
+	ActivityWaiting: "waiting",
+}
+
+func (a SessionActivity) String() string {
+	if name, ok := activityNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("activity(%d)", int(a))
+}
+
+// MarshalText writes the activity by name.
+func (a SessionActivity) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText reads a name written by MarshalText. An unknown name is an
+// error rather than idle: a log written by a newer version should fail loudly
+// instead of quietly turning into idle time.
+func (a *SessionActivity) UnmarshalText(text []byte) error {
+	for activity, name := range activityNames {
+		if name == string(text) {
+			*a = activity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown activity %q", text)
+}
+
+// Working reports an activity in which the agent holds the turn: busy, or
+// busy and stalled.
+func (a SessionActivity) Working() bool {
+	return a == ActivityBusy || a == ActivityStalled
+}
+
 // AgentPatterns holds detection patterns for a specific agent
 type AgentPatterns struct {
-	WaitingPatterns []string // Patterns that indicate waiting for user input
-	BusyPatterns    []string // Patterns that indicate agent is working (kept for compatibility)
-	Spinners        []string // Spinner characters - primary busy indicator
+	WaitingPatterns   []string // Patterns that indicate waiting for user input
+	BusyPatterns      []string // Patterns that indicate agent is working (kept for compatibility)
+	Spinners          []string // Spinner characters - primary busy indicator
+	RateLimitPatterns []string // Patterns of a usage or rate limit message
 }
 
 // Default spinner characters (braille dots)
@@ -79,6 +122,12 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"yes, and always allow",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"usage limit reached",
+			"limit reached ∙ resets",
+			"limit reached · resets",
+			"you've hit your limit",
+		},
 	},
 	AgentGemini: {
 		WaitingPatterns: []string{
@@ -87,9 +136,14 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 			"do you want to proceed",
 			"keep trying",
-			"high demand",
 		},
 		Spinners: append(defaultSpinners, "∴", "∵", "⋮", "⋯", "✦"),
+		RateLimitPatterns: []string{
+			"high demand",
+			"quota exceeded",
+			"rate limit exceeded",
+			"resource_exhausted",
+		},
 	},
 	AgentAider: {
 		WaitingPatterns: []string{
@@ -99,6 +153,10 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"rate limit exceeded",
+			"ratelimiterror",
+		},
 	},
 	AgentCodex: {
 		// Codex phrases its approval prompt its own way; the generic
@@ -116,6 +174,11 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"you've hit your usage limit",
+			"usage limit reached",
+			"rate limit reached",
+		},
 	},
 	AgentAmazonQ: {
 		WaitingPatterns: []string{
@@ -125,6 +188,10 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"rate limit exceeded",
+			"usage limit reached",
+		},
 	},
 	AgentOpenCode: {
 		WaitingPatterns: []string{
@@ -134,6 +201,10 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"rate limit exceeded",
+			"usage limit reached",
+		},
 	},
 	AgentCustom: {
 		WaitingPatterns: []string{
@@ -143,6 +214,10 @@ var agentPatterns = map[AgentType]AgentPatterns{
 			"waiting for user",
 		},
 		Spinners: defaultSpinners,
+		RateLimitPatterns: []string{
+			"rate limit exceeded",
+			"usage limit reached",
+		},
 	},
 }
 
@@ -198,9 +273,10 @@ func (i *Instance) DetectActivityForWindowWithValidity(windowIdx int) (SessionAc
 		}
 	}
 
-	// Terminal tabs are not AI agents - skip activity detection entirely
+	// Terminal tabs are not AI agents: the only thing worth reporting about
+	// one is a program in it that has stopped making progress.
 	if agent == AgentTerminal {
-		return ActivityIdle, true
+		return detectTerminalActivity(target)
 	}
 
 	cmd := TmuxCommand("capture-pane", "-t", target, "-p", "-S", "-50")
@@ -223,12 +299,28 @@ func (i *Instance) DetectActivityForWindowWithValidity(windowIdx int) (SessionAc
 		activity = detectGenericActivity(lines, patterns, target)
 	}
 
+	// An agent that reports on itself through hooks knows its state better
+	// than its screen does, except where the screen plainly says otherwise.
+	if report, ok := ReadAgentReport(target); ok && report.Agent == agent {
+		reported, decided := reconcileReport(report, activity, time.Now())
+		activity = reported
+		if decided && activity != ActivityBusy {
+			// Not a gap between phases: the agent said it is done
+			lastBusyTime.Delete(target)
+		}
+	}
+
 	// Apply busy grace period: if we detected busy, update the timestamp.
 	// If we got idle but were busy recently, keep reporting busy.
 	if activity == ActivityBusy {
 		lastBusyTime.Store(target, time.Now())
+		rateLimitMarks.Delete(target)
+		if isStalled(target, lines) {
+			return ActivityStalled, true
+		}
 		return ActivityBusy, true
 	}
+	forgetStall(target)
 	if activity == ActivityIdle {
 		if lastTime, ok := lastBusyTime.Load(target); ok {
 			if time.Since(lastTime.(time.Time)) < busyGracePeriod {
@@ -242,12 +334,14 @@ func (i *Instance) DetectActivityForWindowWithValidity(windowIdx int) (SessionAc
 	if activity == ActivityWaiting {
 		lastBusyTime.Delete(target)
 	}
+	// An agent stopped by its limit sits idle, or asks what to do about it
+	trackRateLimit(target, lines, patterns.RateLimitPatterns, time.Now())
 
 	return activity, true
 }
 
 // DetectAggregatedActivity checks all followed windows and returns highest priority activity
-// Priority: Waiting > Busy > Idle
+// Priority: Waiting > Stalled > Busy > Idle
 func (i *Instance) DetectAggregatedActivity() SessionActivity {
 	if !i.IsAlive() {
 		return ActivityIdle
@@ -272,9 +366,9 @@ func (i *Instance) DetectAggregatedActivity() SessionActivity {
 		if activity == ActivityWaiting {
 			return ActivityWaiting
 		}
-		// Busy is higher than Idle
-		if activity == ActivityBusy && highestActivity == ActivityIdle {
-			highestActivity = ActivityBusy
+		// Stalled is higher than Busy, which is higher than Idle
+		if activity > highestActivity {
+			highestActivity = activity
 		}
 	}
 
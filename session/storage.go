@@ -36,6 +36,12 @@ type Settings struct {
 	MarkedSessionID   string `json:"marked_session_id,omitempty"`
 	Cursor            int    `json:"cursor,omitempty"`
 	SplitFocus        int    `json:"split_focus,omitempty"`
+	Hooks             *Hooks `json:"hooks,omitempty"` // Project-level hook commands
+	Approval          *ApprovalPolicy `json:"approval,omitempty"` // Project-level auto-approval rules
+	Stall             *StallConfig    `json:"stall,omitempty"`    // Stalled-agent timeout and interrupt
+	RateLimit         *RateLimitConfig `json:"rate_limit,omitempty"` // Auto-continue after a usage limit
+	Snippets          []Snippet        `json:"snippets,omitempty"`   // Project-level saved prompts
+	WorktreeRoot      string           `json:"worktree_root,omitempty"` // Where session worktrees are made
 }
 
 type StorageData struct {
@@ -92,7 +98,12 @@ func (s *Storage) getLockPath(projectID string) string {
 
 // IsProjectLocked checks if a project is already running
 func (s *Storage) IsProjectLocked(projectID string) (bool, int) {
-	lockPath := s.getLockPath(projectID)
+	return lockHolder(s.getLockPath(projectID))
+}
+
+// lockHolder checks whether the process that wrote a lock file is still
+// running, removing the file if not
+func lockHolder(lockPath string) (bool, int) {
 	data, err := os.ReadFile(lockPath)
 	if os.IsNotExist(err) {
 		return false, 0
@@ -0,0 +1,262 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Git worktrees for sessions that must not share a checkout.
+//
+// Two agents working in the same directory edit the same files and see each
+// other's half-done changes. A worktree is a second checkout of the same
+// repository on a branch of its own: the agent in it works alone, and what it
+// did is that branch's diff from where it started. Worktrees live under the
+// config directory, or the project's worktree_root setting, one directory per
+// repository, so deleting one never touches the repository's own tree.
+//
+// A session can be made in one of its own: it then remembers the repository
+// it came from, its branch and the worktree, so the list can show the branch,
+// its diff can start at the branch point and deleting it can clean up.
+
+// worktreesDir is the directory, under the config directory, that holds the
+// worktrees.
+const worktreesDir = "worktrees"
+
+// WorktreesDir is where new worktrees are made, one directory per
+// repository: the configured root, ~ expanded, or the default under the
+// config directory.
+func (s *Storage) WorktreesDir(configured string) string {
+	if configured = strings.TrimSpace(configured); configured != "" {
+		return expandTilde(configured)
+	}
+	return filepath.Join(s.configDir, worktreesDir)
+}
+
+// git runs a git command in dir and returns its trimmed output, or its
+// stderr as the error.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RepoHead is the top of the git repository dir is in, with its current
+// commit and branch; the branch is empty on a detached HEAD.
+func RepoHead(dir string) (root, commit, branch string, err error) {
+	root, err = git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	commit, err = git(root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", "", fmt.Errorf("the repository has no commit to branch from")
+	}
+	branch, _ = git(root, "symbolic-ref", "--quiet", "--short", "HEAD")
+	return root, commit, branch, nil
+}
+
+// BranchSlug turns a name into something usable in a branch and a
+// directory name: lowercase letters, digits, dots and dashes.
+func BranchSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
+
+// branchExists is whether the repository has a local branch of that name.
+func branchExists(repo, branch string) bool {
+	_, err := git(repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// FreeWorktree returns a branch name and a worktree directory, under root,
+// that are not taken yet, suffixing name with -2, -3, ... as needed.
+func FreeWorktree(repo, root, prefix, name string) (branch, dir string) {
+	base := filepath.Join(root, BranchSlug(filepath.Base(repo)))
+	for n := 1; ; n++ {
+		slug := name
+		if n > 1 {
+			slug += "-" + strconv.Itoa(n)
+		}
+		branch, dir = prefix+slug, filepath.Join(base, slug)
+		if _, err := os.Stat(dir); os.IsNotExist(err) && !branchExists(repo, branch) {
+			return branch, dir
+		}
+	}
+}
+
+// AddWorktree checks out a new branch, started at base, in a new worktree
+// at dir.
+func AddWorktree(repo, branch, dir, base string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	if _, err := git(repo, "worktree", "add", "-b", branch, dir, base); err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
+	}
+	return nil
+}
+
+// RemoveWorktree deletes a worktree, whatever is uncommitted in it. A
+// worktree already gone is pruned from the repository's list.
+func RemoveWorktree(repo, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		_, err := git(repo, "worktree", "prune")
+		return err
+	}
+	if _, err := git(repo, "worktree", "remove", "--force", dir); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	return nil
+}
+
+// DeleteBranch deletes a local branch, merged or not. A branch already gone
+// is not an error.
+func DeleteBranch(repo, branch string) error {
+	if !branchExists(repo, branch) {
+		return nil
+	}
+	if _, err := git(repo, "branch", "-D", branch); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// UseWorktree moves a new session into a worktree of its own, under root,
+// on a new branch named after the session and started at the repository's
+// HEAD. A session made in a subdirectory of the repository works in the same
+// subdirectory of the worktree.
+func (i *Instance) UseWorktree(root string) error {
+	repo, commit, branch, err := RepoHead(i.Path)
+	if err != nil {
+		return err
+	}
+	name := BranchSlug(i.Name)
+	if name == "" {
+		name = "session"
+	}
+	newBranch, dir := FreeWorktree(repo, root, "", name)
+	if err := AddWorktree(repo, newBranch, dir, commit); err != nil {
+		return err
+	}
+	path := dir
+	// git reports the repository with symlinks resolved
+	if realPath, err := filepath.EvalSymlinks(i.Path); err == nil {
+		if rel, err := filepath.Rel(repo, realPath); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			path = filepath.Join(dir, rel)
+		}
+	}
+	i.Path = path
+	i.RepoPath = repo
+	i.Branch = newBranch
+	i.WorktreePath = dir
+	i.BaseBranch = branch
+	i.BaseCommitSHA = commit
+	return nil
+}
+
+// RemoveOwnWorktree deletes the session's worktree and, if asked, its
+// branch, once the session no longer runs in it. Nothing for a session
+// without one.
+func (i *Instance) RemoveOwnWorktree(deleteBranch bool) error {
+	if i.WorktreePath == "" {
+		return nil
+	}
+	if err := RemoveWorktree(i.RepoPath, i.WorktreePath); err != nil {
+		return err
+	}
+	if deleteBranch && i.Branch != "" {
+		return DeleteBranch(i.RepoPath, i.Branch)
+	}
+	return nil
+}
+
+// branchPoint is where the session's branch left the branch it was made
+// from, so its diff is its own work even after that branch moved and this
+// one was rebased onto it. Empty for a session not in a worktree of its own,
+// or when the branch it was made from is gone.
+func (i *Instance) branchPoint() string {
+	if i.WorktreePath == "" || i.BaseBranch == "" {
+		return ""
+	}
+	base, err := git(i.Path, "merge-base", "refs/heads/"+i.BaseBranch, "HEAD")
+	if err != nil {
+		return ""
+	}
+	return base
+}
+
+// DiffSummary is how much a checkout changed since a commit.
+type DiffSummary struct {
+	Files   int
+	Added   int
+	Removed int
+}
+
+// SummarizeDiff counts the files and lines changed in dir since base,
+// committed or not, new files included. It reads only: nothing is staged.
+func SummarizeDiff(dir, base string) (DiffSummary, error) {
+	var sum DiffSummary
+	numstat, err := git(dir, "diff", "--numstat", base)
+	if err != nil {
+		return sum, err
+	}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		sum.Files++
+		// Binary files count as a file, with "-" for lines
+		added, _ := strconv.Atoi(fields[0])
+		removed, _ := strconv.Atoi(fields[1])
+		sum.Added += added
+		sum.Removed += removed
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return sum, err
+	}
+	for _, name := range strings.Split(untracked, "\n") {
+		if name == "" {
+			continue
+		}
+		sum.Files++
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			sum.Added++
+		}
+		f.Close()
+	}
+	return sum, nil
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A session made in a subdirectory of a repository moves to the same
+// subdirectory of a worktree of its own, on a branch named after it, and a
+// second one of the same name gets the next free branch.
+func TestInstanceUseWorktree(t *testing.T) {
+	repo := gitRepo(t)
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	inst := &Instance{Name: "Fix Login!", Path: filepath.Join(repo, "web")}
+	if err := inst.UseWorktree(root); err != nil {
+		t.Fatal(err)
+	}
+	realRepo, _ := filepath.EvalSymlinks(repo)
+	if inst.Branch != "fix-login" || inst.BaseBranch != "main" || inst.RepoPath != realRepo {
+		t.Errorf("branch = %q from %q in %q", inst.Branch, inst.BaseBranch, inst.RepoPath)
+	}
+	if inst.Path != filepath.Join(inst.WorktreePath, "web") || !strings.HasPrefix(inst.WorktreePath, root) {
+		t.Errorf("path = %q, worktree = %q", inst.Path, inst.WorktreePath)
+	}
+	if inst.BaseCommitSHA == "" {
+		t.Error("no base commit")
+	}
+
+	again := &Instance{Name: "fix login", Path: repo}
+	if err := again.UseWorktree(root); err != nil {
+		t.Fatal(err)
+	}
+	if again.Branch != "fix-login-2" || again.Path != again.WorktreePath {
+		t.Errorf("second session: branch = %q, path = %q", again.Branch, again.Path)
+	}
+
+	if err := (&Instance{Name: "x", Path: t.TempDir()}).UseWorktree(root); err == nil {
+		t.Error("made a worktree outside a repository")
+	}
+}
+
+// The session's diff starts where its branch left the one it was made from,
+// so work merged there since, and rebased onto, is not counted as its own.
+// Removing the worktree with its branch leaves neither behind.
+func TestInstanceWorktreeDiffAndRemove(t *testing.T) {
+	repo := gitRepo(t)
+	inst := &Instance{Name: "feature", Path: repo}
+	if err := inst.UseWorktree(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(dir, file string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{
+			{"add", file},
+			{"-c", "user.email=a@b", "-c", "user.name=a", "commit", "-q", "-m", file},
+		} {
+			if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %s: %s", args[0], out)
+			}
+		}
+	}
+	commit(inst.Path, "feature.go")
+	commit(repo, "upstream.go")
+	if out, err := exec.Command("git", "-C", inst.Path, "-c", "user.email=a@b", "-c", "user.name=a", "rebase", "-q", "main").CombinedOutput(); err != nil {
+		t.Fatalf("rebase: %s", out)
+	}
+
+	diff := inst.GetSessionDiff()
+	if diff.Error != nil {
+		t.Fatal(diff.Error)
+	}
+	if !strings.Contains(diff.Content, "feature.go") || strings.Contains(diff.Content, "upstream.go") {
+		t.Errorf("session diff:\n%s", diff.Content)
+	}
+
+	if err := inst.RemoveOwnWorktree(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(inst.WorktreePath); !os.IsNotExist(err) {
+		t.Errorf("worktree still there: %v", err)
+	}
+	if branchExists(repo, inst.Branch) {
+		t.Errorf("branch %s still there", inst.Branch)
+	}
+}
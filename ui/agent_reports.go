@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"github.com/izll/agent-session-manager/session"
+)
+
+// What agents report about themselves, in the list.
+//
+// The poll already folds reports into each tab's state; what is left for the
+// list is the rest of what they carry. A report names the conversation the
+// agent is in, which is exactly what resume needs and what otherwise has to
+// be guessed from the agent's own files after a detach — so it is saved as
+// soon as it changes, including after a /clear. And a report on a permission
+// prompt names the tool asking, which the preview shows.
+
+// syncReportedSessionIDs saves the conversation IDs agents reported, where
+// they differ from what the sessions have stored.
+func (m *Model) syncReportedSessionIDs(reports map[string]map[int]session.AgentReport) {
+	for _, inst := range m.instances {
+		windows, ok := reports[inst.ID]
+		if !ok {
+			continue
+		}
+		changed := false
+		for index, report := range windows {
+			if report.SessionID == "" {
+				continue
+			}
+			if index == m.mainWindowIndex[inst.ID] {
+				agent := inst.Agent
+				if agent == "" {
+					agent = session.AgentClaude
+				}
+				if agent == report.Agent && inst.ResumeSessionID != report.SessionID {
+					inst.ResumeSessionID = report.SessionID
+					changed = true
+				}
+				continue
+			}
+			for i := range inst.FollowedWindows {
+				fw := &inst.FollowedWindows[i]
+				if fw.Index == index && fw.Agent == report.Agent && fw.ResumeSessionID != report.SessionID {
+					fw.ResumeSessionID = report.SessionID
+					changed = true
+				}
+			}
+		}
+		if changed && m.storage != nil {
+			m.storage.UpdateInstance(inst)
+		}
+	}
+}
+
+// pendingTool describes the tool request a waiting tab of a session reported,
+// e.g. "Bash: npm test", or "" if none did.
+func (m *Model) pendingTool(inst *session.Instance) string {
+	for _, index := range m.waitingWindows(inst) {
+		report, ok := m.agentReports[inst.ID][index]
+		if !ok || report.State != session.ActivityWaiting || report.Tool == "" {
+			continue
+		}
+		if report.Subject == "" {
+			return report.Tool
+		}
+		return report.Tool + ": " + report.Subject
+	}
+	return ""
+}
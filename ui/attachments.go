@@ -0,0 +1,237 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+	"github.com/sahilm/fuzzy"
+)
+
+// Adding attachments to the prompt dialog.
+//
+// ctrl+o in the prompt dialog opens a menu of what can go with the prompt:
+// a file of the session, picked with a fuzzy finder; its session or full
+// diff; the last lines of one of its tabs; or another session's last
+// answer. Attachments are only named here. They are read, and written into
+// the prompt in the form its agent takes, when the prompt is sent.
+
+// Attach dialog steps.
+const (
+	attachMenu   = iota // Choosing what to attach
+	attachFile          // The file finder
+	attachPane          // Choosing a tab, and how many lines
+	attachAnswer        // Choosing another session
+)
+
+// Pane attachment line counts: the first, the step, and the most.
+const (
+	attachDefaultLines = 50
+	attachLinesStep    = 25
+	attachMaxLines     = 1000
+)
+
+// openAttach opens the attach menu from the prompt dialog.
+func (m *Model) openAttach() {
+	m.attachStep = attachMenu
+	m.attachCursor = 0
+	m.attachErr = ""
+	m.promptInput.Blur()
+	m.state = stateAttach
+}
+
+// closeAttach goes back to the prompt dialog.
+func (m *Model) closeAttach() tea.Cmd {
+	m.state = statePrompt
+	return m.promptInput.Focus()
+}
+
+// addAttachment adds to the prompt's attachments and goes back to the prompt.
+func (m *Model) addAttachment(a session.Attachment) tea.Cmd {
+	m.promptAttachments = append(m.promptAttachments, a)
+	return m.closeAttach()
+}
+
+// attachFilterMatches returns the indexes of the files matching the filter,
+// best first; the first files in order for an empty one.
+func attachFilterMatches(files []string, query string) []int {
+	if strings.TrimSpace(query) == "" {
+		indexes := make([]int, 0, min(len(files), 200))
+		for i := range files {
+			if i == cap(indexes) {
+				break
+			}
+			indexes = append(indexes, i)
+		}
+		return indexes
+	}
+	var indexes []int
+	for _, match := range fuzzy.Find(query, files) {
+		indexes = append(indexes, match.Index)
+	}
+	return indexes
+}
+
+// attachPaneWindows are the tabs whose panes can be attached: every window
+// of the session but the one the prompt goes to.
+func attachPaneWindows(inst *session.Instance) []session.WindowInfo {
+	var windows []session.WindowInfo
+	current := inst.GetCurrentWindowIndex()
+	for _, w := range inst.GetWindowList() {
+		if w.Index != current {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// attachAnswerSources are the other running sessions.
+func (m Model) attachAnswerSources() []*session.Instance {
+	inst := m.getSelectedInstance()
+	var sources []*session.Instance
+	for _, other := range m.instances {
+		if other.Status == session.StatusRunning && (inst == nil || other.ID != inst.ID) {
+			sources = append(sources, other)
+		}
+	}
+	return sources
+}
+
+// handleAttachKeys handles keyboard input in the attach dialog.
+func (m Model) handleAttachKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return m, m.closeAttach()
+	}
+	key := msg.String()
+
+	switch m.attachStep {
+	case attachMenu:
+		switch key {
+		case "esc":
+			return m, m.closeAttach()
+		case "f":
+			files, err := session.ListFiles(inst.Path)
+			if err != nil {
+				m.attachErr = err.Error()
+				return m, nil
+			}
+			m.attachFiles = files
+			m.attachFilter = textinput.New()
+			m.attachFilter.Placeholder = "Find a file..."
+			m.attachFilter.Prompt = "/ "
+			m.attachFilter.CharLimit = 200
+			m.attachFilter.Width = 60
+			m.attachMatches = attachFilterMatches(files, "")
+			m.attachCursor = 0
+			m.attachStep = attachFile
+			return m, m.attachFilter.Focus()
+		case "s":
+			return m, m.addAttachment(session.Attachment{Kind: session.AttachSessionDiff})
+		case "d":
+			return m, m.addAttachment(session.Attachment{Kind: session.AttachFullDiff})
+		case "p":
+			m.attachWindows = attachPaneWindows(inst)
+			if len(m.attachWindows) == 0 {
+				m.attachErr = "The session has no other tab"
+				return m, nil
+			}
+			m.attachCursor = 0
+			m.attachLines = attachDefaultLines
+			m.attachStep = attachPane
+		case "a":
+			if len(m.attachAnswerSources()) == 0 {
+				m.attachErr = "No other session is running"
+				return m, nil
+			}
+			m.attachCursor = 0
+			m.attachStep = attachAnswer
+		}
+		return m, nil
+
+	case attachFile:
+		switch key {
+		case "esc":
+			m.attachStep = attachMenu
+			return m, nil
+		case "up", "ctrl+p":
+			if m.attachCursor > 0 {
+				m.attachCursor--
+			}
+			return m, nil
+		case "down", "ctrl+n":
+			if m.attachCursor < len(m.attachMatches)-1 {
+				m.attachCursor++
+			}
+			return m, nil
+		case "enter", "tab":
+			if m.attachCursor < len(m.attachMatches) {
+				path := m.attachFiles[m.attachMatches[m.attachCursor]]
+				return m, m.addAttachment(session.Attachment{Kind: session.AttachFile, Path: path})
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.attachFilter, cmd = m.attachFilter.Update(msg)
+		m.attachMatches = attachFilterMatches(m.attachFiles, m.attachFilter.Value())
+		if m.attachCursor >= len(m.attachMatches) {
+			m.attachCursor = max(len(m.attachMatches)-1, 0)
+		}
+		return m, cmd
+
+	case attachPane:
+		switch key {
+		case "esc":
+			m.attachStep = attachMenu
+		case "up", "k":
+			if m.attachCursor > 0 {
+				m.attachCursor--
+			}
+		case "down", "j":
+			if m.attachCursor < len(m.attachWindows)-1 {
+				m.attachCursor++
+			}
+		case "+", "=", "right", "l":
+			m.attachLines = min(m.attachLines+attachLinesStep, attachMaxLines)
+		case "-", "left", "h":
+			m.attachLines = max(m.attachLines-attachLinesStep, attachLinesStep)
+		case "enter":
+			w := m.attachWindows[m.attachCursor]
+			return m, m.addAttachment(session.Attachment{
+				Kind: session.AttachPane, Source: inst, Window: w.Index, Tab: w.Name, Lines: m.attachLines,
+			})
+		}
+		return m, nil
+
+	case attachAnswer:
+		sources := m.attachAnswerSources()
+		switch key {
+		case "esc":
+			m.attachStep = attachMenu
+		case "up", "k":
+			if m.attachCursor > 0 {
+				m.attachCursor--
+			}
+		case "down", "j":
+			if m.attachCursor < len(sources)-1 {
+				m.attachCursor++
+			}
+		case "enter":
+			if m.attachCursor < len(sources) {
+				return m, m.addAttachment(session.Attachment{Kind: session.AttachAnswer, Source: sources[m.attachCursor]})
+			}
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+// attachmentLabels lists the prompt's attachments for the prompt dialog.
+func (m Model) attachmentLabels() string {
+	labels := make([]string, len(m.promptAttachments))
+	for i, a := range m.promptAttachments {
+		labels[i] = a.Label()
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,299 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Sending one prompt to several sessions.
+//
+// "Pull latest main and rebase", "stop and summarise your progress": the
+// same words, to every session of a group. The broadcast dialog picks the
+// sessions — a group, the favorites, what the search filter shows, or any
+// hand-picked set — then takes the prompt, and sends it to the main agent of
+// each running one. Before anything is sent, the plan can be looked at: which
+// sessions get the prompt and which are skipped, and why.
+//
+// A session waiting on a permission prompt is skipped rather than sent to.
+// Typed into a prompt dialog, the text would be read as the answer — and an
+// agent asking something is one that should be looked at by a person first.
+
+// Broadcast dialog steps.
+const (
+	broadcastPick    = iota // Choosing the sessions
+	broadcastMessage        // Writing the prompt
+	broadcastPreview        // Dry run: what would happen
+	broadcastSending        // Waiting for the sends
+	broadcastResults        // What did happen
+)
+
+// broadcastTarget is one selected session and what the broadcast does with
+// it: sends to window, or skips it for skip.
+type broadcastTarget struct {
+	inst     *session.Instance
+	window   int
+	activity session.SessionActivity // As last polled, for the prompt history
+	skip     string
+	err      error // Set once sent, if sending failed
+}
+
+// broadcastDoneMsg carries the sent broadcast back to the update loop.
+type broadcastDoneMsg struct {
+	targets []broadcastTarget
+}
+
+// planBroadcast decides what happens to each selected session, in list
+// order.
+func planBroadcast(instances []*session.Instance, selected map[string]bool, activity map[string]session.SessionActivity, mainWindow map[string]int) []broadcastTarget {
+	var plan []broadcastTarget
+	for _, inst := range instances {
+		if !selected[inst.ID] {
+			continue
+		}
+		// Not polled yet: asked of tmux when sending
+		target := broadcastTarget{inst: inst, window: -1, activity: activity[inst.ID]}
+		if window, ok := mainWindow[inst.ID]; ok {
+			target.window = window
+		}
+		switch {
+		case inst.Status != session.StatusRunning:
+			target.skip = "not running"
+		case activity[inst.ID] == session.ActivityWaiting:
+			target.skip = "waiting on a prompt"
+		}
+		plan = append(plan, target)
+	}
+	return plan
+}
+
+// sendBroadcast sends text to every target the plan does not skip, and adds
+// what was sent to history. Blocks while it is typed, so call it off the UI
+// thread.
+func sendBroadcast(plan []broadcastTarget, text string, history *session.PromptHistory) []broadcastTarget {
+	sent := append([]broadcastTarget(nil), plan...)
+	for i := range sent {
+		if sent[i].skip != "" {
+			continue
+		}
+		if sent[i].window < 0 {
+			sent[i].window = sent[i].inst.GetMainWindowIndex()
+		}
+		sent[i].err = sent[i].inst.SendPromptToWindow(sent[i].window, text)
+		if sent[i].err == nil {
+			history.Append(sent[i].inst.SentPrompt(sent[i].window, text, session.SentFromBroadcast, sent[i].activity))
+		}
+	}
+	return sent
+}
+
+// openBroadcast opens the broadcast dialog with a first guess at the
+// sessions: what the search filter shows, else the group under the cursor,
+// else the selected session.
+func (m *Model) openBroadcast() {
+	m.broadcastSelected = make(map[string]bool)
+	m.broadcastCursor = 0
+	m.broadcastGroup = -1
+	m.broadcastPlan = nil
+	switch {
+	case m.searchActive && m.searchQuery != "":
+		for _, inst := range m.getFilteredInstances() {
+			m.broadcastSelected[inst.ID] = true
+		}
+	case m.cursorGroup() != nil:
+		m.selectBroadcastGroup(m.cursorGroup().ID)
+	default:
+		if inst := m.getSelectedInstance(); inst != nil {
+			m.broadcastSelected[inst.ID] = true
+		}
+	}
+	m.promptInput.SetValue("")
+	m.promptInput.Blur()
+	m.broadcastStep = broadcastPick
+	m.state = stateBroadcast
+}
+
+// cursorGroup returns the group whose header the cursor is on, if it is on
+// one.
+func (m *Model) cursorGroup() *session.Group {
+	if len(m.groups) == 0 {
+		return nil
+	}
+	m.buildVisibleItems()
+	if m.cursor < 0 || m.cursor >= len(m.visibleItems) || !m.visibleItems[m.cursor].isGroup {
+		return nil
+	}
+	return m.visibleItems[m.cursor].group
+}
+
+// selectBroadcastGroup selects exactly the sessions of a group.
+func (m *Model) selectBroadcastGroup(groupID string) {
+	m.broadcastSelected = make(map[string]bool)
+	for _, inst := range m.instances {
+		if inst.GroupID == groupID {
+			m.broadcastSelected[inst.ID] = true
+		}
+	}
+}
+
+// broadcastCount is how many sessions are selected.
+func (m Model) broadcastCount() int {
+	count := 0
+	for _, selected := range m.broadcastSelected {
+		if selected {
+			count++
+		}
+	}
+	return count
+}
+
+// handleBroadcastKeys handles keyboard input in the broadcast dialog.
+func (m Model) handleBroadcastKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch m.broadcastStep {
+	case broadcastPick:
+		switch key {
+		case "esc":
+			m.state = stateList
+		case "up", "k":
+			if m.broadcastCursor > 0 {
+				m.broadcastCursor--
+			}
+		case "down", "j":
+			if m.broadcastCursor < len(m.instances)-1 {
+				m.broadcastCursor++
+			}
+		case " ", "x":
+			if m.broadcastCursor < len(m.instances) {
+				id := m.instances[m.broadcastCursor].ID
+				m.broadcastSelected[id] = !m.broadcastSelected[id]
+			}
+		case "a":
+			// Every running session; again for none
+			running := make(map[string]bool)
+			for _, inst := range m.instances {
+				if inst.Status == session.StatusRunning {
+					running[inst.ID] = true
+				}
+			}
+			if m.broadcastCount() == len(running) {
+				running = make(map[string]bool)
+			}
+			m.broadcastSelected = running
+		case "f":
+			m.broadcastSelected = make(map[string]bool)
+			for _, inst := range m.instances {
+				if inst.Favorite {
+					m.broadcastSelected[inst.ID] = true
+				}
+			}
+		case "g":
+			// Cycle through the groups
+			if len(m.groups) > 0 {
+				m.broadcastGroup = (m.broadcastGroup + 1) % len(m.groups)
+				m.selectBroadcastGroup(m.groups[m.broadcastGroup].ID)
+			}
+		case "/":
+			if m.searchActive && m.searchQuery != "" {
+				m.broadcastSelected = make(map[string]bool)
+				for _, inst := range m.getFilteredInstances() {
+					m.broadcastSelected[inst.ID] = true
+				}
+			}
+		case "enter":
+			if m.broadcastCount() > 0 {
+				m.broadcastStep = broadcastMessage
+				m.promptInput.Focus()
+			}
+		}
+		return m, nil
+
+	case broadcastMessage:
+		switch key {
+		case "esc":
+			m.broadcastStep = broadcastPick
+			m.promptInput.Blur()
+			return m, nil
+		case "ctrl+d":
+			if m.promptInput.Value() != "" {
+				m.broadcastPlan = planBroadcast(m.instances, m.broadcastSelected, m.activityState, m.mainWindowIndex)
+				m.broadcastStep = broadcastPreview
+			}
+			return m, nil
+		case "ctrl+s", "ctrl+enter":
+			if m.promptInput.Value() != "" {
+				m.broadcastPlan = planBroadcast(m.instances, m.broadcastSelected, m.activityState, m.mainWindowIndex)
+				return m, m.broadcastCmd()
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
+
+	case broadcastPreview:
+		switch key {
+		case "esc":
+			m.broadcastStep = broadcastMessage
+		case "enter", "ctrl+s":
+			// Planned again: a session may have started waiting meanwhile
+			m.broadcastPlan = planBroadcast(m.instances, m.broadcastSelected, m.activityState, m.mainWindowIndex)
+			return m, m.broadcastCmd()
+		}
+		return m, nil
+
+	case broadcastResults:
+		if key == "esc" || key == "enter" || key == "q" {
+			m.state = stateList
+			m.broadcastPlan = nil
+		}
+	}
+	return m, nil
+}
+
+// broadcastCmd sends the prompt as planned, off the UI thread.
+func (m *Model) broadcastCmd() tea.Cmd {
+	m.broadcastStep = broadcastSending
+	plan := m.broadcastPlan
+	text := m.promptInput.Value()
+	history := m.promptLog
+	return func() tea.Msg {
+		return broadcastDoneMsg{targets: sendBroadcast(plan, text, history)}
+	}
+}
+
+// broadcastSummary counts a broadcast's outcome.
+func broadcastSummary(targets []broadcastTarget, sent bool) string {
+	var ok, failed, skipped int
+	for _, t := range targets {
+		switch {
+		case t.skip != "":
+			skipped++
+		case t.err != nil:
+			failed++
+		default:
+			ok++
+		}
+	}
+	verb := "will send"
+	if sent {
+		verb = "sent"
+	}
+	summary := fmt.Sprintf("%d %s", ok, verb)
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return summary
+}
+
+// broadcastGroupName names the group "g" last selected, for the dialog.
+func (m Model) broadcastGroupName() string {
+	if m.broadcastGroup < 0 || m.broadcastGroup >= len(m.groups) {
+		return ""
+	}
+	return m.groups[m.broadcastGroup].Name
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Only selected sessions are planned, in list order; stopped ones and ones
+// waiting on a prompt are skipped with the reason, and the rest go to their
+// main agent's window.
+func TestPlanBroadcastSkipsWaitingAndStopped(t *testing.T) {
+	instances := []*session.Instance{
+		{ID: "a", Name: "api", Status: session.StatusRunning},
+		{ID: "b", Name: "web", Status: session.StatusRunning},
+		{ID: "c", Name: "docs", Status: session.StatusStopped},
+		{ID: "d", Name: "infra", Status: session.StatusRunning},
+		{ID: "e", Name: "new", Status: session.StatusRunning},
+	}
+	selected := map[string]bool{"a": true, "b": true, "c": true, "e": true}
+	activity := map[string]session.SessionActivity{"a": session.ActivityBusy, "b": session.ActivityWaiting}
+	mainWindow := map[string]int{"a": 1, "b": 0}
+
+	plan := planBroadcast(instances, selected, activity, mainWindow)
+	want := []struct {
+		id     string
+		window int
+		skip   string
+	}{
+		{"a", 1, ""},
+		{"b", 0, "waiting on a prompt"},
+		{"c", -1, "not running"},
+		{"e", -1, ""},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("planned %d targets, want %d", len(plan), len(want))
+	}
+	for i, w := range want {
+		if plan[i].inst.ID != w.id || plan[i].window != w.window || plan[i].skip != w.skip {
+			t.Errorf("target %d = {%s %d %q}, want %+v", i, plan[i].inst.ID, plan[i].window, plan[i].skip, w)
+		}
+	}
+	if got := broadcastSummary(plan, false); got != "2 will send, 2 skipped" {
+		t.Errorf("summary = %q", got)
+	}
+}
@@ -0,0 +1,623 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Comparing agents on one prompt.
+//
+// The setup takes a name, the agents and the prompt, and starts from the
+// repository of the selected session. The worktrees and sessions are made
+// and started on the spot; waiting for each agent to be ready and sending it
+// the prompt happens off the UI thread, all agents at once.
+//
+// The comparison view shows one comparison at a time: each agent's session,
+// whether it is still working, how long it took and how much it changed.
+// Two runs can be read side by side, and keeping one as the winner deletes
+// the others' sessions, worktrees and branches. A run finishes on its first
+// busy-to-idle after it was sent the prompt, seen by the list's poll or the
+// attach watcher.
+
+const (
+	compareList = iota
+	compareSetup
+	compareSideBySide
+	compareConfirmWinner
+	compareConfirmDelete
+)
+
+// compareSentMsg reports the prompt sent, or not, to a comparison's runs.
+type compareSentMsg struct {
+	comparisonID string
+	sent         map[string]time.Time // By session ID
+	errs         map[string]error
+}
+
+// compareStatsMsg carries the diff stats of a comparison's runs.
+type compareStatsMsg struct {
+	comparisonID string
+	stats        map[string]session.DiffSummary // By session ID
+	errs         map[string]string
+}
+
+// compareAgents are the agents a comparison can run: those started by
+// their own command.
+func compareAgents() []session.AgentType {
+	var agents []session.AgentType
+	for _, a := range agentTypes {
+		if a != session.AgentCustom {
+			agents = append(agents, a)
+		}
+	}
+	return agents
+}
+
+// openCompare opens the comparison view on the newest comparison, or the
+// setup when there is none.
+func (m *Model) openCompare() tea.Cmd {
+	m.compareErr = ""
+	m.compareMarked = nil
+	m.state = stateCompare
+	if m.comparisonsLoadErr == nil && len(m.comparisons) == 0 {
+		return m.setupCompare()
+	}
+	m.compareStep = compareList
+	m.compareIndex = max(len(m.comparisons)-1, 0)
+	m.compareCursor = 0
+	return m.compareStatsCmd()
+}
+
+// setupCompare opens the setup for a new comparison in the selected
+// session's repository.
+func (m *Model) setupCompare() tea.Cmd {
+	m.compareErr = ""
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		m.compareErr = "Select a session in the repository to compare in"
+		m.compareStep = compareList
+		return nil
+	}
+	m.compareDir = inst.Path
+	m.compareName = textinput.New()
+	m.compareName.CharLimit = 60
+	m.compareName.Width = 40
+	m.compareName.Prompt = ""
+	m.compareName.SetValue("compare-" + time.Now().Format("0102-1504"))
+	m.compareChosen = map[session.AgentType]bool{}
+	m.compareAgentCursor = 0
+	m.compareYolo = false
+	m.compareFocus = 0
+	m.promptInput.SetValue("")
+	m.promptInput.Blur()
+	m.compareStep = compareSetup
+	return m.compareName.Focus()
+}
+
+// currentComparison is the comparison the view shows, or nil.
+func (m Model) currentComparison() *session.Comparison {
+	if m.compareIndex < len(m.comparisons) {
+		return m.comparisons[m.compareIndex]
+	}
+	return nil
+}
+
+// saveComparisons writes the comparisons, reporting a failure in the view.
+func (m *Model) saveComparisons() {
+	if m.storage == nil || m.comparisonsLoadErr != nil {
+		return
+	}
+	if err := session.SaveComparisons(m.storage.ComparisonsPath(), m.comparisons); err != nil {
+		m.compareErr = err.Error()
+	}
+}
+
+// markComparisonsFinished records runs that events show finishing, in
+// every undecided comparison. Reports whether any did.
+func markComparisonsFinished(comparisons []*session.Comparison, events []session.ActivityEvent) bool {
+	changed := false
+	for _, c := range comparisons {
+		if !c.Decided() && c.MarkFinished(events) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// compareCmd records finished runs after a poll, and refreshes the view's
+// diff stats when one of them finished.
+func (m *Model) compareCmd(events []session.ActivityEvent) tea.Cmd {
+	if m.comparisonsLoadErr != nil || !markComparisonsFinished(m.comparisons, events) {
+		return nil
+	}
+	m.saveComparisons()
+	if m.state == stateCompare {
+		return m.compareStatsCmd()
+	}
+	return nil
+}
+
+// compareStatsCmd counts the changes of the shown comparison's runs, off the
+// UI thread.
+func (m Model) compareStatsCmd() tea.Cmd {
+	c := m.currentComparison()
+	if c == nil {
+		return nil
+	}
+	id, base, runs := c.ID, c.Base, append([]session.ComparisonRun(nil), c.Runs...)
+	return func() tea.Msg {
+		msg := compareStatsMsg{comparisonID: id, stats: map[string]session.DiffSummary{}, errs: map[string]string{}}
+		for _, run := range runs {
+			sum, err := session.SummarizeDiff(run.Worktree, base)
+			if err != nil {
+				msg.errs[run.SessionID] = err.Error()
+				continue
+			}
+			msg.stats[run.SessionID] = sum
+		}
+		return msg
+	}
+}
+
+// sendComparePrompt waits for each session's agent and sends it the
+// comparison's prompt, all at once, off the UI thread.
+func sendComparePrompt(c *session.Comparison, instances []*session.Instance, history *session.PromptHistory) tea.Cmd {
+	id, prompt := c.ID, c.Prompt
+	return func() tea.Msg {
+		msg := compareSentMsg{comparisonID: id, sent: map[string]time.Time{}, errs: map[string]error{}}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, inst := range instances {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := session.SendFirstPrompt(inst, inst.GetMainWindowIndex(), prompt, session.SentFromCompare, history)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					msg.errs[inst.ID] = err
+				} else {
+					msg.sent[inst.ID] = time.Now()
+				}
+			}()
+		}
+		wg.Wait()
+		return msg
+	}
+}
+
+// applyCompareSent records which runs were sent the prompt, and why the
+// others were not.
+func applyCompareSent(comparisons []*session.Comparison, msg compareSentMsg) {
+	for _, c := range comparisons {
+		if c.ID != msg.comparisonID {
+			continue
+		}
+		for id, at := range msg.sent {
+			if run := c.Run(id); run != nil {
+				run.SentAt, run.FinishedAt, run.Error = at, time.Time{}, ""
+			}
+		}
+		for id, err := range msg.errs {
+			if run := c.Run(id); run != nil {
+				run.Error = err.Error()
+			}
+		}
+	}
+}
+
+// startComparison makes the worktrees and sessions of the setup's
+// comparison, starts them, and sends them the prompt. A session that cannot
+// be made is left out, with its worktree removed; one that cannot start
+// keeps its run, with the reason.
+func (m *Model) startComparison() tea.Cmd {
+	prompt := strings.TrimSpace(m.promptInput.Value())
+	var agents []session.AgentType
+	for _, a := range compareAgents() {
+		if m.compareChosen[a] {
+			agents = append(agents, a)
+		}
+	}
+	switch {
+	case prompt == "":
+		m.compareErr = "The prompt is empty"
+		return nil
+	case len(agents) < 2:
+		m.compareErr = "Choose at least two agents"
+		return nil
+	}
+	for _, a := range agents {
+		if err := session.CheckAgentCommand(&session.Instance{Agent: a}); err != nil {
+			m.compareErr = fmt.Sprintf("%s: %s", a, err)
+			return nil
+		}
+	}
+	c, err := session.NewComparison(strings.TrimSpace(m.compareName.Value()), prompt, m.compareDir, time.Now())
+	if err != nil {
+		m.compareErr = err.Error()
+		return nil
+	}
+
+	var started []*session.Instance
+	var errs []error
+	for _, agent := range agents {
+		inst, err := c.AddRun(m.storage.WorktreesDir(m.worktreeRoot), agent, m.compareYolo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", agent, err))
+			continue
+		}
+		run := c.Run(inst.ID)
+		if err := m.storage.AddInstance(inst); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", agent, err), c.RemoveRunCheckout(*run))
+			c.Runs = c.Runs[:len(c.Runs)-1]
+			continue
+		}
+		m.instances = append(m.instances, inst)
+		if err := inst.Start(); err != nil {
+			run.Error = err.Error()
+			continue
+		}
+		m.storage.UpdateInstance(inst)
+		started = append(started, inst)
+	}
+	if len(c.Runs) == 0 {
+		m.compareErr = errors.Join(errs...).Error()
+		return nil
+	}
+
+	m.comparisons = append(m.comparisons, c)
+	m.saveComparisons()
+	m.compareIndex = len(m.comparisons) - 1
+	m.compareCursor = 0
+	m.compareMarked = nil
+	m.compareStats = nil
+	m.compareStep = compareList
+	m.promptInput.Blur()
+	if len(errs) > 0 {
+		m.compareErr = errors.Join(errs...).Error()
+	}
+	return tea.Batch(sendComparePrompt(c, started, m.promptLog), m.compareStatsCmd())
+}
+
+// handleCompareKeys handles keyboard input in the comparison view.
+func (m Model) handleCompareKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.compareStep {
+	case compareSetup:
+		return m.handleCompareSetupKeys(msg)
+	case compareSideBySide:
+		return m.handleSideBySideKeys(msg)
+	case compareConfirmWinner, compareConfirmDelete:
+		return m.handleCompareConfirmKeys(msg)
+	}
+
+	c := m.currentComparison()
+	switch msg.String() {
+	case "esc", "q", "C":
+		m.state = stateList
+	case "n":
+		if m.comparisonsLoadErr == nil {
+			return m, m.setupCompare()
+		}
+	case "left", "h", "[":
+		if m.compareIndex > 0 {
+			m.compareIndex--
+			m.compareCursor, m.compareMarked, m.compareStats = 0, nil, nil
+			return m, m.compareStatsCmd()
+		}
+	case "right", "l", "]":
+		if m.compareIndex < len(m.comparisons)-1 {
+			m.compareIndex++
+			m.compareCursor, m.compareMarked, m.compareStats = 0, nil, nil
+			return m, m.compareStatsCmd()
+		}
+	}
+	if c == nil {
+		return m, nil
+	}
+
+	var run *session.ComparisonRun
+	if m.compareCursor < len(c.Runs) {
+		run = &c.Runs[m.compareCursor]
+	}
+	switch msg.String() {
+	case "up", "k":
+		if m.compareCursor > 0 {
+			m.compareCursor--
+		}
+	case "down", "j":
+		if m.compareCursor < len(c.Runs)-1 {
+			m.compareCursor++
+		}
+	case "g":
+		m.compareErr = ""
+		return m, m.compareStatsCmd()
+	case " ":
+		// Mark for side by side; a third mark drops the oldest
+		if run != nil {
+			for i, id := range m.compareMarked {
+				if id == run.SessionID {
+					m.compareMarked = append(m.compareMarked[:i:i], m.compareMarked[i+1:]...)
+					return m, nil
+				}
+			}
+			m.compareMarked = append(m.compareMarked, run.SessionID)
+			if len(m.compareMarked) > 2 {
+				m.compareMarked = m.compareMarked[1:]
+			}
+		}
+	case "v":
+		m.openSideBySide(c)
+	case "d", "delete":
+		m.compareStep = compareConfirmDelete
+	case "enter":
+		// To the run's session in the list
+		if run != nil {
+			if inst := findInstance(m.instances, run.SessionID); inst != nil {
+				m.selectInstance(inst.ID)
+				m.state = stateList
+			}
+		}
+	case "s":
+		// Again, to a run that was not sent the prompt
+		if run != nil && run.SentAt.IsZero() && !c.Decided() {
+			inst := findInstance(m.instances, run.SessionID)
+			if inst == nil || !inst.IsAlive() {
+				m.compareErr = "That session is gone or not running"
+				return m, nil
+			}
+			run.Error = ""
+			m.compareErr = ""
+			return m, sendComparePrompt(c, []*session.Instance{inst}, m.promptLog)
+		}
+	case "w":
+		if run != nil && !c.Decided() {
+			m.compareStep = compareConfirmWinner
+		}
+	}
+	return m, nil
+}
+
+// selectInstance moves the list's cursor to a session.
+func (m *Model) selectInstance(id string) {
+	if len(m.groups) > 0 {
+		m.buildVisibleItems()
+		for i, item := range m.visibleItems {
+			if !item.isGroup && item.instance != nil && item.instance.ID == id {
+				m.cursor = i
+				return
+			}
+		}
+		return
+	}
+	for i, inst := range m.instances {
+		if inst.ID == id {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// handleCompareSetupKeys handles keyboard input in the setup: the name, the
+// agents, and the prompt.
+func (m Model) handleCompareSetupKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch key {
+	case "esc":
+		m.promptInput.Blur()
+		if len(m.comparisons) == 0 {
+			m.state = stateList
+		}
+		m.compareStep = compareList
+		return m, nil
+	case "ctrl+s":
+		return m, m.startComparison()
+	case "tab", "shift+tab":
+		delta := 1
+		if key == "shift+tab" {
+			delta = 2
+		}
+		m.compareFocus = (m.compareFocus + delta) % 3
+		m.compareName.Blur()
+		m.promptInput.Blur()
+		switch m.compareFocus {
+		case 0:
+			return m, m.compareName.Focus()
+		case 2:
+			return m, m.promptInput.Focus()
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	switch m.compareFocus {
+	case 0:
+		m.compareName, cmd = m.compareName.Update(msg)
+	case 2:
+		m.promptInput, cmd = m.promptInput.Update(msg)
+	default:
+		agents := compareAgents()
+		switch key {
+		case "up", "k":
+			if m.compareAgentCursor > 0 {
+				m.compareAgentCursor--
+			}
+		case "down", "j":
+			if m.compareAgentCursor < len(agents)-1 {
+				m.compareAgentCursor++
+			}
+		case " ", "x":
+			a := agents[m.compareAgentCursor]
+			m.compareChosen[a] = !m.compareChosen[a]
+		case "y":
+			m.compareYolo = !m.compareYolo
+		}
+	}
+	return m, cmd
+}
+
+// handleCompareConfirmKeys confirms keeping the winner, or deleting the
+// comparison.
+func (m Model) handleCompareConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.currentComparison()
+	switch msg.String() {
+	case "y", "Y":
+		if c == nil {
+			break
+		}
+		var err error
+		if m.compareStep == compareConfirmWinner && m.compareCursor < len(c.Runs) {
+			err = m.keepWinner(c, c.Runs[m.compareCursor].SessionID)
+		} else if m.compareStep == compareConfirmDelete {
+			err = m.deleteComparison(c)
+		}
+		if err != nil {
+			m.compareErr = err.Error()
+		}
+		m.compareStep = compareList
+	case "n", "N", "esc":
+		m.compareStep = compareList
+	}
+	return m, nil
+}
+
+// removeRun deletes a run's session, worktree and branch.
+func (m *Model) removeRun(c *session.Comparison, run session.ComparisonRun) error {
+	var errs []error
+	if findInstance(m.instances, run.SessionID) != nil {
+		if err := m.storage.RemoveInstance(run.SessionID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", run.Agent, err))
+		}
+	}
+	errs = append(errs, c.RemoveRunCheckout(run))
+	return errors.Join(errs...)
+}
+
+// keepWinner keeps one run and deletes the rest. The comparison is decided
+// only once every other run is gone: until then it keeps them all, for
+// keeping the winner again, or deleting the comparison, to clean up what is
+// left.
+func (m *Model) keepWinner(c *session.Comparison, winner string) error {
+	var errs []error
+	for _, run := range c.Runs {
+		if run.SessionID != winner {
+			errs = append(errs, m.removeRun(c, run))
+		}
+	}
+	err := errors.Join(errs...)
+	if err == nil {
+		c.Winner = winner
+	}
+	m.compareMarked = nil
+	m.reloadAfterCompare()
+	m.saveComparisons()
+	if err != nil {
+		return fmt.Errorf("%w; not decided yet: keep the winner again to retry", err)
+	}
+	return nil
+}
+
+// deleteComparison forgets a comparison. An undecided one has its runs
+// deleted with it; a decided one's winner is left alone.
+func (m *Model) deleteComparison(c *session.Comparison) error {
+	var errs []error
+	if !c.Decided() {
+		for _, run := range c.Runs {
+			errs = append(errs, m.removeRun(c, run))
+		}
+		m.reloadAfterCompare()
+	}
+	m.comparisons = append(m.comparisons[:m.compareIndex:m.compareIndex], m.comparisons[m.compareIndex+1:]...)
+	if m.compareIndex >= len(m.comparisons) && m.compareIndex > 0 {
+		m.compareIndex--
+	}
+	m.compareCursor, m.compareMarked, m.compareStats = 0, nil, nil
+	m.saveComparisons()
+	return errors.Join(errs...)
+}
+
+// reloadAfterCompare reloads the sessions after some were removed, as
+// deleting one from the list does.
+func (m *Model) reloadAfterCompare() {
+	instances, err := m.storage.Load()
+	if err != nil {
+		m.compareErr = fmt.Sprintf("failed to reload instances: %s", err)
+		return
+	}
+	m.instances = instances
+	if m.cursor >= len(m.instances) && m.cursor > 0 {
+		m.cursor = len(m.instances) - 1
+	}
+}
+
+// openSideBySide shows the diffs of the two marked runs, or of the only two
+// runs, next to each other.
+func (m *Model) openSideBySide(c *session.Comparison) {
+	ids := m.compareMarked
+	if len(ids) != 2 && len(c.Runs) == 2 {
+		ids = []string{c.Runs[0].SessionID, c.Runs[1].SessionID}
+	}
+	if len(ids) != 2 {
+		m.compareErr = "Mark two runs with space first"
+		return
+	}
+	for i, id := range ids {
+		inst := findInstance(m.instances, id)
+		if inst == nil {
+			m.compareErr = "A marked run's session is gone"
+			return
+		}
+		m.compareTitles[i] = inst.Name
+		diff := inst.GetSessionDiff()
+		switch {
+		case diff.Error != nil:
+			m.compareDiffs[i] = []string{diff.Error.Error()}
+		case strings.TrimSpace(diff.Content) == "":
+			m.compareDiffs[i] = []string{"No changes"}
+		default:
+			m.compareDiffs[i] = strings.Split(strings.TrimRight(diff.Content, "\n"), "\n")
+		}
+	}
+	m.compareScroll = 0
+	m.compareErr = ""
+	m.compareStep = compareSideBySide
+}
+
+// handleSideBySideKeys scrolls the two diffs together.
+func (m Model) handleSideBySideKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := m.sideBySideHeight()
+	last := max(max(len(m.compareDiffs[0]), len(m.compareDiffs[1]))-page, 0)
+	switch msg.String() {
+	case "esc", "q", "v":
+		m.compareStep = compareList
+	case "up", "k":
+		m.compareScroll--
+	case "down", "j":
+		m.compareScroll++
+	case "pgup", "ctrl+u":
+		m.compareScroll -= page
+	case "pgdown", "ctrl+d", " ":
+		m.compareScroll += page
+	case "home", "g":
+		m.compareScroll = 0
+	case "end", "G":
+		m.compareScroll = last
+	}
+	m.compareScroll = min(max(m.compareScroll, 0), last)
+	return m, nil
+}
+
+// compareWhileAttached is markComparisonsFinished for the attach watcher, which
+// saves what it records.
+func compareWhileAttached(storage *session.Storage, comparisons []*session.Comparison, events []session.ActivityEvent) error {
+	if !markComparisonsFinished(comparisons, events) || storage == nil {
+		return nil
+	}
+	return session.SaveComparisons(storage.ComparisonsPath(), comparisons)
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Nothing is made for a comparison without a prompt, or with fewer than two
+// agents to compare.
+func TestCompareSetupNeedsAPromptAndTwoAgents(t *testing.T) {
+	m := newTestModel()
+	m.instances = []*session.Instance{{ID: "a", Name: "a", Path: t.TempDir()}}
+	m.promptInput = textarea.New()
+	m.state = stateCompare
+	m.setupCompare()
+	if m.compareStep != compareSetup {
+		t.Fatalf("step = %d, want the setup", m.compareStep)
+	}
+
+	m.compareChosen[session.AgentClaude] = true
+	m.compareChosen[session.AgentCodex] = true
+	if m.startComparison(); m.compareErr != "The prompt is empty" {
+		t.Errorf("empty prompt: %q", m.compareErr)
+	}
+	m.promptInput.SetValue("fix the login")
+	m.compareChosen[session.AgentCodex] = false
+	if m.startComparison(); m.compareErr != "Choose at least two agents" {
+		t.Errorf("one agent: %q", m.compareErr)
+	}
+	if len(m.comparisons) != 0 || m.compareStep != compareSetup {
+		t.Errorf("comparisons = %d, step = %d", len(m.comparisons), m.compareStep)
+	}
+}
+
+// The prompt's results land in their runs, and a decided comparison no
+// longer records finishes.
+func TestCompareSentAndFinished(t *testing.T) {
+	sent := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	open := &session.Comparison{ID: "open", Runs: []session.ComparisonRun{{SessionID: "a"}, {SessionID: "b", Error: "old"}}}
+	decided := &session.Comparison{ID: "decided", Winner: "c", Runs: []session.ComparisonRun{{SessionID: "c", SentAt: sent}}}
+	comparisons := []*session.Comparison{open, decided}
+
+	applyCompareSent(comparisons, compareSentMsg{
+		comparisonID: "open",
+		sent:         map[string]time.Time{"b": sent},
+		errs:         map[string]error{"a": errors.New("a is asking something")},
+	})
+	if open.Runs[0].Error == "" || !open.Runs[0].SentAt.IsZero() {
+		t.Errorf("failed run = %+v", open.Runs[0])
+	}
+	if open.Runs[1].Error != "" || !open.Runs[1].SentAt.Equal(sent) {
+		t.Errorf("sent run = %+v", open.Runs[1])
+	}
+
+	events := []session.ActivityEvent{
+		{Time: sent.Add(time.Minute), SessionID: "b", Kind: session.EventActivity, From: session.ActivityBusy, To: session.ActivityIdle},
+		{Time: sent.Add(time.Minute), SessionID: "c", Kind: session.EventActivity, From: session.ActivityBusy, To: session.ActivityIdle},
+	}
+	if !markComparisonsFinished(comparisons, events) {
+		t.Fatal("nothing finished")
+	}
+	if open.Runs[1].FinishedAt.IsZero() || !decided.Runs[0].FinishedAt.IsZero() {
+		t.Errorf("finished: open %v, decided %v", open.Runs[1].FinishedAt, decided.Runs[0].FinishedAt)
+	}
+}
+
+// The two diffs scroll together, and no further than the longer one's end.
+func TestSideBySideScrollsTogether(t *testing.T) {
+	m := newTestModel()
+	m.height = 20
+	m.compareStep = compareSideBySide
+	m.compareDiffs = [2][]string{make([]string, 30), make([]string, 5)}
+
+	press := func(key string) {
+		var msg tea.KeyMsg
+		switch key {
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "up":
+			msg = tea.KeyMsg{Type: tea.KeyUp}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		model, _ := m.handleSideBySideKeys(msg)
+		next := model.(Model)
+		m = &next
+	}
+	press("down")
+	press("down")
+	if m.compareScroll != 2 {
+		t.Errorf("scroll = %d, want 2", m.compareScroll)
+	}
+	press("G")
+	if want := 30 - m.sideBySideHeight(); m.compareScroll != want {
+		t.Errorf("bottom = %d, want %d", m.compareScroll, want)
+	}
+	press("down")
+	if want := 30 - m.sideBySideHeight(); m.compareScroll != want {
+		t.Errorf("past the bottom = %d, want %d", m.compareScroll, want)
+	}
+	press("q")
+	if m.compareStep != compareList {
+		t.Errorf("step = %d, want the list", m.compareStep)
+	}
+}
@@ -23,7 +23,8 @@ type DiffMode int
 
 const (
 	DiffModeSession DiffMode = iota // Changes since session start
-	DiffModeFull                    // All uncommitted changes
+	DiffModeFull                    // Uncommitted changes not yet staged
+	DiffModeStaged                  // Changes staged for the next commit
 )
 
 // DiffPane manages the diff display with scrolling support
@@ -33,6 +34,21 @@ type DiffPane struct {
 	mode     DiffMode
 	width    int
 	height   int
+
+	files      []session.DiffFile // stats.Content split by file
+	shown      []int              // Indexes into files of those matching the filter
+	fileStarts []int              // Content line each shown file starts at
+	hunkStarts []int              // Content line each shown hunk starts at
+	fileCursor int                // Index into shown of the file being read
+	hunkCursor int                // Index into hunkStarts of the selected hunk, or -1 for the file
+	lineCursor int                // Index into the selected file's Lines of the selected line, or -1
+	lineRows   [][]int            // Content line each shown file's lines are on, from its start
+	collapsed  map[string]bool    // Folded files, by path
+	filter     string             // Glob or text the shown files' paths match
+	showFiles  bool               // File list beside the diff
+	layout     DiffLayout         // Unified, side by side, or by width
+
+	comments []session.ReviewComment // The session's review comments, shown under their lines
 }
 
 // NewDiffPane creates a new diff pane
@@ -40,13 +56,21 @@ func NewDiffPane() *DiffPane {
 	vp := viewport.New(0, 0)
 	vp.Style = lipgloss.NewStyle()
 	return &DiffPane{
-		viewport: vp,
-		mode:     DiffModeFull,
+		viewport:   vp,
+		mode:       DiffModeFull,
+		collapsed:  make(map[string]bool),
+		showFiles:  true,
+		hunkCursor: -1,
+		lineCursor: -1,
 	}
 }
 
 // SetSize updates the diff pane dimensions
 func (d *DiffPane) SetSize(width, height int) {
+	// Called on every render: the content only changes with the size
+	if width == d.width && height == d.height {
+		return
+	}
 	d.width = width
 	d.height = height
 	d.viewport.Width = width
@@ -58,24 +82,32 @@ func (d *DiffPane) SetSize(width, height int) {
 func (d *DiffPane) SetDiff(inst *session.Instance) {
 	if inst == nil {
 		d.stats = nil
+		d.comments = nil
 		d.updateContent()
 		return
 	}
+	d.comments = inst.ReviewComments
 
 	switch d.mode {
 	case DiffModeSession:
 		d.stats = inst.GetSessionDiff()
 	case DiffModeFull:
 		d.stats = inst.GetFullDiff()
+	case DiffModeStaged:
+		d.stats = inst.GetStagedDiff()
 	}
 	d.updateContent()
 }
 
-// ToggleMode switches between session diff and full diff
+// ToggleMode switches from session diff to full diff, to staged changes,
+// and back
 func (d *DiffPane) ToggleMode() {
-	if d.mode == DiffModeSession {
+	switch d.mode {
+	case DiffModeSession:
 		d.mode = DiffModeFull
-	} else {
+	case DiffModeFull:
+		d.mode = DiffModeStaged
+	default:
 		d.mode = DiffModeSession
 	}
 }
@@ -87,8 +119,11 @@ func (d *DiffPane) GetMode() DiffMode {
 
 // GetModeLabel returns a human-readable label for current mode
 func (d *DiffPane) GetModeLabel() string {
-	if d.mode == DiffModeSession {
+	switch d.mode {
+	case DiffModeSession:
 		return "Session"
+	case DiffModeStaged:
+		return "Staged"
 	}
 	return "Full"
 }
@@ -96,40 +131,51 @@ func (d *DiffPane) GetModeLabel() string {
 // ScrollUp scrolls the viewport up
 func (d *DiffPane) ScrollUp() {
 	d.viewport.LineUp(1)
+	d.syncFileCursor()
 }
 
 // ScrollDown scrolls the viewport down
 func (d *DiffPane) ScrollDown() {
 	d.viewport.LineDown(1)
+	d.syncFileCursor()
 }
 
 // PageUp scrolls the viewport up by a page
 func (d *DiffPane) PageUp() {
 	d.viewport.ViewUp()
+	d.syncFileCursor()
 }
 
 // PageDown scrolls the viewport down by a page
 func (d *DiffPane) PageDown() {
 	d.viewport.ViewDown()
+	d.syncFileCursor()
 }
 
 // GotoTop scrolls to the beginning of the content
 func (d *DiffPane) GotoTop() {
 	d.viewport.GotoTop()
+	d.syncFileCursor()
 }
 
 // GotoBottom scrolls to the end of the content
 func (d *DiffPane) GotoBottom() {
 	d.viewport.GotoBottom()
+	d.syncFileCursor()
 }
 
-// View renders the diff pane
+// View renders the diff pane, with the file list beside it
 func (d *DiffPane) View() string {
-	return d.viewport.View()
+	if width := d.sidebarWidth(); width > 0 {
+		return lipgloss.JoinHorizontal(lipgloss.Top, d.sidebarView(width), d.markCursor(d.viewport.View()))
+	}
+	return d.markCursor(d.viewport.View())
 }
 
 // updateContent refreshes the viewport content
 func (d *DiffPane) updateContent() {
+	d.files, d.shown, d.fileStarts, d.hunkStarts, d.lineRows = nil, nil, nil, nil, nil
+	d.viewport.Width = d.width
 	if d.stats == nil {
 		d.viewport.SetContent(dimStyle.Render("No diff available"))
 		return
@@ -145,40 +191,7 @@ func (d *DiffPane) updateContent() {
 		return
 	}
 
-	// Stats header - horizontal join like claude-squad
-	additions := diffAdditionStyle.Render(fmt.Sprintf("+%d", d.stats.Added))
-	deletions := diffDeletionStyle.Render(fmt.Sprintf("-%d", d.stats.Removed))
-	statsLine := " " + lipgloss.JoinHorizontal(lipgloss.Center, additions, "  ", deletions)
-
-	// Colorized diff content
-	diffContent := colorizeDiff(d.stats.Content)
-
-	// Join stats and diff vertically
-	d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, statsLine, "", diffContent))
-}
-
-// colorizeDiff applies syntax highlighting to diff content
-func colorizeDiff(diff string) string {
-	if diff == "" {
-		return ""
-	}
-
-	var result strings.Builder
-	lines := strings.Split(diff, "\n")
-
-	for _, line := range lines {
-		if len(line) == 0 {
-			result.WriteString("\n")
-			continue
-		}
-
-		// Add space padding for a more spacious look
-		coloredLine := " " + colorDiffLine(line) + " "
-		result.WriteString(coloredLine)
-		result.WriteString("\n")
-	}
-
-	return result.String()
+	d.renderFiles()
 }
 
 // colorDiffLine applies color to a single diff line
@@ -0,0 +1,391 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// The diff view by file.
+//
+// An agent's change easily spans thirty files, and one long stream is no
+// way to find the one you want. The diff is split by file: a list beside it
+// shows each file's status and counts, and the view jumps between files and
+// hunks, folds files out of the way and narrows to the paths matching a
+// filter. Folds are kept by path, so they survive the refresh while the
+// agent keeps working.
+
+// minSidebarDiffWidth is the narrowest diff pane the file list is shown in.
+const minSidebarDiffWidth = 60
+
+// maxSidebarWidth caps the file list's width.
+const maxSidebarWidth = 36
+
+// sidebarWidth is how wide the file list is, or 0 when it is not shown.
+func (d *DiffPane) sidebarWidth() int {
+	if !d.showFiles || len(d.files) == 0 || d.width < minSidebarDiffWidth {
+		return 0
+	}
+	return min(maxSidebarWidth, d.width/3)
+}
+
+// matchesFilter is whether a path is shown under the filter: a glob
+// matched against the whole path or its base name, dir/** for everything
+// under dir, or else text the path contains.
+func (d *DiffPane) matchesFilter(p string) bool {
+	if d.filter == "" {
+		return true
+	}
+	if dir, ok := strings.CutSuffix(d.filter, "/**"); ok {
+		return strings.HasPrefix(p, dir+"/")
+	}
+	if !strings.ContainsAny(d.filter, "*?[") {
+		return strings.Contains(p, d.filter)
+	}
+	if ok, _ := path.Match(d.filter, p); ok {
+		return true
+	}
+	ok, _ := path.Match(d.filter, path.Base(p))
+	return ok
+}
+
+// renderFiles splits the diff by file and renders the shown files, folded
+// ones as their first line, noting where each file and hunk starts.
+func (d *DiffPane) renderFiles() {
+	d.files = session.ParseDiff(d.stats.Content)
+	for i, f := range d.files {
+		if d.matchesFilter(f.Path) || (f.OldPath != "" && d.matchesFilter(f.OldPath)) {
+			d.shown = append(d.shown, i)
+		}
+	}
+	d.viewport.Width = d.width - d.sidebarWidth()
+
+	additions := diffAdditionStyle.Render(fmt.Sprintf("+%d", d.stats.Added))
+	deletions := diffDeletionStyle.Render(fmt.Sprintf("-%d", d.stats.Removed))
+	files := fmt.Sprintf("%d files", len(d.files))
+	if len(d.files) == 1 {
+		files = "1 file"
+	}
+	if d.filter != "" {
+		files = fmt.Sprintf("%d of %d files match %s", len(d.shown), len(d.files), d.filter)
+	}
+	if open := openComments(d.comments); open == 1 {
+		files += " · 1 review comment (^R)"
+	} else if open > 1 {
+		files += fmt.Sprintf(" · %d review comments (^R)", open)
+	}
+	lines := []string{" " + additions + "  " + deletions + "  " + dimStyle.Render(files), ""}
+
+	split := d.split()
+	for _, i := range d.shown {
+		f := d.files[i]
+		d.fileStarts = append(d.fileStarts, len(lines))
+		if d.collapsed[f.Path] {
+			lines = append(lines, " "+colorDiffLine(f.Lines[0])+" ",
+				" "+dimStyle.Render(fmt.Sprintf("⋯ %d lines folded", len(f.Lines)-1)))
+			d.lineRows = append(d.lineRows, []int{0})
+			continue
+		}
+		var rows []string
+		var lineRows []int
+		if split {
+			rows, lineRows = splitFileLines(f, d.viewport.Width)
+		} else {
+			rows, lineRows = unifiedFileLines(f)
+		}
+		rows, lineRows = d.withComments(f, rows, lineRows)
+		d.lineRows = append(d.lineRows, lineRows)
+		for _, h := range f.Hunks {
+			d.hunkStarts = append(d.hunkStarts, len(lines)+lineRows[h.Start])
+		}
+		lines = append(lines, rows...)
+	}
+	if len(d.shown) == 0 {
+		lines = append(lines, " "+dimStyle.Render("No file matches "+d.filter))
+	}
+	d.fileCursor = min(d.fileCursor, max(len(d.shown)-1, 0))
+	d.hunkCursor = min(d.hunkCursor, len(d.hunkStarts)-1)
+	if d.hunkCursor >= 0 {
+		// A hunk staged or discarded leaves the next one selected
+		d.fileCursor = d.fileOfRow(d.hunkStarts[d.hunkCursor])
+	}
+	d.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// unifiedFileLines renders a file's diff as git prints it, with the row each
+// of its lines is on.
+func unifiedFileLines(f session.DiffFile) (rows []string, lineRows []int) {
+	for _, line := range f.Lines {
+		lineRows = append(lineRows, len(rows))
+		if line == "" {
+			rows = append(rows, "")
+			continue
+		}
+		rows = append(rows, " "+colorDiffLine(line)+" ")
+	}
+	return rows, lineRows
+}
+
+// fileOfRow is the shown file a content line belongs to.
+func (d *DiffPane) fileOfRow(row int) int {
+	file := 0
+	for i, start := range d.fileStarts {
+		if start <= row {
+			file = i
+		}
+	}
+	return file
+}
+
+// syncFileCursor makes the file being read the one at the top of the view,
+// after scrolling, and selects the hunk there, if any.
+func (d *DiffPane) syncFileCursor() {
+	d.fileCursor = d.fileOfRow(d.viewport.YOffset)
+	d.hunkCursor = -1
+	d.lineCursor = -1
+	for i, start := range d.hunkStarts {
+		if start <= d.viewport.YOffset && d.fileOfRow(start) == d.fileCursor {
+			d.hunkCursor = i
+		}
+	}
+}
+
+// scrollToFile shows a shown file from its first line, selecting the whole
+// file.
+func (d *DiffPane) scrollToFile(i int) {
+	if i < 0 || i >= len(d.fileStarts) {
+		return
+	}
+	d.fileCursor = i
+	d.hunkCursor = -1
+	d.lineCursor = -1
+	d.viewport.SetYOffset(d.fileStarts[i])
+}
+
+// selectHunk selects a hunk and shows it from its header. Near the end of
+// the diff the view may stop short of it; it is selected all the same.
+func (d *DiffPane) selectHunk(i int) {
+	d.hunkCursor = i
+	d.lineCursor = -1
+	d.fileCursor = d.fileOfRow(d.hunkStarts[i])
+	d.viewport.SetYOffset(d.hunkStarts[i])
+}
+
+// NextFile jumps to the next file.
+func (d *DiffPane) NextFile() {
+	d.scrollToFile(d.fileCursor + 1)
+}
+
+// PrevFile jumps to the previous file, or the start of this one.
+func (d *DiffPane) PrevFile() {
+	if d.fileCursor < len(d.fileStarts) && d.viewport.YOffset > d.fileStarts[d.fileCursor] {
+		d.scrollToFile(d.fileCursor)
+		return
+	}
+	d.scrollToFile(d.fileCursor - 1)
+}
+
+// NextHunk selects the next hunk: after the selected one, or the first of
+// the file being read.
+func (d *DiffPane) NextHunk() {
+	if d.hunkCursor >= 0 {
+		if d.hunkCursor+1 < len(d.hunkStarts) {
+			d.selectHunk(d.hunkCursor + 1)
+		}
+		return
+	}
+	for i, start := range d.hunkStarts {
+		if d.fileCursor < len(d.fileStarts) && start > d.fileStarts[d.fileCursor] {
+			d.selectHunk(i)
+			return
+		}
+	}
+}
+
+// PrevHunk selects the previous hunk: before the selected one, or the last
+// of the files above.
+func (d *DiffPane) PrevHunk() {
+	if d.hunkCursor >= 0 {
+		if d.hunkCursor > 0 {
+			d.selectHunk(d.hunkCursor - 1)
+		}
+		return
+	}
+	for i := len(d.hunkStarts) - 1; i >= 0; i-- {
+		if d.fileCursor < len(d.fileStarts) && d.hunkStarts[i] < d.fileStarts[d.fileCursor] {
+			d.selectHunk(i)
+			return
+		}
+	}
+}
+
+// Selection is the file being read and the index among its hunks of the
+// selected one, or -1 when the whole file is selected.
+func (d *DiffPane) Selection() (file session.DiffFile, hunk int, ok bool) {
+	if d.fileCursor >= len(d.shown) {
+		return session.DiffFile{}, -1, false
+	}
+	file = d.files[d.shown[d.fileCursor]]
+	if d.hunkCursor < 0 || d.hunkCursor >= len(d.hunkStarts) || d.collapsed[file.Path] {
+		return file, -1, true
+	}
+	hunk = d.hunkCursor
+	for _, start := range d.hunkStarts {
+		if start < d.fileStarts[d.fileCursor] {
+			hunk--
+		}
+	}
+	if hunk < 0 || hunk >= len(file.Hunks) {
+		return file, -1, true
+	}
+	return file, hunk, true
+}
+
+// SelectionLabel describes the selection: a hunk of a file, or the file.
+func (d *DiffPane) SelectionLabel() string {
+	file, hunk, ok := d.Selection()
+	if !ok {
+		return ""
+	}
+	if hunk < 0 {
+		return "file " + file.Path
+	}
+	label := fmt.Sprintf("hunk %d/%d of %s", hunk+1, len(file.Hunks), file.Path)
+	if line := d.selectedLine(file, hunk); line >= 0 {
+		oldNo, newNo := file.LineNumbers()
+		if newNo[line] != 0 {
+			return fmt.Sprintf("line %d in %s", newNo[line], label)
+		}
+		return fmt.Sprintf("removed line %d in %s", oldNo[line], label)
+	}
+	return label
+}
+
+// ToggleFold folds the file being read, or unfolds it.
+func (d *DiffPane) ToggleFold() {
+	if d.fileCursor >= len(d.shown) {
+		return
+	}
+	p := d.files[d.shown[d.fileCursor]].Path
+	d.collapsed[p] = !d.collapsed[p]
+	d.rerender()
+}
+
+// ToggleFoldAll folds every shown file, or unfolds them all if they are.
+func (d *DiffPane) ToggleFoldAll() {
+	fold := false
+	for _, i := range d.shown {
+		if !d.collapsed[d.files[i].Path] {
+			fold = true
+		}
+	}
+	for _, i := range d.shown {
+		d.collapsed[d.files[i].Path] = fold
+	}
+	d.rerender()
+}
+
+// rerender renders again after a fold, keeping the file being read at the
+// top.
+func (d *DiffPane) rerender() {
+	cursor := d.fileCursor
+	d.updateContent()
+	d.scrollToFile(cursor)
+}
+
+// ToggleFiles shows or hides the file list.
+func (d *DiffPane) ToggleFiles() {
+	d.showFiles = !d.showFiles
+	d.rerender()
+}
+
+// Filter is the glob or text the shown files match, or "".
+func (d *DiffPane) Filter() string {
+	return d.filter
+}
+
+// SetFilter shows only the files matching a glob or text; "" shows all.
+func (d *DiffPane) SetFilter(filter string) {
+	d.filter = strings.TrimSpace(filter)
+	d.fileCursor = 0
+	d.hunkCursor = -1
+	d.lineCursor = -1
+	d.updateContent()
+	d.viewport.GotoTop()
+}
+
+// fileStatusStyle colours a file's status letter.
+func fileStatusStyle(status session.FileStatus) lipgloss.Style {
+	switch status {
+	case session.FileNew:
+		return diffAdditionStyle
+	case session.FileDeleted:
+		return diffDeletionStyle
+	case session.FileRenamed:
+		return diffHunkStyle
+	}
+	return diffFileStyle
+}
+
+// truncateLeft shortens a path to maxLen runes from the left, keeping the
+// file name.
+func truncateLeft(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	if maxLen < 2 {
+		return string(runes[len(runes)-maxLen:])
+	}
+	return "…" + string(runes[len(runes)-maxLen+1:])
+}
+
+// sidebarView renders the file list: each shown file's status, path and
+// counts, scrolled to keep the file being read in sight.
+func (d *DiffPane) sidebarView(width int) string {
+	inner := width - 1
+	title := fmt.Sprintf(" Files %d", len(d.files))
+	if d.filter != "" {
+		title = fmt.Sprintf(" Files %d/%d", len(d.shown), len(d.files))
+	}
+	rows := []string{dimStyle.Render(truncateRunes(title, inner))}
+
+	visible := max(d.height-1, 1)
+	start := 0
+	if d.fileCursor >= visible {
+		start = d.fileCursor - visible + 1
+	}
+	for n := start; n < len(d.shown) && len(rows) < d.height; n++ {
+		f := d.files[d.shown[n]]
+		counts := fmt.Sprintf("+%d -%d", f.Added, f.Removed)
+		if f.Binary {
+			counts = "bin"
+		}
+		room := max(inner-4-len(counts), 4)
+		name := truncateLeft(f.Path, room)
+		name += strings.Repeat(" ", max(room-len([]rune(name)), 0))
+
+		marker := " "
+		nameStyle := lipgloss.NewStyle()
+		if n == d.fileCursor {
+			marker = listSelectedStyle.Render("▸")
+			nameStyle = nameStyle.Bold(true)
+		}
+		if d.collapsed[f.Path] {
+			nameStyle = nameStyle.Foreground(lipgloss.Color(ColorLightGray))
+		}
+		rows = append(rows, marker+fileStatusStyle(f.Status).Render(f.Status.Letter())+" "+nameStyle.Render(name)+" "+dimStyle.Render(counts))
+	}
+
+	return lipgloss.NewStyle().
+		Width(inner).
+		Height(d.height).
+		MaxHeight(d.height).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderRight(true).
+		BorderForeground(lipgloss.Color(ColorLightGray)).
+		Render(strings.Join(rows, "\n"))
+}
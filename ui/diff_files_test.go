@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// newFilesPane is a diff pane showing three files, tall enough to jump to
+// each.
+func newFilesPane() *DiffPane {
+	var diff strings.Builder
+	for _, name := range []string{"main.go", "internal/store.go", "README.md"} {
+		diff.WriteString("diff --git a/" + name + " b/" + name + "\n--- a/" + name + "\n+++ b/" + name + "\n")
+		for hunk := 0; hunk < 2; hunk++ {
+			diff.WriteString("@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n")
+		}
+	}
+	d := NewDiffPane()
+	d.stats = &session.DiffStats{Content: diff.String(), Added: 6, Removed: 6}
+	d.SetSize(100, 5)
+	return d
+}
+
+// J and K move a file at a time, and the file list follows; { and } stop at
+// each hunk.
+func TestDiffPaneFileNavigation(t *testing.T) {
+	d := newFilesPane()
+	if len(d.files) != 3 || d.sidebarWidth() == 0 {
+		t.Fatalf("%d files, sidebar %d wide", len(d.files), d.sidebarWidth())
+	}
+	d.NextFile()
+	d.NextFile()
+	if d.fileCursor != 2 || d.viewport.YOffset != d.fileStarts[2] {
+		t.Fatalf("cursor %d at line %d, want the third file at %d", d.fileCursor, d.viewport.YOffset, d.fileStarts[2])
+	}
+	d.PrevFile()
+	if d.fileCursor != 1 {
+		t.Errorf("cursor = %d after K, want 1", d.fileCursor)
+	}
+	d.NextHunk()
+	d.NextHunk()
+	if d.fileCursor != 1 || d.viewport.YOffset != d.hunkStarts[3] {
+		t.Errorf("at line %d in file %d, want the second file's second hunk", d.viewport.YOffset, d.fileCursor)
+	}
+	d.PrevHunk()
+	if d.viewport.YOffset != d.hunkStarts[2] {
+		t.Errorf("at line %d, want the second file's first hunk", d.viewport.YOffset)
+	}
+	if !strings.Contains(d.View(), "internal/store.go") {
+		t.Error("the file list lacks a file")
+	}
+}
+
+// A folded file shrinks to its first line and stays folded when the diff is
+// refreshed; the filter hides the files it does not match.
+func TestDiffPaneFoldAndFilter(t *testing.T) {
+	d := newFilesPane()
+	lines := d.viewport.TotalLineCount()
+	d.ToggleFold()
+	if !d.collapsed["main.go"] || d.viewport.TotalLineCount() >= lines {
+		t.Fatalf("main.go not folded: %d lines, were %d", d.viewport.TotalLineCount(), lines)
+	}
+	d.updateContent()
+	if !d.collapsed["main.go"] || d.viewport.TotalLineCount() >= lines {
+		t.Error("the fold did not survive a refresh")
+	}
+	d.ToggleFoldAll()
+	if !d.collapsed["README.md"] {
+		t.Error("Z left a file unfolded")
+	}
+	d.ToggleFoldAll()
+	if d.collapsed["main.go"] {
+		t.Error("Z with every file folded left one folded")
+	}
+
+	for filter, want := range map[string]int{
+		"*.go":        2,
+		"internal/**": 1,
+		"internal/*":  1,
+		"READ":        1,
+		"*.rs":        0,
+		"":            3,
+	} {
+		d.SetFilter(filter)
+		if len(d.shown) != want {
+			t.Errorf("filter %q shows %d files, want %d", filter, len(d.shown), want)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Review comments in the diff view.
+//
+// j and k move a line at a time through the diff's hunks, from hunk to hunk;
+// i puts a comment on the selected line, or on the whole hunk when its
+// header is selected. Comments are shown under their lines, and Ctrl+R lists
+// the session's comments: s sends the open ones to the agent's tab as one
+// review prompt, q queues it for when the agent next finishes. A comment
+// whose lines the agent has changed since is marked resolved and left out of
+// the next review.
+
+// openComments is how many of the comments are not resolved.
+func openComments(comments []session.ReviewComment) int {
+	open := 0
+	for _, c := range comments {
+		if !c.Resolved {
+			open++
+		}
+	}
+	return open
+}
+
+// withComments puts a row under each line with comments, one per comment,
+// and moves the rows of the lines after them down.
+func (d *DiffPane) withComments(f session.DiffFile, rows []string, lineRows []int) ([]string, []int) {
+	under := map[int][]string{}
+	width := max(d.viewport.Width-6, 10)
+	for _, c := range d.comments {
+		line := f.CommentLine(c)
+		if line < 0 {
+			continue
+		}
+		text := strings.ReplaceAll(c.Text, "\n", " ")
+		row := reviewOpenStyle.Render("● " + truncateToWidth(text, width))
+		if c.Resolved {
+			row = dimStyle.Render("✓ " + truncateToWidth(text, width-11) + " (resolved)")
+		}
+		under[lineRows[line]] = append(under[lineRows[line]], "    "+row)
+	}
+	if len(under) == 0 {
+		return rows, lineRows
+	}
+
+	moved := make([]int, len(rows))
+	var out []string
+	for r, row := range rows {
+		moved[r] = len(out)
+		out = append(out, row)
+		out = append(out, under[r]...)
+	}
+	remapped := make([]int, len(lineRows))
+	for n, r := range lineRows {
+		remapped[n] = moved[r]
+	}
+	return out, remapped
+}
+
+// reviewOpenStyle colours an open comment.
+var reviewOpenStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+
+// SetComments shows a session's review comments under their lines.
+func (d *DiffPane) SetComments(comments []session.ReviewComment) {
+	d.comments = comments
+	d.updateContent()
+}
+
+// selectedLine is the selected line of a file's hunk, or -1 when it is not
+// one of the hunk's lines.
+func (d *DiffPane) selectedLine(file session.DiffFile, hunk int) int {
+	if hunk < 0 || hunk >= len(file.Hunks) {
+		return -1
+	}
+	h := file.Hunks[hunk]
+	if d.lineCursor <= h.Start || d.lineCursor >= h.End {
+		return -1
+	}
+	return d.lineCursor
+}
+
+// cursorRow is the content line of the selection: the selected line, the
+// selected hunk's header, or the file's first line.
+func (d *DiffPane) cursorRow() int {
+	if d.fileCursor >= len(d.shown) || d.fileCursor >= len(d.lineRows) {
+		return -1
+	}
+	file, hunk, _ := d.Selection()
+	if line := d.selectedLine(file, hunk); line >= 0 && line < len(d.lineRows[d.fileCursor]) {
+		return d.fileStarts[d.fileCursor] + d.lineRows[d.fileCursor][line]
+	}
+	if hunk >= 0 {
+		return d.hunkStarts[d.hunkCursor]
+	}
+	return d.fileStarts[d.fileCursor]
+}
+
+// markCursor marks the selection in the rendered view, when it is in sight.
+func (d *DiffPane) markCursor(view string) string {
+	row := d.cursorRow() - d.viewport.YOffset
+	if row < 0 || row >= d.viewport.Height {
+		return view
+	}
+	lines := strings.Split(view, "\n")
+	if row < len(lines) && strings.HasPrefix(lines[row], " ") {
+		lines[row] = listSelectedStyle.Render("▸") + lines[row][1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showRow scrolls just enough for a content line to be in sight.
+func (d *DiffPane) showRow(row int) {
+	switch {
+	case row < 0:
+	case row < d.viewport.YOffset:
+		d.viewport.SetYOffset(row)
+	case row >= d.viewport.YOffset+d.viewport.Height:
+		d.viewport.SetYOffset(row - d.viewport.Height + 1)
+	}
+}
+
+// NextLine selects the next line of the selected hunk, going on into the
+// next hunk; from a whole file, its first hunk.
+func (d *DiffPane) NextLine() {
+	file, hunk, ok := d.Selection()
+	if !ok {
+		return
+	}
+	if hunk < 0 {
+		d.NextHunk()
+		return
+	}
+	h := file.Hunks[hunk]
+	next := h.Start + 1
+	if line := d.selectedLine(file, hunk); line >= 0 {
+		next = line + 1
+	}
+	for next < h.End && strings.HasPrefix(file.Lines[next], `\`) {
+		next++
+	}
+	if next >= h.End {
+		if d.hunkCursor+1 < len(d.hunkStarts) {
+			d.selectHunk(d.hunkCursor + 1)
+		}
+		return
+	}
+	d.lineCursor = next
+	d.showRow(d.cursorRow())
+}
+
+// PrevLine selects the line before: the hunk's header from its first line,
+// and from a header the last line of the hunk before.
+func (d *DiffPane) PrevLine() {
+	file, hunk, ok := d.Selection()
+	if !ok {
+		return
+	}
+	line := d.selectedLine(file, hunk)
+	if line < 0 {
+		d.PrevHunk()
+		file, hunk, _ = d.Selection()
+		if hunk < 0 || hunk >= len(file.Hunks) {
+			return
+		}
+		line = file.Hunks[hunk].End
+	}
+	h := file.Hunks[hunk]
+	prev := line - 1
+	for prev > h.Start && strings.HasPrefix(file.Lines[prev], `\`) {
+		prev--
+	}
+	d.lineCursor = -1
+	if prev > h.Start {
+		d.lineCursor = prev
+	}
+	d.showRow(d.cursorRow())
+}
+
+// SelectComment shows a comment's line, selected, or its file if the diff
+// no longer has the line. Reports whether the file is in the diff shown.
+func (d *DiffPane) SelectComment(c session.ReviewComment) bool {
+	for k, i := range d.shown {
+		f := d.files[i]
+		if f.Path != c.Path {
+			continue
+		}
+		d.scrollToFile(k)
+		line := f.CommentLine(c)
+		if line < 0 || d.collapsed[f.Path] {
+			return true
+		}
+		first := 0
+		for _, start := range d.hunkStarts {
+			if start < d.fileStarts[k] {
+				first++
+			}
+		}
+		for h, hunk := range f.Hunks {
+			if line >= hunk.Start && line < hunk.End {
+				d.selectHunk(first + h)
+				if line > hunk.Start {
+					d.lineCursor = line
+				}
+				d.showRow(d.cursorRow())
+				return true
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// openReviewComment opens the dialog for a comment on the selected line or
+// hunk of the diff.
+func (m *Model) openReviewComment() {
+	inst := m.getSelectedInstance()
+	file, hunk, ok := m.diffPane.Selection()
+	if inst == nil || !ok {
+		return
+	}
+	if hunk < 0 {
+		m.showError(fmt.Errorf("select a hunk (}) or a line (j/k) to comment on"))
+		return
+	}
+	m.reviewSession = inst
+	m.reviewFile = file
+	m.reviewHunk = hunk
+	m.reviewLine = m.diffPane.selectedLine(file, hunk)
+	m.reviewTarget = m.diffPane.SelectionLabel()
+	m.reviewErr = ""
+	m.promptInput.SetValue("")
+	m.promptInput.Focus()
+	m.state = stateReviewComment
+}
+
+// closeReviewComment leaves the comment dialog for the diff.
+func (m *Model) closeReviewComment() {
+	m.reviewSession = nil
+	m.promptInput.Blur()
+	m.promptInput.SetValue("")
+	m.state = stateList
+}
+
+// handleReviewCommentKeys handles keyboard input in the comment dialog.
+func (m Model) handleReviewCommentKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeReviewComment()
+		return m, nil
+	case "ctrl+s":
+		inst := m.reviewSession
+		c, err := session.NewReviewComment(m.reviewFile, m.reviewHunk, m.reviewLine, m.promptInput.Value(), time.Now())
+		if err != nil {
+			m.reviewErr = err.Error()
+			return m, nil
+		}
+		inst.ReviewComments = append(inst.ReviewComments, c)
+		if m.storage != nil {
+			if err := m.storage.UpdateInstance(inst); err != nil {
+				m.reviewErr = err.Error()
+				return m, nil
+			}
+		}
+		m.diffPane.SetComments(inst.ReviewComments)
+		m.closeReviewComment()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+// openReview opens the selected session's review comments, resolving those
+// whose lines have changed first.
+func (m *Model) openReview() {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return
+	}
+	if inst.UpdateReviewComments() {
+		m.saveReview(inst)
+	}
+	m.reviewSession = inst
+	m.reviewCursor = 0
+	m.reviewErr = ""
+	m.state = stateReview
+}
+
+// saveReview saves a session whose comments changed and shows them as they
+// are now.
+func (m *Model) saveReview(inst *session.Instance) {
+	if m.storage != nil {
+		if err := m.storage.UpdateInstance(inst); err != nil {
+			m.reviewErr = err.Error()
+		}
+	}
+	if inst == m.getSelectedInstance() {
+		m.diffPane.SetComments(inst.ReviewComments)
+	}
+}
+
+// sendReview sends the open comments to the session's agent as one prompt,
+// or queues it for when the agent next finishes.
+func (m *Model) sendReview(queue bool) {
+	inst := m.reviewSession
+	var open []session.ReviewComment
+	for _, c := range inst.ReviewComments {
+		if !c.Resolved {
+			open = append(open, c)
+		}
+	}
+	if len(open) == 0 {
+		m.reviewErr = "No open comments to send"
+		return
+	}
+	text := session.ReviewPrompt(open)
+	window := inst.GetMainWindowIndex()
+	if queue {
+		if err := inst.EnqueuePrompt(window, text, time.Now()); err != nil {
+			m.reviewErr = err.Error()
+			return
+		}
+		m.successMsg = fmt.Sprintf("Queued %d review comments for %s: sent when the agent next finishes", len(open), inst.Name)
+	} else {
+		activity := m.windowActivityState[inst.ID][window]
+		if err := inst.SendPromptToWindow(window, text); err != nil {
+			m.reviewErr = fmt.Sprintf("failed to send to %s: %s", inst.Name, err)
+			return
+		}
+		m.promptLog.Append(inst.SentPrompt(window, text, session.SentFromReview, activity))
+		m.successMsg = fmt.Sprintf("Sent %d review comments to %s", len(open), inst.Name)
+	}
+	for k := range inst.ReviewComments {
+		if !inst.ReviewComments[k].Resolved {
+			inst.ReviewComments[k].Sent = true
+		}
+	}
+	m.saveReview(inst)
+	m.reviewSession = nil
+	m.previousState = stateList
+	m.state = stateUpdateSuccess
+}
+
+// handleReviewKeys handles keyboard input in the review dialog.
+func (m Model) handleReviewKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	inst := m.reviewSession
+	if inst == nil {
+		m.state = stateList
+		return m, nil
+	}
+	m.reviewErr = ""
+	comments := inst.ReviewComments
+	switch msg.String() {
+	case "esc", "ctrl+r":
+		m.reviewSession = nil
+		m.state = stateList
+	case "up", "k":
+		if m.reviewCursor > 0 {
+			m.reviewCursor--
+		}
+	case "down", "j":
+		if m.reviewCursor < len(comments)-1 {
+			m.reviewCursor++
+		}
+	case "s":
+		m.sendReview(false)
+	case "q":
+		m.sendReview(true)
+	case "r":
+		// Resolve by hand, or open again
+		if m.reviewCursor < len(comments) {
+			comments[m.reviewCursor].Resolved = !comments[m.reviewCursor].Resolved
+			m.saveReview(inst)
+		}
+	case "d", "delete":
+		if m.reviewCursor < len(comments) {
+			inst.ReviewComments = append(comments[:m.reviewCursor:m.reviewCursor], comments[m.reviewCursor+1:]...)
+			m.reviewCursor = max(min(m.reviewCursor, len(inst.ReviewComments)-1), 0)
+			m.saveReview(inst)
+		}
+	case "c":
+		// Clear the resolved comments
+		var open []session.ReviewComment
+		for _, c := range comments {
+			if !c.Resolved {
+				open = append(open, c)
+			}
+		}
+		inst.ReviewComments = open
+		m.reviewCursor = 0
+		m.saveReview(inst)
+	case "enter":
+		// Show the comment's line in the diff
+		if m.reviewCursor < len(comments) && inst == m.getSelectedInstance() {
+			if !m.showDiff {
+				m.showDiff = true
+				m.showTimeline = false
+				m.diffPane.SetDiff(inst)
+			}
+			if !m.diffPane.SelectComment(comments[m.reviewCursor]) {
+				m.reviewErr = comments[m.reviewCursor].Path + " is not in this diff (F switches diffs)"
+				return m, nil
+			}
+			m.reviewSession = nil
+			m.state = stateList
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// j goes into the first hunk and down its lines to the next hunk's header;
+// k goes back up to the hunk before's last line.
+func TestDiffPaneLineCursor(t *testing.T) {
+	d := newFilesPane()
+	d.NextLine()
+	if label := d.SelectionLabel(); label != "hunk 1/2 of main.go" {
+		t.Fatalf("after j: %s", label)
+	}
+	d.NextLine()
+	d.NextLine()
+	if label := d.SelectionLabel(); label != "removed line 2 in hunk 1/2 of main.go" {
+		t.Fatalf("after jjj: %s", label)
+	}
+	d.NextLine()
+	if label := d.SelectionLabel(); label != "line 2 in hunk 1/2 of main.go" {
+		t.Errorf("after jjjj: %s", label)
+	}
+	if row := d.cursorRow(); row != d.hunkStarts[0]+3 {
+		t.Errorf("cursor on line %d, want %d", row, d.hunkStarts[0]+3)
+	}
+	d.NextLine()
+	d.NextLine()
+	if label := d.SelectionLabel(); label != "hunk 2/2 of main.go" {
+		t.Errorf("past the hunk's end: %s", label)
+	}
+	d.PrevLine()
+	if label := d.SelectionLabel(); label != "line 3 in hunk 1/2 of main.go" {
+		t.Errorf("k from the header: %s", label)
+	}
+	if !strings.Contains(d.View(), "▸") {
+		t.Error("the selected line is not marked")
+	}
+}
+
+// A comment is shown under its line, pushing the lines after it down; once
+// resolved it is dimmed, and one whose line the diff lacks is not shown.
+func TestDiffPaneComments(t *testing.T) {
+	d := newFilesPane()
+	d.SetSize(100, 40)
+	file := d.files[0]
+	lines := d.viewport.TotalLineCount()
+	c, err := session.NewReviewComment(file, 0, file.Hunks[0].Start+3, "Use a name, not a number", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone := session.ReviewComment{Path: "main.go", Line: 40, Text: "Not in this diff"}
+	d.SetComments([]session.ReviewComment{c, gone})
+
+	if got := d.viewport.TotalLineCount(); got != lines+1 {
+		t.Fatalf("%d lines with one comment shown, want %d", got, lines+1)
+	}
+	if row := d.lineRows[0][file.Hunks[0].Start+4]; row != d.lineRows[0][file.Hunks[0].Start+3]+2 {
+		t.Errorf("the line after the comment is on row %d", row)
+	}
+	if !strings.Contains(d.viewport.View(), "● Use a name") || strings.Contains(d.viewport.View(), "Not in this diff") {
+		t.Errorf("comments shown:\n%s", d.viewport.View())
+	}
+	if !strings.Contains(d.viewport.View(), "2 review comments (^R)") {
+		t.Error("the summary does not count the open comments")
+	}
+
+	c.Resolved = true
+	d.SetComments([]session.ReviewComment{c})
+	if !strings.Contains(d.viewport.View(), "(resolved)") {
+		t.Error("a resolved comment is not marked")
+	}
+}
+
+// In the review dialog r resolves a comment, d deletes one and c clears the
+// resolved ones.
+func TestReviewKeys(t *testing.T) {
+	m := newTestModel()
+	inst := &session.Instance{Name: "s", ReviewComments: []session.ReviewComment{
+		{Path: "a.go", Line: 1, Text: "one"},
+		{Path: "a.go", Line: 2, Text: "two"},
+		{Path: "b.go", Line: 3, Text: "three"},
+	}}
+	m.reviewSession = inst
+	m.state = stateReview
+
+	press := func(key string) {
+		model, _ := m.handleReviewKeys(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		next := model.(Model)
+		m = &next
+	}
+	press("r")
+	press("j")
+	press("d")
+	if len(inst.ReviewComments) != 2 || !inst.ReviewComments[0].Resolved || inst.ReviewComments[1].Text != "three" {
+		t.Fatalf("after r j d: %+v", inst.ReviewComments)
+	}
+	if inst.OpenReviewComments() != 1 {
+		t.Errorf("%d open comments, want 1", inst.OpenReviewComments())
+	}
+	press("c")
+	if len(inst.ReviewComments) != 1 || inst.ReviewComments[0].Text != "three" {
+		t.Errorf("after c: %+v", inst.ReviewComments)
+	}
+	model, _ := m.handleReviewKeys(tea.KeyMsg{Type: tea.KeyEsc})
+	if next := model.(Model); next.state != stateList || next.reviewSession != nil {
+		t.Errorf("after esc: state %d", next.state)
+	}
+}
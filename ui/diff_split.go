@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// The diff side by side.
+//
+// An agent's refactor read as one column on a wide terminal leaves most of
+// the screen empty and puts a function's old and new bodies a screen apart.
+// Side by side, the old file is on the left and the new on the right, each
+// line with its number: a run of removed lines is set against the run of
+// added lines that replaced it, row by row, and the shorter side is padded
+// so both sides' following context lines up again. Widths are measured in
+// columns, not runes, so wide characters keep the columns straight.
+//
+// The layout follows the preview pane's width unless chosen with V.
+
+// DiffLayout is how the diff is laid out.
+type DiffLayout int
+
+const (
+	DiffLayoutAuto    DiffLayout = iota // Side by side when the preview is wide enough
+	DiffLayoutUnified                   // One column, as git prints it
+	DiffLayoutSplit                     // Old on the left, new on the right
+)
+
+// minSplitPreviewWidth is the narrowest preview pane the automatic layout
+// puts the diff side by side in.
+const minSplitPreviewWidth = 140
+
+// split is whether the diff is shown side by side.
+func (d *DiffPane) split() bool {
+	switch d.layout {
+	case DiffLayoutUnified:
+		return false
+	case DiffLayoutSplit:
+		return true
+	}
+	return d.width >= minSplitPreviewWidth
+}
+
+// CycleLayout switches from the automatic layout to unified, side by side,
+// and back.
+func (d *DiffPane) CycleLayout() {
+	d.layout = (d.layout + 1) % 3
+	d.rerender()
+}
+
+// GetLayoutLabel returns a human-readable label for the layout in use.
+func (d *DiffPane) GetLayoutLabel() string {
+	label := "Unified"
+	if d.split() {
+		label = "Side by side"
+	}
+	if d.layout == DiffLayoutAuto {
+		label += " (auto)"
+	}
+	return label
+}
+
+// splitSide renders one side of a row: the line's number and text, cut and
+// padded to width columns. A side with no line (number 0) is left blank.
+func splitSide(number, gutter int, line string, width int) string {
+	if number == 0 {
+		return strings.Repeat(" ", width)
+	}
+	textWidth := max(width-gutter-1, 1)
+	text := truncateToWidth(strings.ReplaceAll(line, "\t", "    "), textWidth)
+	padding := strings.Repeat(" ", max(textWidth-displayWidth(text), 0))
+	return dimStyle.Render(fmt.Sprintf("%*d", gutter, number)) + " " + colorDiffLine(text) + padding
+}
+
+// splitFileLines renders a file's diff in two columns, width wide, with the
+// row each of its lines is on. The lines before the first hunk, the hunk
+// headers and git's "\ No newline" notes span both columns.
+func splitFileLines(f session.DiffFile, width int) (rows []string, lineRows []int) {
+	gutter := 3
+	for _, h := range f.Hunks {
+		if _, oldEnd, _, newEnd, ok := session.ParseHunkHeader(f.Lines[h.Start]); ok {
+			gutter = max(gutter, len(strconv.Itoa(max(oldEnd, newEnd))))
+		}
+	}
+	side := max((width-4)/2, gutter+2)
+	separator := dimStyle.Render("│")
+	spanning := func(line string) string {
+		return " " + colorDiffLine(truncateToWidth(strings.ReplaceAll(line, "\t", "    "), max(width-2, 1)))
+	}
+
+	lineRows = make([]int, len(f.Lines))
+	var removed, added []int // Indexes into f.Lines, waiting to be paired
+	oldLine, newLine := 0, 0
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			left, right := splitSide(0, gutter, "", side), splitSide(0, gutter, "", side)
+			if i < len(removed) {
+				left = splitSide(oldLine, gutter, f.Lines[removed[i]], side)
+				lineRows[removed[i]] = len(rows)
+				oldLine++
+			}
+			if i < len(added) {
+				right = splitSide(newLine, gutter, f.Lines[added[i]], side)
+				lineRows[added[i]] = len(rows)
+				newLine++
+			}
+			rows = append(rows, " "+left+" "+separator+" "+right)
+		}
+		removed, added = nil, nil
+	}
+
+	hunk := -1
+	for n, line := range f.Lines {
+		if hunk+1 < len(f.Hunks) && f.Hunks[hunk+1].Start == n {
+			flush()
+			hunk++
+			oldLine, _, newLine, _, _ = session.ParseHunkHeader(line)
+			lineRows[n] = len(rows)
+			rows = append(rows, spanning(line))
+			continue
+		}
+		if hunk < 0 || n >= f.Hunks[hunk].End {
+			flush()
+			lineRows[n] = len(rows)
+			rows = append(rows, spanning(line))
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, n)
+		case strings.HasPrefix(line, "+"):
+			added = append(added, n)
+		case strings.HasPrefix(line, `\`):
+			flush()
+			lineRows[n] = len(rows)
+			rows = append(rows, " "+dimStyle.Render(truncateToWidth(line, max(width-2, 1))))
+		default:
+			// A context line, on both sides
+			flush()
+			lineRows[n] = len(rows)
+			rows = append(rows, " "+splitSide(oldLine, gutter, line, side)+" "+separator+" "+splitSide(newLine, gutter, line, side))
+			oldLine++
+			newLine++
+		}
+	}
+	flush()
+	return rows, lineRows
+}
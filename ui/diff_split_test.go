@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Removed lines are set against the added lines that replaced them, each
+// with its number, the shorter side padded; every row is as wide as the
+// pane, wide characters or not.
+func TestSplitFileLines(t *testing.T) {
+	files := session.ParseDiff("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+		"@@ -8,4 +8,5 @@ func main() {\n ctx := 1\n-name := \"日本語\"\n-old()\n+name := \"en\"\n+new()\n+more()\n return\n")
+	rows, lineRows := splitFileLines(files[0], 100)
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 5, 6, 7, 8}; fmt.Sprint(lineRows) != fmt.Sprint(want) {
+		t.Fatalf("lines on rows %v, want %v", lineRows, want)
+	}
+	body := rows[4:]
+	if len(body) != 5 {
+		t.Fatalf("%d rows for the hunk, want 5:\n%s", len(body), strings.Join(rows, "\n"))
+	}
+	for i, want := range [][2]string{
+		{"8 ctx := 1", "8 ctx := 1"},
+		{`9 -name := "日本語"`, `9 +name := "en"`},
+		{"10 -old()", "10 +new()"},
+		{"", "11 +more()"},
+		{"11 return", "12 return"},
+	} {
+		row := ansi.Strip(body[i])
+		if w := displayWidth(row); w != 100 {
+			t.Errorf("row %d is %d columns, want 100: %q", i, w, row)
+		}
+		left, right, _ := strings.Cut(row, "│")
+		if strings.Join(strings.Fields(left), " ") != want[0] || strings.Join(strings.Fields(right), " ") != want[1] {
+			t.Errorf("row %d = %q | %q, want %q | %q", i, left, right, want[0], want[1])
+		}
+	}
+}
+
+// The layout goes side by side on a wide preview unless chosen, and V cycles
+// through the choices back to the automatic one.
+func TestDiffPaneLayout(t *testing.T) {
+	d := newFilesPane()
+	if d.split() {
+		t.Error("split on a 100-column preview")
+	}
+	d.SetSize(160, 5)
+	if !d.split() || d.GetLayoutLabel() != "Side by side (auto)" {
+		t.Errorf("layout on a 160-column preview = %s", d.GetLayoutLabel())
+	}
+	d.CycleLayout()
+	if d.split() || d.GetLayoutLabel() != "Unified" {
+		t.Errorf("after V: %s", d.GetLayoutLabel())
+	}
+	d.SetSize(100, 5)
+	d.CycleLayout()
+	if !d.split() {
+		t.Error("side by side not kept on a narrow preview")
+	}
+	d.CycleLayout()
+	if d.layout != DiffLayoutAuto {
+		t.Errorf("layout = %d after three presses, want auto", d.layout)
+	}
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Staging, unstaging and discarding from the diff view.
+//
+// J selects a file, } and { a hunk; + stages the selection, - unstages it and
+// ! discards it once confirmed. What can be done depends on the diff shown:
+// the full diff is the working tree against the index, so its hunks can be
+// staged or discarded; the staged diff is the index against HEAD, so its
+// hunks can be unstaged; the session diff counts from the session's start,
+// and its hunks are staged or discarded as long as git finds they still fit.
+// The diff is read again straight after, so what was staged moves from one
+// diff to the other at once.
+
+// diffPatchActions maps the diff view's keys to what they do.
+var diffPatchActions = map[string]session.PatchAction{
+	"+": session.PatchStage,
+	"-": session.PatchUnstage,
+	"!": session.PatchDiscard,
+}
+
+// diffPatchAllowed is why an action makes no sense on the diff shown, or nil.
+func diffPatchAllowed(mode DiffMode, action session.PatchAction) error {
+	switch {
+	case mode == DiffModeStaged && action == session.PatchStage:
+		return fmt.Errorf("already staged: the Staged diff shows the index (F to switch)")
+	case mode == DiffModeStaged && action == session.PatchDiscard:
+		return fmt.Errorf("staged changes are not discarded: unstage them first (-)")
+	case mode != DiffModeStaged && action == session.PatchUnstage:
+		return fmt.Errorf("nothing to unstage here: press F for the Staged diff")
+	}
+	return nil
+}
+
+// handleDiffPatchKey stages, unstages or discards the hunk or file selected
+// in the diff view; a discard is confirmed first.
+func (m Model) handleDiffPatchKey(key string) (tea.Model, tea.Cmd) {
+	inst := m.getSelectedInstance()
+	file, hunk, ok := m.diffPane.Selection()
+	if inst == nil || !ok {
+		return m, nil
+	}
+	action := diffPatchActions[key]
+	err := diffPatchAllowed(m.diffPane.GetMode(), action)
+	patch := ""
+	if err == nil {
+		patch, err = file.Patch(hunk)
+	}
+	if err != nil {
+		m.err = err
+		m.previousState = m.state
+		m.state = stateError
+		return m, nil
+	}
+
+	if action == session.PatchDiscard {
+		m.discardSession = inst
+		m.discardPatch = patch
+		m.discardLabel = m.diffPane.SelectionLabel()
+		m.state = stateConfirmDiscard
+		return m, nil
+	}
+	m.applyDiffPatch(inst, action, patch)
+	return m, nil
+}
+
+// applyDiffPatch applies a patch cut from the diff view and shows the diff as
+// it now is, or why git refused.
+func (m *Model) applyDiffPatch(inst *session.Instance, action session.PatchAction, patch string) {
+	err := inst.ApplyPatch(action, patch)
+	m.diffPane.SetDiff(inst)
+	if err != nil {
+		m.err = err
+		m.previousState = stateList
+		m.state = stateError
+	}
+}
+
+// handleConfirmDiscardKeys handles keyboard input in the discard
+// confirmation dialog
+func (m Model) handleConfirmDiscardKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		m.state = stateList
+		if m.discardSession != nil {
+			m.applyDiffPatch(m.discardSession, session.PatchDiscard, m.discardPatch)
+		}
+	case "n", "N", "esc":
+		m.state = stateList
+	default:
+		return m, nil
+	}
+	m.discardSession, m.discardPatch, m.discardLabel = nil, "", ""
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// J selects a whole file and } a hunk in it; when the selected hunk goes, as
+// a staged one does from the full diff, the next one is selected, in
+// whichever file it is.
+func TestDiffPaneSelection(t *testing.T) {
+	d := newFilesPane()
+	d.NextFile()
+	if file, hunk, ok := d.Selection(); !ok || file.Path != "internal/store.go" || hunk != -1 {
+		t.Fatalf("after J: %s hunk %d", file.Path, hunk)
+	}
+	d.NextHunk()
+	d.NextHunk()
+	if label := d.SelectionLabel(); label != "hunk 2/2 of internal/store.go" {
+		t.Fatalf("after }}: %s", label)
+	}
+
+	// The second file's second hunk staged: the diff has the rest
+	d.stats.Content = strings.Replace(d.stats.Content, "+++ b/internal/store.go\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		"+++ b/internal/store.go\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n", 1)
+	d.updateContent()
+	if label := d.SelectionLabel(); label != "hunk 1/2 of README.md" {
+		t.Errorf("after the hunk went: %s", label)
+	}
+
+	d.ToggleFold()
+	if _, hunk, _ := d.Selection(); hunk != -1 {
+		t.Errorf("hunk %d selected in a folded file", hunk)
+	}
+}
+
+// Each diff offers what makes sense on it: the working tree's changes are
+// staged or discarded, the index's unstaged.
+func TestDiffPatchAllowed(t *testing.T) {
+	for _, c := range []struct {
+		mode   DiffMode
+		action session.PatchAction
+		ok     bool
+	}{
+		{DiffModeFull, session.PatchStage, true},
+		{DiffModeFull, session.PatchDiscard, true},
+		{DiffModeFull, session.PatchUnstage, false},
+		{DiffModeSession, session.PatchStage, true},
+		{DiffModeStaged, session.PatchUnstage, true},
+		{DiffModeStaged, session.PatchStage, false},
+		{DiffModeStaged, session.PatchDiscard, false},
+	} {
+		if err := diffPatchAllowed(c.mode, c.action); (err == nil) != c.ok {
+			t.Errorf("%s in mode %d: %v", c.action, c.mode, err)
+		}
+	}
+
+	m := newTestModel()
+	m.state = stateConfirmDiscard
+	m.discardSession = &session.Instance{Name: "s"}
+	m.discardPatch = "diff --git a/x b/x\n"
+	model, _ := m.handleConfirmDiscardKeys(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	next := model.(Model)
+	if next.state != stateList || next.discardSession != nil || next.discardPatch != "" {
+		t.Errorf("after n: state %d, patch %q", next.state, next.discardPatch)
+	}
+}
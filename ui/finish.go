@@ -0,0 +1,236 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Finishing the selected session's work.
+//
+// The dialog shows the session's diff and takes a commit message, typed or
+// drafted by the session's own agent, then what to do with the commit: merge
+// or rebase it into the branch the session was made from, write a patch or a
+// bundle, or leave it. Asking the agent and running git happen off the UI
+// thread. Once done the session is stopped, and a session in a worktree of
+// its own is deleted with the worktree; its branch too once merged. A
+// worktree with changes left uncommitted, the rest of a partial staging, is
+// kept with its session.
+
+// Steps of the finish dialog.
+const (
+	finishMessage = iota // The diff and the commit message
+	finishActions        // What to do with the commit
+)
+
+// finishDraftMsg brings the commit message the agent drafted, or why not.
+type finishDraftMsg struct {
+	sessionID string
+	message   string
+	err       error
+}
+
+// finishDoneMsg reports the session's work finished, or not.
+type finishDoneMsg struct {
+	sessionID string
+	action    session.FinishAction
+	result    string
+	err       error
+}
+
+// openFinish opens the finish dialog on the selected session.
+func (m *Model) openFinish() {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return
+	}
+	if !inst.CanFinish() {
+		m.showError(fmt.Errorf("%s has no worktree or branch of its own, so its changes may be anyone's: commit them with git", inst.Name))
+		return
+	}
+	dirty, err := inst.HasUncommitted()
+	if err != nil {
+		m.showError(fmt.Errorf("%s is not in a git repository", inst.Name))
+		return
+	}
+	m.finishSession = inst
+	m.finishDirty = dirty
+	m.finishStaged, _ = inst.HasStaged()
+	m.finishDiff = nil
+	m.finishSummary = ""
+	diff := inst.GetSessionDiff()
+	switch {
+	case diff.Error != nil:
+		m.finishSummary = diff.Error.Error()
+	case strings.TrimSpace(diff.Content) == "":
+		m.finishSummary = "No changes since the session started"
+	default:
+		m.finishDiff = strings.Split(strings.TrimRight(diff.Content, "\n"), "\n")
+		m.finishSummary = fmt.Sprintf("+%d -%d", diff.Added, diff.Removed)
+	}
+	m.finishStep = finishMessage
+	m.finishScroll = 0
+	m.finishCursor = 0
+	m.finishBusy = ""
+	m.finishErr = ""
+	m.promptInput.SetValue("")
+	m.promptInput.Focus()
+	m.state = stateFinish
+}
+
+// handleFinishKeys handles keyboard input in the finish dialog.
+func (m Model) handleFinishKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	inst := m.finishSession
+	if m.finishBusy != "" {
+		// git is running: wait for it. A draft can be given up on.
+		if key == "esc" && m.finishStep == finishMessage {
+			m.closeFinish()
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "pgup":
+		m.finishScroll = max(m.finishScroll-m.finishDiffHeight(), 0)
+		return m, nil
+	case "pgdown":
+		m.finishScroll = min(m.finishScroll+m.finishDiffHeight(), max(len(m.finishDiff)-m.finishDiffHeight(), 0))
+		return m, nil
+	}
+
+	if m.finishStep == finishActions {
+		actions := inst.FinishActions()
+		switch key {
+		case "esc", "shift+tab":
+			m.finishStep = finishMessage
+			m.finishErr = ""
+			return m, m.promptInput.Focus()
+		case "up", "k":
+			if m.finishCursor > 0 {
+				m.finishCursor--
+			}
+		case "down", "j":
+			if m.finishCursor < len(actions)-1 {
+				m.finishCursor++
+			}
+		case "enter":
+			if m.finishCursor >= len(actions) {
+				return m, nil
+			}
+			action := actions[m.finishCursor]
+			message := m.promptInput.Value()
+			exports := m.storage.ExportsDir()
+			m.finishBusy = "Finishing…"
+			m.finishErr = ""
+			return m, func() tea.Msg {
+				result, err := inst.Finish(action, message, exports)
+				return finishDoneMsg{sessionID: inst.ID, action: action, result: result, err: err}
+			}
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "esc":
+		m.closeFinish()
+		return m, nil
+	case "ctrl+d":
+		if !inst.IsAlive() {
+			m.finishErr = "Start the session to have its agent draft the message"
+			return m, nil
+		}
+		m.finishBusy = fmt.Sprintf("Asking %s for a commit message…", inst.Name)
+		m.finishErr = ""
+		return m, func() tea.Msg {
+			message, err := inst.DraftCommitMessage()
+			return finishDraftMsg{sessionID: inst.ID, message: message, err: err}
+		}
+	case "ctrl+s", "tab":
+		if m.finishDirty && strings.TrimSpace(m.promptInput.Value()) == "" {
+			m.finishErr = "Write a commit message, or ctrl+d to have the agent draft one"
+			return m, nil
+		}
+		m.finishErr = ""
+		m.finishStep = finishActions
+		m.promptInput.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+// closeFinish leaves the finish dialog for the list.
+func (m *Model) closeFinish() {
+	m.finishSession = nil
+	m.finishBusy = ""
+	m.promptInput.Blur()
+	m.promptInput.SetValue("")
+	m.state = stateList
+}
+
+// applyFinishDraft puts the drafted message in the dialog, if it is still
+// open on the session.
+func (m *Model) applyFinishDraft(msg finishDraftMsg) {
+	if m.state != stateFinish || m.finishSession == nil || m.finishSession.ID != msg.sessionID {
+		return
+	}
+	m.finishBusy = ""
+	if msg.err != nil {
+		m.finishErr = msg.err.Error()
+		return
+	}
+	m.promptInput.SetValue(msg.message)
+}
+
+// applyFinishDone stops the finished session and cleans up after it, then
+// tells what was done; or shows why it could not be finished.
+func (m *Model) applyFinishDone(msg finishDoneMsg) {
+	if m.state != stateFinish || m.finishSession == nil || m.finishSession.ID != msg.sessionID {
+		return
+	}
+	inst := m.finishSession
+	m.finishBusy = ""
+	if msg.err != nil {
+		m.finishErr = msg.err.Error()
+		return
+	}
+	m.closeFinish()
+
+	result := msg.result
+	if leftover, _ := inst.HasUncommitted(); inst.WorktreePath == "" || leftover {
+		inst.Stop()
+		m.storage.UpdateInstance(inst)
+		result += "; session stopped"
+		if inst.WorktreePath != "" {
+			result += ", worktree kept for the changes not committed"
+		}
+	} else {
+		// Merged, the branch is in the base branch; otherwise it is the work
+		merged := msg.action == session.FinishMerge || msg.action == session.FinishRebase
+		if err := m.storage.RemoveInstance(inst.ID); err != nil {
+			m.showError(fmt.Errorf("%s, but the session was not deleted: %w", result, err))
+			return
+		}
+		if instances, err := m.storage.Load(); err == nil {
+			m.instances = instances
+		}
+		if m.cursor >= len(m.instances) && m.cursor > 0 {
+			m.cursor--
+		}
+		if err := inst.RemoveOwnWorktree(merged); err != nil {
+			m.showError(fmt.Errorf("%s, but the worktree was not removed: %w", result, err))
+			return
+		}
+		result += "; session deleted with its worktree"
+		if merged {
+			result += " and branch"
+		}
+	}
+	m.successMsg = result
+	m.previousState = stateList
+	m.state = stateUpdateSuccess
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Changes are not committed without a message; with one, the dialog goes on
+// to the actions, and back. A draft for a dialog since closed, or for another
+// session, is dropped.
+func TestFinishDialog(t *testing.T) {
+	m := newTestModel()
+	m.promptInput = textarea.New()
+	m.state = stateFinish
+	m.finishSession = &session.Instance{ID: "s1", Name: "login", Branch: "login", BaseBranch: "main", RepoPath: "/src/app", WorktreePath: "/wt/login"}
+	m.finishDirty = true
+
+	press := func(msg tea.KeyMsg) {
+		model, _ := m.handleFinishKeys(msg)
+		next := model.(Model)
+		m = &next
+	}
+	press(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if m.finishStep != finishMessage || m.finishErr == "" {
+		t.Fatalf("went on without a message: step %d", m.finishStep)
+	}
+
+	m.applyFinishDraft(finishDraftMsg{sessionID: "other", message: "Wrong"})
+	m.applyFinishDraft(finishDraftMsg{sessionID: "s1", message: "Fix login"})
+	if got := m.promptInput.Value(); got != "Fix login" {
+		t.Fatalf("message = %q", got)
+	}
+	press(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if m.finishStep != finishActions {
+		t.Fatalf("step = %d, want the actions", m.finishStep)
+	}
+	if actions := m.finishSession.FinishActions(); len(actions) != 5 || actions[0] != session.FinishMerge {
+		t.Errorf("actions = %v", actions)
+	}
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	if m.finishCursor != 1 {
+		t.Errorf("cursor = %d, want 1", m.finishCursor)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.state != stateList || m.finishSession != nil {
+		t.Fatalf("state = %d, want the list", m.state)
+	}
+	m.applyFinishDraft(finishDraftMsg{sessionID: "s1", message: "Late"})
+	if m.promptInput.Value() == "Late" {
+		t.Error("a late draft filled the prompt")
+	}
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -21,8 +22,15 @@ func (m Model) handleNewNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.pendingInstance = nil
 		m.isParallelSession = false
 		m.parallelOriginalID = ""
+		m.newWorktree = false
 		m.state = stateList
 		return m, nil
+	case "tab":
+		// A parallel session shares its original's directory
+		if !m.isParallelSession {
+			m.newWorktree = !m.newWorktree
+		}
+		return m, nil
 	case "enter":
 		if m.nameInput.Value() != "" {
 			// Check if we're creating a parallel session
@@ -113,9 +121,21 @@ func (m Model) handleNewNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
-			// Check for existing agent sessions (for agents that support resume)
+			// Move it into a worktree of its own, on a new branch
+			if m.newWorktree {
+				m.newWorktree = false
+				if err := inst.UseWorktree(m.storage.WorktreesDir(m.worktreeRoot)); err != nil {
+					m.err = err
+					m.previousState = stateList
+					m.state = stateError
+					return m, nil
+				}
+			}
+
+			// Check for existing agent sessions (for agents that support resume).
+			// A new worktree has none to resume.
 			agentConfig := session.AgentConfigs[m.pendingAgent]
-			if agentConfig.SupportsResume {
+			if agentConfig.SupportsResume && inst.WorktreePath == "" {
 				// For Claude: ask user if they want new or continue existing
 				if m.pendingAgent == session.AgentClaude || m.pendingAgent == "" {
 					m.pendingInstance = inst
@@ -161,7 +181,7 @@ func (m Model) handleNewNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 
 			if err := m.storage.AddInstance(inst); err != nil {
-				m.err = err
+				m.err = errors.Join(err, inst.RemoveOwnWorktree(true))
 				m.previousState = stateList
 				m.state = stateError
 				return m, nil
@@ -219,6 +239,7 @@ func (m Model) handleNewPathKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 			m.nameInput.SetValue(folderName)
 			m.nameInput.Focus()
+			m.newWorktree = false
 			m.state = stateNewName
 			return m, textinput.Blink
 		}
@@ -407,11 +428,20 @@ func (m Model) handleSelectSessionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleConfirmDeleteKeys handles keyboard input in the delete confirmation dialog
 func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "y", "Y":
+	key := msg.String()
+	// w and b only for a session with a worktree of its own
+	if (key == "w" || key == "b") && (m.deleteTarget == nil || m.deleteTarget.WorktreePath == "") {
+		return m, nil
+	}
+	switch key {
+	case "y", "Y", "w", "b":
+		var worktreeErr error
 		if m.deleteTarget != nil {
 			if err := m.storage.RemoveInstance(m.deleteTarget.ID); err != nil {
 				m.err = fmt.Errorf("failed to remove instance: %w", err)
+			} else if key == "w" || key == "b" {
+				// Stopped by now, so nothing runs in the worktree
+				worktreeErr = m.deleteTarget.RemoveOwnWorktree(key == "b")
 			}
 			// Reload instances
 			instances, err := m.storage.Load()
@@ -426,6 +456,9 @@ func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.deleteTarget = nil
 		m.state = stateList
+		if worktreeErr != nil {
+			m.showError(worktreeErr)
+		}
 	case "n", "N", "esc":
 		m.deleteTarget = nil
 		m.state = stateList
@@ -554,6 +587,24 @@ func (m Model) handleRenameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// handleDiffFilterKeys handles keyboard input in the diff filter dialog
+func (m Model) handleDiffFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateList
+		return m, nil
+	case "enter":
+		// An empty filter shows every file again
+		m.diffPane.SetFilter(m.nameInput.Value())
+		m.state = stateList
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
 // handleHelpKeys handles keyboard input in the help view
 func (m Model) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Get actual line count from help content
@@ -592,10 +643,27 @@ func (m Model) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handlePromptKeys handles keyboard input in the prompt dialog
 func (m Model) handlePromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.recallSearching {
+		return m.handleRecallSearchKeys(msg)
+	}
 	switch msg.String() {
 	case "esc":
 		m.state = stateList
 		return m, nil
+	case "up":
+		// Past prompts, from the first line, as in a shell
+		if m.promptInput.Line() == 0 && m.recallStep(-1) {
+			return m, nil
+		}
+	case "down":
+		if m.promptInput.Line() == m.promptInput.LineCount()-1 && m.recallStep(1) {
+			return m, nil
+		}
+	case "ctrl+r":
+		if len(m.recallTexts) > 0 {
+			return m, m.openRecallSearch()
+		}
+		return m, nil
 	case "tab":
 		// Accept suggestion if available and input is empty
 		if m.promptSuggestion != "" && m.promptInput.Value() == "" {
@@ -604,12 +672,56 @@ func (m Model) handlePromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "ctrl+s", "ctrl+enter":
 		// Send message with Ctrl+S or Ctrl+Enter
-		if m.promptInput.Value() != "" {
+		if m.promptInput.Value() != "" || len(m.promptAttachments) > 0 {
 			if inst := m.getSelectedInstance(); inst != nil && inst.Status == session.StatusRunning {
+				// Attachments are read now, and written in the tab's agent's form
+				text, err := inst.ExpandAttachments(inst.GetCurrentWindowIndex(), m.promptInput.Value(), m.promptAttachments)
+				if err != nil {
+					m.showError(err)
+					return m, nil
+				}
 				// Send prompt text followed by Enter in a single command
-				text := m.promptInput.Value()
+				window := inst.GetCurrentWindowIndex()
+				activity := m.windowActivityState[inst.ID][window]
 				if err := inst.SendPrompt(text); err != nil {
 					m.err = err
+				} else {
+					m.promptLog.Append(inst.SentPrompt(window, text, session.SentFromDialog, activity))
+				}
+			}
+			m.state = stateList
+			return m, nil
+		}
+	case "ctrl+l":
+		return m, m.openSnippetPicker()
+	case "ctrl+o":
+		m.openAttach()
+		return m, nil
+	case "ctrl+x":
+		// Remove the last attachment
+		if n := len(m.promptAttachments); n > 0 {
+			m.promptAttachments = m.promptAttachments[:n-1]
+		}
+		return m, nil
+	case "ctrl+q":
+		// Queue for the tab in view, to be sent when it next finishes. Its
+		// attachments are read now: the queue keeps text.
+		if m.promptInput.Value() != "" || len(m.promptAttachments) > 0 {
+			if inst := m.getSelectedInstance(); inst != nil {
+				text, err := inst.ExpandAttachments(inst.GetCurrentWindowIndex(), m.promptInput.Value(), m.promptAttachments)
+				if err != nil {
+					m.showError(err)
+					return m, nil
+				}
+				if err := inst.EnqueuePrompt(inst.GetCurrentWindowIndex(), text, time.Now()); err != nil {
+					m.showError(err)
+					return m, nil
+				}
+				if m.storage != nil {
+					if err := m.storage.UpdateInstance(inst); err != nil {
+						m.showError(err)
+						return m, nil
+					}
 				}
 			}
 			m.state = stateList
@@ -1070,9 +1182,7 @@ func (m Model) handleNewTabKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.newTabContinueExisting = false
 					m.state = stateList
 					attachCmd := session.TmuxCommand("attach-session", "-t", sessionName)
-					return m, tea.ExecProcess(attachCmd, func(err error) tea.Msg {
-						return reattachMsg{}
-					})
+					return m, m.execAttach(attachCmd, inst)
 				}
 
 				if m.newTabIsAgent {
@@ -1334,6 +1444,38 @@ func (m Model) handleResumeTabChoiceKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleAnswerPromptKeys handles keyboard input in the answer prompt dialog
+func (m Model) handleAnswerPromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	options := m.answerDialog.Options
+	switch key := msg.String(); key {
+	case "up", "k":
+		if m.answerCursor > 0 {
+			m.answerCursor--
+		}
+	case "down", "j":
+		if m.answerCursor < len(options)-1 {
+			m.answerCursor++
+		}
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if choice := int(key[0] - '1'); choice < len(options) {
+			m.sendAnswer(choice)
+		}
+	case "enter":
+		if len(options) > 0 {
+			m.sendAnswer(m.answerCursor)
+		}
+	case "tab":
+		m.loadAnswerWindow((m.answerWindow + 1) % len(m.answerWindows))
+	case "esc":
+		m.state = stateList
+	}
+	if m.state != stateAnswerPrompt {
+		m.answerTarget = nil
+		m.answerWindows = nil
+	}
+	return m, nil
+}
+
 // handleConfirmYoloKeys handles keyboard input in the YOLO mode confirmation dialog
 func (m Model) handleConfirmYoloKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -2423,9 +2565,7 @@ func (m Model) handleResumeChoiceKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.resumeTarget = nil
 					m.state = stateList
 					attachCmd := session.TmuxCommand("attach-session", "-t", sessionName)
-					return m, tea.ExecProcess(attachCmd, func(err error) tea.Msg {
-						return reattachMsg{}
-					})
+					return m, m.execAttach(attachCmd, inst)
 				} else {
 					// Tab window - kill and recreate with resume picker
 					target := fmt.Sprintf("%s:%d", sessionName, currentWindowIdx)
@@ -2482,9 +2622,7 @@ func (m Model) handleResumeChoiceKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.resumeTarget = nil
 					m.state = stateList
 					attachCmd := session.TmuxCommand("attach-session", "-t", sessionName)
-					return m, tea.ExecProcess(attachCmd, func(err error) tea.Msg {
-						return reattachMsg{}
-					})
+					return m, m.execAttach(attachCmd, inst)
 				}
 			} else {
 				// Session not running - just start with resume picker
@@ -145,6 +145,11 @@ func (m *Model) saveSettings() {
 		MarkedSessionID: m.markedSessionID,
 		Cursor:          m.cursor,
 		SplitFocus:      m.splitFocus,
+		Hooks:           m.projectHooks,
+		Approval:        m.projectApproval,
+		Stall:           m.stallConfig,
+		RateLimit:       m.rateLimitConfig,
+		Snippets:        m.projectSnippets,
 	})
 }
 
@@ -761,19 +766,94 @@ func (m Model) handleListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "D":
 		// Toggle diff view in preview pane
 		m.showDiff = !m.showDiff
+		m.showTimeline = false
 		if m.showDiff {
 			if inst := m.getSelectedInstance(); inst != nil {
 				m.diffPane.SetDiff(inst)
 			}
 		}
 
+	case "V":
+		// Cycle the diff layout: by width, unified, side by side
+		if m.showDiff {
+			m.diffPane.CycleLayout()
+		}
+
+	case "J", "K", "}", "{", "z", "Z", "L":
+		// Move between files and hunks, fold files and show the file list,
+		// in diff view
+		if !m.showDiff {
+			break
+		}
+		switch msg.String() {
+		case "J":
+			m.diffPane.NextFile()
+		case "K":
+			m.diffPane.PrevFile()
+		case "}":
+			m.diffPane.NextHunk()
+		case "{":
+			m.diffPane.PrevHunk()
+		case "z":
+			m.diffPane.ToggleFold()
+		case "Z":
+			m.diffPane.ToggleFoldAll()
+		case "L":
+			m.diffPane.ToggleFiles()
+		}
+
+	case "+", "-", "!":
+		// Stage, unstage or discard the selected hunk or file, in diff view
+		if m.showDiff {
+			return m.handleDiffPatchKey(msg.String())
+		}
+
+	case "j":
+		// Next line of the diff's hunks, in diff view
+		if m.showDiff {
+			m.diffPane.NextLine()
+		}
+
+	case "k":
+		// Previous line of the diff's hunks, in diff view
+		if m.showDiff {
+			m.diffPane.PrevLine()
+		}
+
+	case "i":
+		// Comment on the selected line or hunk, in diff view
+		if m.showDiff {
+			m.openReviewComment()
+		}
+
+	case "ctrl+r":
+		// Review comments of the selected session, to send to its agent
+		m.openReview()
+
+	case "ctrl+g":
+		// Filter the diff's files by path
+		if m.showDiff {
+			m.nameInput.SetValue(m.diffPane.Filter())
+			m.nameInput.Focus()
+			m.nameInput.CursorEnd()
+			m.state = stateDiffFilter
+		}
+
+	case "A":
+		// Toggle activity timeline in preview pane
+		m.showTimeline = !m.showTimeline
+		m.showDiff = false
+
 	case "F":
-		// Toggle diff mode (Session/Full) when in diff view
+		// Toggle diff mode (Session/Full) when in diff view,
+		// or cycle the time range when in timeline view
 		if m.showDiff {
 			m.diffPane.ToggleMode()
 			if inst := m.getSelectedInstance(); inst != nil {
 				m.diffPane.SetDiff(inst)
 			}
+		} else if m.showTimeline {
+			m.timelineRange = (m.timelineRange + 1) % len(timelineRanges)
 		}
 
 	case "I":
@@ -833,9 +913,44 @@ func (m Model) handleListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.buildVisibleItems()
 		}
 
+	case "M":
+		// Toggle notifications for this session
+		inst := m.getSelectedInstance()
+		if inst != nil {
+			inst.Muted = !inst.Muted
+			m.storage.UpdateInstance(inst)
+		}
+
+	case "y":
+		m.handleAnswerPrompt()
+
 	case "p":
 		m.handleSendPrompt()
 
+	case "Q":
+		m.openQueue()
+
+	case "B":
+		m.openBroadcast()
+
+	case "S":
+		m.openSchedules()
+
+	case "H":
+		m.openHistory()
+
+	case "P":
+		m.openPipelines()
+
+	case "C":
+		return m, m.openCompare()
+
+	case "O":
+		m.openHandoff()
+
+	case "X":
+		m.openFinish()
+
 	case "R":
 		m.handleForceResize()
 
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -49,8 +50,9 @@ func configureTmuxStatusBarWithYolo(sessionName, instanceName, fgColor, bgColor
 	// Status bar style - dark background
 	session.TmuxCommand("set-option", "-t", target, "status-style", "bg=#1a1a2e,fg=#888888").Run()
 
-	// Get window list with names, index, active status, and dead status
-	windowListOutput, _ := session.TmuxCommand("list-windows", "-t", sessionName, "-F", "#{window_index}:#{window_name}:#{window_active}:#{pane_dead}").Output()
+	// Get window list with names, index, active status, dead status, and the
+	// stalled mark the status poll keeps in a window option
+	windowListOutput, _ := session.TmuxCommand("list-windows", "-t", sessionName, "-F", "#{window_index}:#{window_name}:#{window_active}:#{pane_dead}:#{@asmgr_stalled}").Output()
 	windowLines := strings.Split(strings.TrimSpace(string(windowListOutput)), "\n")
 
 	// Build status line with session name and tabs
@@ -78,11 +80,16 @@ func configureTmuxStatusBarWithYolo(sessionName, instanceName, fgColor, bgColor
 			windowName := parts[1]
 			isActive := parts[2] == "1"
 			isDead := parts[3] == "1"
+			isStalled := len(parts) > 4 && parts[4] == "1"
 
 			deadPrefix := ""
 			if isDead {
 				deadPrefix = "○ "
 			}
+			stalledIndicator := ""
+			if isStalled && !isDead {
+				stalledIndicator = " #[fg=" + ColorMagenta + "]◉"
+			}
 
 			// Show YOLO indicator for this window if it has YOLO enabled
 			yoloIndicator := ""
@@ -93,14 +100,22 @@ func configureTmuxStatusBarWithYolo(sessionName, instanceName, fgColor, bgColor
 			}
 
 			if isActive {
-				statusLeft.WriteString(fmt.Sprintf("#[fg=#FAFAFA,bold]%s%s#[nobold]%s", deadPrefix, windowName, yoloIndicator))
+				statusLeft.WriteString(fmt.Sprintf("#[fg=#FAFAFA,bold]%s%s#[nobold]%s%s", deadPrefix, windowName, yoloIndicator, stalledIndicator))
 				statusLeft.WriteString("#[fg=#555555] | ")
 			} else {
-				statusLeft.WriteString(fmt.Sprintf("#[fg=#888888]%s%s%s #[fg=#555555]| ", deadPrefix, windowName, yoloIndicator))
+				statusLeft.WriteString(fmt.Sprintf("#[fg=#888888]%s%s%s%s #[fg=#555555]| ", deadPrefix, windowName, yoloIndicator, stalledIndicator))
 			}
 		}
-	} else if windowYolo[0] {
-		statusLeft.WriteString("#[fg=#FFA500,bold]YOLO !")
+	} else {
+		var badges []string
+		if windowYolo[0] {
+			badges = append(badges, "#[fg=#FFA500,bold]YOLO !")
+		}
+		// With one window its stalled mark is the session's
+		if parts := strings.Split(windowLines[0], ":"); len(parts) > 4 && parts[3] != "1" && parts[4] == "1" {
+			badges = append(badges, "#[fg="+ColorMagenta+",bold]◉ STALLED")
+		}
+		statusLeft.WriteString(strings.Join(badges, " "))
 	}
 
 	// Set status-left with our tab list
@@ -235,9 +250,7 @@ func (m *Model) handleEnterSession() tea.Cmd {
 	}
 
 	cmd := session.TmuxCommand("attach-session", "-t", sessionName)
-	return tea.ExecProcess(cmd, func(err error) tea.Msg {
-		return reattachMsg{}
-	})
+	return m.execAttach(cmd, inst)
 }
 
 // handleResumeSession shows agent sessions for the current instance's active tab
@@ -441,6 +454,8 @@ func (m *Model) handleSendPrompt() {
 		return
 	}
 	m.promptInput.SetValue("")
+	m.promptAttachments = nil
+	m.loadRecall(inst)
 	inputWidth := PromptMinWidth
 	if m.width > 80 {
 		inputWidth = m.width/2 - 10
@@ -457,6 +472,66 @@ func (m *Model) handleSendPrompt() {
 	m.state = statePrompt
 }
 
+// handleAnswerPrompt opens the answer dialog on the selected session's first
+// waiting tab.
+func (m *Model) handleAnswerPrompt() {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return
+	}
+	windows := m.waitingWindows(inst)
+	if len(windows) == 0 {
+		m.showError(fmt.Errorf("%s is not waiting for an answer", inst.Name))
+		return
+	}
+	m.answerTarget = inst
+	m.answerWindows = windows
+	m.loadAnswerWindow(0)
+	m.state = stateAnswerPrompt
+}
+
+// waitingWindows lists the session's waiting tabs by window index, as the
+// last poll saw them.
+func (m *Model) waitingWindows(inst *session.Instance) []int {
+	var windows []int
+	for index, activity := range m.windowActivityState[inst.ID] {
+		if activity == session.ActivityWaiting {
+			windows = append(windows, index)
+		}
+	}
+	sort.Ints(windows)
+	return windows
+}
+
+// loadAnswerWindow reads the prompt of the idx-th waiting tab, with the
+// cursor where the agent has it.
+func (m *Model) loadAnswerWindow(idx int) {
+	m.answerWindow = idx
+	m.answerCursor = 0
+	m.answerDialog, _ = m.answerTarget.ReadPromptDialog(m.answerWindows[idx])
+	for i, option := range m.answerDialog.Options {
+		if option.Selected {
+			m.answerCursor = i
+		}
+	}
+}
+
+// sendAnswer picks an option in the prompt the dialog showed. The pane is
+// read again first: if the agent has moved on to another question, an answer
+// meant for the old one is not sent to it.
+func (m *Model) sendAnswer(choice int) {
+	inst, window := m.answerTarget, m.answerWindows[m.answerWindow]
+	m.state = stateList
+	current, ok := inst.ReadPromptDialog(window)
+	if !ok || !current.Same(m.answerDialog) {
+		m.showError(fmt.Errorf("the prompt in %s changed before the answer was sent; press y to read it again", inst.Name))
+		return
+	}
+	if err := inst.AnswerPrompt(window, current, choice); err != nil {
+		m.showError(fmt.Errorf("failed to answer: %w", err))
+	}
+}
+
 // handleForceResize forces resize of the selected pane
 func (m *Model) handleForceResize() {
 	inst := m.getSelectedInstance()
@@ -531,9 +606,7 @@ func (m *Model) launchAgentResume(inst *session.Instance) tea.Cmd {
 
 		// Attach to the session
 		attachCmd := session.TmuxCommand("attach-session", "-t", sessionName)
-		return tea.ExecProcess(attachCmd, func(err error) tea.Msg {
-			return reattachMsg{}
-		})
+		return m.execAttach(attachCmd, inst)
 	}
 
 	// Build the resume command for running session (picker mode)
@@ -561,9 +634,7 @@ func (m *Model) launchAgentResume(inst *session.Instance) tea.Cmd {
 
 	// Attach to the session
 	attachCmd := session.TmuxCommand("attach-session", "-t", sessionName)
-	return tea.ExecProcess(attachCmd, func(err error) tea.Msg {
-		return reattachMsg{}
-	})
+	return m.execAttach(attachCmd, inst)
 }
 
 // handleToggleAutoYes shows confirmation dialog for toggling YOLO mode on the active tab
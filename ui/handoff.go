@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Handing the selected tab's task to another agent.
+//
+// The dialog picks the agent and how many turns to carry, then shows the
+// handoff prompt to read and edit before it goes. Sending opens a new tab
+// of the session running that agent, in the same directory; waiting for the
+// agent to be ready and typing the prompt happen off the UI thread.
+
+// handoffSentMsg reports the handoff prompt sent, or not.
+type handoffSentMsg struct {
+	err error
+}
+
+// handoffAgents are the agents the handoff can go to: any but the one
+// handing over.
+func (m Model) handoffAgents() []session.AgentType {
+	var agents []session.AgentType
+	for _, a := range compareAgents() {
+		if a != m.handoffFrom {
+			agents = append(agents, a)
+		}
+	}
+	return agents
+}
+
+// openHandoff opens the handoff dialog on the selected session's current
+// tab.
+func (m *Model) openHandoff() {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return
+	}
+	if inst.Status != session.StatusRunning {
+		m.showError(fmt.Errorf("session not running"))
+		return
+	}
+	window := inst.GetCurrentWindowIndex()
+	fw := inst.GetFollowedWindow(window)
+	if fw == nil || fw.Agent == session.AgentTerminal {
+		m.showError(fmt.Errorf("the current tab of %s is not an agent", inst.Name))
+		return
+	}
+	m.handoffSession = inst
+	m.handoffWindow = window
+	m.handoffTab = fw.Name
+	m.handoffFrom = fw.Agent
+	if m.handoffFrom == "" {
+		m.handoffFrom = session.AgentClaude
+	}
+	m.handoffCursor = 0
+	if m.handoffTurns == 0 {
+		m.handoffTurns = session.DefaultHandoffTurns
+	}
+	m.handoffEditing = false
+	m.handoffErr = ""
+	m.promptInput.Blur()
+	m.state = stateHandoff
+}
+
+// handleHandoffKeys handles keyboard input in the handoff dialog: the
+// agent and turns, then the prompt.
+func (m Model) handleHandoffKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if m.handoffEditing {
+		switch key {
+		case "esc":
+			m.handoffEditing = false
+			m.promptInput.Blur()
+			m.promptInput.CharLimit = promptCharLimit
+			return m, nil
+		case "ctrl+s":
+			return m, m.sendHandoff()
+		}
+		var cmd tea.Cmd
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
+	}
+
+	agents := m.handoffAgents()
+	switch key {
+	case "esc", "q":
+		m.state = stateList
+	case "up", "k":
+		if m.handoffCursor > 0 {
+			m.handoffCursor--
+		}
+	case "down", "j":
+		if m.handoffCursor < len(agents)-1 {
+			m.handoffCursor++
+		}
+	case "+", "=":
+		if m.handoffTurns < 50 {
+			m.handoffTurns++
+		}
+	case "-":
+		if m.handoffTurns > 1 {
+			m.handoffTurns--
+		}
+	case "enter":
+		// Read the handoff now, to review before it goes
+		h, err := m.handoffSession.PrepareHandoff(m.handoffWindow, m.handoffTurns)
+		if err != nil {
+			m.handoffErr = err.Error()
+			return m, nil
+		}
+		m.handoffErr = ""
+		// A transcript is longer than anything typed by hand
+		m.promptInput.CharLimit = 0
+		m.promptInput.SetValue(h.Prompt())
+		m.handoffEditing = true
+		return m, m.promptInput.Focus()
+	}
+	return m, nil
+}
+
+// sendHandoff opens the new agent's tab and sends it the reviewed prompt.
+func (m *Model) sendHandoff() tea.Cmd {
+	inst := m.handoffSession
+	agents := m.handoffAgents()
+	text := m.promptInput.Value()
+	if inst == nil || m.handoffCursor >= len(agents) || text == "" {
+		return nil
+	}
+	if !inst.IsAlive() {
+		m.handoffErr = "The session is not running"
+		return nil
+	}
+	agent := agents[m.handoffCursor]
+	if err := session.CheckAgentCommand(&session.Instance{Agent: agent}); err != nil {
+		m.handoffErr = err.Error()
+		return nil
+	}
+	window, err := inst.NewAgentWindow(string(agent)+"-handoff", agent, "")
+	if err != nil {
+		m.handoffErr = fmt.Sprintf("failed to open the tab: %s", err)
+		return nil
+	}
+	configureTmuxStatusBar(inst.TmuxSessionName(), inst.Name, inst.Color, inst.BgColor, inst.AutoYes)
+	m.storage.UpdateInstance(inst)
+
+	m.promptInput.Blur()
+	m.promptInput.CharLimit = promptCharLimit
+	m.handoffEditing = false
+	m.state = stateList
+	history := m.promptLog
+	return func() tea.Msg {
+		return handoffSentMsg{err: session.SendFirstPrompt(inst, window, text, session.SentFromHandoff, history)}
+	}
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// The handoff goes to any agent but the one handing over, and the review of
+// a long transcript does not leave the prompt dialog without its limit.
+func TestHandoffDialog(t *testing.T) {
+	m := newTestModel()
+	m.promptInput = textarea.New()
+	m.promptInput.CharLimit = promptCharLimit
+	m.state = stateHandoff
+	m.handoffFrom = session.AgentClaude
+	m.handoffTurns = 1
+
+	for _, a := range m.handoffAgents() {
+		if a == session.AgentClaude || a == session.AgentCustom {
+			t.Errorf("can hand off to %s", a)
+		}
+	}
+
+	press := func(msg tea.KeyMsg) {
+		model, _ := m.handleHandoffKeys(msg)
+		next := model.(Model)
+		m = &next
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("-")})
+	if m.handoffTurns != 1 {
+		t.Errorf("turns = %d, want at least 1", m.handoffTurns)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if m.handoffTurns != 2 {
+		t.Errorf("turns = %d, want 2", m.handoffTurns)
+	}
+
+	m.handoffEditing = true
+	m.promptInput.CharLimit = 0
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.handoffEditing || m.promptInput.CharLimit != promptCharLimit {
+		t.Errorf("editing = %v, limit = %d", m.handoffEditing, m.promptInput.CharLimit)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.state != stateList {
+		t.Errorf("state = %d, want the list", m.state)
+	}
+}
@@ -174,6 +174,7 @@ func stripANSI(s string) string {
 // sessionStatusCounts holds the count of sessions in each status
 type sessionStatusCounts struct {
 	active  int
+	stalled int
 	waiting int
 	idle    int
 	stopped int
@@ -188,6 +189,8 @@ func (m *Model) countSessionStatuses() sessionStatusCounts {
 			switch m.activityState[inst.ID] {
 			case session.ActivityBusy:
 				counts.active++
+			case session.ActivityStalled:
+				counts.stalled++
 			case session.ActivityWaiting:
 				counts.waiting++
 			default:
@@ -214,6 +217,10 @@ func (m *Model) buildSessionListHeader(listWidth int) string {
 			waitingStyle.Render("●"), counts.waiting,
 			idleStyle.Render("●"), counts.idle,
 			stoppedStyle.Render("○"), counts.stopped)
+		// Stalled sessions are rare; the count only takes room when there are some
+		if counts.stalled > 0 {
+			countsStr += fmt.Sprintf(" %s %d", stalledStyle.Render("◉"), counts.stalled)
+		}
 		header += dimStyle.Render(countsStr)
 	}
 	sb.WriteString(header)
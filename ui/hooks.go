@@ -0,0 +1,65 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/hooks"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// hookErrorMsg carries the failures of a batch of hooks back to the update
+// loop, for the error overlay.
+type hookErrorMsg struct{ err error }
+
+// hookTargets resolves the hooks for the sessions events belong to. Only
+// sessions with something to run are included.
+func hookTargets(global session.Hooks, project *session.Hooks, projectName string, instances []*session.Instance, events []session.ActivityEvent) map[string]hooks.Target {
+	wanted := make(map[string]bool, len(events))
+	for _, e := range events {
+		wanted[e.SessionID] = true
+	}
+	targets := make(map[string]hooks.Target)
+	for _, inst := range instances {
+		if !wanted[inst.ID] {
+			continue
+		}
+		resolved := hooks.Resolve(global, project, inst)
+		if resolved == (session.Hooks{}) {
+			continue
+		}
+		targets[inst.ID] = hooks.Target{Hooks: resolved, Path: inst.Path, Project: projectName, Inst: inst}
+	}
+	return targets
+}
+
+// projectName is the active project's name, or "" for the default project.
+func (m *Model) projectName() string {
+	if m.activeProject == nil {
+		return ""
+	}
+	return m.activeProject.Name
+}
+
+// hooksCmd runs the hooks the events call for, off the UI thread: a hook may
+// take up to its timeout. A hooks.json that failed to load is reported here
+// too, once, since the project switch that read it has no overlay to show.
+func (m *Model) hooksCmd(events []session.ActivityEvent) tea.Cmd {
+	if err := m.hooksLoadErr; err != nil {
+		m.hooksLoadErr = nil
+		return tea.Batch(func() tea.Msg { return hookErrorMsg{err} }, m.hooksCmd(events))
+	}
+	if m.hookRunner == nil || len(events) == 0 {
+		return nil
+	}
+	targets := hookTargets(m.hooksConfig.Hooks, m.projectHooks, m.projectName(), m.instances, events)
+	if len(targets) == 0 {
+		return nil
+	}
+	// Queued here, in poll order: two polls' commands may run in either
+	handle := m.hookRunner.Queue(events, targets)
+	return func() tea.Msg {
+		if err := handle(); err != nil {
+			return hookErrorMsg{err}
+		}
+		return nil
+	}
+}
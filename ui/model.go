@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,6 +11,9 @@ import (
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/approval"
+	"github.com/izll/agent-session-manager/hooks"
+	"github.com/izll/agent-session-manager/notify"
 	"github.com/izll/agent-session-manager/session"
 	"github.com/izll/agent-session-manager/updater"
 )
@@ -115,8 +119,26 @@ const (
 	stateResumeChoice            // Choose between new tab or replace for resume
 	stateNewSessionChoice        // Choose between new session or continue existing
 	stateNewTabSessionChoice     // Choose between new session or continue existing for new tab
+	stateAnswerPrompt            // Answering a waiting tab's permission prompt
+	stateQueue                   // Managing a session's prompt queues
+	stateBroadcast               // Sending one prompt to several sessions
+	stateSnippetPicker           // Picking a saved prompt for the prompt dialog
+	stateSchedules               // Scheduled prompts of the project
+	stateAttach                  // Adding an attachment to the prompt dialog
+	stateHistory                 // Prompts sent to the project's sessions
+	statePipelines               // Pipelines passing answers between sessions
+	stateCompare                 // Agents compared on one prompt
+	stateHandoff                 // Handing a tab's task to another agent
+	stateFinish                  // Committing and finishing a session's work
+	stateDiffFilter              // Filtering the diff's files by path
+	stateConfirmDiscard          // Confirming a hunk or file discarded from the diff
+	stateReviewComment           // Writing a review comment on the diff
+	stateReview                  // A session's review comments, to send to its agent
 )
 
+// promptCharLimit is the most a prompt typed in the prompt dialog may hold.
+const promptCharLimit = 5000
+
 // Model represents the main TUI application state for Agent Session Manager.
 // It manages multiple Claude Code instances, handles user input, and renders
 // the split-pane interface with session list and preview.
@@ -154,6 +176,8 @@ type Model struct {
 	isActive             map[string]bool                            // Whether instance has recent activity
 	activityState        map[string]session.SessionActivity         // Activity state (idle/busy/waiting)
 	windowActivityState  map[string]map[int]session.SessionActivity // Window-level activity (session ID -> window index -> activity)
+	rateLimits           map[string]map[int]session.RateLimit       // Limit messages on screen (session ID -> window index -> limit)
+	agentReports         map[string]map[int]session.AgentReport     // Hook reports (session ID -> window index -> report)
 	// mainWindowIndex is the agent's own window per session, recorded by the
 	// poll so rendering can look its activity up without asking tmux on every
 	// frame. Keyed by session ID; absent for a session that is not running.
@@ -205,6 +229,152 @@ type Model struct {
 	diffPane *DiffPane // Diff display component
 	showDiff bool      // Show diff tab instead of preview
 
+	// Activity timeline
+	activityRecorder *session.ActivityRecorder // Transition history for the active project
+	showTimeline     bool                      // Show timeline tab instead of preview
+	timelineRange    int                       // Index into timelineRanges
+
+	// Notifications
+	notifyConfig notify.Config // notifications.json, reread on project switch
+
+	// User hooks
+	hooksConfig  hooks.Config   // hooks.json, reread on project switch
+	projectHooks *session.Hooks // The active project's hooks, from its settings
+	hookRunner   *hooks.Runner  // Shared with the attach watcher
+	hooksLoadErr error          // A broken hooks.json, reported by the next poll
+
+	// Auto-approval
+	projectApproval *session.ApprovalPolicy // The active project's rules, from its settings
+	approver        *approval.Approver      // Shared with the attach watcher
+
+	// Stalled agents
+	stallConfig *session.StallConfig // The active project's stall settings
+
+	// Rate limits
+	rateLimitConfig *session.RateLimitConfig // The active project's auto-continue settings
+	continuer       *autoContinuer           // Shared with the attach watcher
+
+	// Snippets
+	projectSnippets []session.Snippet // The active project's, from its settings
+	snippets        []session.Snippet // Those offered by the open picker
+	snippetMatches  []int             // Indexes into snippets matching the filter
+	snippetCursor   int               // Index into snippetMatches
+	snippetFilter   textinput.Model   // Fuzzy filter, then the answer to a question
+	snippetChosen   *session.Snippet  // Picked, with questions still to answer
+	snippetAsk      []string          // Its {{ask:...}} labels
+	snippetAnswers  map[string]string // Answers so far, by label
+	snippetErr      string            // Why the last pick could not be used
+
+	// Session worktrees
+	worktreeRoot string // The active project's worktree_root setting
+	newWorktree  bool   // The session being made gets a worktree of its own
+
+	// Prompt attachments
+	promptAttachments []session.Attachment // Sent with the prompt dialog's text
+	attachStep        int                  // attachMenu, attachFile, ...
+	attachCursor      int                  // Row in the current step
+	attachFilter      textinput.Model      // The file finder's filter
+	attachFiles       []string             // The session's files
+	attachMatches     []int                // Indexes into attachFiles matching the filter
+	attachWindows     []session.WindowInfo // Tabs whose panes can be attached
+	attachLines       int                  // How many lines of a pane
+	attachErr         string               // Why the last choice could not be attached
+
+	// Prompt history
+	promptLog         *session.PromptHistory // The active project's prompts.jsonl
+	recallTexts       []string               // The prompt dialog's session's past prompts, oldest first
+	recallIndex       int                    // Into recallTexts; len(recallTexts) while editing the draft
+	recallDraft       string                 // What was typed before Up recalled a prompt
+	recallSearching   bool                   // ctrl+r search is open
+	recallSearch      textinput.Model        // Its query
+	recallMatch       int                    // Index into recallTexts of its match, -1 for none
+	historyEntries    []session.SentPrompt   // The history panel's rows, newest first
+	historyAll        bool                   // Every session's prompts, not just historySession's
+	historySession    *session.Instance      // The session selected when the panel opened
+	historyCursor     int                    // Index into historyEntries
+	historyPicking    bool                   // Choosing the session to send to
+	historyPickCursor int                    // Index into historyTargets()
+	historyErr        string                 // Why the last re-send was refused
+
+	// Pipelines
+	pipelines          []*session.Pipeline // The active project's pipelines.json
+	pipelinesLoadErr   error               // A broken pipelines.json: nothing runs until it is fixed
+	pipelineCursor     int                 // Index into pipelines
+	pipelineEditing    bool                // The editor is open
+	pipelineDraft      session.Pipeline    // What the editor is changing; no ID for a new one
+	pipelineStage      int                 // The stage under the editor's cursor
+	pipelineFocus      int                 // 0: stages, 1: the stage's template
+	pipelinePicking    bool                // Choosing a session to add as a stage
+	pipelinePickCursor int                 // Index into instances
+	pipelineErr        string              // Why the last change was refused
+
+	// Agent comparisons
+	comparisons        []*session.Comparison          // The active project's comparisons.json
+	comparisonsLoadErr error                          // A broken comparisons.json: nothing is recorded until it is fixed
+	compareStep        int                            // compareList, compareSetup, ...
+	compareIndex       int                            // The comparison shown, index into comparisons
+	compareCursor      int                            // The run under the cursor
+	compareMarked      []string                       // Session IDs of the runs to show side by side, at most two
+	compareStats       map[string]session.DiffSummary // The shown comparison's changes, by session ID
+	compareStatsErrs   map[string]string              // Why a run's changes could not be counted
+	compareDir         string                         // The setup's repository, from the selected session
+	compareName        textinput.Model                // The setup's name
+	compareChosen      map[session.AgentType]bool     // The setup's agents
+	compareAgentCursor int                            // Index into compareAgents()
+	compareYolo        bool                           // Start the agents in YOLO mode
+	compareFocus       int                            // 0: name, 1: agents, 2: prompt
+	compareDiffs       [2][]string                    // The side by side diffs' lines
+	compareTitles      [2]string                      // Their sessions' names
+	compareScroll      int                            // The first line shown of both
+	compareErr         string                         // Why the last change was refused
+
+	// Handoff
+	handoffSession *session.Instance // The session handing over
+	handoffWindow  int               // Its tab handing over
+	handoffTab     string            // That tab's name
+	handoffFrom    session.AgentType // That tab's agent
+	handoffCursor  int               // Index into handoffAgents()
+	handoffTurns   int               // How many turns to carry
+	handoffEditing bool              // Reviewing the prompt in promptInput
+	handoffErr     string            // Why the last step failed
+
+	// Finishing a session's work
+	finishSession *session.Instance // The session being finished
+	finishStep    int               // finishMessage, finishActions
+	finishDiff    []string          // Its session diff's lines
+	finishSummary string            // The diff's counts, or why there is no diff
+	finishDirty   bool              // Something to commit, so a message is needed
+	finishStaged  bool              // Something staged: only that is committed
+	finishScroll  int               // First diff line shown
+	finishCursor  int               // Index into the session's FinishActions()
+	finishBusy    string            // What is running off the UI thread, if anything
+	finishErr     string            // Why the last step failed
+
+	// Review comments on the diff
+	reviewSession *session.Instance // The session commented on or reviewed
+	reviewFile    session.DiffFile  // The file a comment is being written on
+	reviewHunk    int               // Its hunk
+	reviewLine    int               // Its line, or -1 for the whole hunk
+	reviewTarget  string            // Where the comment goes, for the dialog
+	reviewCursor  int               // Index into the session's ReviewComments
+	reviewErr     string            // Why the last action failed
+
+	// Discarding part of the diff, once confirmed
+	discardSession *session.Instance // Whose working tree it is discarded from
+	discardPatch   string            // The hunk or file, as a patch
+	discardLabel   string            // What it is, for the confirmation
+
+	// Scheduled prompts
+	schedules        []*session.Schedule // The active project's schedules.json
+	schedulesLoadErr error               // A broken schedules.json: nothing runs until it is fixed
+	scheduleCursor   int                 // Index into schedules
+	scheduleSession  *session.Instance   // The session selected when the view opened
+	scheduleEditing  bool                // The editor is open
+	scheduleDraft    session.Schedule    // What the editor is changing; no ID for a new one
+	scheduleWhen     textinput.Model     // The editor's time or cron expression
+	scheduleFocus    int                 // 0: when, 1: prompt
+	scheduleErr      string              // Why the last change was refused
+
 	// Fork dialog
 	forkNameInput textinput.Model   // Input for fork name
 	forkToTab     bool              // true = fork to new tab, false = fork to new session
@@ -257,6 +427,25 @@ type Model struct {
 	resumeTabTarget      *session.Instance        // Target instance for resume tab
 	resumeTabStoppedTabs []session.FollowedWindow // List of stopped tabs
 	resumeTabCursor      int                      // Cursor for selecting stopped tab
+
+	// Answer permission prompt
+	answerTarget  *session.Instance    // Session with the waiting tab
+	answerWindows []int                // Its waiting tabs, by window index
+	answerWindow  int                  // Index into answerWindows
+	answerDialog  session.PromptDialog // The prompt as read when the dialog opened
+	answerCursor  int                  // Option under the cursor
+
+	// Prompt queue view
+	queueTarget  *session.Instance // Session whose queues are shown
+	queueCursor  int               // Row under the cursor, across its tabs
+	queueEditing bool              // Editing the row in promptInput
+
+	// Broadcast dialog
+	broadcastStep     int               // broadcastPick, broadcastMessage, ...
+	broadcastSelected map[string]bool   // Selected sessions, by ID
+	broadcastCursor   int               // Row under the cursor, into m.instances
+	broadcastGroup    int               // Group "g" last selected, -1 for none
+	broadcastPlan     []broadcastTarget // The dry run, then the outcome
 }
 
 // globalSearchMatch represents a matched session/tab for selection
@@ -286,7 +475,9 @@ type sessionGroupItem struct {
 type tickMsg time.Time
 
 // reattachMsg is sent when returning from an attached session
-type reattachMsg struct{}
+type reattachMsg struct {
+	err error // Hooks and approvals that failed while attached
+}
 
 // globalSearchDebounceMsg triggers delayed search after typing stops
 type globalSearchDebounceMsg struct{}
@@ -318,7 +509,7 @@ func NewModel() (Model, error) {
 
 	promptInput := textarea.New()
 	promptInput.Placeholder = "Enter message to send..."
-	promptInput.CharLimit = 5000
+	promptInput.CharLimit = promptCharLimit
 	promptInput.ShowLineNumbers = false
 	promptInput.Prompt = ""
 	promptInput.SetHeight(10)
@@ -390,6 +581,8 @@ func NewModel() (Model, error) {
 		isActive:             make(map[string]bool),
 		activityState:        make(map[string]session.SessionActivity),
 		windowActivityState:  make(map[string]map[int]session.SessionActivity),
+		rateLimits:           make(map[string]map[int]session.RateLimit),
+		agentReports:         make(map[string]map[int]session.AgentReport),
 		mainWindowIndex:      make(map[string]int),
 		diffPane:             NewDiffPane(),
 		updateAvailable:      updater.GetCachedAvailableUpdate(), // Load cached update
@@ -530,8 +723,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if inst := m.getSelectedInstance(); inst != nil {
 				checkTime := m.resumeSyncTime.Add(-5 * time.Second)
 				// Try multiple methods to find the active session ID
+				// 0. The agent's own hook report, when hooks are installed
+				var newID string
+				if report, ok := inst.AgentReportForWindow(m.resumeSyncWindowIdx); ok && !report.Time.Before(checkTime) {
+					newID = report.SessionID
+				}
 				// 1. Debug logs (most reliable - captures SessionStart even without interaction)
-				newID := session.GetActiveSessionFromDebugLogs(inst.Path, checkTime)
+				if newID == "" {
+					newID = session.GetActiveSessionFromDebugLogs(inst.Path, checkTime)
+				}
 				// 2. Fallback to history.jsonl (only updated when user sends message)
 				if newID == "" {
 					newID = session.GetActiveSessionIDFromHistory(inst.Path, checkTime)
@@ -566,6 +766,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.resumeSyncTime = time.Time{}
 			m.resumeSyncWindowIdx = 0
 		}
+		if msg.err != nil {
+			m.showError(msg.err)
+		}
 		// Request window size to refresh dimensions after reattach
 		return m, tea.Batch(tea.ClearScreen, tea.EnableMouseCellMotion, tea.WindowSize())
 
@@ -673,7 +876,108 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case statusPollResultMsg:
 		m.statusPollRunning = false
-		m.applyStatusPoll(msg)
+		events := m.applyStatusPoll(msg)
+		if len(msg.errs) > 0 && m.state != stateError {
+			m.showError(errors.Join(msg.errs...))
+		}
+		// Pipelines look at the queues before queueCmd takes from them, and
+		// the queue leaves alone the tabs being continued
+		continued := m.continuer.due(msg, time.Now())
+		return m, tea.Batch(m.notifyCmd(events), m.hooksCmd(events), m.stallCmd(events), m.continueCmd(continued), m.pipelineCmd(events, msg), m.queueCmd(events, msg, continued), m.compareCmd(events))
+
+	case hookErrorMsg:
+		// The first failure stays up until dismissed; later ones would only
+		// bury it.
+		if m.state != stateError {
+			m.showError(msg.err)
+		}
+		return m, nil
+
+	case stallErrorMsg:
+		if m.state != stateError {
+			m.showError(msg.err)
+		}
+		return m, nil
+
+	case continueErrorMsg:
+		if m.state != stateError {
+			m.showError(msg.err)
+		}
+		return m, nil
+
+	case broadcastDoneMsg:
+		m.broadcastPlan = msg.targets
+		if m.state == stateBroadcast {
+			m.broadcastStep = broadcastResults
+		}
+		return m, nil
+
+	case queueErrorMsg:
+		requeuePrompts(msg.failed)
+		saveQueues(m.storage, msg.failed)
+		if m.state != stateError {
+			m.showError(msg.err)
+		}
+		return m, nil
+
+	case pipelineDoneMsg:
+		queued, err := applyPipelineResults(m.pipelines, msg.results)
+		err = errors.Join(err, saveQueues(m.storage, queued))
+		if err != nil && m.state != stateError {
+			m.showError(err)
+		}
+		m.savePipelines()
+		return m, nil
+
+	case compareSentMsg:
+		applyCompareSent(m.comparisons, msg)
+		m.saveComparisons()
+		if len(msg.errs) > 0 && m.state != stateCompare && m.state != stateError {
+			errs := make([]error, 0, len(msg.errs))
+			for _, err := range msg.errs {
+				errs = append(errs, err)
+			}
+			m.showError(errors.Join(errs...))
+		}
+		return m, nil
+
+	case compareStatsMsg:
+		if c := m.currentComparison(); c != nil && c.ID == msg.comparisonID {
+			m.compareStats, m.compareStatsErrs = msg.stats, msg.errs
+		}
+		return m, nil
+
+	case handoffSentMsg:
+		if msg.err != nil && m.state != stateError {
+			m.showError(fmt.Errorf("handoff not sent: %w", msg.err))
+		}
+		return m, nil
+
+	case finishDraftMsg:
+		m.applyFinishDraft(msg)
+		return m, nil
+
+	case finishDoneMsg:
+		m.applyFinishDone(msg)
+		return m, nil
+
+	case historySentMsg:
+		if msg.err != nil {
+			if m.state == stateHistory {
+				m.historyErr = msg.err.Error()
+			} else if m.state != stateError {
+				m.showError(msg.err)
+			}
+		} else if m.state == stateHistory {
+			m.loadHistory()
+			m.historyCursor = 0
+		}
+		return m, nil
+
+	case scheduleDoneMsg:
+		if err := recordScheduleResults(m.storage, m.schedules, m.instances, msg.results); err != nil && m.state != stateError {
+			m.showError(err)
+		}
 		return m, nil
 
 	case tickMsg:
@@ -757,6 +1061,36 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleConfirmStopTabKeys(msg)
 		case stateResumeTabChoice:
 			return m.handleResumeTabChoiceKeys(msg)
+		case stateAnswerPrompt:
+			return m.handleAnswerPromptKeys(msg)
+		case stateQueue:
+			return m.handleQueueKeys(msg)
+		case stateBroadcast:
+			return m.handleBroadcastKeys(msg)
+		case stateSnippetPicker:
+			return m.handleSnippetPickerKeys(msg)
+		case stateSchedules:
+			return m.handleSchedulesKeys(msg)
+		case stateAttach:
+			return m.handleAttachKeys(msg)
+		case stateHistory:
+			return m.handleHistoryKeys(msg)
+		case statePipelines:
+			return m.handlePipelinesKeys(msg)
+		case stateCompare:
+			return m.handleCompareKeys(msg)
+		case stateHandoff:
+			return m.handleHandoffKeys(msg)
+		case stateFinish:
+			return m.handleFinishKeys(msg)
+		case stateDiffFilter:
+			return m.handleDiffFilterKeys(msg)
+		case stateConfirmDiscard:
+			return m.handleConfirmDiscardKeys(msg)
+		case stateReviewComment:
+			return m.handleReviewCommentKeys(msg)
+		case stateReview:
+			return m.handleReviewKeys(msg)
 		case stateConfirmYolo:
 			return m.handleConfirmYoloKeys(msg)
 		case stateSearch:
@@ -835,9 +1169,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleTick processes tick messages for periodic UI updates
 func (m Model) handleTick() (tea.Model, tea.Cmd) {
+	// Scheduled prompts go out whatever dialog is open
+	scheduleCmd := m.checkSchedules(time.Now())
+
 	// Skip heavy processing during dialogs - only update in list view
 	if m.state != stateList {
-		return m, tickCmd()
+		return m, tea.Batch(tickCmd(), scheduleCmd)
 	}
 
 	m.tickCount++
@@ -868,7 +1205,7 @@ func (m Model) handleTick() (tea.Model, tea.Cmd) {
 		}
 		if len(toPoll) > 0 {
 			m.statusPollRunning = true
-			pollCmd = statusPollCmd(toPoll)
+			pollCmd = statusPollCmd(toPoll, m.approver)
 		}
 	}
 
@@ -883,13 +1220,14 @@ func (m Model) handleTick() (tea.Model, tea.Cmd) {
 
 		// Update diff content if showing diff tab (only on slow tick to avoid git overload)
 		if m.showDiff && slowTick {
+			// Comments whose lines the agent has changed are resolved
+			if selectedInst.UpdateReviewComments() && m.storage != nil {
+				m.storage.UpdateInstance(selectedInst)
+			}
 			m.diffPane.SetDiff(selectedInst)
 		}
 	}
-	if pollCmd != nil {
-		return m, tea.Batch(tickCmd(), pollCmd)
-	}
-	return m, tickCmd()
+	return m, tea.Batch(tickCmd(), pollCmd, scheduleCmd)
 }
 
 // calculatePreviewWidth returns the width for the preview panel
@@ -1197,6 +1535,31 @@ func (m *Model) switchToProject(project *session.Project) error {
 	m.isActive = make(map[string]bool)
 	m.activityState = make(map[string]session.SessionActivity)
 	m.windowActivityState = make(map[string]map[int]session.SessionActivity)
+	m.rateLimits = make(map[string]map[int]session.RateLimit)
+	m.agentReports = make(map[string]map[int]session.AgentReport)
+
+	// The recorder is per project, like the log it writes to. Loaded before
+	// the first poll so that poll is compared with what was last recorded.
+	m.activityRecorder = session.NewActivityRecorder(session.NewActivityLog(m.storage.ActivityLogPath()))
+	m.activityRecorder.Load(time.Now())
+	m.notifyConfig = notify.LoadConfig()
+	m.projectHooks = settings.Hooks
+	m.hooksConfig, m.hooksLoadErr = hooks.LoadConfig()
+	m.hookRunner = hooks.NewRunner(m.hooksConfig)
+	m.projectApproval = settings.Approval
+	m.approver = approval.NewApprover(m.projectApproval, approval.NewLog(m.storage.ApprovalLogPath()))
+	m.stallConfig = settings.Stall
+	session.SetStallTimeout(m.stallConfig.Timeout())
+	session.SetStallTerminalCommands(m.stallConfig.TerminalCommands())
+	m.rateLimitConfig = settings.RateLimit
+	m.continuer = newAutoContinuer(m.rateLimitConfig)
+	m.projectSnippets = settings.Snippets
+	m.worktreeRoot = settings.WorktreeRoot
+	m.schedules, m.schedulesLoadErr = session.LoadSchedules(m.storage.SchedulesPath())
+	m.promptLog = session.NewPromptHistory(m.storage.PromptHistoryPath())
+	m.pipelines, m.pipelinesLoadErr = session.LoadPipelines(m.storage.PipelinesPath())
+	m.comparisons, m.comparisonsLoadErr = session.LoadComparisons(m.storage.ComparisonsPath())
+	m.promptLog.Compact()
 
 	// Initialize status and last lines for all instances
 	for _, inst := range m.instances {
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/notify"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Notifications, in the list and while attached.
+//
+// In the list, notifications ride on the status poll: the transitions the
+// activity recorder reports are the ones worth telling someone about. While a
+// session is attached there is no list — tea.ExecProcess blocks the update
+// loop until tmux returns — so the attach watcher takes over, polling on its
+// own goroutine and feeding the same recorder. Because every transition goes
+// through that one recorder, a change seen by the watcher is not seen again
+// by the first poll after detaching, and nothing is announced twice.
+
+// attachWatchInterval is how often the watcher polls. Slower than the list:
+// nobody is looking at the result, only waiting to be told.
+const attachWatchInterval = 2 * time.Second
+
+// pickNotifications chooses the events worth a notification. skipID is a
+// session the user is already looking at.
+func pickNotifications(cfg notify.Config, events []session.ActivityEvent, muted map[string]bool, skipID string, now time.Time) []notify.Notification {
+	var notes []notify.Notification
+	for _, e := range events {
+		if e.SessionID == skipID || muted[e.SessionID] || !cfg.Wants(e, now) {
+			continue
+		}
+		notes = append(notes, notify.ForEvent(e))
+	}
+	return notes
+}
+
+// mutedSessions collects the sessions that asked for silence.
+func mutedSessions(instances []*session.Instance) map[string]bool {
+	muted := make(map[string]bool)
+	for _, inst := range instances {
+		if inst.Muted {
+			muted[inst.ID] = true
+		}
+	}
+	return muted
+}
+
+// notifyCmd delivers the notifications the events call for, off the UI
+// thread: notify-send is a process start.
+func (m *Model) notifyCmd(events []session.ActivityEvent) tea.Cmd {
+	notes := pickNotifications(m.notifyConfig, events, mutedSessions(m.instances), "", time.Now())
+	if len(notes) == 0 {
+		return nil
+	}
+	cfg := m.notifyConfig
+	return func() tea.Msg {
+		for _, n := range notes {
+			notify.Send(cfg, n)
+		}
+		return nil
+	}
+}
+
+// attachWatcher polls while a session is attached.
+type attachWatcher struct {
+	stop chan struct{}
+	done sync.WaitGroup
+	// err holds hook, approval and interrupt failures until the list is
+	// back to show them.
+	err error
+}
+
+// startAttachWatcher begins polling every session but attachedID. Its
+// transitions are recorded as usual, announced if they warrant it, and run
+// the hooks they call for.
+func (m *Model) startAttachWatcher(attachedID string) *attachWatcher {
+	w := &attachWatcher{stop: make(chan struct{})}
+	if m.activityRecorder == nil {
+		return w
+	}
+
+	// Copied: the watcher must not read the model's slices, which belong to
+	// the update loop once it resumes.
+	instances := append([]*session.Instance(nil), m.instances...)
+	recorder := m.activityRecorder
+	cfg := m.notifyConfig
+	muted := mutedSessions(instances)
+	runner := m.hookRunner
+	approver := m.approver
+	stall := m.stallConfig
+	continuer := m.continuer
+	storage := m.storage
+	history := m.promptLog
+	var pipelines []*session.Pipeline
+	if m.pipelinesLoadErr == nil {
+		pipelines = m.pipelines
+	}
+	var comparisons []*session.Comparison
+	if m.comparisonsLoadErr == nil {
+		comparisons = m.comparisons
+	}
+	var schedules []*session.Schedule
+	if m.schedulesLoadErr == nil {
+		schedules = m.schedules
+	}
+	globalHooks, projectHooks, projectName := m.hooksConfig.Hooks, m.projectHooks, m.projectName()
+
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		ticker := time.NewTicker(attachWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+			msg := pollSessions(instances, approver)
+			w.err = errors.Join(append([]error{w.err}, msg.errs...)...)
+			now := time.Now()
+			events, _ := recorder.Record(now, observationsFrom(msg, instances))
+			for _, n := range pickNotifications(cfg, events, muted, attachedID, now) {
+				notify.Send(cfg, n)
+			}
+			if err := handleStalls(events, instances, stall); err != nil {
+				w.err = errors.Join(w.err, err)
+			}
+			continued := continuer.due(msg, now)
+			if err := continuer.send(instances, continued); err != nil {
+				w.err = errors.Join(w.err, err)
+			}
+			// Before the queue, which would empty the tab's queue first
+			if len(pipelines) > 0 {
+				w.err = errors.Join(w.err, runPipelinesWhileAttached(storage, pipelines, events, msg, instances, history, now))
+			}
+			if sends := takeQueuedPrompts(events, msg, instances, continued, now); len(sends) > 0 {
+				failed, err := sendQueuedPrompts(sends, history)
+				requeuePrompts(failed)
+				w.err = errors.Join(w.err, err, saveQueues(storage, sends))
+			}
+			if len(schedules) > 0 {
+				w.err = errors.Join(w.err, runSchedulesWhileAttached(storage, schedules, instances, history, now))
+			}
+			w.err = errors.Join(w.err, compareWhileAttached(storage, comparisons, events))
+			// Unlike notifications, hooks run for the attached session too:
+			// an auto-commit should not depend on where the user is looking.
+			if runner != nil {
+				targets := hookTargets(globalHooks, projectHooks, projectName, instances, events)
+				if err := runner.Handle(events, targets); err != nil {
+					w.err = errors.Join(w.err, err)
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// Stop ends the watcher and waits for it, so no poll of its own is still
+// running once the update loop takes over again. It returns the hook and
+// approval failures seen while attached.
+func (w *attachWatcher) Stop() error {
+	close(w.stop)
+	w.done.Wait()
+	return w.err
+}
+
+// execAttach attaches to inst through cmd, watching the other sessions for
+// as long as the list is out of sight.
+func (m *Model) execAttach(cmd *exec.Cmd, inst *session.Instance) tea.Cmd {
+	watcher := m.startAttachWatcher(inst.ID)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return reattachMsg{err: watcher.Stop()}
+	})
+}
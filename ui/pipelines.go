@@ -0,0 +1,537 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Running pipelines, and the pipeline editor.
+//
+// A pipeline moves on the same busy-to-idle transitions as a prompt queue,
+// and only on those: the stage it waits on saying it is done. A stage that
+// finished by asking a question or hitting its usage limit is left alone,
+// and so is one with prompts queued — the queue goes first, and the pipeline
+// takes the answer to the last of them.
+//
+// A step is sent only to a tab that is idle with nothing queued. Otherwise it
+// joins the end of that tab's queue, so the queue alone types into the tab
+// and the pipeline takes the answer once the queue has run dry.
+//
+// A step is taken — the pipeline advanced and saved — before it is made, so
+// the next poll cannot make it again. A step that fails stops its pipeline,
+// with the reason shown in the pipeline view. The list and the attach
+// watcher both take steps, as they do queued prompts.
+
+// pipelineResult is a step made, or failed.
+type pipelineResult struct {
+	step   session.PipelineStep
+	mark   int        // The receiving tab's pane line before it was sent to
+	at     time.Time  // When it was sent: the receiving tab's work starts here
+	queued *queueSend // The step's prompt, for the receiving tab's queue instead
+	err    error
+}
+
+// pipelineDoneMsg carries made steps back to the update loop.
+type pipelineDoneMsg struct {
+	results []pipelineResult
+}
+
+// takePipelineSteps advances every running pipeline whose awaited stage
+// events show finishing idle, and returns the steps to make. Pipelines whose
+// stage can no longer be found are stopped. Reports whether any pipeline
+// changed.
+func takePipelineSteps(pipelines []*session.Pipeline, events []session.ActivityEvent, msg statusPollResultMsg, instances []*session.Instance, now time.Time) ([]session.PipelineStep, bool) {
+	var steps []session.PipelineStep
+	changed := false
+	for _, p := range pipelines {
+		if !p.Running || p.Stage >= len(p.Stages) {
+			continue
+		}
+		stage := p.Stages[p.Stage]
+		inst := findInstance(instances, stage.SessionID)
+		if inst == nil {
+			p.Stop("a stage's session no longer exists")
+			changed = true
+			continue
+		}
+		window, err := inst.TabWindow(stage.Tab)
+		if err != nil {
+			p.Stop(err.Error())
+			changed = true
+			continue
+		}
+		if !pipelineStageFinished(events, inst.ID, window, p.Since) {
+			continue
+		}
+		// Still idle as of this poll, not already asking something
+		if msg.windowActivity[inst.ID][window] != session.ActivityIdle {
+			continue
+		}
+		if limit, ok := msg.rateLimits[inst.ID][window]; ok && limit.Active(now) {
+			continue
+		}
+		if len(inst.QueuedPrompts(window)) > 0 {
+			continue
+		}
+		step := p.Advance(now)
+		// Decided here, before this poll's queued prompts are taken
+		if to := findInstance(instances, step.To.SessionID); to != nil {
+			if toWindow, err := to.TabWindow(step.To.Tab); err == nil {
+				step.Queue = msg.windowActivity[to.ID][toWindow] != session.ActivityIdle || len(to.QueuedPrompts(toWindow)) > 0
+			}
+		}
+		steps = append(steps, step)
+		changed = true
+	}
+	return steps, changed
+}
+
+// pipelineStageFinished is whether events show a window going from busy to
+// idle after since.
+func pipelineStageFinished(events []session.ActivityEvent, sessionID string, window int, since time.Time) bool {
+	for _, e := range events {
+		if e.Kind == session.EventActivity && e.SessionID == sessionID && e.Window == window &&
+			e.From.Working() && e.To == session.ActivityIdle && !e.Time.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPipelineSteps reads each step's answer and sends it on, wrapped in the
+// receiving stage's template. Blocks while it is typed, so call it off the
+// UI thread.
+func runPipelineSteps(steps []session.PipelineStep, instances []*session.Instance, history *session.PromptHistory) []pipelineResult {
+	results := make([]pipelineResult, 0, len(steps))
+	for _, step := range steps {
+		result := pipelineResult{step: step, mark: -1}
+		result.mark, result.queued, result.err = runPipelineStep(step, instances, history)
+		result.at = time.Now()
+		results = append(results, result)
+	}
+	return results
+}
+
+// runPipelineStep makes one step, and returns the receiving tab's pane line
+// from before the prompt, or the prompt to queue for it when the tab is not
+// idle. Queuing is left to the caller: the queue is the UI thread's.
+func runPipelineStep(step session.PipelineStep, instances []*session.Instance, history *session.PromptHistory) (int, *queueSend, error) {
+	from := findInstance(instances, step.From.SessionID)
+	to := findInstance(instances, step.To.SessionID)
+	if from == nil || to == nil {
+		return -1, nil, fmt.Errorf("a stage's session no longer exists")
+	}
+	fromWindow, err := from.TabWindow(step.From.Tab)
+	if err != nil {
+		return -1, nil, err
+	}
+	toWindow, err := to.TabWindow(step.To.Tab)
+	if err != nil {
+		return -1, nil, err
+	}
+	answer, err := from.AnswerSince(fromWindow, step.Mark, step.Since)
+	if err != nil {
+		return -1, nil, fmt.Errorf("no answer from %s: %w", from.Name, err)
+	}
+	if !to.IsAlive() {
+		return -1, nil, fmt.Errorf("%s is not running", to.Name)
+	}
+	text := session.ExpandPipelineTemplate(step.To.Template, answer, from.Name, step.Round, step.Rounds)
+	activity := to.DetectActivityForWindow(toWindow)
+	if step.Queue || activity != session.ActivityIdle {
+		return -1, &queueSend{inst: to, window: toWindow, prompt: session.QueuedPrompt{Text: text}}, nil
+	}
+	mark, err := to.PaneMark(toWindow)
+	if err != nil {
+		mark = -1
+	}
+	if err := to.SendPromptToWindow(toWindow, text); err != nil {
+		return -1, nil, fmt.Errorf("failed to send to %s: %w", to.Name, err)
+	}
+	history.Append(to.SentPrompt(toWindow, text, session.SentFromPipeline, activity))
+	return mark, nil, nil
+}
+
+// applyPipelineResults records made steps in their pipelines: the receiving
+// tab's mark and when it was sent to, or, for a failed step, the pipeline
+// stopped with the reason. Finishing what it was doing before then does not
+// count as answering. Steps for a busy tab are queued for it; those are
+// returned, for their sessions to be saved.
+func applyPipelineResults(pipelines []*session.Pipeline, results []pipelineResult) ([]queueSend, error) {
+	var queued []queueSend
+	var errs []error
+	for _, r := range results {
+		if r.err == nil && r.queued != nil {
+			q := r.queued
+			if err := q.inst.EnqueuePrompt(q.window, q.prompt.Text, r.at); err != nil {
+				r.err = err
+			} else {
+				queued = append(queued, *q)
+			}
+		}
+		for _, p := range pipelines {
+			if p.ID != r.step.PipelineID {
+				continue
+			}
+			if r.err != nil {
+				p.Stop(r.err.Error())
+				errs = append(errs, fmt.Errorf("pipeline stopped: %w", r.err))
+			} else if p.Running {
+				p.Mark, p.Since = r.mark, r.at
+			}
+		}
+	}
+	return queued, errors.Join(errs...)
+}
+
+// pipelineCmd makes the steps a poll's events allow, off the UI thread.
+func (m *Model) pipelineCmd(events []session.ActivityEvent, msg statusPollResultMsg) tea.Cmd {
+	if m.pipelinesLoadErr != nil {
+		return nil
+	}
+	steps, changed := takePipelineSteps(m.pipelines, events, msg, m.instances, time.Now())
+	if changed {
+		m.savePipelines()
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+	instances, history := m.instances, m.promptLog
+	return func() tea.Msg {
+		return pipelineDoneMsg{results: runPipelineSteps(steps, instances, history)}
+	}
+}
+
+// runPipelinesWhileAttached is pipelineCmd for the attach watcher, which can
+// make the steps itself.
+func runPipelinesWhileAttached(storage *session.Storage, pipelines []*session.Pipeline, events []session.ActivityEvent, msg statusPollResultMsg, instances []*session.Instance, history *session.PromptHistory, now time.Time) error {
+	steps, changed := takePipelineSteps(pipelines, events, msg, instances, now)
+	if !changed {
+		return nil
+	}
+	queued, err := applyPipelineResults(pipelines, runPipelineSteps(steps, instances, history))
+	err = errors.Join(err, saveQueues(storage, queued))
+	if storage != nil {
+		err = errors.Join(err, session.SavePipelines(storage.PipelinesPath(), pipelines))
+	}
+	return err
+}
+
+// savePipelines writes the pipelines, reporting a failure in the view.
+func (m *Model) savePipelines() {
+	if m.storage == nil {
+		return
+	}
+	if err := session.SavePipelines(m.storage.PipelinesPath(), m.pipelines); err != nil {
+		m.pipelineErr = err.Error()
+	}
+}
+
+// pipelineLabel names a pipeline by its stages: "impl → review ↺".
+func pipelineLabel(instances []*session.Instance, p session.Pipeline) string {
+	names := make([]string, len(p.Stages))
+	for i, s := range p.Stages {
+		names[i] = pipelineStageName(instances, s)
+	}
+	label := strings.Join(names, " → ")
+	if p.Loop {
+		label += " ↺"
+	}
+	return label
+}
+
+// pipelineStageName names a stage by its session, and its tab if not the
+// main agent.
+func pipelineStageName(instances []*session.Instance, s session.PipelineStage) string {
+	name := "(deleted session)"
+	if inst := findInstance(instances, s.SessionID); inst != nil {
+		name = inst.Name
+	}
+	if s.Tab != "" {
+		name += "/" + s.Tab
+	}
+	return name
+}
+
+// openPipelines opens the pipeline view.
+func (m *Model) openPipelines() {
+	m.pipelineEditing = false
+	m.pipelineErr = ""
+	if m.pipelineCursor >= len(m.pipelines) {
+		m.pipelineCursor = max(len(m.pipelines)-1, 0)
+	}
+	m.state = statePipelines
+}
+
+// editPipeline opens the editor on a copy of a pipeline, or on a new one
+// when p is nil: from the session marked in split view to the selected one,
+// and back, as the two panes are used by hand.
+func (m *Model) editPipeline(p *session.Pipeline) {
+	if p != nil {
+		m.pipelineDraft = *p
+		m.pipelineDraft.Stages = append([]session.PipelineStage(nil), p.Stages...)
+	} else {
+		m.pipelineDraft = session.Pipeline{Loop: true, Rounds: 3, Mark: -1}
+		if marked := findInstance(m.instances, m.markedSessionID); m.splitView && marked != nil {
+			m.pipelineDraft.Stages = append(m.pipelineDraft.Stages, session.PipelineStage{SessionID: marked.ID})
+		}
+		if inst := m.getSelectedInstance(); inst != nil && inst.ID != m.markedSessionID {
+			m.pipelineDraft.Stages = append(m.pipelineDraft.Stages, session.PipelineStage{
+				SessionID: inst.ID,
+				Template:  "Review these changes:\n\n{{output}}",
+			})
+		}
+		if len(m.pipelineDraft.Stages) == 2 {
+			m.pipelineDraft.Stages[0].Template = "Address this review (round {{round}} of {{rounds}}):\n\n{{output}}"
+		}
+	}
+	m.pipelineStage = 0
+	m.pipelineFocus = 0
+	m.pipelinePicking = false
+	m.pipelineErr = ""
+	m.promptInput.Blur()
+	m.pipelineEditing = true
+}
+
+// pipelineTabs are the tabs a stage of a session can use: "" for the main
+// agent, then the followed agent tabs by name.
+func (m Model) pipelineTabs(sessionID string) []string {
+	tabs := []string{""}
+	if inst := findInstance(m.instances, sessionID); inst != nil {
+		for _, fw := range inst.FollowedWindows {
+			if fw.Agent != session.AgentTerminal && fw.Name != "" {
+				tabs = append(tabs, fw.Name)
+			}
+		}
+	}
+	return tabs
+}
+
+// handlePipelinesKeys handles keyboard input in the pipeline view.
+func (m Model) handlePipelinesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pipelineEditing {
+		return m.handlePipelineEditKeys(msg)
+	}
+
+	var current *session.Pipeline
+	if m.pipelineCursor < len(m.pipelines) {
+		current = m.pipelines[m.pipelineCursor]
+	}
+	switch msg.String() {
+	case "esc", "q", "P":
+		m.state = stateList
+	case "up", "k":
+		if m.pipelineCursor > 0 {
+			m.pipelineCursor--
+		}
+	case "down", "j":
+		if m.pipelineCursor < len(m.pipelines)-1 {
+			m.pipelineCursor++
+		}
+	case "n":
+		if m.pipelinesLoadErr == nil {
+			m.editPipeline(nil)
+		}
+	case "e", "enter":
+		if current != nil {
+			m.editPipeline(current)
+		}
+	case "d", "delete":
+		if current != nil {
+			m.pipelines = append(m.pipelines[:m.pipelineCursor], m.pipelines[m.pipelineCursor+1:]...)
+			if m.pipelineCursor >= len(m.pipelines) && m.pipelineCursor > 0 {
+				m.pipelineCursor--
+			}
+			m.savePipelines()
+		}
+	case " ", "s":
+		// Stop, or go on from the stage it stopped at
+		if current != nil {
+			if current.Running {
+				current.Stop("stopped")
+			} else if current.LastResult == "done" || current.Round == 0 {
+				m.startPipeline(current)
+			} else {
+				current.Running = true
+				current.Since = time.Now()
+				current.LastResult = ""
+			}
+			m.savePipelines()
+		}
+	case "r":
+		// Again from the first stage and round
+		if current != nil {
+			m.startPipeline(current)
+			m.savePipelines()
+		}
+	}
+	return m, nil
+}
+
+// startPipeline starts a pipeline from its first stage, marking the stage's
+// pane so what it prints next can be read if its agent has no transcript.
+func (m *Model) startPipeline(p *session.Pipeline) {
+	p.Start(time.Now())
+	if inst := findInstance(m.instances, p.Stages[0].SessionID); inst != nil && inst.IsAlive() {
+		if window, err := inst.TabWindow(p.Stages[0].Tab); err == nil {
+			if mark, err := inst.PaneMark(window); err == nil {
+				p.Mark = mark
+			}
+		}
+	}
+}
+
+// handlePipelineEditKeys handles keyboard input in the pipeline editor:
+// the stages, or the template of the stage under the cursor.
+func (m Model) handlePipelineEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pipelinePicking {
+		return m.handlePipelinePickKeys(msg)
+	}
+	key := msg.String()
+	switch key {
+	case "esc":
+		m.pipelineEditing = false
+		m.promptInput.Blur()
+		return m, nil
+	case "ctrl+s":
+		return m.savePipelineDraft()
+	case "tab", "shift+tab":
+		if len(m.pipelineDraft.Stages) == 0 {
+			return m, nil
+		}
+		if m.pipelineFocus == 1 {
+			m.pipelineDraft.Stages[m.pipelineStage].Template = m.promptInput.Value()
+			m.pipelineFocus = 0
+			m.promptInput.Blur()
+			return m, nil
+		}
+		m.pipelineFocus = 1
+		m.promptInput.SetValue(m.pipelineDraft.Stages[m.pipelineStage].Template)
+		return m, m.promptInput.Focus()
+	}
+
+	if m.pipelineFocus == 1 {
+		var cmd tea.Cmd
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
+	}
+
+	stages := m.pipelineDraft.Stages
+	switch key {
+	case "up", "k":
+		if m.pipelineStage > 0 {
+			m.pipelineStage--
+		}
+	case "down", "j":
+		if m.pipelineStage < len(stages)-1 {
+			m.pipelineStage++
+		}
+	case "a":
+		m.pipelinePickCursor = 0
+		m.pipelinePicking = true
+	case "x", "d":
+		if m.pipelineStage < len(stages) {
+			m.pipelineDraft.Stages = append(stages[:m.pipelineStage:m.pipelineStage], stages[m.pipelineStage+1:]...)
+			if m.pipelineStage >= len(m.pipelineDraft.Stages) && m.pipelineStage > 0 {
+				m.pipelineStage--
+			}
+		}
+	case "K", "shift+up":
+		if i := m.pipelineStage; i > 0 {
+			stages[i-1], stages[i] = stages[i], stages[i-1]
+			m.pipelineStage--
+		}
+	case "J", "shift+down":
+		if i := m.pipelineStage; i < len(stages)-1 {
+			stages[i+1], stages[i] = stages[i], stages[i+1]
+			m.pipelineStage++
+		}
+	case "t":
+		// Next tab of the stage's session
+		if m.pipelineStage < len(stages) {
+			stage := &stages[m.pipelineStage]
+			tabs := m.pipelineTabs(stage.SessionID)
+			next := 0
+			for i, tab := range tabs {
+				if tab == stage.Tab {
+					next = (i + 1) % len(tabs)
+				}
+			}
+			stage.Tab = tabs[next]
+		}
+	case "l":
+		m.pipelineDraft.Loop = !m.pipelineDraft.Loop
+	case "+", "=", "right":
+		m.pipelineDraft.Rounds = min(m.pipelineDraft.Rounds+1, 99)
+	case "-", "left":
+		m.pipelineDraft.Rounds = max(m.pipelineDraft.Rounds-1, 1)
+	}
+	return m, nil
+}
+
+// handlePipelinePickKeys handles keyboard input while choosing a session to
+// add as a stage after the one under the cursor.
+func (m Model) handlePipelinePickKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.pipelinePicking = false
+	case "up", "k":
+		if m.pipelinePickCursor > 0 {
+			m.pipelinePickCursor--
+		}
+	case "down", "j":
+		if m.pipelinePickCursor < len(m.instances)-1 {
+			m.pipelinePickCursor++
+		}
+	case "enter":
+		if m.pipelinePickCursor < len(m.instances) {
+			stage := session.PipelineStage{SessionID: m.instances[m.pipelinePickCursor].ID}
+			at := min(m.pipelineStage+1, len(m.pipelineDraft.Stages))
+			stages := append([]session.PipelineStage(nil), m.pipelineDraft.Stages[:at]...)
+			stages = append(stages, stage)
+			m.pipelineDraft.Stages = append(stages, m.pipelineDraft.Stages[at:]...)
+			m.pipelineStage = at
+			m.pipelinePicking = false
+		}
+	}
+	return m, nil
+}
+
+// savePipelineDraft checks the editor's pipeline and puts it in the list. A
+// new pipeline starts at once; an edited one goes on where it was.
+func (m Model) savePipelineDraft() (tea.Model, tea.Cmd) {
+	draft := m.pipelineDraft
+	if m.pipelineFocus == 1 && m.pipelineStage < len(draft.Stages) {
+		draft.Stages[m.pipelineStage].Template = m.promptInput.Value()
+	}
+	if err := draft.Validate(); err != nil {
+		m.pipelineErr = err.Error()
+		return m, nil
+	}
+	if draft.ID == "" {
+		created := session.NewPipeline(draft.Stages, draft.Loop, draft.Rounds)
+		m.startPipeline(created)
+		m.pipelines = append(m.pipelines, created)
+		m.pipelineCursor = len(m.pipelines) - 1
+	} else {
+		// Progress made while the editor was open is kept
+		for _, p := range m.pipelines {
+			if p.ID == draft.ID {
+				p.Stages, p.Loop, p.Rounds = draft.Stages, draft.Loop, draft.Rounds
+				if p.Stage >= len(p.Stages) {
+					p.Stage = 0
+				}
+			}
+		}
+	}
+	m.pipelineEditing = false
+	m.pipelineErr = ""
+	m.promptInput.Blur()
+	m.savePipelines()
+	return m, nil
+}
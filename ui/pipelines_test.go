@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// A pipeline moves when the stage it waits on finishes idle after it was
+// prompted — not on an earlier finish, not on another tab, and not while the
+// tab has queued prompts to go first.
+func TestPipelineStepsTakenAsStagesFinish(t *testing.T) {
+	since := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	impl := &session.Instance{ID: "impl", Name: "impl"}
+	review := &session.Instance{ID: "review", Name: "review"}
+	instances := []*session.Instance{impl, review}
+
+	p := session.NewPipeline([]session.PipelineStage{{SessionID: "impl"}, {SessionID: "review"}}, true, 2)
+	p.Start(since)
+	pipelines := []*session.Pipeline{p}
+
+	finished := func(id string, at time.Time) []session.ActivityEvent {
+		return []session.ActivityEvent{{Time: at, Kind: session.EventActivity, SessionID: id, Window: 0, From: session.ActivityBusy, To: session.ActivityIdle}}
+	}
+	poll := statusPollResultMsg{
+		windowActivity: map[string]map[int]session.SessionActivity{"impl": {0: session.ActivityIdle}, "review": {0: session.ActivityIdle}},
+		rateLimits:     map[string]map[int]session.RateLimit{},
+	}
+	now := since.Add(time.Minute)
+
+	if steps, _ := takePipelineSteps(pipelines, finished("impl", since.Add(-time.Second)), poll, instances, now); len(steps) != 0 {
+		t.Errorf("moved on a finish from before it started: %+v", steps)
+	}
+	if steps, _ := takePipelineSteps(pipelines, finished("review", now), poll, instances, now); len(steps) != 0 {
+		t.Errorf("moved on the wrong stage: %+v", steps)
+	}
+
+	impl.EnqueuePrompt(0, "also run the tests", now)
+	if steps, _ := takePipelineSteps(pipelines, finished("impl", now), poll, instances, now); len(steps) != 0 {
+		t.Errorf("moved ahead of the queue: %+v", steps)
+	}
+	impl.DequeuePrompt(0)
+
+	steps, changed := takePipelineSteps(pipelines, finished("impl", now), poll, instances, now)
+	if len(steps) != 1 || !changed || steps[0].To.SessionID != "review" || p.Stage != 1 || steps[0].Queue {
+		t.Fatalf("steps = %+v, stage %d", steps, p.Stage)
+	}
+
+	// A failed step stops the pipeline, saying why
+	applyPipelineResults(pipelines, []pipelineResult{{step: steps[0], err: errors.New("review is not running")}})
+	if p.Running || p.LastResult == "" {
+		t.Errorf("after a failed step: running %v, result %q", p.Running, p.LastResult)
+	}
+}
+
+// A step for a tab that is busy or has prompts queued goes behind them in
+// its queue, rather than being typed over its work or next to its queue.
+func TestPipelineStepsQueueForBusyTabs(t *testing.T) {
+	since := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	now := since.Add(time.Minute)
+	impl := &session.Instance{ID: "impl", Name: "impl"}
+	review := &session.Instance{ID: "review", Name: "review"}
+	instances := []*session.Instance{impl, review}
+	finished := []session.ActivityEvent{{Time: now, Kind: session.EventActivity, SessionID: "impl", Window: 0, From: session.ActivityBusy, To: session.ActivityIdle}}
+	poll := func(review session.SessionActivity) statusPollResultMsg {
+		return statusPollResultMsg{
+			windowActivity: map[string]map[int]session.SessionActivity{"impl": {0: session.ActivityIdle}, "review": {0: review}},
+			rateLimits:     map[string]map[int]session.RateLimit{},
+		}
+	}
+	take := func(msg statusPollResultMsg) (*session.Pipeline, session.PipelineStep) {
+		p := session.NewPipeline([]session.PipelineStage{{SessionID: "impl"}, {SessionID: "review"}}, true, 2)
+		p.Start(since)
+		steps, _ := takePipelineSteps([]*session.Pipeline{p}, finished, msg, instances, now)
+		if len(steps) != 1 {
+			t.Fatalf("steps = %+v", steps)
+		}
+		return p, steps[0]
+	}
+
+	if _, step := take(poll(session.ActivityBusy)); !step.Queue {
+		t.Error("a step for a busy tab is sent")
+	}
+	review.EnqueuePrompt(0, "fix the flaky test", now)
+	if _, step := take(poll(session.ActivityIdle)); !step.Queue {
+		t.Error("a step for a tab with prompts queued is sent")
+	}
+
+	p, step := take(poll(session.ActivityIdle))
+	queued, err := applyPipelineResults([]*session.Pipeline{p}, []pipelineResult{{
+		step: step, mark: -1, at: now,
+		queued: &queueSend{inst: review, window: 0, prompt: session.QueuedPrompt{Text: "review this"}},
+	}})
+	if err != nil || len(queued) != 1 {
+		t.Fatalf("queued %+v, err %v", queued, err)
+	}
+	if got := review.QueuedPrompts(0); len(got) != 2 || got[1].Text != "review this" {
+		t.Errorf("review's queue = %+v, want the step last", got)
+	}
+	if p.Mark != -1 || !p.Since.Equal(now) {
+		t.Errorf("pipeline waits from mark %d at %v", p.Mark, p.Since)
+	}
+}
@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Recalling and re-sending past prompts.
+//
+// In the prompt dialog, Up on the first line steps back through the
+// session's sent prompts and Down on the last line steps forward to what was
+// being typed, as a shell does; ctrl+r searches them, newest first. The
+// history panel lists what was sent to the selected session, or to every
+// session of the project, with the tab, its agent, what it was doing at the
+// time and how the prompt went out. From there a prompt can be edited in the
+// prompt dialog, sent again to the same tab, or sent to another session.
+
+// historySentMsg reports a re-send from the history panel.
+type historySentMsg struct {
+	err error
+}
+
+// loadRecall loads the session's past prompts into the prompt dialog, with
+// the empty draft after them.
+func (m *Model) loadRecall(inst *session.Instance) {
+	prompts, _ := m.promptLog.ForSession(inst.ID)
+	m.recallTexts = session.RecallTexts(prompts)
+	m.recallIndex = len(m.recallTexts)
+	m.recallDraft = ""
+	m.recallSearching = false
+}
+
+// recallStep moves through the recalled prompts by delta, keeping the draft
+// to come back to. Reports whether it moved.
+func (m *Model) recallStep(delta int) bool {
+	next := m.recallIndex + delta
+	if next < 0 || next > len(m.recallTexts) || next == m.recallIndex {
+		return false
+	}
+	if m.recallIndex == len(m.recallTexts) {
+		m.recallDraft = m.promptInput.Value()
+	}
+	m.recallIndex = next
+	if next == len(m.recallTexts) {
+		m.promptInput.SetValue(m.recallDraft)
+	} else {
+		m.promptInput.SetValue(m.recallTexts[next])
+	}
+	return true
+}
+
+// findRecall returns the index of the newest text at or before from that
+// contains query, ignoring case, or -1.
+func findRecall(texts []string, query string, from int) int {
+	query = strings.ToLower(query)
+	for i := min(from, len(texts)-1); i >= 0; i-- {
+		if strings.Contains(strings.ToLower(texts[i]), query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// openRecallSearch starts a ctrl+r search of the recalled prompts.
+func (m *Model) openRecallSearch() tea.Cmd {
+	m.recallSearch = textinput.New()
+	m.recallSearch.Prompt = "(search) "
+	m.recallSearch.CharLimit = 200
+	m.recallSearch.Width = 40
+	m.recallSearching = true
+	m.recallMatch = -1
+	m.promptInput.Blur()
+	return m.recallSearch.Focus()
+}
+
+// handleRecallSearchKeys handles keyboard input while a ctrl+r search is
+// open: typing narrows it, ctrl+r finds an older match, enter takes the
+// match into the prompt.
+func (m Model) handleRecallSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+g":
+		m.recallSearching = false
+		return m, m.promptInput.Focus()
+	case "ctrl+r":
+		if m.recallMatch > 0 {
+			if older := findRecall(m.recallTexts, m.recallSearch.Value(), m.recallMatch-1); older >= 0 {
+				m.recallMatch = older
+			}
+		}
+		return m, nil
+	case "enter", "tab":
+		m.recallSearching = false
+		if m.recallMatch >= 0 {
+			if m.recallIndex == len(m.recallTexts) {
+				m.recallDraft = m.promptInput.Value()
+			}
+			m.recallIndex = m.recallMatch
+			m.promptInput.SetValue(m.recallTexts[m.recallMatch])
+		}
+		return m, m.promptInput.Focus()
+	}
+	var cmd tea.Cmd
+	m.recallSearch, cmd = m.recallSearch.Update(msg)
+	m.recallMatch = -1
+	if m.recallSearch.Value() != "" {
+		m.recallMatch = findRecall(m.recallTexts, m.recallSearch.Value(), len(m.recallTexts)-1)
+	}
+	return m, cmd
+}
+
+// openHistory opens the history panel on the selected session's prompts.
+func (m *Model) openHistory() {
+	m.historySession = m.getSelectedInstance()
+	m.historyAll = m.historySession == nil
+	m.historyCursor = 0
+	m.historyPicking = false
+	m.historyErr = ""
+	m.loadHistory()
+	m.state = stateHistory
+}
+
+// loadHistory reads the panel's rows, newest first.
+func (m *Model) loadHistory() {
+	var prompts []session.SentPrompt
+	var err error
+	if m.historyAll || m.historySession == nil {
+		prompts, err = m.promptLog.All()
+	} else {
+		prompts, err = m.promptLog.ForSession(m.historySession.ID)
+	}
+	if err != nil {
+		m.historyErr = err.Error()
+	}
+	m.historyEntries = make([]session.SentPrompt, 0, len(prompts))
+	for i := len(prompts) - 1; i >= 0; i-- {
+		m.historyEntries = append(m.historyEntries, prompts[i])
+	}
+	if m.historyCursor >= len(m.historyEntries) {
+		m.historyCursor = max(len(m.historyEntries)-1, 0)
+	}
+}
+
+// historyTargets are the sessions a prompt can be sent to from the panel.
+func (m Model) historyTargets() []*session.Instance {
+	var targets []*session.Instance
+	for _, inst := range m.instances {
+		if inst.Status == session.StatusRunning {
+			targets = append(targets, inst)
+		}
+	}
+	return targets
+}
+
+// resendPrompt sends a prompt to a window and adds it to history, off the UI
+// thread. A tab waiting on a permission prompt is refused: the text would be
+// taken as the answer.
+func resendPrompt(inst *session.Instance, window int, text string, history *session.PromptHistory) tea.Cmd {
+	return func() tea.Msg {
+		activity := inst.DetectActivityForWindow(window)
+		if activity == session.ActivityWaiting {
+			return historySentMsg{err: fmt.Errorf("%s is waiting on a prompt", inst.Name)}
+		}
+		if err := inst.SendPromptToWindow(window, text); err != nil {
+			return historySentMsg{err: fmt.Errorf("failed to send to %s: %w", inst.Name, err)}
+		}
+		history.Append(inst.SentPrompt(window, text, session.SentFromHistory, activity))
+		return historySentMsg{}
+	}
+}
+
+// handleHistoryKeys handles keyboard input in the history panel.
+func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if m.historyPicking {
+		targets := m.historyTargets()
+		switch key {
+		case "esc", "q":
+			m.historyPicking = false
+		case "up", "k":
+			if m.historyPickCursor > 0 {
+				m.historyPickCursor--
+			}
+		case "down", "j":
+			if m.historyPickCursor < len(targets)-1 {
+				m.historyPickCursor++
+			}
+		case "enter":
+			if m.historyPickCursor < len(targets) && m.historyCursor < len(m.historyEntries) {
+				inst := targets[m.historyPickCursor]
+				m.historyPicking = false
+				m.historyErr = ""
+				return m, resendPrompt(inst, inst.GetMainWindowIndex(), m.historyEntries[m.historyCursor].Text, m.promptLog)
+			}
+		}
+		return m, nil
+	}
+
+	var current *session.SentPrompt
+	if m.historyCursor < len(m.historyEntries) {
+		current = &m.historyEntries[m.historyCursor]
+	}
+	switch key {
+	case "esc", "q", "H":
+		m.state = stateList
+	case "up", "k":
+		if m.historyCursor > 0 {
+			m.historyCursor--
+		}
+	case "down", "j":
+		if m.historyCursor < len(m.historyEntries)-1 {
+			m.historyCursor++
+		}
+	case "a":
+		if m.historySession != nil {
+			m.historyAll = !m.historyAll
+			m.historyCursor = 0
+			m.loadHistory()
+		}
+	case "enter", "e":
+		// Into the prompt dialog of the selected session, to edit first
+		if current != nil {
+			inst := m.getSelectedInstance()
+			if inst == nil || inst.Status != session.StatusRunning {
+				m.historyErr = "The selected session is not running"
+				return m, nil
+			}
+			m.handleSendPrompt()
+			m.promptInput.SetValue(current.Text)
+		}
+	case "s":
+		// Again, to the tab it went to
+		if current != nil {
+			inst := findInstance(m.instances, current.SessionID)
+			if inst == nil || inst.Status != session.StatusRunning {
+				m.historyErr = "That session is gone or not running"
+				return m, nil
+			}
+			window, err := inst.TabWindow(current.Tab)
+			if err != nil {
+				m.historyErr = err.Error()
+				return m, nil
+			}
+			m.historyErr = ""
+			return m, resendPrompt(inst, window, current.Text, m.promptLog)
+		}
+	case "o":
+		// To another session's main agent
+		if current != nil {
+			if len(m.historyTargets()) == 0 {
+				m.historyErr = "No session is running"
+				return m, nil
+			}
+			m.historyPickCursor = 0
+			m.historyPicking = true
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Search runs newest first and ignores case; asking again from before a
+// match finds the next older one.
+func TestFindRecall(t *testing.T) {
+	texts := []string{"run the tests", "fix lint", "Run the tests again"}
+	if got := findRecall(texts, "run", len(texts)-1); got != 2 {
+		t.Errorf("newest match = %d, want 2", got)
+	}
+	if got := findRecall(texts, "run", 1); got != 0 {
+		t.Errorf("older match = %d, want 0", got)
+	}
+	if got := findRecall(texts, "deploy", len(texts)-1); got != -1 {
+		t.Errorf("no match = %d, want -1", got)
+	}
+}
+
+// Up steps back through past prompts and Down comes back to the draft as it
+// was typed.
+func TestRecallKeepsTheDraft(t *testing.T) {
+	m := newTestModel()
+	m.state = statePrompt
+	m.promptInput = textarea.New()
+	m.promptInput.Focus()
+	m.recallTexts = []string{"older", "newer"}
+	m.recallIndex = len(m.recallTexts)
+	m.promptInput.SetValue("half typed")
+
+	press := func(k tea.KeyType) {
+		model, _ := m.handlePromptKeys(tea.KeyMsg{Type: k})
+		next := model.(Model)
+		m = &next
+	}
+	press(tea.KeyUp)
+	if m.promptInput.Value() != "newer" {
+		t.Fatalf("first Up recalled %q", m.promptInput.Value())
+	}
+	press(tea.KeyUp)
+	press(tea.KeyUp)
+	if m.promptInput.Value() != "older" {
+		t.Fatalf("Up past the oldest left %q", m.promptInput.Value())
+	}
+	press(tea.KeyDown)
+	press(tea.KeyDown)
+	if m.promptInput.Value() != "half typed" {
+		t.Errorf("Down back to the end gave %q, want the draft", m.promptInput.Value())
+	}
+}
@@ -0,0 +1,286 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Sending queued prompts as tabs finish.
+//
+// A tab's queue moves on a busy-to-idle transition and nothing else: that is
+// the agent saying it is done with the last prompt. Idle on its own is not
+// enough — a prompt queued into an idle tab would otherwise go out at once,
+// which is what "send" is for — and a tab that finished by asking a question
+// or hitting its usage limit is left alone, since the next prompt would land
+// as the answer.
+//
+// Prompts are taken off the queue before they are sent, so the next poll
+// cannot send the same one again, and put back at the front if sending fails.
+// The list and the attach watcher both do this, on the transitions each of
+// them records; with the list out of sight while attached, they never run at
+// the same time.
+
+// queueSend is a prompt taken off a tab's queue to be sent.
+type queueSend struct {
+	inst   *session.Instance
+	window int
+	prompt session.QueuedPrompt
+}
+
+// queueErrorMsg carries prompts that could not be sent back to the update
+// loop, to be queued again.
+type queueErrorMsg struct {
+	err    error
+	failed []queueSend
+}
+
+// takeQueuedPrompts takes the next prompt off the queue of every tab that
+// events show finishing, if it finished idle and is not being continued
+// after a limit.
+func takeQueuedPrompts(events []session.ActivityEvent, msg statusPollResultMsg, instances []*session.Instance, continued []continueKey, now time.Time) []queueSend {
+	byID := make(map[string]*session.Instance, len(instances))
+	for _, inst := range instances {
+		byID[inst.ID] = inst
+	}
+	var sends []queueSend
+	for _, e := range events {
+		if e.Kind != session.EventActivity || !e.From.Working() || e.To != session.ActivityIdle {
+			continue
+		}
+		inst, ok := byID[e.SessionID]
+		if !ok || inst.QueuePaused {
+			continue
+		}
+		// Still idle as of this poll, not already asking something
+		if msg.windowActivity[e.SessionID][e.Window] != session.ActivityIdle {
+			continue
+		}
+		if limit, ok := msg.rateLimits[e.SessionID][e.Window]; ok && limit.Active(now) {
+			continue
+		}
+		if continuing(continued, e.SessionID, e.Window) {
+			continue
+		}
+		if prompt, ok := inst.DequeuePrompt(e.Window); ok {
+			sends = append(sends, queueSend{inst: inst, window: e.Window, prompt: prompt})
+		}
+	}
+	return sends
+}
+
+// sendQueuedPrompts types each prompt into its tab, adds those sent to
+// history, and returns those that could not be. Blocks while they are typed,
+// so call it off the UI thread.
+func sendQueuedPrompts(sends []queueSend, history *session.PromptHistory) ([]queueSend, error) {
+	var failed []queueSend
+	var errs []error
+	for _, s := range sends {
+		activity := s.inst.DetectActivityForWindow(s.window)
+		if err := s.inst.SendPromptToWindow(s.window, s.prompt.Text); err != nil {
+			failed = append(failed, s)
+			errs = append(errs, fmt.Errorf("failed to send queued prompt to %s: %w", s.inst.Name, err))
+			continue
+		}
+		history.Append(s.inst.SentPrompt(s.window, s.prompt.Text, session.SentFromQueue, activity))
+	}
+	return failed, errors.Join(errs...)
+}
+
+// requeuePrompts puts prompts back at the front of their queues.
+func requeuePrompts(sends []queueSend) {
+	for _, s := range sends {
+		s.inst.RequeuePrompt(s.window, s.prompt)
+	}
+}
+
+// saveQueues writes the sessions the sends came from.
+func saveQueues(storage *session.Storage, sends []queueSend) error {
+	if storage == nil {
+		return nil
+	}
+	saved := make(map[string]bool)
+	var errs []error
+	for _, s := range sends {
+		if saved[s.inst.ID] {
+			continue
+		}
+		saved[s.inst.ID] = true
+		if err := storage.UpdateInstance(s.inst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// queueCmd sends the next queued prompt to every tab a poll saw finish.
+func (m *Model) queueCmd(events []session.ActivityEvent, msg statusPollResultMsg, continued []continueKey) tea.Cmd {
+	sends := takeQueuedPrompts(events, msg, m.instances, continued, time.Now())
+	if len(sends) == 0 {
+		return nil
+	}
+	saveQueues(m.storage, sends)
+	history := m.promptLog
+	return func() tea.Msg {
+		if failed, err := sendQueuedPrompts(sends, history); err != nil {
+			return queueErrorMsg{err: err, failed: failed}
+		}
+		return nil
+	}
+}
+
+// queueWindows are the tabs of a session that can have a queue, main agent
+// first: terminal tabs take commands, not prompts.
+func (m Model) queueWindows(inst *session.Instance) []int {
+	windows := []int{m.mainWindowIndex[inst.ID]}
+	for _, fw := range inst.FollowedWindows {
+		if fw.Agent != session.AgentTerminal && fw.Index != windows[0] {
+			windows = append(windows, fw.Index)
+		}
+	}
+	return windows
+}
+
+// queueItem is a row of the queue view: a prompt in one of the tabs.
+type queueItem struct {
+	window int
+	index  int // Position in that tab's queue
+}
+
+// queueItems lists the queue view's rows, tab by tab.
+func (m Model) queueItems(inst *session.Instance) []queueItem {
+	var items []queueItem
+	for _, window := range m.queueWindows(inst) {
+		for idx := range inst.QueuedPrompts(window) {
+			items = append(items, queueItem{window: window, index: idx})
+		}
+	}
+	return items
+}
+
+// openQueue shows the selected session's queues.
+func (m *Model) openQueue() {
+	inst := m.getSelectedInstance()
+	if inst == nil {
+		return
+	}
+	m.queueTarget = inst
+	m.queueCursor = 0
+	m.queueEditing = false
+	m.state = stateQueue
+}
+
+// saveQueue writes the queue view's session after a change.
+func (m *Model) saveQueue() {
+	if m.storage == nil || m.queueTarget == nil {
+		return
+	}
+	if err := m.storage.UpdateInstance(m.queueTarget); err != nil {
+		m.showError(err)
+	}
+}
+
+// handleQueueKeys handles keyboard input in the queue view.
+func (m Model) handleQueueKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	inst := m.queueTarget
+	if inst == nil {
+		m.state = stateList
+		return m, nil
+	}
+	items := m.queueItems(inst)
+
+	if m.queueEditing {
+		switch msg.String() {
+		case "esc":
+			m.queueEditing = false
+			return m, nil
+		case "ctrl+s", "ctrl+enter":
+			if m.queueCursor < len(items) && m.promptInput.Value() != "" {
+				item := items[m.queueCursor]
+				prompts := inst.QueuedPrompts(item.window)
+				prompts[item.index].Text = m.promptInput.Value()
+				m.saveQueue()
+			}
+			m.queueEditing = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "esc", "q", "Q":
+		m.state = stateList
+		m.queueTarget = nil
+	case "up", "k":
+		if m.queueCursor > 0 {
+			m.queueCursor--
+		}
+	case "down", "j":
+		if m.queueCursor < len(items)-1 {
+			m.queueCursor++
+		}
+	case "ctrl+up", "K", "ctrl+down", "J":
+		// Within the tab's own queue: a prompt is written for its agent
+		if m.queueCursor >= len(items) {
+			break
+		}
+		item := items[m.queueCursor]
+		prompts := inst.QueuedPrompts(item.window)
+		step := 1
+		if key := msg.String(); key == "ctrl+up" || key == "K" {
+			step = -1
+		}
+		other := item.index + step
+		if other < 0 || other >= len(prompts) {
+			break
+		}
+		prompts[item.index], prompts[other] = prompts[other], prompts[item.index]
+		m.queueCursor += step
+		m.saveQueue()
+	case "e", "enter":
+		if m.queueCursor < len(items) {
+			item := items[m.queueCursor]
+			m.promptInput.SetValue(inst.QueuedPrompts(item.window)[item.index].Text)
+			m.promptInput.Focus()
+			m.queueEditing = true
+		}
+	case "d", "delete":
+		if m.queueCursor < len(items) {
+			item := items[m.queueCursor]
+			prompts := inst.QueuedPrompts(item.window)
+			inst.SetQueuedPrompts(item.window, append(prompts[:item.index:item.index], prompts[item.index+1:]...))
+			if m.queueCursor >= len(items)-1 && m.queueCursor > 0 {
+				m.queueCursor--
+			}
+			m.saveQueue()
+		}
+	case "s":
+		// Now rather than when the tab next finishes
+		if m.queueCursor < len(items) {
+			item := items[m.queueCursor]
+			prompts := inst.QueuedPrompts(item.window)
+			send := queueSend{inst: inst, window: item.window, prompt: prompts[item.index]}
+			inst.SetQueuedPrompts(item.window, append(prompts[:item.index:item.index], prompts[item.index+1:]...))
+			if m.queueCursor >= len(items)-1 && m.queueCursor > 0 {
+				m.queueCursor--
+			}
+			m.saveQueue()
+			history := m.promptLog
+			return m, func() tea.Msg {
+				if failed, err := sendQueuedPrompts([]queueSend{send}, history); err != nil {
+					return queueErrorMsg{err: err, failed: failed}
+				}
+				return nil
+			}
+		}
+	case " ", "p":
+		inst.QueuePaused = !inst.QueuePaused
+		m.saveQueue()
+	}
+	return m, nil
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// A queue moves only when its tab finishes: busy to idle, still idle as of
+// the poll, not stopped by a limit or being continued after one, and not
+// paused. One prompt per finish, in
+// order, each tab from its own queue.
+func TestQueuedPromptsGoOutAsTabsFinish(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	inst := &session.Instance{
+		ID:              "s",
+		Name:            "api",
+		FollowedWindows: []session.FollowedWindow{{Index: 2, Agent: session.AgentCodex, Name: "review"}},
+	}
+	inst.EnqueuePrompt(0, "now run the tests", now)
+	inst.EnqueuePrompt(0, "now fix lint", now)
+	inst.EnqueuePrompt(2, "review the diff", now)
+
+	finished := func(window int, from, to session.SessionActivity) []session.ActivityEvent {
+		return []session.ActivityEvent{{Kind: session.EventActivity, SessionID: "s", Window: window, From: from, To: to}}
+	}
+	poll := func(window int, activity session.SessionActivity) statusPollResultMsg {
+		return statusPollResultMsg{
+			windowActivity: map[string]map[int]session.SessionActivity{"s": {window: activity}},
+			rateLimits:     map[string]map[int]session.RateLimit{},
+		}
+	}
+	take := func(events []session.ActivityEvent, msg statusPollResultMsg) []queueSend {
+		return takeQueuedPrompts(events, msg, []*session.Instance{inst}, nil, now)
+	}
+
+	if sends := take(finished(0, session.ActivityBusy, session.ActivityWaiting), poll(0, session.ActivityWaiting)); len(sends) != 0 {
+		t.Errorf("sent into a question: %v", sends)
+	}
+	if sends := take(finished(0, session.ActivityIdle, session.ActivityIdle), poll(0, session.ActivityIdle)); len(sends) != 0 {
+		t.Errorf("sent to a tab that was already idle: %v", sends)
+	}
+
+	limited := poll(0, session.ActivityIdle)
+	limited.rateLimits["s"] = map[int]session.RateLimit{0: {Message: "limit reached", Until: now.Add(time.Hour)}}
+	if sends := take(finished(0, session.ActivityBusy, session.ActivityIdle), limited); len(sends) != 0 {
+		t.Errorf("sent while rate limited: %v", sends)
+	}
+
+	continued := []continueKey{{session: "s", window: 0, message: "limit reached"}}
+	if sends := takeQueuedPrompts(finished(0, session.ActivityBusy, session.ActivityIdle), poll(0, session.ActivityIdle), []*session.Instance{inst}, continued, now); len(sends) != 0 {
+		t.Errorf("sent into a tab being continued after its limit: %v", sends)
+	}
+
+	inst.QueuePaused = true
+	if sends := take(finished(0, session.ActivityBusy, session.ActivityIdle), poll(0, session.ActivityIdle)); len(sends) != 0 {
+		t.Errorf("sent while paused: %v", sends)
+	}
+	inst.QueuePaused = false
+
+	sends := take(finished(0, session.ActivityStalled, session.ActivityIdle), poll(0, session.ActivityIdle))
+	if len(sends) != 1 || sends[0].prompt.Text != "now run the tests" || sends[0].window != 0 {
+		t.Fatalf("sends = %+v, want the first prompt", sends)
+	}
+	if got := inst.QueuedPrompts(0); len(got) != 1 || got[0].Text != "now fix lint" {
+		t.Errorf("main queue after sending = %+v", got)
+	}
+
+	// A failed send goes back where it was
+	requeuePrompts(sends)
+	if got := inst.QueuedPrompts(0); len(got) != 2 || got[0].Text != "now run the tests" {
+		t.Errorf("main queue after requeue = %+v", got)
+	}
+
+	sends = take(finished(2, session.ActivityBusy, session.ActivityIdle), poll(2, session.ActivityIdle))
+	if len(sends) != 1 || sends[0].prompt.Text != "review the diff" {
+		t.Fatalf("tab sends = %+v", sends)
+	}
+	if inst.QueuedCount() != 2 {
+		t.Errorf("QueuedCount = %d, want 2", inst.QueuedCount())
+	}
+}
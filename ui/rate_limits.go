@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Picking up after a usage limit.
+//
+// The poll reads limit messages and their reset times; what is done about
+// them is here. Once a tab's limit has passed, and the tab is idle at its
+// prompt, the project's continue prompt is typed into it — once per limit
+// message, however many polls see it before it scrolls out of the pane. A
+// message still on screen when the agent goes idle again is read afresh,
+// with the same reset time long past: it is the same limit, not a new one,
+// so the tab is remembered by the message, and forgotten only once an idle
+// poll no longer shows it, or the session stops or goes. The list and the
+// attach watcher share one continuer, so a tab continued while attached is
+// not continued again on the way back.
+//
+// The tabs due are picked on the poll, before anything is sent, so the queue
+// leaves them alone: one prompt goes into a pane at a time.
+
+// continueErrorMsg carries failed continue prompts back to the update loop.
+type continueErrorMsg struct{ err error }
+
+type continueKey struct {
+	session string
+	window  int
+	message string // The limit message continued after
+}
+
+// autoContinuer sends the continue prompt to tabs whose limit has passed.
+type autoContinuer struct {
+	cfg *session.RateLimitConfig
+
+	mu   sync.Mutex
+	sent map[continueKey]bool // Tabs continued, until their message is gone
+}
+
+// newAutoContinuer returns a continuer for a project's settings.
+func newAutoContinuer(cfg *session.RateLimitConfig) *autoContinuer {
+	return &autoContinuer{cfg: cfg, sent: make(map[continueKey]bool)}
+}
+
+// due picks the tabs in a poll that are ready to continue at now, and marks
+// them sent; tabs no longer showing the message they were continued after
+// are forgotten. A working tab is not read for limits, so it is kept.
+func (c *autoContinuer) due(msg statusPollResultMsg, now time.Time) []continueKey {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.sent {
+		activity, polled := msg.windowActivity[key.session][key.window]
+		if polled && activity.Working() {
+			continue
+		}
+		if limit, ok := msg.rateLimits[key.session][key.window]; !ok || limit.Message != key.message {
+			delete(c.sent, key)
+		}
+	}
+	if !c.cfg.Enabled() {
+		return nil
+	}
+	var keys []continueKey
+	for id, limits := range msg.rateLimits {
+		for window, limit := range limits {
+			// No reset time means no way to know when to try
+			if limit.Until.IsZero() || now.Before(limit.Until.Add(c.cfg.Margin())) {
+				continue
+			}
+			if msg.windowActivity[id][window] != session.ActivityIdle {
+				continue
+			}
+			key := continueKey{session: id, window: window, message: limit.Message}
+			if c.sent[key] {
+				continue
+			}
+			c.sent[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// send types the continue prompt into the tabs picked by due. Blocks while
+// the prompts are typed, so call it off the UI thread.
+func (c *autoContinuer) send(instances []*session.Instance, keys []continueKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	byID := make(map[string]*session.Instance, len(instances))
+	for _, inst := range instances {
+		byID[inst.ID] = inst
+	}
+	var errs []error
+	for _, key := range keys {
+		inst, ok := byID[key.session]
+		if !ok {
+			continue
+		}
+		if err := inst.SendPromptToWindow(key.window, c.cfg.ContinuePrompt()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to continue %s after its limit: %w", inst.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// continuing is whether keys has a session's tab in it.
+func continuing(keys []continueKey, sessionID string, window int) bool {
+	for _, key := range keys {
+		if key.session == sessionID && key.window == window {
+			return true
+		}
+	}
+	return false
+}
+
+// continueCmd continues the tabs picked from a poll by due.
+func (m *Model) continueCmd(keys []continueKey) tea.Cmd {
+	if len(keys) == 0 {
+		return nil
+	}
+	instances := append([]*session.Instance(nil), m.instances...)
+	continuer := m.continuer
+	return func() tea.Msg {
+		if err := continuer.send(instances, keys); err != nil {
+			return continueErrorMsg{err}
+		}
+		return nil
+	}
+}
+
+// rateLimitBadge is the status line of a tab held by a limit, or "" if it is
+// not. An expired limit still on screen is not a badge: the tab is free.
+func (m Model) rateLimitBadge(instID string, window int) string {
+	limit, ok := m.rateLimits[instID][window]
+	if !ok || !limit.Active(time.Now()) {
+		return ""
+	}
+	if limit.Until.IsZero() {
+		return "Rate limited"
+	}
+	return "Rate limited until " + formatResetTime(limit.Until, time.Now())
+}
+
+// sessionRateLimit returns the limit holding any of a session's tabs, the
+// one lifting soonest if there are several.
+func (m Model) sessionRateLimit(instID string) (session.RateLimit, bool) {
+	var found session.RateLimit
+	ok := false
+	now := time.Now()
+	for _, limit := range m.rateLimits[instID] {
+		if !limit.Active(now) {
+			continue
+		}
+		if !ok || (!limit.Until.IsZero() && (found.Until.IsZero() || limit.Until.Before(found.Until))) {
+			found, ok = limit, true
+		}
+	}
+	return found, ok
+}
+
+// formatResetTime prints a reset time as a clock, with the day when it is
+// not today.
+func formatResetTime(t, now time.Time) string {
+	t = t.In(now.Location())
+	if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+		return t.Format("15:04")
+	}
+	return t.Format("Mon 15:04")
+}
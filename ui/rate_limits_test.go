@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// A tab is continued once its limit and the margin have passed, only while it
+// sits idle, and only once per limit however many polls still show the
+// message — the same message read again after the agent's next task
+// included; once an idle poll no longer shows it, the tab is forgotten.
+func TestAutoContinueIsDueOncePerLimit(t *testing.T) {
+	reset := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	c := newAutoContinuer(&session.RateLimitConfig{AutoContinue: true})
+	poll := func(activity session.SessionActivity) statusPollResultMsg {
+		return statusPollResultMsg{
+			windowActivity: map[string]map[int]session.SessionActivity{"s": {1: activity}},
+			rateLimits:     map[string]map[int]session.RateLimit{"s": {1: {Message: "limit reached", Until: reset}}},
+		}
+	}
+	// A working tab is not read for limits
+	busy := statusPollResultMsg{
+		windowActivity: map[string]map[int]session.SessionActivity{"s": {1: session.ActivityBusy}},
+		rateLimits:     map[string]map[int]session.RateLimit{"s": {}},
+	}
+
+	if due := c.due(poll(session.ActivityIdle), reset.Add(30*time.Second)); len(due) != 0 {
+		t.Errorf("continued inside the margin: %v", due)
+	}
+	if due := c.due(poll(session.ActivityWaiting), reset.Add(2*time.Minute)); len(due) != 0 {
+		t.Errorf("typed into a waiting tab: %v", due)
+	}
+	if due := c.due(poll(session.ActivityIdle), reset.Add(2*time.Minute)); len(due) != 1 || due[0].window != 1 {
+		t.Fatalf("due = %v, want the limited tab", due)
+	}
+	if due := c.due(poll(session.ActivityIdle), reset.Add(3*time.Minute)); len(due) != 0 {
+		t.Errorf("continued twice: %v", due)
+	}
+	c.due(busy, reset.Add(4*time.Minute))
+	if due := c.due(poll(session.ActivityIdle), reset.Add(10*time.Minute)); len(due) != 0 {
+		t.Errorf("continued again when the old message was read after the next task: %v", due)
+	}
+	gone := poll(session.ActivityIdle)
+	gone.rateLimits["s"] = map[int]session.RateLimit{}
+	c.due(gone, reset.Add(11*time.Minute))
+	if len(c.sent) != 0 {
+		t.Errorf("%d tabs remembered after their limit went", len(c.sent))
+	}
+
+	off := newAutoContinuer(nil)
+	if due := off.due(poll(session.ActivityIdle), reset.Add(time.Hour)); len(due) != 0 {
+		t.Errorf("continued without auto_continue: %v", due)
+	}
+}
@@ -0,0 +1,401 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/notify"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Running scheduled prompts from the TUI, and the schedule view.
+//
+// While a project is open here its schedules are run by the tick, whatever
+// dialog is showing, and by the attach watcher while a session is attached;
+// `asmgr scheduler` leaves a locked project alone. Runs are taken on the
+// update loop — their schedules moved on and saved — and made off it, since
+// starting a stopped session for one waits for its agent to come up.
+
+// scheduleResult is the outcome of one run.
+type scheduleResult struct {
+	run     session.ScheduleRun
+	at      time.Time
+	err     error
+	started *session.Instance // Started for the run, to be saved
+}
+
+// scheduleDoneMsg carries finished runs back to the update loop.
+type scheduleDoneMsg struct {
+	results []scheduleResult
+}
+
+// runSchedules makes each run against its session. Blocks, so call it off
+// the UI thread.
+func runSchedules(runs []session.ScheduleRun, instances []*session.Instance, history *session.PromptHistory) []scheduleResult {
+	results := make([]scheduleResult, 0, len(runs))
+	for _, run := range runs {
+		result := scheduleResult{run: run}
+		inst := findInstance(instances, run.Schedule.SessionID)
+		if inst == nil {
+			result.err = fmt.Errorf("the session no longer exists")
+		} else {
+			wasAlive := inst.IsAlive()
+			result.err = session.RunSchedule(inst, run, history)
+			if !wasAlive && inst.IsAlive() {
+				inst.UpdateStatus()
+				result.started = inst
+			}
+		}
+		result.at = time.Now()
+		results = append(results, result)
+	}
+	return results
+}
+
+// findInstance returns the session of an ID.
+func findInstance(instances []*session.Instance, id string) *session.Instance {
+	for _, inst := range instances {
+		if inst.ID == id {
+			return inst
+		}
+	}
+	return nil
+}
+
+// recordScheduleResults stores the outcomes on their schedules, saves any
+// session started for a run and the schedules, and returns the failures.
+func recordScheduleResults(storage *session.Storage, schedules []*session.Schedule, instances []*session.Instance, results []scheduleResult) error {
+	var errs []error
+	for _, r := range results {
+		session.RecordScheduleRun(schedules, r.run, r.at, r.err)
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("scheduled prompt for %s: %w", scheduleSessionName(instances, r.run.Schedule), r.err))
+		}
+		if r.started != nil && storage != nil {
+			errs = append(errs, storage.UpdateInstance(r.started))
+		}
+	}
+	if storage != nil {
+		errs = append(errs, session.SaveSchedules(storage.SchedulesPath(), schedules))
+	}
+	return errors.Join(errs...)
+}
+
+// missedSchedulesError words missed runs for the error overlay.
+func missedSchedulesError(missed []session.ScheduleRun, instances []*session.Instance) error {
+	if len(missed) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("Scheduled prompts were missed while nothing was running them:")
+	for _, run := range missed {
+		b.WriteString(fmt.Sprintf("\n  %s: %q — %d run(s) from %s",
+			scheduleSessionName(instances, run.Schedule), truncateRunes(firstLine(run.Schedule.Text), 40),
+			len(run.Missed), run.Due.Format("Jan 2 15:04")))
+	}
+	b.WriteString("\nThey were not sent late. S lists them; c clears them.")
+	return errors.New(b.String())
+}
+
+// missedNotification is the desktop notification for missed runs.
+func missedNotification(missed []session.ScheduleRun) notify.Notification {
+	count := 0
+	for _, run := range missed {
+		count += len(run.Missed)
+	}
+	return notify.Notification{
+		Title: "Scheduled prompts missed",
+		Body:  fmt.Sprintf("%d scheduled run(s) were not made on time", count),
+	}
+}
+
+// scheduleSessionName is the name of a schedule's session, for messages.
+func scheduleSessionName(instances []*session.Instance, s session.Schedule) string {
+	if inst := findInstance(instances, s.SessionID); inst != nil {
+		if s.Tab != "" {
+			return inst.Name + " / " + s.Tab
+		}
+		return inst.Name
+	}
+	return "(deleted session)"
+}
+
+// firstLine is the first line of a prompt, marked if there is more.
+func firstLine(text string) string {
+	if idx := strings.Index(text, "\n"); idx != -1 {
+		return text[:idx] + " …"
+	}
+	return text
+}
+
+// checkSchedules takes the runs that are due and returns the command making
+// them. Missed runs are reported at once.
+func (m *Model) checkSchedules(now time.Time) tea.Cmd {
+	if m.schedulesLoadErr != nil || len(m.schedules) == 0 {
+		return nil
+	}
+	runs, missed := session.TakeDueSchedules(m.schedules, now)
+	if len(runs) == 0 && len(missed) == 0 {
+		return nil
+	}
+	if m.storage != nil {
+		if err := session.SaveSchedules(m.storage.SchedulesPath(), m.schedules); err != nil && m.state != stateError {
+			m.showError(err)
+		}
+	}
+
+	var cmds []tea.Cmd
+	if len(missed) > 0 {
+		if m.state != stateError {
+			m.showError(missedSchedulesError(missed, m.instances))
+		}
+		cfg, note := m.notifyConfig, missedNotification(missed)
+		cmds = append(cmds, func() tea.Msg {
+			notify.Send(cfg, note)
+			return nil
+		})
+	}
+	if len(runs) > 0 {
+		instances, history := m.instances, m.promptLog
+		cmds = append(cmds, func() tea.Msg {
+			return scheduleDoneMsg{results: runSchedules(runs, instances, history)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// runSchedulesWhileAttached is checkSchedules for the attach watcher, which
+// can make the runs itself.
+func runSchedulesWhileAttached(storage *session.Storage, schedules []*session.Schedule, instances []*session.Instance, history *session.PromptHistory, now time.Time) error {
+	runs, missed := session.TakeDueSchedules(schedules, now)
+	if len(runs) == 0 && len(missed) == 0 {
+		return nil
+	}
+	return errors.Join(missedSchedulesError(missed, instances),
+		recordScheduleResults(storage, schedules, instances, runSchedules(runs, instances, history)))
+}
+
+// openSchedules opens the schedule view, with the cursor on the selected
+// session's first schedule.
+func (m *Model) openSchedules() {
+	m.scheduleSession = m.getSelectedInstance()
+	m.scheduleCursor = 0
+	m.scheduleEditing = false
+	m.scheduleErr = ""
+	if m.scheduleSession != nil {
+		for i, s := range m.schedules {
+			if s.SessionID == m.scheduleSession.ID {
+				m.scheduleCursor = i
+				break
+			}
+		}
+	}
+	m.state = stateSchedules
+}
+
+// saveSchedules writes the schedules, reporting a failure in the view.
+func (m *Model) saveSchedules() {
+	if m.storage == nil {
+		return
+	}
+	if err := session.SaveSchedules(m.storage.SchedulesPath(), m.schedules); err != nil {
+		m.scheduleErr = err.Error()
+	}
+}
+
+// editSchedule opens the editor on a copy of a schedule, or on a new one for
+// the selected session when s is nil.
+func (m *Model) editSchedule(s *session.Schedule) tea.Cmd {
+	m.scheduleDraft = session.Schedule{}
+	when := ""
+	if s != nil {
+		m.scheduleDraft = *s
+		when = s.When()
+	} else if m.scheduleSession != nil {
+		m.scheduleDraft.SessionID = m.scheduleSession.ID
+	}
+	m.scheduleWhen = textinput.New()
+	m.scheduleWhen.Placeholder = "0 9 * * mon-fri, 17:30, 2026-11-02 09:00 or in 2h"
+	m.scheduleWhen.CharLimit = 100
+	m.scheduleWhen.Width = 60
+	m.scheduleWhen.Prompt = ""
+	m.scheduleWhen.SetValue(when)
+	m.promptInput.SetValue(m.scheduleDraft.Text)
+	m.promptInput.Blur()
+	m.scheduleFocus = 0
+	m.scheduleErr = ""
+	m.scheduleEditing = true
+	return m.scheduleWhen.Focus()
+}
+
+// scheduleTabs are the tabs a schedule of the draft's session can send to:
+// "" for the main agent, then the followed agent tabs by name.
+func (m Model) scheduleTabs() []string {
+	tabs := []string{""}
+	if inst := findInstance(m.instances, m.scheduleDraft.SessionID); inst != nil {
+		for _, fw := range inst.FollowedWindows {
+			if fw.Agent != session.AgentTerminal && fw.Name != "" {
+				tabs = append(tabs, fw.Name)
+			}
+		}
+	}
+	return tabs
+}
+
+// handleSchedulesKeys handles keyboard input in the schedule view.
+func (m Model) handleSchedulesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.scheduleEditing {
+		return m.handleScheduleEditKeys(msg)
+	}
+
+	var current *session.Schedule
+	if m.scheduleCursor < len(m.schedules) {
+		current = m.schedules[m.scheduleCursor]
+	}
+	switch msg.String() {
+	case "esc", "q", "S":
+		m.state = stateList
+	case "up", "k":
+		if m.scheduleCursor > 0 {
+			m.scheduleCursor--
+		}
+	case "down", "j":
+		if m.scheduleCursor < len(m.schedules)-1 {
+			m.scheduleCursor++
+		}
+	case "n":
+		if m.schedulesLoadErr != nil {
+			return m, nil
+		}
+		if m.scheduleSession == nil {
+			m.scheduleErr = "Select a session in the list first"
+			return m, nil
+		}
+		return m, m.editSchedule(nil)
+	case "e", "enter":
+		if current != nil && m.schedulesLoadErr == nil {
+			return m, m.editSchedule(current)
+		}
+	case "d":
+		if current != nil {
+			m.schedules = append(m.schedules[:m.scheduleCursor], m.schedules[m.scheduleCursor+1:]...)
+			if m.scheduleCursor >= len(m.schedules) && m.scheduleCursor > 0 {
+				m.scheduleCursor--
+			}
+			m.saveSchedules()
+		}
+	case " ", "p":
+		if current != nil {
+			current.Paused = !current.Paused
+			if !current.Paused && current.Cron != "" {
+				// Resumed from now, not from when it was paused
+				if err := current.SetWhen(current.Cron, time.Now()); err != nil {
+					m.scheduleErr = err.Error()
+				}
+			}
+			m.saveSchedules()
+		}
+	case "c":
+		if current != nil && len(current.Missed) > 0 {
+			current.Missed = nil
+			m.saveSchedules()
+		}
+	case "r":
+		// Run now, as well as at its times
+		if current != nil {
+			run := session.ScheduleRun{Schedule: *current, Due: time.Now()}
+			instances, history := m.instances, m.promptLog
+			return m, func() tea.Msg {
+				return scheduleDoneMsg{results: runSchedules([]session.ScheduleRun{run}, instances, history)}
+			}
+		}
+	}
+	return m, nil
+}
+
+// handleScheduleEditKeys handles keyboard input in the schedule editor.
+func (m Model) handleScheduleEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.scheduleEditing = false
+		m.promptInput.Blur()
+		return m, nil
+	case "tab", "shift+tab":
+		m.scheduleFocus = 1 - m.scheduleFocus
+		if m.scheduleFocus == 0 {
+			m.promptInput.Blur()
+			return m, m.scheduleWhen.Focus()
+		}
+		m.scheduleWhen.Blur()
+		return m, m.promptInput.Focus()
+	case "ctrl+t":
+		// Next tab to send to
+		tabs := m.scheduleTabs()
+		next := 0
+		for i, tab := range tabs {
+			if tab == m.scheduleDraft.Tab {
+				next = (i + 1) % len(tabs)
+			}
+		}
+		m.scheduleDraft.Tab = tabs[next]
+		return m, nil
+	case "ctrl+o":
+		m.scheduleDraft.StartIfStopped = !m.scheduleDraft.StartIfStopped
+		return m, nil
+	case "ctrl+s":
+		return m.saveScheduleDraft()
+	}
+
+	var cmd tea.Cmd
+	if m.scheduleFocus == 0 {
+		if msg.String() == "enter" {
+			m.scheduleFocus = 1
+			m.scheduleWhen.Blur()
+			return m, m.promptInput.Focus()
+		}
+		m.scheduleWhen, cmd = m.scheduleWhen.Update(msg)
+	} else {
+		m.promptInput, cmd = m.promptInput.Update(msg)
+	}
+	return m, cmd
+}
+
+// saveScheduleDraft checks the editor's schedule and puts it in the list.
+func (m Model) saveScheduleDraft() (tea.Model, tea.Cmd) {
+	draft := m.scheduleDraft
+	draft.Text = strings.TrimSpace(m.promptInput.Value())
+	if draft.Text == "" {
+		m.scheduleErr = "The prompt is empty"
+		return m, nil
+	}
+	now := time.Now()
+	if draft.ID == "" {
+		created, err := session.NewSchedule(draft.SessionID, draft.Tab, m.scheduleWhen.Value(), draft.Text, now)
+		if err != nil {
+			m.scheduleErr = err.Error()
+			return m, nil
+		}
+		created.StartIfStopped = draft.StartIfStopped
+		m.schedules = append(m.schedules, created)
+		m.scheduleCursor = len(m.schedules) - 1
+	} else {
+		if err := draft.SetWhen(m.scheduleWhen.Value(), now); err != nil {
+			m.scheduleErr = err.Error()
+			return m, nil
+		}
+		for i, s := range m.schedules {
+			if s.ID == draft.ID {
+				*m.schedules[i] = draft
+			}
+		}
+	}
+	m.scheduleEditing = false
+	m.scheduleErr = ""
+	m.promptInput.Blur()
+	m.saveSchedules()
+	return m, nil
+}
@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+	"github.com/sahilm/fuzzy"
+)
+
+// The snippet picker of the prompt dialog.
+//
+// ctrl+l in the prompt dialog lists the snippets the selected session can
+// use, narrowed by a fuzzy filter over their names and descriptions. Picking
+// one asks its {{ask:...}} questions one at a time, in the same input the
+// filter was typed in, then inserts the filled-in text at the prompt's
+// cursor — inserted rather than sent, so it can still be edited first.
+
+// snippetSource lets fuzzy match on a snippet's name and description.
+type snippetSource []session.Snippet
+
+func (s snippetSource) String(i int) string { return s[i].Name + " " + s[i].Description }
+func (s snippetSource) Len() int            { return len(s) }
+
+// filterSnippets returns the indexes of the snippets matching a query, best
+// first; all of them, in order, for an empty one.
+func filterSnippets(snippets []session.Snippet, query string) []int {
+	if strings.TrimSpace(query) == "" {
+		indexes := make([]int, len(snippets))
+		for i := range snippets {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	var indexes []int
+	for _, match := range fuzzy.FindFrom(query, snippetSource(snippets)) {
+		indexes = append(indexes, match.Index)
+	}
+	return indexes
+}
+
+// openSnippetPicker switches from the prompt dialog to the snippet picker.
+func (m *Model) openSnippetPicker() tea.Cmd {
+	dir := ""
+	if inst := m.getSelectedInstance(); inst != nil {
+		dir = inst.Path
+	}
+	snippets, err := session.LoadSnippets(m.projectSnippets, dir)
+	m.snippets = snippets
+	m.snippetErr = ""
+	if err != nil {
+		// The other files' snippets are still usable
+		m.snippetErr = err.Error()
+	}
+	m.snippetFilter = textinput.New()
+	m.snippetFilter.CharLimit = 200
+	m.snippetFilter.Width = 60
+	m.showSnippetList()
+	m.promptInput.Blur()
+	m.state = stateSnippetPicker
+	return m.snippetFilter.Focus()
+}
+
+// showSnippetList puts the picker back on the list of snippets, unfiltered.
+func (m *Model) showSnippetList() {
+	m.snippetChosen = nil
+	m.snippetAsk = nil
+	m.snippetAnswers = nil
+	m.snippetFilter.Reset()
+	m.snippetFilter.Prompt = "/ "
+	m.snippetFilter.Placeholder = "Filter snippets..."
+	m.snippetMatches = filterSnippets(m.snippets, "")
+	m.snippetCursor = 0
+}
+
+// closeSnippetPicker goes back to the prompt dialog.
+func (m *Model) closeSnippetPicker() tea.Cmd {
+	m.snippetChosen = nil
+	m.state = statePrompt
+	return m.promptInput.Focus()
+}
+
+// handleSnippetPickerKeys handles keyboard input in the snippet picker: first
+// the filter, then the chosen snippet's questions.
+func (m Model) handleSnippetPickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.snippetChosen != nil {
+		return m.handleSnippetQuestionKeys(msg)
+	}
+
+	switch msg.String() {
+	case "esc":
+		return m, m.closeSnippetPicker()
+	case "up", "ctrl+p":
+		if m.snippetCursor > 0 {
+			m.snippetCursor--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.snippetCursor < len(m.snippetMatches)-1 {
+			m.snippetCursor++
+		}
+		return m, nil
+	case "enter", "tab":
+		if m.snippetCursor >= len(m.snippetMatches) {
+			return m, nil
+		}
+		chosen := m.snippets[m.snippetMatches[m.snippetCursor]]
+		m.snippetChosen = &chosen
+		m.snippetAsk = session.SnippetQuestions(chosen.Text)
+		m.snippetAnswers = make(map[string]string)
+		m.snippetErr = ""
+		return m.nextSnippetQuestion()
+	}
+
+	var cmd tea.Cmd
+	m.snippetFilter, cmd = m.snippetFilter.Update(msg)
+	m.snippetMatches = filterSnippets(m.snippets, m.snippetFilter.Value())
+	if m.snippetCursor >= len(m.snippetMatches) {
+		m.snippetCursor = max(len(m.snippetMatches)-1, 0)
+	}
+	return m, cmd
+}
+
+// handleSnippetQuestionKeys takes the answer to the current question.
+func (m Model) handleSnippetQuestionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.showSnippetList()
+		return m, nil
+	case "enter":
+		label := m.snippetAsk[len(m.snippetAnswers)]
+		m.snippetAnswers[label] = m.snippetFilter.Value()
+		return m.nextSnippetQuestion()
+	}
+	var cmd tea.Cmd
+	m.snippetFilter, cmd = m.snippetFilter.Update(msg)
+	return m, cmd
+}
+
+// nextSnippetQuestion asks the chosen snippet's next question, or, with all
+// of them answered, inserts it into the prompt.
+func (m Model) nextSnippetQuestion() (tea.Model, tea.Cmd) {
+	if len(m.snippetAnswers) < len(m.snippetAsk) {
+		m.snippetFilter.Reset()
+		m.snippetFilter.Prompt = m.snippetAsk[len(m.snippetAnswers)] + ": "
+		m.snippetFilter.Placeholder = ""
+		return m, nil
+	}
+
+	text, err := session.ExpandSnippet(m.snippetChosen.Text, session.SnippetContext{
+		Instance:  m.getSelectedInstance(),
+		Answers:   m.snippetAnswers,
+		Clipboard: clipboard.ReadAll,
+	})
+	if err != nil {
+		// Stay in the picker with the reason; another snippet may do
+		m.showSnippetList()
+		m.snippetErr = err.Error()
+		return m, nil
+	}
+	m.promptInput.InsertString(text)
+	return m, m.closeSnippetPicker()
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// The filter matches names and descriptions loosely; an empty one shows all.
+func TestFilterSnippets(t *testing.T) {
+	snippets := []session.Snippet{
+		{Name: "pr", Description: "Write the PR description"},
+		{Name: "review", Description: "Review the diff"},
+		{Name: "tests", Description: "Add missing tests"},
+	}
+	if got := filterSnippets(snippets, ""); len(got) != 3 {
+		t.Errorf("empty filter matched %v", got)
+	}
+	if got := filterSnippets(snippets, "rvw"); len(got) != 1 || got[0] != 1 {
+		t.Errorf("rvw matched %v", got)
+	}
+	if got := filterSnippets(snippets, "descr"); len(got) != 1 || got[0] != 0 {
+		t.Errorf("descr matched %v", got)
+	}
+}
+
+// Picking a snippet asks its questions in turn, then inserts it into the
+// prompt and goes back to the prompt dialog.
+func TestSnippetPickerAsksThenInserts(t *testing.T) {
+	m := newTestModel()
+	m.promptInput = textarea.New()
+	m.promptInput.SetValue("Please: ")
+	m.snippets = []session.Snippet{{Name: "pr", Text: "PR for {{ask:Ticket}} ({{ask:Scope}})"}}
+	m.showSnippetList()
+	m.state = stateSnippetPicker
+
+	model, _ := m.handleSnippetPickerKeys(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, answer := range []string{"ENG-42", "api"} {
+		next := model.(Model)
+		next.snippetFilter.SetValue(answer)
+		model, _ = next.handleSnippetPickerKeys(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	got := model.(Model)
+	if got.state != statePrompt {
+		t.Fatalf("state = %v, want the prompt dialog", got.state)
+	}
+	if want := "Please: PR for ENG-42 (api)"; got.promptInput.Value() != want {
+		t.Errorf("prompt = %q, want %q", got.promptInput.Value(), want)
+	}
+}
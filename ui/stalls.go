@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// Acting on stalled tabs.
+//
+// The list shows a stalled tab from the poll's own results; the tmux status
+// bar is another matter. It is text set once and redrawn by tmux, so a tab
+// that stalls or recovers has to be written into it — which is done here, on
+// the transitions the activity recorder reports, by both the list and the
+// attach watcher. The watcher matters most: the status bar is what someone
+// attached to the session is looking at.
+
+// stallErrorMsg carries failed interrupts back to the update loop.
+type stallErrorMsg struct{ err error }
+
+// handleStalls marks the windows events move into or out of stalled, redraws
+// the status bars of their sessions, and interrupts newly stalled agents if
+// cfg asks for it. Runs tmux commands, so call it off the UI thread.
+func handleStalls(events []session.ActivityEvent, instances []*session.Instance, cfg *session.StallConfig) error {
+	byID := make(map[string]*session.Instance, len(instances))
+	for _, inst := range instances {
+		byID[inst.ID] = inst
+	}
+
+	var errs []error
+	redraw := make(map[string]*session.Instance)
+	for _, e := range events {
+		inst, ok := byID[e.SessionID]
+		if !ok || e.Window < 0 {
+			continue
+		}
+		switch {
+		case e.Kind == session.EventActivity && e.To == session.ActivityStalled:
+			inst.SetWindowStalled(e.Window, true)
+			redraw[inst.ID] = inst
+			// A first sighting is a stall asmgr was not running for, and
+			// may have been left alone on purpose.
+			if e.From != e.To && cfg.InterruptOnStall() && e.Agent != session.AgentTerminal {
+				if err := inst.InterruptWindow(e.Window); err != nil {
+					errs = append(errs, fmt.Errorf("failed to interrupt %s in %s: %w", e.Tab, inst.Name, err))
+				}
+			}
+		case e.Kind == session.EventActivity && e.From == session.ActivityStalled,
+			e.Kind == session.EventExit:
+			// A tab that exits while stalled keeps its window, and the mark
+			// would outlive a respawn.
+			inst.SetWindowStalled(e.Window, false)
+			redraw[inst.ID] = inst
+		}
+	}
+	for _, inst := range redraw {
+		if inst.IsAlive() {
+			RefreshTmuxStatusBarFull(inst.TmuxSessionName(), inst.Name, inst.Color, inst.BgColor, inst)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stallCmd acts on the stalls among events off the UI thread.
+func (m *Model) stallCmd(events []session.ActivityEvent) tea.Cmd {
+	if !hasStallChange(events) {
+		return nil
+	}
+	instances := append([]*session.Instance(nil), m.instances...)
+	cfg := m.stallConfig
+	return func() tea.Msg {
+		if err := handleStalls(events, instances, cfg); err != nil {
+			return stallErrorMsg{err}
+		}
+		return nil
+	}
+}
+
+// hasStallChange reports whether any event moves a tab into or out of
+// stalled, or ends a tab that might have been.
+func hasStallChange(events []session.ActivityEvent) bool {
+	for _, e := range events {
+		if e.Kind == session.EventExit || e.To == session.ActivityStalled || e.From == session.ActivityStalled {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,10 @@ package ui
 
 import (
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/izll/agent-session-manager/approval"
 	"github.com/izll/agent-session-manager/session"
 )
 
@@ -31,6 +33,15 @@ type statusPollResultMsg struct {
 	windowActivity map[string]map[int]session.SessionActivity
 	mainWindow     map[string]int
 	stopped        map[string]bool
+	// tabs is what the activity log needs beyond the activity itself: names,
+	// exited processes, and which captures failed.
+	tabs map[string][]session.TabObservation
+	// rateLimits are the limit messages on screen, by window.
+	rateLimits map[string]map[int]session.RateLimit
+	// agentReports are what agents last reported through hooks, by window.
+	agentReports map[string]map[int]session.AgentReport
+	// errs are prompts the approver failed to answer or log.
+	errs []error
 }
 
 // sessionPoll is one session's worth of results, before merging.
@@ -41,6 +52,10 @@ type sessionPoll struct {
 	windowActivity map[int]session.SessionActivity
 	mainWindow     int
 	stopped        bool
+	tabs           []session.TabObservation
+	rateLimits     map[int]session.RateLimit
+	agentReports   map[int]session.AgentReport
+	errs           []error
 }
 
 // statusPollCmd probes the given sessions and reports what it found.
@@ -49,50 +64,64 @@ type sessionPoll struct {
 // the results are returned rather than written: the model is owned by the
 // update loop, and writing to it from this goroutine would be a data race
 // however carefully it were done.
-func statusPollCmd(instances []*session.Instance) tea.Cmd {
+func statusPollCmd(instances []*session.Instance, approver *approval.Approver) tea.Cmd {
 	if len(instances) == 0 {
 		return nil
 	}
 
 	return func() tea.Msg {
-		results := make([]sessionPoll, len(instances))
-		var wg sync.WaitGroup
-
-		for idx, inst := range instances {
-			wg.Add(1)
-			go func(idx int, inst *session.Instance) {
-				defer wg.Done()
-				results[idx] = pollSession(inst)
-			}(idx, inst)
-		}
-		wg.Wait()
-
-		msg := statusPollResultMsg{
-			lastLines:      make(map[string]string, len(results)),
-			activity:       make(map[string]session.SessionActivity, len(results)),
-			windowActivity: make(map[string]map[int]session.SessionActivity, len(results)),
-			mainWindow:     make(map[string]int, len(results)),
-			stopped:        make(map[string]bool, len(results)),
+		return pollSessions(instances, approver)
+	}
+}
+
+// pollSessions probes the sessions concurrently and gathers the results.
+// Waiting tabs are offered to approver, if there is one, before they are
+// reported.
+func pollSessions(instances []*session.Instance, approver *approval.Approver) statusPollResultMsg {
+	results := make([]sessionPoll, len(instances))
+	var wg sync.WaitGroup
+
+	for idx, inst := range instances {
+		wg.Add(1)
+		go func(idx int, inst *session.Instance) {
+			defer wg.Done()
+			results[idx] = pollSession(inst, approver)
+		}(idx, inst)
+	}
+	wg.Wait()
+
+	msg := statusPollResultMsg{
+		lastLines:      make(map[string]string, len(results)),
+		activity:       make(map[string]session.SessionActivity, len(results)),
+		windowActivity: make(map[string]map[int]session.SessionActivity, len(results)),
+		mainWindow:     make(map[string]int, len(results)),
+		stopped:        make(map[string]bool, len(results)),
+		tabs:           make(map[string][]session.TabObservation, len(results)),
+		rateLimits:     make(map[string]map[int]session.RateLimit, len(results)),
+		agentReports:   make(map[string]map[int]session.AgentReport, len(results)),
+	}
+	for _, result := range results {
+		if result.id == "" {
+			continue
 		}
-		for _, result := range results {
-			if result.id == "" {
-				continue
-			}
-			msg.lastLines[result.id] = result.lastLine
-			msg.stopped[result.id] = result.stopped
-			if result.stopped {
-				continue
-			}
-			msg.activity[result.id] = result.activity
-			msg.windowActivity[result.id] = result.windowActivity
-			msg.mainWindow[result.id] = result.mainWindow
+		msg.lastLines[result.id] = result.lastLine
+		msg.stopped[result.id] = result.stopped
+		msg.errs = append(msg.errs, result.errs...)
+		if result.stopped {
+			continue
 		}
-		return msg
+		msg.activity[result.id] = result.activity
+		msg.windowActivity[result.id] = result.windowActivity
+		msg.mainWindow[result.id] = result.mainWindow
+		msg.tabs[result.id] = result.tabs
+		msg.rateLimits[result.id] = result.rateLimits
+		msg.agentReports[result.id] = result.agentReports
 	}
+	return msg
 }
 
 // pollSession reads one session's state. Runs in its own goroutine.
-func pollSession(inst *session.Instance) sessionPoll {
+func pollSession(inst *session.Instance, approver *approval.Approver) sessionPoll {
 	inst.UpdateStatus()
 
 	result := sessionPoll{id: inst.ID, lastLine: inst.GetLastLine()}
@@ -103,19 +132,63 @@ func pollSession(inst *session.Instance) sessionPoll {
 
 	result.mainWindow = inst.GetMainWindowIndex()
 	result.windowActivity = make(map[int]session.SessionActivity)
+	result.rateLimits = make(map[int]session.RateLimit)
+	result.agentReports = make(map[int]session.AgentReport)
+
+	// Names and exited processes for the activity log, in one list-windows
+	// call for the whole session.
+	windows := make(map[int]session.WindowInfo)
+	for _, w := range inst.GetWindowList() {
+		windows[w.Index] = w
+	}
+	detect := func(index int, agent session.AgentType) session.SessionActivity {
+		activity, valid := inst.DetectActivityForWindowWithValidity(index)
+		w, listed := windows[index]
+		// A prompt the policy answers is reported as the work it lets
+		// continue: nobody has to be told about it.
+		if activity == session.ActivityWaiting && approver != nil {
+			answered, err := approver.Review(inst, index, w.Name)
+			if err != nil {
+				result.errs = append(result.errs, err)
+			}
+			if answered {
+				activity = session.ActivityBusy
+			}
+		}
+		if limit, ok := inst.RateLimitForWindow(index); ok {
+			result.rateLimits[index] = limit
+		}
+		// A report from an agent no longer in the tab is about someone else
+		if report, ok := inst.AgentReportForWindow(index); ok && report.Agent == agent {
+			result.agentReports[index] = report
+		}
+		result.tabs = append(result.tabs, session.TabObservation{
+			Window:     index,
+			Tab:        w.Name,
+			Agent:      agent,
+			Activity:   activity,
+			Dead:       w.Dead,
+			Unreadable: !listed || (!valid && !w.Dead),
+		})
+		return activity
+	}
 
 	// The session's state is the strongest of its windows': one tab asking for
 	// an answer is what the user needs to see about the whole session. Derived
 	// from the per-window pass rather than asked for separately, because
 	// DetectAggregatedActivity walks the same windows and probes each again.
-	aggregate := inst.DetectActivityForWindow(result.mainWindow)
+	mainAgent := inst.Agent
+	if mainAgent == "" {
+		mainAgent = session.AgentClaude
+	}
+	aggregate := detect(result.mainWindow, mainAgent)
 	result.windowActivity[result.mainWindow] = aggregate
 
 	for _, fw := range inst.FollowedWindows {
 		if fw.Index == result.mainWindow {
 			continue
 		}
-		windowActivity := inst.DetectActivityForWindow(fw.Index)
+		windowActivity := detect(fw.Index, fw.Agent)
 		result.windowActivity[fw.Index] = windowActivity
 		if windowActivity > aggregate {
 			aggregate = windowActivity
@@ -125,8 +198,9 @@ func pollSession(inst *session.Instance) sessionPoll {
 	return result
 }
 
-// applyStatusPoll merges a completed poll into the model.
-func (m *Model) applyStatusPoll(msg statusPollResultMsg) {
+// applyStatusPoll merges a completed poll into the model, and returns the
+// transitions it recorded for whatever reacts to them.
+func (m *Model) applyStatusPoll(msg statusPollResultMsg) []session.ActivityEvent {
 	for id, line := range msg.lastLines {
 		// isActive is "the output changed since we last looked", which the
 		// views use as a fallback indicator. Compared before storing, since
@@ -143,6 +217,8 @@ func (m *Model) applyStatusPoll(msg statusPollResultMsg) {
 		}
 		m.activityState[id] = session.ActivityIdle
 		m.windowActivityState[id] = nil
+		delete(m.rateLimits, id)
+		delete(m.agentReports, id)
 		// Dropped with the activity: on restart the agent can land on a
 		// different index, and a leftover entry would be read against the old
 		// one.
@@ -155,7 +231,46 @@ func (m *Model) applyStatusPoll(msg statusPollResultMsg) {
 	for id, windows := range msg.windowActivity {
 		m.windowActivityState[id] = windows
 	}
+	for id, limits := range msg.rateLimits {
+		m.rateLimits[id] = limits
+	}
 	for id, index := range msg.mainWindow {
 		m.mainWindowIndex[id] = index
 	}
+	for id, reports := range msg.agentReports {
+		m.agentReports[id] = reports
+	}
+	m.syncReportedSessionIDs(msg.agentReports)
+
+	return m.recordActivity(msg)
+}
+
+// recordActivity hands the poll to the activity recorder, which logs what
+// changed since the last one, and returns those changes.
+func (m *Model) recordActivity(msg statusPollResultMsg) []session.ActivityEvent {
+	if m.activityRecorder == nil {
+		return nil
+	}
+	// A log that cannot be written is not worth interrupting the list for;
+	// the live state is unaffected and the next transition tries again.
+	events, _ := m.activityRecorder.Record(time.Now(), observationsFrom(msg, m.instances))
+	return events
+}
+
+// observationsFrom restates a poll the way the activity recorder reads it.
+func observationsFrom(msg statusPollResultMsg, instances []*session.Instance) []session.SessionObservation {
+	names := make(map[string]string, len(instances))
+	for _, inst := range instances {
+		names[inst.ID] = inst.Name
+	}
+	observations := make([]session.SessionObservation, 0, len(msg.stopped))
+	for id, stopped := range msg.stopped {
+		observations = append(observations, session.SessionObservation{
+			ID:      id,
+			Name:    names[id],
+			Stopped: stopped,
+			Tabs:    msg.tabs[id],
+		})
+	}
+	return observations
 }
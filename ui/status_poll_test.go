@@ -134,5 +134,7 @@ func newTestModel() *Model {
 		activityState:       map[string]session.SessionActivity{},
 		windowActivityState: map[string]map[int]session.SessionActivity{},
 		mainWindowIndex:     map[string]int{},
+		rateLimits:          map[string]map[int]session.RateLimit{},
+		agentReports:        map[string]map[int]session.AgentReport{},
 	}
 }
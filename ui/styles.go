@@ -14,6 +14,7 @@ const (
 	ColorCyan        = "#00CED1"
 	ColorRed         = "#FF5F87"
 	ColorYellow      = "#FFD700"
+	ColorMagenta     = "#D75FD7"
 	ColorProjectLabel = "#9CA3AF"
 	ColorProjectName  = "#A78BFA"
 )
@@ -48,6 +49,9 @@ var (
 	stoppedStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color(ColorRed))
 
+	stalledStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(ColorMagenta))
+
 	previewStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(lipgloss.Color(ColorPurple)).
@@ -89,6 +89,36 @@ func (m Model) View() string {
 		return m.confirmStopTabView()
 	case stateResumeTabChoice:
 		return m.resumeTabChoiceView()
+	case stateAnswerPrompt:
+		return m.answerPromptView()
+	case stateQueue:
+		return m.queueView()
+	case stateBroadcast:
+		return m.broadcastView()
+	case stateSnippetPicker:
+		return m.snippetPickerView()
+	case stateSchedules:
+		return m.schedulesView()
+	case stateAttach:
+		return m.attachView()
+	case stateHistory:
+		return m.historyView()
+	case statePipelines:
+		return m.pipelinesView()
+	case stateCompare:
+		return m.compareView()
+	case stateHandoff:
+		return m.handoffView()
+	case stateFinish:
+		return m.finishView()
+	case stateDiffFilter:
+		return m.diffFilterView()
+	case stateConfirmDiscard:
+		return m.confirmDiscardView()
+	case stateReviewComment:
+		return m.reviewCommentView()
+	case stateReview:
+		return m.reviewView()
 	case stateConfirmYolo:
 		return m.confirmYoloView()
 	case stateSearch:
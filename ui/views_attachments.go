@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// attachView renders the attach dialog's current step over the list
+func (m Model) attachView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+	row := func(i int, text string) string {
+		if i == m.attachCursor {
+			return "  ▸ " + selectedStyle.Render(text) + "\n"
+		}
+		return "    " + normalStyle.Render(text) + "\n"
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+	switch m.attachStep {
+	case attachMenu:
+		for _, item := range [][2]string{
+			{"f", "A file of the session"},
+			{"s", "The session diff (since it started)"},
+			{"d", "The full diff (all uncommitted changes)"},
+			{"p", "The last lines of another tab"},
+			{"a", "Another session's last answer"},
+		} {
+			boxContent.WriteString("  " + keyStyle.Render(item[0]) + "  " + item[1] + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(dimStyle.Render("  Files go as @path to agents that read them, else inline.") + "\n")
+		if m.attachErr != "" {
+			boxContent.WriteString("\n  " + errStyle.Render(m.attachErr) + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  esc: back to prompt"))
+
+	case attachFile:
+		boxContent.WriteString("  " + m.attachFilter.View() + "\n\n")
+		if len(m.attachMatches) == 0 {
+			boxContent.WriteString(dimStyle.Render("  No matching files") + "\n")
+		}
+		visible := max(m.height-16, 5)
+		start := 0
+		if m.attachCursor >= visible {
+			start = m.attachCursor - visible + 1
+		}
+		end := min(start+visible, len(m.attachMatches))
+		for i := start; i < end; i++ {
+			boxContent.WriteString(row(i, truncateRunes(m.attachFiles[m.attachMatches[i]], boxWidth-10)))
+		}
+		if len(m.attachMatches) > end {
+			boxContent.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.attachMatches)-end)) + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ↑/↓: select  enter: attach  esc: back"))
+
+	case attachPane:
+		boxContent.WriteString(fmt.Sprintf("  Last %s lines of:\n\n", selectedStyle.Render(fmt.Sprint(m.attachLines))))
+		for i, w := range m.attachWindows {
+			name := w.Name
+			if w.Followed {
+				name += dimStyle.Render("  " + string(w.Agent))
+			}
+			boxContent.WriteString(row(i, name))
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ↑/↓: tab  +/-: lines  enter: attach  esc: back"))
+
+	case attachAnswer:
+		boxContent.WriteString("  The last answer of:\n\n")
+		for i, inst := range m.attachAnswerSources() {
+			boxContent.WriteString(row(i, truncateRunes(inst.Name, boxWidth-10)))
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ↑/↓: select  enter: attach  esc: back"))
+	}
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Attach ", boxContent.String(), boxWidth, "#7D56F4")
+}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// broadcastView renders the broadcast dialog's current step over the list
+func (m Model) broadcastView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+	switch m.broadcastStep {
+	case broadcastPick:
+		boxContent.WriteString(m.broadcastPickContent(boxWidth))
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  space: toggle  a: all running  f: favorites  g: next group"))
+		boxContent.WriteString("\n")
+		help := "  enter: write prompt  esc: cancel"
+		if m.searchActive && m.searchQuery != "" {
+			help = "  /: search results  enter: write prompt  esc: cancel"
+		}
+		boxContent.WriteString(helpStyle.Render(help))
+
+	case broadcastMessage:
+		boxContent.WriteString(fmt.Sprintf("  To the main agent of %d sessions\n\n", m.broadcastCount()))
+		m.promptInput.SetWidth(boxWidth - 6)
+		boxContent.WriteString("  Message:\n")
+		for _, line := range strings.Split(m.promptInput.View(), "\n") {
+			boxContent.WriteString("  " + line + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ctrl+s: send  ctrl+d: dry run  esc: back"))
+
+	case broadcastPreview, broadcastSending, broadcastResults:
+		sent := m.broadcastStep == broadcastResults
+		title := "Dry run — nothing has been sent"
+		if m.broadcastStep == broadcastSending {
+			title = "Sending…"
+		} else if sent {
+			title = "Sent"
+		}
+		boxContent.WriteString("  " + lipgloss.NewStyle().Bold(true).Render(title) + "\n\n")
+		boxContent.WriteString(m.broadcastOutcomeContent(boxWidth, sent))
+		boxContent.WriteString("\n")
+		boxContent.WriteString("  " + dimStyle.Render(broadcastSummary(m.broadcastPlan, sent)) + "\n\n")
+		switch m.broadcastStep {
+		case broadcastPreview:
+			boxContent.WriteString(helpStyle.Render("  enter: send  esc: back"))
+		case broadcastResults:
+			boxContent.WriteString(helpStyle.Render("  enter/esc: close"))
+		}
+	}
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Broadcast Prompt ", boxContent.String(), boxWidth, ColorPurple)
+}
+
+// broadcastPickContent lists the sessions with their checkboxes, scrolled to
+// keep the cursor in view.
+func (m Model) broadcastPickContent(boxWidth int) string {
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	checkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorGreen))
+
+	var b strings.Builder
+	header := fmt.Sprintf("  %d selected", m.broadcastCount())
+	if name := m.broadcastGroupName(); name != "" {
+		header += dimStyle.Render("  (group: " + name + ")")
+	}
+	b.WriteString(header + "\n\n")
+
+	visible := max(m.height-16, 5)
+	start := 0
+	if m.broadcastCursor >= visible {
+		start = m.broadcastCursor - visible + 1
+	}
+	end := min(start+visible, len(m.instances))
+	for i := start; i < end; i++ {
+		inst := m.instances[i]
+		check := "[ ]"
+		if m.broadcastSelected[inst.ID] {
+			check = checkStyle.Render("[x]")
+		}
+		prefix := "  "
+		style := normalStyle
+		if i == m.broadcastCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		note := ""
+		switch {
+		case inst.Status != session.StatusRunning:
+			note = dimStyle.Render("  stopped")
+		case m.activityState[inst.ID] == session.ActivityWaiting:
+			note = waitingStyle.Render("  waiting")
+		}
+		b.WriteString(fmt.Sprintf("  %s%s %s%s\n", prefix, check, style.Render(truncateRunes(inst.Name, boxWidth-30)), note))
+	}
+	if len(m.instances) > end {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.instances)-end)) + "\n")
+	}
+	return b.String()
+}
+
+// broadcastOutcomeContent lists what happens, or happened, to each target.
+func (m Model) broadcastOutcomeContent(boxWidth int, sent bool) string {
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorGreen))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	var b strings.Builder
+	for _, t := range m.broadcastPlan {
+		name := truncateRunes(t.inst.Name, 28)
+		var outcome string
+		switch {
+		case t.skip != "":
+			outcome = dimStyle.Render("– skipped: " + t.skip)
+		case t.err != nil:
+			outcome = failStyle.Render("✗ " + truncateRunes(t.err.Error(), boxWidth-40))
+		case sent:
+			outcome = okStyle.Render("✓ sent")
+		default:
+			outcome = okStyle.Render("→ will send")
+		}
+		b.WriteString(fmt.Sprintf("  %-28s  %s\n", name, outcome))
+	}
+	return b.String()
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// compareView renders the comparison view, its setup, or two runs' diffs
+// side by side, over the list
+func (m Model) compareView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+
+	switch m.compareStep {
+	case compareSetup:
+		return m.compareSetupView(boxWidth)
+	case compareSideBySide:
+		return m.sideBySideView()
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+
+	if m.comparisonsLoadErr != nil {
+		boxContent.WriteString("  " + errStyle.Render(truncateRunes(m.comparisonsLoadErr.Error(), boxWidth-6)) + "\n")
+		boxContent.WriteString(dimStyle.Render("  No comparison is recorded until comparisons.json is fixed.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Compare Agents ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	c := m.currentComparison()
+	if c == nil {
+		boxContent.WriteString("  No comparisons.\n")
+		if m.compareErr != "" {
+			boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.compareErr, boxWidth-6)) + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  n: new, in the selected session's repository  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Compare Agents ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	title := fmt.Sprintf(" Compare: %s (%d/%d) ", truncateRunes(c.Name, 40), m.compareIndex+1, len(m.comparisons))
+	from := c.Base
+	if len(from) > 8 {
+		from = from[:8]
+	}
+	if c.Branch != "" {
+		from = c.Branch + " at " + from
+	}
+	boxContent.WriteString("  " + dimStyle.Render(truncateRunes(fmt.Sprintf("%s, from %s", filepath.Base(c.Repo), from), boxWidth-6)) + "\n")
+	boxContent.WriteString("  " + truncateRunes(firstLine(c.Prompt), boxWidth-6) + "\n\n")
+
+	now := time.Now()
+	for i, run := range c.Runs {
+		boxContent.WriteString(m.compareRunRow(c, i, run, now, boxWidth) + "\n")
+		if run.Error != "" && !c.Decided() {
+			boxContent.WriteString("      " + errStyle.Render(truncateRunes(run.Error, boxWidth-12)) + "\n")
+		}
+	}
+
+	if m.compareErr != "" {
+		boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.compareErr, boxWidth-6)) + "\n")
+	}
+	boxContent.WriteString("\n")
+	switch m.compareStep {
+	case compareConfirmWinner:
+		run := c.Runs[m.compareCursor]
+		boxContent.WriteString(warnStyle.Render(fmt.Sprintf("  Keep %s on %s, and delete the other sessions,", run.Agent, run.Branch)) + "\n")
+		boxContent.WriteString(warnStyle.Render("  their worktrees and branches, uncommitted work included? (y/n)") + "\n")
+	case compareConfirmDelete:
+		if c.Decided() {
+			boxContent.WriteString(warnStyle.Render("  Forget this comparison? The kept session stays. (y/n)") + "\n")
+		} else {
+			boxContent.WriteString(warnStyle.Render("  Delete this comparison with all its sessions, worktrees and branches? (y/n)") + "\n")
+		}
+	default:
+		if c.Decided() {
+			boxContent.WriteString(helpStyle.Render("  enter: go to the session  g: refresh  d: forget"))
+		} else {
+			boxContent.WriteString(helpStyle.Render("  space: mark  v: side by side  enter: go to the session  g: refresh"))
+			boxContent.WriteString("\n")
+			boxContent.WriteString(helpStyle.Render("  w: keep as the winner  s: send the prompt again  d: delete"))
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  n: new  ←/→: other comparisons  esc: close"))
+		boxContent.WriteString("\n")
+	}
+
+	return m.renderOverlayDialog(title, boxContent.String(), boxWidth, ColorCyan)
+}
+
+// compareRunRow is one run's line: its session, what it is doing, how long
+// it took, and what it changed.
+func (m Model) compareRunRow(c *session.Comparison, i int, run session.ComparisonRun, now time.Time, boxWidth int) string {
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	runningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorGreen))
+	waitingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	prefix, style := "  ", normalStyle
+	if i == m.compareCursor {
+		prefix, style = "▸ ", selectedStyle
+	}
+	mark := "  "
+	for _, id := range m.compareMarked {
+		if id == run.SessionID {
+			mark = "◆ "
+		}
+	}
+	name := string(run.Agent)
+	inst := findInstance(m.instances, run.SessionID)
+	if inst != nil {
+		name = inst.Name
+	}
+
+	var status string
+	switch {
+	case c.Decided() && run.SessionID == c.Winner:
+		status = runningStyle.Render("★ kept     ")
+	case c.Decided():
+		status = dimStyle.Render("deleted    ")
+	case inst == nil:
+		status = errStyle.Render("gone       ")
+	case run.Error != "":
+		status = errStyle.Render("✗ not sent ")
+	case run.SentAt.IsZero():
+		status = dimStyle.Render("starting   ")
+	case !run.FinishedAt.IsZero():
+		status = runningStyle.Render("✓ done     ")
+	case m.activityState[run.SessionID] == session.ActivityWaiting:
+		status = waitingStyle.Render("? waiting  ")
+	default:
+		status = runningStyle.Render("▶ working  ")
+	}
+
+	elapsed := ""
+	if !run.SentAt.IsZero() {
+		elapsed = run.Elapsed(now).Round(time.Second).String()
+	}
+	changes := "…"
+	if sum, ok := m.compareStats[run.SessionID]; ok {
+		changes = fmt.Sprintf("%d files +%d -%d", sum.Files, sum.Added, sum.Removed)
+	} else if err, ok := m.compareStatsErrs[run.SessionID]; ok {
+		changes = truncateRunes(err, 30)
+	}
+	if c.Decided() && run.SessionID != c.Winner {
+		elapsed, changes = "", ""
+	}
+
+	line := fmt.Sprintf("  %s%s%s %s %-9s %-22s", prefix, mark, style.Render(fmt.Sprintf("%-24s", truncateRunes(name, 24))), status, elapsed, changes)
+	return line + dimStyle.Render(truncateRunes(run.Branch, max(boxWidth-82, 0)))
+}
+
+// compareSetupView renders the setup of a new comparison.
+func (m Model) compareSetupView(boxWidth int) string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("  " + dimStyle.Render(truncateRunes("Each agent gets a worktree of "+m.compareDir+" at its HEAD.", boxWidth-6)) + "\n\n")
+
+	b.WriteString("  " + labelStyle.Render("Name") + "  " + m.compareName.View() + "\n\n")
+
+	b.WriteString("  " + labelStyle.Render("Agents") + "\n")
+	for i, a := range compareAgents() {
+		check := "[ ]"
+		if m.compareChosen[a] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s %s %s", check, agentIcons[a], a)
+		if m.compareFocus == 1 && i == m.compareAgentCursor {
+			b.WriteString("  ▸ " + selectedStyle.Render(line) + "\n")
+		} else {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	yolo := "off"
+	if m.compareYolo {
+		yolo = "on: the agents do not ask before acting"
+	}
+	b.WriteString("  " + dimStyle.Render("YOLO: "+yolo) + "\n\n")
+
+	b.WriteString("  " + labelStyle.Render("Prompt") + "\n")
+	m.promptInput.SetWidth(boxWidth - 6)
+	for _, line := range strings.Split(m.promptInput.View(), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+
+	if m.compareErr != "" {
+		b.WriteString("\n  " + errStyle.Render(truncateRunes(m.compareErr, boxWidth-6)) + "\n")
+	}
+	b.WriteString("\n")
+	if m.compareFocus == 1 {
+		b.WriteString(helpStyle.Render("  space: choose  y: YOLO  tab: next field  ctrl+s: start  esc: cancel"))
+	} else {
+		b.WriteString(helpStyle.Render("  tab: next field  ctrl+s: start  esc: cancel"))
+	}
+	b.WriteString("\n")
+	return m.renderOverlayDialog(" New Comparison ", b.String(), boxWidth, ColorCyan)
+}
+
+// sideBySideHeight is how many diff lines the side by side view shows.
+func (m Model) sideBySideHeight() int {
+	return max(m.height-10, 5)
+}
+
+// sideBySideView renders two runs' diffs in two columns, scrolled together.
+func (m Model) sideBySideView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+
+	boxWidth := max(m.width-4, 60)
+	column := (boxWidth - 7) / 2
+	cell := func(lines []string, i int) string {
+		text := ""
+		if i < len(lines) {
+			text = colorDiffLine(truncateRunes(strings.ReplaceAll(lines[i], "\t", "    "), column))
+		}
+		return text + strings.Repeat(" ", max(column-lipgloss.Width(text), 0))
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("  " + labelStyle.Render(fmt.Sprintf("%-*s", column, truncateRunes(m.compareTitles[0], column))) + " │ " +
+		labelStyle.Render(truncateRunes(m.compareTitles[1], column)) + "\n")
+	for i := m.compareScroll; i < m.compareScroll+m.sideBySideHeight(); i++ {
+		b.WriteString("  " + cell(m.compareDiffs[0], i) + " │ " + cell(m.compareDiffs[1], i) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("  ↑/↓: scroll  pgup/pgdn: page  g/G: top/bottom  esc: back"))
+	b.WriteString("\n")
+	return m.renderOverlayDialog(" Side by Side ", b.String(), boxWidth, ColorCyan)
+}
@@ -12,6 +12,16 @@ import (
 func (m Model) confirmDeleteView() string {
 	var boxContent strings.Builder
 	boxContent.WriteString("\n\n")
+	if m.deleteTarget != nil && m.deleteTarget.WorktreePath != "" {
+		t := m.deleteTarget
+		boxContent.WriteString(fmt.Sprintf("  Delete session '%s'?\n\n", t.Name))
+		boxContent.WriteString("  " + dimStyle.Render("Worktree: "+truncateRunes(t.WorktreePath, 48)) + "\n")
+		boxContent.WriteString("  " + dimStyle.Render("Branch:   "+t.Branch) + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  y: keep the worktree  w: remove it") + "\n")
+		boxContent.WriteString(helpStyle.Render("  b: remove it and the branch  n: no"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Confirm Delete ", boxContent.String(), 64, "#FF5F87")
+	}
 	if m.deleteTarget != nil {
 		boxContent.WriteString(fmt.Sprintf("  Delete session '%s'?\n\n", m.deleteTarget.Name))
 	}
@@ -83,10 +93,25 @@ func (m Model) newInstanceView() string {
 		boxContent.WriteString(fmt.Sprintf("  Path: %s\n\n", m.pathInput.Value()))
 		boxContent.WriteString("  Session Name:\n")
 		boxContent.WriteString("  " + m.nameInput.View() + "\n")
+		if !m.isParallelSession {
+			if m.newWorktree {
+				branch := session.BranchSlug(m.nameInput.Value())
+				if branch == "" {
+					branch = "session"
+				}
+				boxContent.WriteString(fmt.Sprintf("\n  [x] Own worktree, on new branch %s\n", branch))
+			} else {
+				boxContent.WriteString("\n  " + dimStyle.Render("[ ] Own worktree, on a new branch") + "\n")
+			}
+		}
 	}
 
 	boxContent.WriteString("\n")
-	boxContent.WriteString(helpStyle.Render("  enter: confirm  esc: cancel"))
+	if m.state == stateNewName && !m.isParallelSession {
+		boxContent.WriteString(helpStyle.Render("  enter: confirm  tab: worktree  esc: cancel"))
+	} else {
+		boxContent.WriteString(helpStyle.Render("  enter: confirm  esc: cancel"))
+	}
 	boxContent.WriteString("\n")
 
 	boxWidth := 60
@@ -126,6 +151,43 @@ func (m Model) renameView() string {
 	return m.renderOverlayDialog(" Rename Session ", boxContent.String(), boxWidth, "#7D56F4")
 }
 
+// confirmDiscardView renders the discard confirmation dialog as an overlay
+func (m Model) confirmDiscardView() string {
+	var boxContent strings.Builder
+	boxContent.WriteString("\n\n")
+	boxContent.WriteString(fmt.Sprintf("  Discard %s?\n\n", truncateRunes(m.discardLabel, 52)))
+	boxContent.WriteString("  " + dimStyle.Render("The change is undone in the working tree;") + "\n")
+	boxContent.WriteString("  " + dimStyle.Render("git keeps no copy of it.") + "\n\n")
+	boxContent.WriteString(helpStyle.Render("  y: yes  n: no"))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Confirm Discard ", boxContent.String(), 64, "#FF5F87")
+}
+
+// diffFilterView renders the diff filter dialog as an overlay
+func (m Model) diffFilterView() string {
+	var boxContent strings.Builder
+	boxContent.WriteString("\n\n")
+
+	boxContent.WriteString("  Show files matching:\n")
+	boxContent.WriteString("  " + m.nameInput.View() + "\n")
+	boxContent.WriteString("\n")
+	boxContent.WriteString("  " + dimStyle.Render("A glob (*.go, internal/**) or text in the path") + "\n")
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  enter: apply (empty shows all)  esc: cancel"))
+	boxContent.WriteString("\n")
+
+	boxWidth := 50
+	if m.width > 80 {
+		boxWidth = m.width / 3
+	}
+	if boxWidth > 60 {
+		boxWidth = 60
+	}
+
+	return m.renderOverlayDialog(" Filter Diff ", boxContent.String(), boxWidth, "#7D56F4")
+}
+
 // promptView renders the prompt input dialog overlaid on the list view
 func (m *Model) promptView() string {
 	var boxContent strings.Builder
@@ -160,6 +222,21 @@ func (m *Model) promptView() string {
 	}
 	boxContent.WriteString("\n")
 
+	if len(m.promptAttachments) > 0 {
+		attachStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan))
+		boxContent.WriteString(attachStyle.Render(truncateRunes("  + "+m.attachmentLabels(), boxWidth-4)) + "\n")
+	}
+
+	if m.recallSearching {
+		searchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan))
+		match := dimStyle.Render("no match")
+		if m.recallMatch >= 0 {
+			match = firstLine(m.recallTexts[m.recallMatch])
+		}
+		boxContent.WriteString("  " + m.recallSearch.View() + "\n")
+		boxContent.WriteString(searchStyle.Render(truncateRunes("  → "+match, boxWidth-4)) + "\n")
+	}
+
 	// Show suggestion if available and input is empty
 	if m.promptSuggestion != "" && m.promptInput.Value() == "" {
 		suggestionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#666666")).Italic(true)
@@ -168,12 +245,25 @@ func (m *Model) promptView() string {
 
 	boxContent.WriteString("\n")
 
-	helpText := "  ctrl+s: send  esc: cancel"
+	helpText := "  ctrl+s: send  ctrl+q: queue  ctrl+l: snippets  esc: cancel"
 	if m.promptSuggestion != "" {
-		helpText = "  tab: accept  ctrl+s: send  esc: cancel"
+		helpText = "  tab: accept  ctrl+s: send  ctrl+q: queue  ctrl+l: snippets  esc: cancel"
 	}
 	boxContent.WriteString(helpStyle.Render(helpText))
 	boxContent.WriteString("\n")
+	attachHelp := "  ctrl+o: attach file, diff, tab output or answer"
+	if len(m.promptAttachments) > 0 {
+		attachHelp = "  ctrl+o: attach more  ctrl+x: remove last attachment"
+	}
+	boxContent.WriteString(helpStyle.Render(attachHelp))
+	boxContent.WriteString("\n")
+	if m.recallSearching {
+		boxContent.WriteString(helpStyle.Render("  ctrl+r: older match  enter: use it  esc: back"))
+		boxContent.WriteString("\n")
+	} else if len(m.recallTexts) > 0 {
+		boxContent.WriteString(helpStyle.Render("  ↑/↓: past prompts  ctrl+r: search them"))
+		boxContent.WriteString("\n")
+	}
 
 	return m.renderOverlayDialog(" Send Message ", boxContent.String(), boxWidth, "#7D56F4")
 }
@@ -728,6 +818,77 @@ func (m Model) resumeTabChoiceView() string {
 	return m.renderOverlayDialog(" Resume Stopped Tab ", boxContent.String(), 50, "#00AA00")
 }
 
+// answerPromptView renders the permission prompt of a waiting tab, to be
+// answered without attaching
+func (m Model) answerPromptView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	questionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow)).Bold(true)
+
+	inst := m.answerTarget
+	if inst == nil || len(m.answerWindows) == 0 {
+		return m.listView()
+	}
+	const boxWidth = 72
+	textWidth := boxWidth - 8
+
+	var boxContent strings.Builder
+	window := m.answerWindows[m.answerWindow]
+	tab := inst.Name
+	if window != m.mainWindowIndex[inst.ID] {
+		for _, fw := range inst.FollowedWindows {
+			if fw.Index == window {
+				tab = fw.Name
+				break
+			}
+		}
+	}
+	boxContent.WriteString("\n")
+	boxContent.WriteString(fmt.Sprintf("  Session: %s  Tab: %s", inst.Name, tab))
+	if len(m.answerWindows) > 1 {
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("  (%d of %d waiting)", m.answerWindow+1, len(m.answerWindows))))
+	}
+	boxContent.WriteString("\n\n")
+
+	d := m.answerDialog
+	if len(d.Options) == 0 {
+		boxContent.WriteString("  The prompt in this tab could not be read.\n")
+		boxContent.WriteString("  Attach with enter to answer it there.\n\n")
+		boxContent.WriteString(helpStyle.Render("  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Answer Prompt ", boxContent.String(), boxWidth, ColorYellow)
+	}
+
+	for _, line := range d.Context {
+		boxContent.WriteString("  " + dimStyle.Render(truncateRunes(line, textWidth)) + "\n")
+	}
+	if len(d.Context) > 0 {
+		boxContent.WriteString("\n")
+	}
+	boxContent.WriteString("  " + questionStyle.Render(truncateRunes(d.Question, textWidth)) + "\n\n")
+	for i, option := range d.Options {
+		prefix := "  "
+		style := normalStyle
+		if i == m.answerCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		label := fmt.Sprintf("%d. %s", option.Number, option.Label)
+		boxContent.WriteString(fmt.Sprintf("  %s%s\n", prefix, style.Render(truncateRunes(label, textWidth-2))))
+	}
+
+	boxContent.WriteString("\n")
+	help := "  ↑/↓: select  enter/1-9: answer  esc: cancel"
+	if len(m.answerWindows) > 1 {
+		help = "  ↑/↓: select  enter/1-9: answer  tab: next  esc: cancel"
+	}
+	boxContent.WriteString(helpStyle.Render(help))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Answer Prompt ", boxContent.String(), boxWidth, ColorYellow)
+}
+
 // confirmYoloView renders the YOLO mode confirmation dialog
 func (m Model) confirmYoloView() string {
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
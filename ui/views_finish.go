@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// finishDiffHeight is how many diff lines the finish dialog shows, leaving
+// room for the commit message under them.
+func (m Model) finishDiffHeight() int {
+	return max(m.height-28, 4)
+}
+
+// finishActionLabel describes what an action does with the session's work.
+func finishActionLabel(inst *session.Instance, action session.FinishAction, exports string) string {
+	switch action {
+	case session.FinishMerge:
+		return fmt.Sprintf("Merge %s into %s", inst.Branch, inst.BaseBranch)
+	case session.FinishRebase:
+		return fmt.Sprintf("Rebase %s onto %s, then fast-forward %s", inst.Branch, inst.BaseBranch, inst.BaseBranch)
+	case session.FinishPatch:
+		return "Write a patch to " + exports
+	case session.FinishBundle:
+		return "Write a git bundle to " + exports
+	}
+	if inst.Branch != "" {
+		return "Leave the commit on " + inst.Branch
+	}
+	return "Leave the commit where it is"
+}
+
+// finishView renders the finish dialog: the session's diff, then the commit
+// message or what to do with the commit, over the list
+func (m Model) finishView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	inst := m.finishSession
+	if inst == nil {
+		return m.listView()
+	}
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+	column := boxWidth - 6
+
+	var b strings.Builder
+	b.WriteString("\n")
+	where := inst.Path
+	if inst.Branch != "" {
+		where = "branch " + inst.Branch
+		if inst.BaseBranch != "" {
+			where += " from " + inst.BaseBranch
+		}
+	}
+	b.WriteString("  " + dimStyle.Render(truncateRunes(where+" · "+m.finishSummary, column)) + "\n\n")
+
+	height := m.finishDiffHeight()
+	for i := m.finishScroll; i < m.finishScroll+height; i++ {
+		line := ""
+		if i < len(m.finishDiff) {
+			line = colorDiffLine(truncateRunes(strings.ReplaceAll(m.finishDiff[i], "\t", "    "), column))
+		}
+		b.WriteString("  " + line + "\n")
+	}
+	if len(m.finishDiff) > height {
+		b.WriteString("  " + dimStyle.Render(fmt.Sprintf("lines %d-%d of %d", m.finishScroll+1, min(m.finishScroll+height, len(m.finishDiff)), len(m.finishDiff))) + "\n")
+	}
+	b.WriteString("\n")
+
+	help := "  ctrl+s: next  ctrl+d: ask the agent to draft  pgup/pgdn: scroll diff  esc: cancel"
+	if m.finishStep == finishActions {
+		b.WriteString("  " + labelStyle.Render("Then") + "\n")
+		exports := m.storage.ExportsDir()
+		for i, action := range inst.FinishActions() {
+			line := finishActionLabel(inst, action, exports)
+			if i == m.finishCursor {
+				b.WriteString("  ▸ " + selectedStyle.Render(truncateRunes(line, column-2)) + "\n")
+			} else {
+				b.WriteString("    " + truncateRunes(line, column-2) + "\n")
+			}
+		}
+		after := "The session is stopped afterwards."
+		if inst.WorktreePath != "" {
+			after = "The session is deleted afterwards, with its worktree; with its branch once merged."
+		}
+		b.WriteString("\n  " + dimStyle.Render(after) + "\n")
+		help = "  ↑/↓: choose  enter: finish  esc: back to the message"
+	} else {
+		label := "Commit message"
+		if m.finishStaged {
+			label = "Commit message, for the staged changes only"
+		}
+		if !m.finishDirty {
+			label = "Nothing uncommitted: the commits are finished as they are"
+		}
+		b.WriteString("  " + labelStyle.Render(label) + "\n")
+		m.promptInput.SetWidth(column)
+		for _, line := range strings.Split(m.promptInput.View(), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	if m.finishBusy != "" {
+		b.WriteString("\n  " + selectedStyle.Render(m.finishBusy) + "\n")
+	}
+	if m.finishErr != "" {
+		b.WriteString("\n  " + errStyle.Render(truncateRunes(m.finishErr, column)) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(help))
+	b.WriteString("\n")
+	return m.renderOverlayDialog(" Finish: "+truncateRunes(inst.Name, 40)+" ", b.String(), boxWidth, ColorGreen)
+}
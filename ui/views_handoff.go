@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handoffView renders the handoff dialog: the agent to hand over to, then
+// the prompt it will be sent, over the list
+func (m Model) handoffView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+
+	from := ""
+	if m.handoffSession != nil {
+		from = m.handoffSession.Name
+		if m.handoffTab != "" && m.handoffTab != m.handoffSession.Name {
+			from += " / " + m.handoffTab
+		}
+	}
+	title := " Hand Off: " + truncateRunes(from, 40) + " "
+
+	var b strings.Builder
+	b.WriteString("\n")
+	agents := m.handoffAgents()
+
+	if m.handoffEditing {
+		to := ""
+		if m.handoffCursor < len(agents) {
+			to = string(agents[m.handoffCursor])
+		}
+		b.WriteString("  " + labelStyle.Render("Opening prompt for a new "+to+" tab") + "\n")
+		m.promptInput.SetWidth(boxWidth - 6)
+		for _, line := range strings.Split(m.promptInput.View(), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+		if m.handoffErr != "" {
+			b.WriteString("\n  " + errStyle.Render(truncateRunes(m.handoffErr, boxWidth-6)) + "\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("  ctrl+s: open the tab and send  esc: back"))
+		b.WriteString("\n")
+		return m.renderOverlayDialog(title, b.String(), boxWidth, ColorCyan)
+	}
+
+	b.WriteString("  " + dimStyle.Render(fmt.Sprintf("Continue %s's task in a new tab, with another agent.", m.handoffFrom)) + "\n\n")
+	b.WriteString("  " + labelStyle.Render("Hand off to") + "\n")
+	for i, a := range agents {
+		line := fmt.Sprintf("%s %s", agentIcons[a], a)
+		if i == m.handoffCursor {
+			b.WriteString("  ▸ " + selectedStyle.Render(line) + "\n")
+		} else {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	b.WriteString("\n  " + dimStyle.Render(fmt.Sprintf("Carries the last %d turns, the notes and the changed files.", m.handoffTurns)) + "\n")
+	if m.handoffErr != "" {
+		b.WriteString("\n  " + errStyle.Render(truncateRunes(m.handoffErr, boxWidth-6)) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("  ↑/↓: agent  +/-: turns  enter: review the prompt  esc: cancel"))
+	b.WriteString("\n")
+	return m.renderOverlayDialog(title, b.String(), boxWidth, ColorCyan)
+}
@@ -101,11 +101,39 @@ func buildHelpContent(width int) (string, int) {
 	b.WriteString("\n")
 	b.WriteString("  " + noteStyle.Render("     ↳ x/d asks session or tab when multiple tabs exist"))
 	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Tab at a new session's name gives it its own git worktree; d offers to remove it"))
+	b.WriteString("\n")
 	b.WriteString(renderRow("r", "Resume conversation", "p", "Send prompt"))
 	b.WriteString("\n")
-	b.WriteString(renderRow("f", "Fork session (Claude)", "", ""))
+	b.WriteString(renderRow("f", "Fork session (Claude)", "y", "Answer waiting prompt"))
 	b.WriteString("\n")
 	b.WriteString("  " + noteStyle.Render("     ↳ Fork to new tab or new session"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("Q", "Prompt queue (reorder, edit, pause)"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Ctrl+Q in the prompt dialog queues instead of sending"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Ctrl+L in the prompt dialog inserts a saved snippet"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Ctrl+O attaches a file, diff, tab output or another session's answer"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("B", "Broadcast prompt to several sessions"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Group, favorites, search results or picked by hand; Ctrl+D for a dry run"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("S", "Scheduled prompts (once or cron)"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("H", "Prompt history (re-send to any session)"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ ↑/↓ and Ctrl+R in the prompt dialog recall past prompts"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("P", "Pipelines (one session's answers to another)"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("C", "Compare agents on one prompt (own worktrees)"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("O", "Hand the tab's task off to another agent"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("X", "Finish: commit, then merge, rebase, export or leave"))
 	b.WriteString("\n\n")
 
 	// ═══════════════════════════════════════════════════════════════════
@@ -173,11 +201,37 @@ func buildHelpContent(width int) (string, int) {
 	b.WriteString("\n")
 	b.WriteString(separatorStyle.Render("  " + strings.Repeat("─", 65)))
 	b.WriteString("\n")
-	b.WriteString(renderRow("D", "Toggle Preview/Diff", "F", "Switch Session/Full diff"))
+	b.WriteString(renderRow("D", "Toggle Preview/Diff", "F", "Session/Full/Staged diff"))
+	b.WriteString("\n")
+	b.WriteString(renderRow("J/K", "Next/previous file", "}/{", "Next/previous hunk"))
+	b.WriteString("\n")
+	b.WriteString(renderRow("z/Z", "Fold file/all files", "L", "Show/hide file list"))
+	b.WriteString("\n")
+	b.WriteString(renderRow("V", "Layout: auto/unified/split", "^G", "Filter files by path"))
+	b.WriteString("\n")
+	b.WriteString(renderRow("+/-", "Stage/unstage hunk or file", "!", "Discard hunk or file"))
+	b.WriteString("\n")
+	b.WriteString(renderRow("j/k", "Next/previous line", "i", "Comment on line or hunk"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("^R", "Review comments: send to the agent, queue, resolve"))
 	b.WriteString("\n")
 	b.WriteString("  " + noteStyle.Render("     ↳ Session diff: changes since session start"))
 	b.WriteString("\n")
-	b.WriteString("  " + noteStyle.Render("     ↳ Full diff: all uncommitted changes"))
+	b.WriteString("  " + noteStyle.Render("     ↳ Full diff: uncommitted changes not yet staged; Staged: the index"))
+	b.WriteString("\n\n")
+
+	// ═══════════════════════════════════════════════════════════════════
+	// ACTIVITY
+	// ═══════════════════════════════════════════════════════════════════
+	b.WriteString(sectionStyle.Render("  Activity"))
+	b.WriteString("\n")
+	b.WriteString(separatorStyle.Render("  " + strings.Repeat("─", 65)))
+	b.WriteString("\n")
+	b.WriteString(renderRow("A", "Toggle activity timeline", "F", "Switch time range"))
+	b.WriteString("\n")
+	b.WriteString("  " + renderKey("M", "Mute/unmute notifications for session"))
+	b.WriteString("\n")
+	b.WriteString("  " + noteStyle.Render("     ↳ Busy time, time waiting on you and prompts per tab"))
 	b.WriteString("\n\n")
 
 	// ═══════════════════════════════════════════════════════════════════
@@ -218,6 +272,8 @@ func buildHelpContent(width int) (string, int) {
 	b.WriteString("\n")
 	b.WriteString("  " + idleStyle.Render("●") + descStyle.Render(" Idle (ready)") + "      ")
 	b.WriteString(stoppedStyle.Render("○") + descStyle.Render(" Stopped"))
+	b.WriteString("\n")
+	b.WriteString("  " + stalledStyle.Render("◉") + descStyle.Render(" Stalled (busy, no progress)"))
 	b.WriteString("\n\n")
 
 	// ═══════════════════════════════════════════════════════════════════
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// pipelinesView renders the project's pipelines, or the editor, over the
+// list
+func (m Model) pipelinesView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	runningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorGreen))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+
+	if m.pipelineEditing {
+		boxContent.WriteString(m.pipelineEditorContent(boxWidth))
+		if m.pipelineErr != "" {
+			boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.pipelineErr, boxWidth-6)) + "\n")
+		}
+		boxContent.WriteString("\n")
+		switch {
+		case m.pipelinePicking:
+			boxContent.WriteString(helpStyle.Render("  ↑/↓: select  enter: add  esc: back"))
+		case m.pipelineFocus == 1:
+			boxContent.WriteString(helpStyle.Render("  tab: back to the stages  ctrl+s: save  esc: cancel"))
+		default:
+			boxContent.WriteString(helpStyle.Render("  a: add stage  x: remove  J/K: move  t: tab  tab: edit template"))
+			boxContent.WriteString("\n")
+			boxContent.WriteString(helpStyle.Render("  l: loop  +/-: rounds  ctrl+s: save  esc: cancel"))
+		}
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Pipelines ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if m.pipelinesLoadErr != nil {
+		boxContent.WriteString("  " + errStyle.Render(truncateRunes(m.pipelinesLoadErr.Error(), boxWidth-6)) + "\n")
+		boxContent.WriteString(dimStyle.Render("  No pipeline runs until pipelines.json is fixed.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Pipelines ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if len(m.pipelines) == 0 {
+		boxContent.WriteString("  No pipelines.\n")
+		boxContent.WriteString(dimStyle.Render("  n passes the split view's marked session's answers to the selected one.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  n: new  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Pipelines ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	visible := max((m.height-16)/2, 3)
+	start := 0
+	if m.pipelineCursor >= visible {
+		start = m.pipelineCursor - visible + 1
+	}
+	end := min(start+visible, len(m.pipelines))
+	for i := start; i < end; i++ {
+		p := m.pipelines[i]
+		prefix := "  "
+		style := normalStyle
+		if i == m.pipelineCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		boxContent.WriteString(fmt.Sprintf("  %s%s\n", prefix, style.Render(truncateRunes(pipelineLabel(m.instances, *p), boxWidth-10))))
+		boxContent.WriteString("      " + m.pipelineStatus(p, runningStyle, errStyle) + "\n")
+	}
+	if len(m.pipelines) > end {
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.pipelines)-end)) + "\n")
+	}
+
+	if m.pipelineErr != "" {
+		boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.pipelineErr, boxWidth-6)) + "\n")
+	}
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  n: new  e: edit  d: delete  space: stop/resume  r: restart"))
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  esc: close"))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Pipelines ", boxContent.String(), boxWidth, ColorCyan)
+}
+
+// pipelineStatus is a pipeline's round counter and what it is doing.
+func (m Model) pipelineStatus(p *session.Pipeline, runningStyle, errStyle lipgloss.Style) string {
+	rounds := ""
+	if p.Loop {
+		rounds = fmt.Sprintf("round %d/%d  ", min(max(p.Round, 1), p.Rounds), p.Rounds)
+	}
+	switch {
+	case p.Running && p.Stage < len(p.Stages):
+		return runningStyle.Render("▶ "+rounds) + dimStyle.Render("waiting for "+pipelineStageName(m.instances, p.Stages[p.Stage]))
+	case p.LastResult == "done":
+		return dimStyle.Render("✓ " + rounds + "done")
+	case p.LastResult == "stopped" || p.LastResult == "":
+		return dimStyle.Render("■ " + rounds + "stopped")
+	}
+	return errStyle.Render(truncateRunes("■ "+rounds+p.LastResult, 80))
+}
+
+// pipelineEditorContent is the editor's stages, the loop, and the template
+// being edited, or the session picker.
+func (m Model) pipelineEditorContent(boxWidth int) string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+
+	var b strings.Builder
+	if m.pipelinePicking {
+		b.WriteString("  Add a stage after the selected one:\n\n")
+		for i, inst := range m.instances {
+			if i == m.pipelinePickCursor {
+				b.WriteString("  ▸ " + selectedStyle.Render(truncateRunes(inst.Name, boxWidth-10)) + "\n")
+			} else {
+				b.WriteString("    " + truncateRunes(inst.Name, boxWidth-10) + "\n")
+			}
+		}
+		return b.String()
+	}
+
+	b.WriteString("  " + labelStyle.Render("Stages") + "\n")
+	if len(m.pipelineDraft.Stages) == 0 {
+		b.WriteString(dimStyle.Render("  None yet: a adds one.") + "\n")
+	}
+	for i, s := range m.pipelineDraft.Stages {
+		name := fmt.Sprintf("%d. %s", i+1, pipelineStageName(m.instances, s))
+		template := "sent the answer as it is"
+		if i == 0 {
+			template = "sent nothing to start: give it its task"
+			if m.pipelineDraft.Loop {
+				template = "sent the last stage's answer"
+			}
+		}
+		if strings.TrimSpace(s.Template) != "" && (i > 0 || m.pipelineDraft.Loop) {
+			template = firstLine(s.Template)
+		}
+		line := truncateRunes(name, 30)
+		if i == m.pipelineStage {
+			line = "▸ " + selectedStyle.Render(line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString("  " + line + "  " + dimStyle.Render(truncateRunes(template, boxWidth-42)) + "\n")
+	}
+	loop := "no: stops once the last stage is sent to"
+	if m.pipelineDraft.Loop {
+		loop = fmt.Sprintf("yes, %d round(s): back to the first stage after the last", m.pipelineDraft.Rounds)
+	}
+	b.WriteString("\n" + dimStyle.Render("  Loop: "+loop) + "\n")
+
+	if m.pipelineFocus == 1 && m.pipelineStage < len(m.pipelineDraft.Stages) {
+		b.WriteString("\n  " + labelStyle.Render(fmt.Sprintf("Template for stage %d", m.pipelineStage+1)) + "\n")
+		m.promptInput.SetWidth(boxWidth - 6)
+		for _, line := range strings.Split(m.promptInput.View(), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString(dimStyle.Render("  {{output}}: the answer  {{from}}: its session  {{round}}, {{rounds}}") + "\n")
+	}
+	return b.String()
+}
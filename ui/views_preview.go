@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/izll/agent-session-manager/session"
@@ -29,15 +30,27 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 		headerInst = m.instances[m.cursor]
 	}
 
-	// Build tab bar (Preview / Diff) - separator between tabs, not before
+	// Build tab bar (Preview / Diff / Timeline) - separator between tabs, not before
 	// Use selectedStyle (no padding) for precise control
 	border := dimStyle.Render("│")
 	activeTab := selectedStyle.Bold(true)
-	var tabBar string
+	tabLabels := []string{"  Preview  ", "  Diff  ", "  Timeline  "}
+	activeIdx := 0
 	if m.showDiff {
-		tabBar = dimStyle.Render("  Preview  ") + border + activeTab.Render("  Diff  ")
-	} else {
-		tabBar = activeTab.Render("  Preview  ") + border + dimStyle.Render("  Diff  ")
+		activeIdx = 1
+	} else if m.showTimeline {
+		activeIdx = 2
+	}
+	var tabBar string
+	for i, label := range tabLabels {
+		if i > 0 {
+			tabBar += border
+		}
+		if i == activeIdx {
+			tabBar += activeTab.Render(label)
+		} else {
+			tabBar += dimStyle.Render(label)
+		}
 	}
 
 	var title string
@@ -107,6 +120,8 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 							switch m.activityState[s.ID] {
 							case session.ActivityBusy:
 								statusIcon = activeStyle.Render("●")
+							case session.ActivityStalled:
+								statusIcon = stalledStyle.Render("◉")
 							case session.ActivityWaiting:
 								statusIcon = waitingStyle.Render("●")
 							default:
@@ -212,9 +227,28 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 
 		// View mode with hint
 		diffModeLabel := m.diffPane.GetModeLabel()
-		rightPane.WriteString("  " + projectLabelStyle.Render("View: ") + projectNameStyle.Render(diffModeLabel) + dimStyle.Render(" (F to switch)"))
+		hint := " (F to switch)"
+		if previewWidth >= 110 {
+			hint = " (F to switch · V layout · J/K files · {/} hunks · j/k lines · z fold · ^G filter)"
+		}
+		rightPane.WriteString("  " + projectLabelStyle.Render("View: ") + projectNameStyle.Render(diffModeLabel+" · "+m.diffPane.GetLayoutLabel()) + dimStyle.Render(hint))
 		rightPane.WriteString("\n")
 
+		// The hunk or file the diff keys act on
+		if selection := m.diffPane.SelectionLabel(); selection != "" {
+			actions := " (+ stage · ! discard"
+			if m.diffPane.GetMode() == DiffModeStaged {
+				actions = " (- unstage"
+			}
+			if _, hunk, _ := m.diffPane.Selection(); hunk >= 0 {
+				actions += " · i comment"
+			}
+			actions += ")"
+			room := max(previewWidth-14-len(actions), 10)
+			rightPane.WriteString("  " + projectLabelStyle.Render("Selected: ") + projectNameStyle.Render(truncateLeft(selection, room)) + dimStyle.Render(actions))
+			rightPane.WriteString("\n")
+		}
+
 		// Horizontal separator
 		rightPane.WriteString(dimStyle.Render(strings.Repeat("─", previewWidth)))
 		rightPane.WriteString("\n")
@@ -223,6 +257,26 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 		return m.buildDiffContent(rightPane.String(), contentHeight, headerLines, previewWidth)
 	}
 
+	// Timeline mode - Path, the range and its hint, then the bands
+	if m.showTimeline {
+		rightPane.WriteString("  " + projectLabelStyle.Render("Path: ") + projectNameStyle.Render(inst.Path))
+		rightPane.WriteString("\n")
+		rangeLabel := fmt.Sprintf("Last %dh", int(m.getTimelineRange().Hours()))
+		rightPane.WriteString("  " + projectLabelStyle.Render("Range: ") + projectNameStyle.Render(rangeLabel) + dimStyle.Render(" (F to switch)"))
+		rightPane.WriteString("\n")
+		rightPane.WriteString(dimStyle.Render(strings.Repeat("─", previewWidth)))
+		rightPane.WriteString("\n")
+
+		headerLines := strings.Count(rightPane.String(), "\n") + 1
+		rightPane.WriteString(m.buildTimelineContent(inst, contentHeight-headerLines, previewWidth))
+
+		resultLines := strings.Split(rightPane.String(), "\n")
+		if len(resultLines) > contentHeight {
+			resultLines = resultLines[:contentHeight]
+		}
+		return strings.Join(resultLines, "\n")
+	}
+
 	// Get window list for tab display
 	windows := inst.GetWindowList()
 	var activeWindow *session.WindowInfo
@@ -260,12 +314,15 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 						break
 					}
 				}
-				// Only show activity indicator for non-terminal agents
-				if !isTerminal {
-					// Read what the poll already worked out. Probing here ran
-					// a tmux capture — and a 60ms sleep for any busy pane —
-					// on every render of every tab.
-					activity := m.windowActivityState[inst.ID][w.Index]
+				// Read what the poll already worked out. Probing here ran
+				// a tmux capture — and a 60ms sleep for any busy pane —
+				// on every render of every tab.
+				activity := m.windowActivityState[inst.ID][w.Index]
+				// Only show activity indicator for non-terminal agents, and
+				// for a terminal whose program has stalled
+				if activity == session.ActivityStalled {
+					tabIndicator = stalledStyle.Render("◉ ")
+				} else if !isTerminal {
 					switch activity {
 					case session.ActivityWaiting:
 						tabIndicator = waitingStyle.Render("● ")
@@ -369,6 +426,57 @@ func (m Model) buildPreviewPane(contentHeight int) string {
 		rightPane.WriteString("\n")
 	}
 
+	// Prompts may be answered without asking; that should never be a surprise
+	if policy := m.projectApproval.Merge(inst.Approval); !policy.Empty() {
+		rightPane.WriteString("  " + projectLabelStyle.Render("Auto-approve: ") +
+			projectNameStyle.Render(fmt.Sprintf("%d allow, %d deny rules", len(policy.Allow), len(policy.Deny))))
+		rightPane.WriteString("\n")
+	}
+
+	// A prompt can be answered from here, so say so
+	if waiting := len(m.waitingWindows(inst)); waiting > 0 {
+		waitingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+		text := "needs an answer"
+		if waiting > 1 {
+			text = fmt.Sprintf("%d tabs need an answer", waiting)
+		}
+		// The agent said what it is asking about
+		if tool := m.pendingTool(inst); tool != "" {
+			text += " — " + truncateRunes(tool, max(previewWidth-40, 10))
+		}
+		rightPane.WriteString("  " + projectLabelStyle.Render("Waiting: ") + waitingStyle.Render(text) + dimStyle.Render(" (y to answer)"))
+		rightPane.WriteString("\n")
+	}
+
+	// Prompts lined up for when the agent finishes
+	if queued := inst.QueuedCount(); queued > 0 {
+		queueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan))
+		text := fmt.Sprintf("%d prompts", queued)
+		if queued == 1 {
+			text = "1 prompt"
+		}
+		if inst.QueuePaused {
+			text += ", paused"
+		}
+		rightPane.WriteString("  " + projectLabelStyle.Render("Queue: ") + queueStyle.Render(text) + dimStyle.Render(" (Q to manage)"))
+		rightPane.WriteString("\n")
+	}
+
+	// Held by a usage limit, and what happens when it lifts
+	if limit, ok := m.sessionRateLimit(inst.ID); ok {
+		limitStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+		text := "until the message goes away"
+		if !limit.Until.IsZero() {
+			text = "until " + formatResetTime(limit.Until, time.Now())
+		}
+		hint := " (auto-continue off)"
+		if m.rateLimitConfig.Enabled() && !limit.Until.IsZero() {
+			hint = fmt.Sprintf(" (then sends %q)", m.rateLimitConfig.ContinuePrompt())
+		}
+		rightPane.WriteString("  " + projectLabelStyle.Render("Rate limited: ") + limitStyle.Render(text) + dimStyle.Render(hint))
+		rightPane.WriteString("\n")
+	}
+
 	// Horizontal separator
 	rightPane.WriteString(dimStyle.Render(strings.Repeat("─", previewWidth)))
 	rightPane.WriteString("\n")
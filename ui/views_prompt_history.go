@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/izll/agent-session-manager/session"
+)
+
+// historyView renders the prompt history panel, or its session picker, over
+// the list
+func (m Model) historyView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+	title := " Prompt History "
+	if !m.historyAll && m.historySession != nil {
+		title = " Prompt History: " + truncateRunes(m.historySession.Name, 30) + " "
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+
+	if m.historyPicking {
+		boxContent.WriteString("  Send to the main agent of:\n\n")
+		for i, inst := range m.historyTargets() {
+			if i == m.historyPickCursor {
+				boxContent.WriteString("  ▸ " + selectedStyle.Render(truncateRunes(inst.Name, boxWidth-10)) + "\n")
+			} else {
+				boxContent.WriteString("    " + normalStyle.Render(truncateRunes(inst.Name, boxWidth-10)) + "\n")
+			}
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ↑/↓: select  enter: send  esc: back"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(title, boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if len(m.historyEntries) == 0 {
+		boxContent.WriteString("  Nothing sent yet.\n")
+		if m.historyErr != "" {
+			boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.historyErr, boxWidth-6)) + "\n")
+		}
+		boxContent.WriteString("\n")
+		help := "  esc: close"
+		if m.historySession != nil {
+			help = "  a: all sessions  esc: close"
+		}
+		boxContent.WriteString(helpStyle.Render(help))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(title, boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	now := time.Now()
+	visible := max((m.height-22)/2, 3)
+	start := 0
+	if m.historyCursor >= visible {
+		start = m.historyCursor - visible + 1
+	}
+	end := min(start+visible, len(m.historyEntries))
+	for i := start; i < end; i++ {
+		p := m.historyEntries[i]
+		prefix := "  "
+		style := normalStyle
+		if i == m.historyCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		target := historyTarget(m.instances, p)
+		details := fmt.Sprintf("%s  %s, %s", p.Agent, p.Activity, p.Via)
+		boxContent.WriteString(fmt.Sprintf("  %s%s  %s  %s\n", prefix,
+			formatHistoryTime(p.Time, now), style.Render(truncateRunes(target, 36)), dimStyle.Render(details)))
+		boxContent.WriteString("      " + dimStyle.Render(truncateRunes(firstLine(p.Text), boxWidth-12)) + "\n")
+	}
+	if len(m.historyEntries) > end {
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.historyEntries)-end)) + "\n")
+	}
+
+	// The whole of the selected prompt, as far as it fits
+	if m.historyCursor < len(m.historyEntries) {
+		lines := strings.Split(m.historyEntries[m.historyCursor].Text, "\n")
+		if len(lines) > 6 {
+			lines = append(lines[:6], "…")
+		}
+		boxContent.WriteString("\n")
+		for _, line := range lines {
+			boxContent.WriteString("  " + normalStyle.Render(truncateRunes(line, boxWidth-6)) + "\n")
+		}
+	}
+
+	if m.historyErr != "" {
+		boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.historyErr, boxWidth-6)) + "\n")
+	}
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  enter: edit and send  s: send again  o: send to another session"))
+	boxContent.WriteString("\n")
+	help := "  esc: close"
+	if m.historySession != nil {
+		help = "  a: this session / all sessions  esc: close"
+	}
+	boxContent.WriteString(helpStyle.Render(help))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(title, boxContent.String(), boxWidth, ColorCyan)
+}
+
+// historyTarget names where a prompt went: the session by its current name,
+// or the name it had, and the tab if not the main agent.
+func historyTarget(instances []*session.Instance, p session.SentPrompt) string {
+	name := p.Session
+	if inst := findInstance(instances, p.SessionID); inst != nil {
+		name = inst.Name
+	}
+	if p.Tab != "" {
+		name += " / " + p.Tab
+	}
+	return name
+}
+
+// formatHistoryTime shows when a prompt was sent: the time alone today, the
+// date within the year, the full date before.
+func formatHistoryTime(t, now time.Time) string {
+	switch {
+	case t.YearDay() == now.YearDay() && t.Year() == now.Year():
+		return t.Format("15:04")
+	case t.Year() == now.Year():
+		return t.Format("Jan 02 15:04")
+	}
+	return t.Format("2006-01-02")
+}
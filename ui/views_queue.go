@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// queueView renders a session's prompt queues, tab by tab, over the list
+func (m Model) queueView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	tabStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+	pausedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow)).Bold(true)
+
+	inst := m.queueTarget
+	if inst == nil {
+		return m.listView()
+	}
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+	textWidth := boxWidth - 12
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+	boxContent.WriteString(fmt.Sprintf("  Session: %s", inst.Name))
+	if inst.QueuePaused {
+		boxContent.WriteString("  " + pausedStyle.Render("⏸ paused"))
+	}
+	boxContent.WriteString("\n\n")
+
+	items := m.queueItems(inst)
+	if len(items) == 0 {
+		boxContent.WriteString("  Nothing queued.\n")
+		boxContent.WriteString(dimStyle.Render("  Press ctrl+q instead of ctrl+s in the prompt dialog (p)") + "\n")
+		boxContent.WriteString(dimStyle.Render("  to send a prompt when the tab next finishes.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  space: pause  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Prompt Queue ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if m.queueEditing {
+		m.promptInput.SetWidth(boxWidth - 6)
+		boxContent.WriteString("  Edit prompt:\n")
+		for _, line := range strings.Split(m.promptInput.View(), "\n") {
+			boxContent.WriteString("  " + line + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ctrl+s: save  esc: cancel"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Prompt Queue ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	window := -1
+	for row, item := range items {
+		if item.window != window {
+			window = item.window
+			if row > 0 {
+				boxContent.WriteString("\n")
+			}
+			boxContent.WriteString("  " + tabStyle.Render(m.queueTabName(window)) + "\n")
+		}
+		text := inst.QueuedPrompts(item.window)[item.index].Text
+		// One line per prompt; the editor shows the rest
+		if idx := strings.Index(text, "\n"); idx != -1 {
+			text = text[:idx] + " …"
+		}
+		prefix := "  "
+		style := normalStyle
+		if row == m.queueCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		label := fmt.Sprintf("%d. %s", item.index+1, text)
+		boxContent.WriteString(fmt.Sprintf("    %s%s\n", prefix, style.Render(truncateRunes(label, textWidth))))
+	}
+
+	boxContent.WriteString("\n")
+	boxContent.WriteString(dimStyle.Render("  Each tab sends its next prompt when it goes from busy to idle.") + "\n\n")
+	boxContent.WriteString(helpStyle.Render("  ↑/↓: select  ctrl+↑/↓: move  e: edit  d: delete  s: send now"))
+	boxContent.WriteString("\n")
+	pause := "pause"
+	if inst.QueuePaused {
+		pause = "resume"
+	}
+	boxContent.WriteString(helpStyle.Render("  space: " + pause + "  esc: close"))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Prompt Queue ", boxContent.String(), boxWidth, ColorCyan)
+}
+
+// queueTabName is the heading of a tab's queue in the queue view.
+func (m Model) queueTabName(window int) string {
+	inst := m.queueTarget
+	if window == m.mainWindowIndex[inst.ID] {
+		return "Main agent"
+	}
+	for _, fw := range inst.FollowedWindows {
+		if fw.Index == window {
+			return "Tab: " + fw.Name
+		}
+	}
+	return fmt.Sprintf("Window %d", window)
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reviewCommentView renders the dialog for a comment on a line or hunk of
+// the diff, over the list
+func (m Model) reviewCommentView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+	column := boxWidth - 6
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("  " + truncateRunes(m.reviewTarget, column) + "\n\n")
+
+	// The code the comment is about, as the prompt will quote it
+	file, hunk := m.reviewFile, m.reviewHunk
+	if hunk >= 0 && hunk < len(file.Hunks) {
+		h := file.Hunks[hunk]
+		quote := file.Lines[h.Start:h.End]
+		if m.reviewLine >= 0 {
+			quote = file.Lines[m.reviewLine : m.reviewLine+1]
+		}
+		for i, line := range quote {
+			if i == 8 {
+				b.WriteString("  " + dimStyle.Render(fmt.Sprintf("… %d more lines", len(quote)-i)) + "\n")
+				break
+			}
+			b.WriteString("  " + colorDiffLine(truncateRunes(strings.ReplaceAll(line, "\t", "    "), column)) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	m.promptInput.SetWidth(column)
+	for _, line := range strings.Split(m.promptInput.View(), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	if m.reviewErr != "" {
+		b.WriteString("\n  " + errStyle.Render(truncateRunes(m.reviewErr, column)) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("  ctrl+s: add comment  esc: cancel"))
+	b.WriteString("\n")
+	return m.renderOverlayDialog(" Review Comment ", b.String(), boxWidth, ColorYellow)
+}
+
+// reviewView renders a session's review comments, open then resolved, over
+// the list
+func (m Model) reviewView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	inst := m.reviewSession
+	if inst == nil {
+		return m.listView()
+	}
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+	textWidth := boxWidth - 10
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("  Session: %s", inst.Name))
+	open := inst.OpenReviewComments()
+	b.WriteString("  " + dimStyle.Render(fmt.Sprintf("%d open, %d resolved", open, len(inst.ReviewComments)-open)) + "\n\n")
+
+	if len(inst.ReviewComments) == 0 {
+		b.WriteString("  No review comments.\n")
+		b.WriteString(dimStyle.Render("  In the diff view (d), select a line with j/k or a hunk") + "\n")
+		b.WriteString(dimStyle.Render("  with }/{ and press i to comment on it.") + "\n\n")
+		b.WriteString(helpStyle.Render("  esc: close"))
+		b.WriteString("\n")
+		return m.renderOverlayDialog(" Review ", b.String(), boxWidth, ColorYellow)
+	}
+
+	for i, c := range inst.ReviewComments {
+		prefix := "  "
+		style := normalStyle
+		if i == m.reviewCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		mark := "● "
+		switch {
+		case c.Resolved:
+			mark = "✓ "
+		case c.Sent:
+			mark = "→ "
+		}
+		b.WriteString("  " + prefix + mark + style.Render(truncateRunes(c.Where(), textWidth)) + "\n")
+		// One line of the comment; the diff shows the rest
+		text := c.Text
+		if idx := strings.Index(text, "\n"); idx != -1 {
+			text = text[:idx] + " …"
+		}
+		b.WriteString("      " + dimStyle.Render(truncateRunes(text, textWidth)) + "\n")
+	}
+	b.WriteString("\n  " + dimStyle.Render("● open  → sent  ✓ resolved: its lines have changed") + "\n")
+
+	if m.reviewErr != "" {
+		b.WriteString("\n  " + errStyle.Render(truncateRunes(m.reviewErr, boxWidth-6)) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("  s: send open  q: queue  enter: show  r: resolve  d: delete  c: clear resolved  esc: close"))
+	b.WriteString("\n")
+	return m.renderOverlayDialog(" Review ", b.String(), boxWidth, ColorYellow)
+}
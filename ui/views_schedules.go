@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// schedulesView renders the project's scheduled prompts, or the editor, over
+// the list
+func (m Model) schedulesView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	pausedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 80
+	if m.width > 120 {
+		boxWidth = 100
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+
+	if m.scheduleEditing {
+		boxContent.WriteString(m.scheduleEditorContent(boxWidth))
+		if m.scheduleErr != "" {
+			boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.scheduleErr, boxWidth-6)) + "\n")
+		}
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  tab: switch field  ctrl+t: tab  ctrl+o: start if stopped"))
+		boxContent.WriteString("\n")
+		boxContent.WriteString(helpStyle.Render("  ctrl+s: save  esc: cancel"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Scheduled Prompts ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if m.schedulesLoadErr != nil {
+		boxContent.WriteString("  " + errStyle.Render(truncateRunes(m.schedulesLoadErr.Error(), boxWidth-6)) + "\n")
+		boxContent.WriteString(dimStyle.Render("  Nothing is scheduled until schedules.json is fixed.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Scheduled Prompts ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	if len(m.schedules) == 0 {
+		boxContent.WriteString("  Nothing scheduled.\n")
+		boxContent.WriteString(dimStyle.Render("  n schedules a prompt for the selected session.") + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  n: new  esc: close"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Scheduled Prompts ", boxContent.String(), boxWidth, ColorCyan)
+	}
+
+	visible := max((m.height-16)/3, 3)
+	start := 0
+	if m.scheduleCursor >= visible {
+		start = m.scheduleCursor - visible + 1
+	}
+	end := min(start+visible, len(m.schedules))
+	for i := start; i < end; i++ {
+		s := m.schedules[i]
+		prefix := "  "
+		style := normalStyle
+		if i == m.scheduleCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		name := scheduleSessionName(m.instances, *s)
+		line := fmt.Sprintf("  %s%s  %s", prefix, style.Render(truncateRunes(name, 30)), dimStyle.Render(s.When()))
+		if s.StartIfStopped {
+			line += dimStyle.Render("  (starts it)")
+		}
+		boxContent.WriteString(line + "\n")
+
+		var next string
+		switch {
+		case s.Paused:
+			next = pausedStyle.Render("⏸ paused")
+		case s.Next.IsZero():
+			next = dimStyle.Render("done")
+		default:
+			next = "next " + formatScheduleTime(s.Next, time.Now())
+		}
+		status := "      " + next
+		if s.LastResult != "" {
+			status += dimStyle.Render("  ·  last: " + truncateRunes(s.LastResult, boxWidth-50))
+		}
+		boxContent.WriteString(status + "\n")
+		text := "      " + dimStyle.Render(truncateRunes(firstLine(s.Text), boxWidth-12))
+		if len(s.Missed) > 0 {
+			text += "  " + waitingStyle.Render(fmt.Sprintf("%d missed", len(s.Missed)))
+		}
+		boxContent.WriteString(text + "\n")
+	}
+	if len(m.schedules) > end {
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.schedules)-end)) + "\n")
+	}
+
+	if m.scheduleErr != "" {
+		boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.scheduleErr, boxWidth-6)) + "\n")
+	}
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  n: new  e: edit  d: delete  space: pause  r: run now  c: clear missed"))
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  esc: close"))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Scheduled Prompts ", boxContent.String(), boxWidth, ColorCyan)
+}
+
+// scheduleEditorContent is the editor's fields.
+func (m Model) scheduleEditorContent(boxWidth int) string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorPurple)).Bold(true)
+
+	var b strings.Builder
+	b.WriteString("  Session: " + scheduleSessionName(m.instances, m.scheduleDraft) + "\n")
+	tab := "main agent"
+	if m.scheduleDraft.Tab != "" {
+		tab = m.scheduleDraft.Tab
+	}
+	start := "no"
+	if m.scheduleDraft.StartIfStopped {
+		start = "yes"
+	}
+	b.WriteString(dimStyle.Render(fmt.Sprintf("  Tab: %s   Start if stopped: %s", tab, start)) + "\n\n")
+
+	b.WriteString("  " + labelStyle.Render("When") + "\n")
+	b.WriteString("  " + m.scheduleWhen.View() + "\n\n")
+
+	b.WriteString("  " + labelStyle.Render("Prompt") + "\n")
+	m.promptInput.SetWidth(boxWidth - 6)
+	for _, line := range strings.Split(m.promptInput.View(), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
+
+// formatScheduleTime shows a run's time briefly: the time alone today, the
+// weekday within the week, the date beyond.
+func formatScheduleTime(t, now time.Time) string {
+	switch {
+	case t.YearDay() == now.YearDay() && t.Year() == now.Year():
+		return t.Format("15:04")
+	case t.Sub(now) < 6*24*time.Hour:
+		return t.Format("Mon 15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
@@ -133,6 +133,8 @@ func (m Model) renderSessionRow(inst *session.Instance, index int, listWidth int
 		switch m.activityState[inst.ID] {
 		case session.ActivityBusy:
 			status = activeStyle.Render("●") // Orange - busy/working
+		case session.ActivityStalled:
+			status = stalledStyle.Render("◉") // Magenta - busy without progress
 		case session.ActivityWaiting:
 			status = waitingStyle.Render("●") // Yellow - waiting for input
 		default:
@@ -196,6 +198,12 @@ func (m Model) renderSessionRow(inst *session.Instance, index int, listWidth int
 		// else: multiple agents with status lines visible - icons shown on each status line
 	}
 
+	// Branch of a session in its own worktree
+	if label := branchLabel(inst, maxNameLen-len([]rune(name))); label != "" {
+		displayName += label
+		displayStyledName += dimStyle.Render(label)
+	}
+
 	// Render the row
 	if selected {
 		row.WriteString(m.renderSelectedRow(inst, displayName, displayStyledName, status, listWidth))
@@ -230,7 +238,7 @@ func (m Model) renderSessionRow(inst *session.Instance, index int, listWidth int
 				mainActivity = act
 			}
 		}
-		mainTextStyle := m.getActivityTextStyle(mainActivity, selected)
+		mainTextStyle := m.tabStatusStyle(inst.ID, m.mainWindowIndex[inst.ID], mainActivity, selected)
 
 		// Main agent status (window 0)
 		lastLine := m.getLastLine(inst)
@@ -258,12 +266,8 @@ func (m Model) renderSessionRow(inst *session.Instance, index int, listWidth int
 				}
 			}
 
-			// Show "Waiting" for waiting activity
-			if fwActivity == session.ActivityWaiting {
-				fwLine = "Waiting"
-			}
-
-			fwTextStyle := m.getActivityTextStyle(fwActivity, selected)
+			fwLine = m.tabStatusLine(inst.ID, fw.Index, fwActivity, fwLine)
+			fwTextStyle := m.tabStatusStyle(inst.ID, fw.Index, fwActivity, selected)
 
 			// Last item gets └─, others get ├─
 			connector := "├─"
@@ -286,6 +290,15 @@ func (m Model) renderSessionRow(inst *session.Instance, index int, listWidth int
 	return row.String()
 }
 
+// branchLabel tags a session working in a worktree of its own with its
+// branch, cut to the room left on the row; empty otherwise
+func branchLabel(inst *session.Instance, room int) string {
+	if inst.WorktreePath == "" || inst.Branch == "" || room < 6 {
+		return ""
+	}
+	return " ⎇ " + truncateRunes(inst.Branch, room-3)
+}
+
 // getStyledName applies color styling to a session name
 func (m Model) getStyledName(inst *session.Instance, name string) string {
 	style := lipgloss.NewStyle()
@@ -394,6 +407,8 @@ func (m Model) getActivityTextStyle(activity session.SessionActivity, selected b
 	switch activity {
 	case session.ActivityBusy:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color(ColorOrange))
+	case session.ActivityStalled:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(ColorMagenta))
 	case session.ActivityWaiting:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan))
 	default:
@@ -402,13 +417,39 @@ func (m Model) getActivityTextStyle(activity session.SessionActivity, selected b
 	}
 }
 
+// tabStatusLine is what a tab's status line shows: its state, when that
+// says more than its output — waiting, stalled, held by a rate limit.
+func (m Model) tabStatusLine(instID string, window int, activity session.SessionActivity, line string) string {
+	switch activity {
+	case session.ActivityWaiting:
+		return "Waiting"
+	case session.ActivityStalled:
+		return "Stalled"
+	}
+	if badge := m.rateLimitBadge(instID, window); badge != "" {
+		return badge
+	}
+	return line
+}
+
+// tabStatusStyle colours a tab's status line to go with tabStatusLine.
+func (m Model) tabStatusStyle(instID string, window int, activity session.SessionActivity, selected bool) lipgloss.Style {
+	if activity == session.ActivityIdle && m.rateLimitBadge(instID, window) != "" {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(ColorYellow))
+	}
+	return m.getActivityTextStyle(activity, selected)
+}
+
 // getLastLine returns the last line of output for a session
 func (m Model) getLastLine(inst *session.Instance) string {
-	// Check if waiting for input - show "Waiting" instead of the actual line
+	// Check if waiting for input, stalled or rate limited - show that instead of the actual line
 	if inst.Status == session.StatusRunning {
 		if winAct, ok := m.windowActivityState[inst.ID]; ok {
-			if act, ok := winAct[m.mainWindowIndex[inst.ID]]; ok && act == session.ActivityWaiting {
-				return "Waiting"
+			window := m.mainWindowIndex[inst.ID]
+			if act, ok := winAct[window]; ok {
+				if line := m.tabStatusLine(inst.ID, window, act, ""); line != "" {
+					return line
+				}
 			}
 		}
 	}
@@ -831,6 +872,8 @@ func (m Model) renderGroupedSessionRow(inst *session.Instance, index int, listWi
 		switch m.activityState[inst.ID] {
 		case session.ActivityBusy:
 			status = activeStyle.Render("●") // Orange - busy/working
+		case session.ActivityStalled:
+			status = stalledStyle.Render("◉") // Magenta - busy without progress
 		case session.ActivityWaiting:
 			status = waitingStyle.Render("●") // Yellow - waiting for input
 		default:
@@ -894,6 +937,12 @@ func (m Model) renderGroupedSessionRow(inst *session.Instance, index int, listWi
 		// else: multiple agents with status lines visible - icons shown on each status line
 	}
 
+	// Branch of a session in its own worktree
+	if label := branchLabel(inst, maxNameLen-len([]rune(name))); label != "" {
+		displayName += label
+		displayStyledName += dimStyle.Render(label)
+	}
+
 	// Render the row
 	treeStyle := dimStyle
 	if selected {
@@ -936,7 +985,7 @@ func (m Model) renderGroupedSessionRow(inst *session.Instance, index int, listWi
 				mainActivity = act
 			}
 		}
-		mainTextStyle := m.getActivityTextStyle(mainActivity, selected)
+		mainTextStyle := m.tabStatusStyle(inst.ID, m.mainWindowIndex[inst.ID], mainActivity, selected)
 
 		// Main agent status (window 0)
 		lastLine := m.getLastLine(inst)
@@ -963,7 +1012,8 @@ func (m Model) renderGroupedSessionRow(inst *session.Instance, index int, listWi
 					fwActivity = act
 				}
 			}
-			fwTextStyle := m.getActivityTextStyle(fwActivity, selected)
+			fwLine = m.tabStatusLine(inst.ID, fw.Index, fwActivity, fwLine)
+			fwTextStyle := m.tabStatusStyle(inst.ID, fw.Index, fwActivity, selected)
 
 			// Last item gets └─, others get ├─
 			connector := "├─"
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// snippetPickerView renders the snippet picker over the list
+func (m Model) snippetPickerView() string {
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorCyan)).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorLightGray))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorRed))
+
+	boxWidth := 72
+	if m.width > 110 {
+		boxWidth = 90
+	}
+
+	var boxContent strings.Builder
+	boxContent.WriteString("\n")
+
+	if m.snippetChosen != nil {
+		// Answering the chosen snippet's questions
+		boxContent.WriteString(fmt.Sprintf("  Snippet: %s", selectedStyle.Render(m.snippetChosen.Name)))
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("  (%d of %d)", len(m.snippetAnswers)+1, len(m.snippetAsk))) + "\n\n")
+		boxContent.WriteString("  " + m.snippetFilter.View() + "\n\n")
+		boxContent.WriteString(helpStyle.Render("  enter: next  esc: back"))
+		boxContent.WriteString("\n")
+		return m.renderOverlayDialog(" Snippets ", boxContent.String(), boxWidth, ColorPurple)
+	}
+
+	boxContent.WriteString("  " + m.snippetFilter.View() + "\n\n")
+	if len(m.snippets) == 0 {
+		boxContent.WriteString("  No snippets yet.\n")
+		boxContent.WriteString(dimStyle.Render("  Add them to ~/.config/agent-session-manager/snippets.json") + "\n")
+		boxContent.WriteString(dimStyle.Render("  or to .asmgr/snippets.json in the repository.") + "\n")
+	} else if len(m.snippetMatches) == 0 {
+		boxContent.WriteString(dimStyle.Render("  No matching snippets") + "\n")
+	}
+
+	visible := max(m.height-16, 5)
+	start := 0
+	if m.snippetCursor >= visible {
+		start = m.snippetCursor - visible + 1
+	}
+	end := min(start+visible, len(m.snippetMatches))
+	for row := start; row < end; row++ {
+		snippet := m.snippets[m.snippetMatches[row]]
+		prefix := "  "
+		style := normalStyle
+		if row == m.snippetCursor {
+			prefix = "▸ "
+			style = selectedStyle
+		}
+		line := fmt.Sprintf("  %s%s", prefix, style.Render(truncateRunes(snippet.Name, 28)))
+		if snippet.Description != "" {
+			line += "  " + dimStyle.Render(truncateRunes(snippet.Description, boxWidth-42))
+		}
+		line += "  " + dimStyle.Render("["+snippet.Source+"]")
+		boxContent.WriteString(line + "\n")
+	}
+	if len(m.snippetMatches) > end {
+		boxContent.WriteString(dimStyle.Render(fmt.Sprintf("      … %d more", len(m.snippetMatches)-end)) + "\n")
+	}
+
+	if m.snippetErr != "" {
+		boxContent.WriteString("\n  " + errStyle.Render(truncateRunes(m.snippetErr, boxWidth-6)) + "\n")
+	}
+	boxContent.WriteString("\n")
+	boxContent.WriteString(helpStyle.Render("  ↑/↓: select  enter: insert  esc: back to prompt"))
+	boxContent.WriteString("\n")
+
+	return m.renderOverlayDialog(" Snippets ", boxContent.String(), boxWidth, ColorPurple)
+}
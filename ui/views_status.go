@@ -85,12 +85,17 @@ func (m Model) buildStatusBar() string {
 	if m.showAgentIcons {
 		iconsStatus = onStyle.Render("ON")
 	}
+	notifyStatus := onStyle.Render("ON")
+	if inst := m.getSelectedInstance(); inst != nil && inst.Muted {
+		notifyStatus = offStyle.Render("OFF")
+	}
 	p5 := []string{
 		keyStyle.Render("l") + descStyle.Render(" compact ") + compactStatus,
 		keyStyle.Render("o") + descStyle.Render(" output ") + statusLinesStatus,
 		keyStyle.Render("I") + descStyle.Render(" icons ") + iconsStatus,
 		keyStyle.Render("^Y") + descStyle.Render(" yolo ") + autoYesStatus,
 		keyStyle.Render("v") + descStyle.Render(" split ") + splitStatus,
+		keyStyle.Render("M") + descStyle.Render(" notify ") + notifyStatus,
 	}
 
 	// Calculate widths and determine what fits
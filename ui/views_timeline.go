@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/izll/agent-session-manager/session"
+)
+
+// timelineRanges are the spans the timeline tab cycles through with F.
+var timelineRanges = []time.Duration{time.Hour, 4 * time.Hour, 12 * time.Hour, 24 * time.Hour}
+
+// timelineLabelWidth is the column the tab names take before their bands.
+const timelineLabelWidth = 14
+
+// getTimelineRange returns the span the timeline currently shows.
+func (m Model) getTimelineRange() time.Duration {
+	if m.timelineRange < 0 || m.timelineRange >= len(timelineRanges) {
+		return timelineRanges[0]
+	}
+	return timelineRanges[m.timelineRange]
+}
+
+// buildTimelineContent renders a session's activity over the selected range:
+// totals, one band per tab, and the latest transitions underneath.
+func (m Model) buildTimelineContent(inst *session.Instance, height, width int) string {
+	var b strings.Builder
+	if m.activityRecorder == nil {
+		b.WriteString(dimStyle.Render("  (no activity recorded yet)"))
+		return b.String()
+	}
+
+	now := time.Now()
+	from := now.Add(-m.getTimelineRange())
+	events := m.activityRecorder.Since(from)
+	summary := session.SummarizeActivity(events, inst.ID, from, now)
+
+	b.WriteString("  " + activeStyle.Render("Busy ") + formatDuration(summary.Busy))
+	b.WriteString(dimStyle.Render("  •  "))
+	b.WriteString(waitingStyle.Render("Waiting on you ") + formatDuration(summary.Waiting))
+	b.WriteString(dimStyle.Render("  •  "))
+	b.WriteString(waitingStyle.Render("Prompts ") + fmt.Sprintf("%d", summary.Prompts))
+	if summary.Stalled > 0 {
+		b.WriteString(dimStyle.Render("  •  "))
+		b.WriteString(stalledStyle.Render("Stalled ") + formatDuration(summary.Stalled))
+	}
+	b.WriteString("\n\n")
+	lines := 2
+
+	if len(summary.Tabs) == 0 {
+		b.WriteString(dimStyle.Render("  (no activity recorded in this range)"))
+		return b.String()
+	}
+
+	barWidth := width - timelineLabelWidth - 4
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	for _, tab := range summary.Tabs {
+		label := tab.Tab
+		if label == "" {
+			label = fmt.Sprintf("tab %d", tab.Window)
+		}
+		label = truncateRunes(label, timelineLabelWidth-1)
+		b.WriteString("  " + metaStyle.Render(label) + strings.Repeat(" ", timelineLabelWidth-displayWidth(label)))
+		b.WriteString(renderTimelineBand(tab.Spans, from, now, barWidth))
+		b.WriteString("\n")
+		lines++
+	}
+
+	// Time axis: start on the left, now on the right.
+	start := from.Format("15:04")
+	axisGap := barWidth - len(start) - len("now")
+	if axisGap < 1 {
+		axisGap = 1
+	}
+	b.WriteString("  " + strings.Repeat(" ", timelineLabelWidth) + dimStyle.Render(start+strings.Repeat(" ", axisGap)+"now"))
+	b.WriteString("\n")
+	b.WriteString("  " + strings.Repeat(" ", timelineLabelWidth) +
+		activeStyle.Render("█") + dimStyle.Render(" busy  ") +
+		stalledStyle.Render("█") + dimStyle.Render(" stalled  ") +
+		waitingStyle.Render("█") + dimStyle.Render(" waiting  ") +
+		idleStyle.Render("▁") + dimStyle.Render(" idle  ") +
+		dimStyle.Render("· not running"))
+	b.WriteString("\n\n")
+	lines += 3
+
+	// The transitions themselves, newest first, for as many as fit.
+	var recent []session.ActivityEvent
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.SessionID != inst.ID || e.Time.Before(from) {
+			continue
+		}
+		if e.Kind == session.EventActivity && e.From == e.To {
+			continue
+		}
+		recent = append(recent, e)
+	}
+	room := height - lines - 1
+	if room > 0 && len(recent) > 0 {
+		b.WriteString("  " + projectLabelStyle.Render("Recent"))
+		b.WriteString("\n")
+		for i := 0; i < len(recent) && i < room-1; i++ {
+			b.WriteString("  " + dimStyle.Render(recent[i].Time.Format("15:04:05")) + "  " + m.describeActivityEvent(recent[i]))
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+// renderTimelineBand draws spans as a row of cells. A cell shows the most
+// urgent state it overlaps, so a thirty-second wait is still visible on a
+// day-long band.
+func renderTimelineBand(spans []session.TimelineSpan, from, to time.Time, width int) string {
+	const (
+		cellNone = iota
+		cellIdle
+		cellBusy
+		cellStalled
+		cellWaiting
+	)
+	rank := map[session.SessionActivity]int{
+		session.ActivityIdle:    cellIdle,
+		session.ActivityBusy:    cellBusy,
+		session.ActivityStalled: cellStalled,
+		session.ActivityWaiting: cellWaiting,
+	}
+
+	cells := make([]int, width)
+	step := to.Sub(from) / time.Duration(width)
+	for _, span := range spans {
+		first := int(span.Start.Sub(from) / step)
+		last := int((span.End.Sub(from) - 1) / step)
+		for c := first; c <= last && c < width; c++ {
+			if c >= 0 && rank[span.Activity] > cells[c] {
+				cells[c] = rank[span.Activity]
+			}
+		}
+	}
+
+	render := func(kind, n int) string {
+		switch kind {
+		case cellWaiting:
+			return waitingStyle.Render(strings.Repeat("█", n))
+		case cellStalled:
+			return stalledStyle.Render(strings.Repeat("█", n))
+		case cellBusy:
+			return activeStyle.Render(strings.Repeat("█", n))
+		case cellIdle:
+			return idleStyle.Render(strings.Repeat("▁", n))
+		}
+		return dimStyle.Render(strings.Repeat("·", n))
+	}
+
+	// Runs of one kind are rendered together; styling cell by cell would put
+	// an escape sequence around every character.
+	var b strings.Builder
+	runStart := 0
+	for c := 1; c <= width; c++ {
+		if c == width || cells[c] != cells[runStart] {
+			b.WriteString(render(cells[runStart], c-runStart))
+			runStart = c
+		}
+	}
+	return b.String()
+}
+
+// describeActivityEvent is one line of the timeline's recent list.
+func (m Model) describeActivityEvent(e session.ActivityEvent) string {
+	tab := e.Tab
+	if tab == "" {
+		tab = "session"
+	}
+	switch e.Kind {
+	case session.EventStart:
+		return runningStyle.Render("started")
+	case session.EventStop:
+		return stoppedStyle.Render("stopped")
+	case session.EventExit:
+		return metaStyle.Render(tab) + " " + stoppedStyle.Render("exited")
+	}
+	text := metaStyle.Render(tab) + " " + m.getActivityTextStyle(e.From, false).Render(e.From.String()) +
+		dimStyle.Render(" → ") + m.getActivityTextStyle(e.To, false).Render(e.To.String())
+	if e.Held > 0 {
+		text += dimStyle.Render(" after " + formatDuration(e.Held))
+	}
+	return text
+}
+
+// formatDuration prints a duration the way people say it: 1h12m, 14m, 40s.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}